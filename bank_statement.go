@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Bank statement formats
+const (
+	formatCAMT053 = "camt053"
+	formatMT940   = "mt940"
+)
+
+// bankEntry is a credit to the university's account on a bank statement.
+type bankEntry struct {
+	// The bank's reference for the entry, when the statement has one
+	Reference   string
+	BookingDate time.Time
+	Amount      float64
+	Currency    string
+	Debtor      string
+	// Remittance information: what the payer wrote on the transfer
+	Remittance string
+}
+
+// bankStatement is one statement of an account. A file may hold several.
+type bankStatement struct {
+	ID      string
+	Account string
+	Entries []bankEntry
+	// Debits, reversals and entries not booked yet, none of them payments
+	Skipped int
+}
+
+// parseBankStatement reads a CAMT.053 or MT940 file and tells which it was.
+func parseBankStatement(data []byte) (string, []bankStatement, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		statements, err := parseCAMT053(data)
+		return formatCAMT053, statements, err
+	}
+	if bytes.Contains(trimmed, []byte(":20:")) && bytes.Contains(trimmed, []byte(":25:")) {
+		statements, err := parseMT940(data)
+		return formatMT940, statements, err
+	}
+	return "", nil, &importRowError{Line: 1, Message: "the file is not a CAMT.053 or MT940 statement"}
+}
+
+// statementText cleans up free text for storing, keeping at most max characters.
+func statementText(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) > max {
+		s = string([]rune(s)[:max])
+	}
+	return s
+}
+
+// statementAmount parses an amount written with a decimal point or, as in MT940,
+// a decimal comma.
+func statementAmount(s string) (float64, bool) {
+	amount, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+// camtDocument is the part of a CAMT.053 document reconciliation needs. Elements are
+// matched by local name, so any version of the message is read.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID      string      `xml:"Id"`
+	IBAN    string      `xml:"Acct>Id>IBAN"`
+	Other   string      `xml:"Acct>Id>Othr>Id"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is plain text in version 2 and a code element from version 4 on.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Ref         string            `xml:"NtryRef"`
+	Amount      camtAmount        `xml:"Amt"`
+	Indicator   string            `xml:"CdtDbtInd"`
+	Reversal    bool              `xml:"RvslInd"`
+	Status      camtStatus        `xml:"Sts"`
+	BookingDate camtDate          `xml:"BookgDt"`
+	ValueDate   camtDate          `xml:"ValDt"`
+	ServicerRef string            `xml:"AcctSvcrRef"`
+	Details     []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTransaction struct {
+	ServicerRef  string      `xml:"Refs>AcctSvcrRef"`
+	Amount       *camtAmount `xml:"Amt"`
+	TxAmount     *camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Debtor       string      `xml:"RltdPties>Dbtr>Nm"`
+	DebtorParty  string      `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Unstructured []string    `xml:"RmtInf>Ustrd"`
+	CreditorRefs []string    `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+}
+
+func (t camtTransaction) amount() *camtAmount {
+	return cmp.Or(t.Amount, t.TxAmount)
+}
+
+func (t camtTransaction) remittance() string {
+	return strings.Join(slices.Concat(t.CreditorRefs, t.Unstructured), " ")
+}
+
+func (d camtDate) time() time.Time {
+	if day, err := time.Parse(time.DateOnly, strings.TrimSpace(d.Date)); err == nil {
+		return day
+	}
+	if len(strings.TrimSpace(d.DateTime)) >= 10 {
+		if day, err := time.Parse(time.DateOnly, strings.TrimSpace(d.DateTime)[:10]); err == nil {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+// parseCAMT053 reads the booked credits of an ISO 20022 bank to customer statement.
+// An entry booking several transfers at once is split into one credit per transfer
+// when each has its own amount.
+func parseCAMT053(data []byte) ([]bankStatement, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, &importRowError{Line: syntaxErr.Line, Message: syntaxErr.Msg}
+		}
+		return nil, &importRowError{Line: 1, Message: err.Error()}
+	}
+	if len(doc.Statements) == 0 {
+		return nil, &importRowError{Line: 1, Message: "the document has no BkToCstmrStmt statement"}
+	}
+
+	var statements []bankStatement
+	for _, s := range doc.Statements {
+		statement := bankStatement{ID: strings.TrimSpace(s.ID), Account: strings.TrimSpace(cmp.Or(s.IBAN, s.Other))}
+		for n, e := range s.Entries {
+			status := strings.TrimSpace(cmp.Or(e.Status.Code, e.Status.Text))
+			if strings.TrimSpace(e.Indicator) != "CRDT" || e.Reversal || (status != "" && status != "BOOK") {
+				statement.Skipped++
+				continue
+			}
+			entry := bankEntry{
+				Reference:   strings.TrimSpace(cmp.Or(e.ServicerRef, e.Ref)),
+				BookingDate: cmp.Or(e.BookingDate.time(), e.ValueDate.time()),
+				Currency:    e.Amount.Currency,
+			}
+
+			split := len(e.Details) > 1
+			for _, t := range e.Details {
+				split = split && t.amount() != nil
+			}
+			if split {
+				for i, t := range e.Details {
+					credit := entry
+					credit.Reference = strings.TrimSpace(t.ServicerRef)
+					if credit.Reference == "" && entry.Reference != "" {
+						credit.Reference = fmt.Sprintf("%s/%d", entry.Reference, i+1)
+					}
+					amount, ok := statementAmount(t.amount().Value)
+					if !ok {
+						return nil, &importRowError{Line: 1, Message: fmt.Sprintf("statement %s entry %d: invalid amount %q", statement.ID, n+1, t.amount().Value)}
+					}
+					credit.Amount = amount
+					credit.Currency = cmp.Or(t.amount().Currency, entry.Currency)
+					credit.Debtor = cmp.Or(t.Debtor, t.DebtorParty)
+					credit.Remittance = t.remittance()
+					statement.Entries = append(statement.Entries, credit)
+				}
+				continue
+			}
+
+			amount, ok := statementAmount(e.Amount.Value)
+			if !ok {
+				return nil, &importRowError{Line: 1, Message: fmt.Sprintf("statement %s entry %d: invalid amount %q", statement.ID, n+1, e.Amount.Value)}
+			}
+			entry.Amount = amount
+			var remittance []string
+			for _, t := range e.Details {
+				entry.Reference = cmp.Or(strings.TrimSpace(t.ServicerRef), entry.Reference)
+				entry.Debtor = cmp.Or(entry.Debtor, t.Debtor, t.DebtorParty)
+				remittance = append(remittance, t.remittance())
+			}
+			entry.Remittance = strings.Join(remittance, " ")
+			statement.Entries = append(statement.Entries, entry)
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// An MT940 field starts a line with its tag, e.g. :61:
+var mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+
+// An MT940 statement line: value date, optional entry date, debit/credit mark,
+// the third letter of the currency, amount, transaction type and references
+var mt940StatementLine = regexp.MustCompile(`^(\d{6})(\d{4})?(C|D|RC|RD)[A-Z]?(\d+,\d*)([A-Z][A-Z0-9]{3})(.*)$`)
+
+// Subfields like ?20 of the structured :86: information some banks send
+var mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+
+type mt940Field struct {
+	Tag   string
+	Value string
+	Line  int
+}
+
+// mt940Fields splits the text block of an MT940 message into its fields. Lines
+// without a tag continue the field before them.
+func mt940Fields(data []byte) []mt940Field {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// SWIFT headers and trailers around the text block
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+		if line == "-" || strings.HasPrefix(line, "-}") || strings.HasPrefix(line, "{") {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{Tag: m[1], Value: line[len(m[0]):], Line: n})
+		} else if len(fields) > 0 {
+			fields[len(fields)-1].Value += "\n" + line
+		}
+	}
+	return fields
+}
+
+// mt940Information reads the :86: field of a statement line. Structured fields
+// give the remittance information in ?20-?29 and ?60-?63 and the payer in ?32-?33;
+// anything else is taken as remittance information. Lines are wrapped at a fixed
+// width, so they are joined without a space.
+func mt940Information(value string) (remittance, debtor string) {
+	value = strings.ReplaceAll(value, "\n", "")
+	if !strings.Contains(value, "?") {
+		return value, ""
+	}
+	var text, name []string
+	parts := mt940Subfield.Split(value, -1)
+	codes := mt940Subfield.FindAllStringSubmatch(value, -1)
+	for i, code := range codes {
+		n, _ := strconv.Atoi(code[1])
+		switch {
+		case n >= 20 && n <= 29, n >= 60 && n <= 63:
+			text = append(text, parts[i+1])
+		case n == 32 || n == 33:
+			name = append(name, parts[i+1])
+		}
+	}
+	return strings.Join(text, ""), strings.Join(name, "")
+}
+
+// parseMT940 reads the credits of SWIFT MT940 customer statements.
+func parseMT940(data []byte) ([]bankStatement, error) {
+	var statements []bankStatement
+	var statement *bankStatement
+	var currency string
+	// The :86: field that follows a credit belongs to it
+	var last *bankEntry
+	for _, f := range mt940Fields(data) {
+		if statement == nil && f.Tag != "20" {
+			return nil, &importRowError{Line: f.Line, Message: fmt.Sprintf("field :%s: before the :20: of a statement", f.Tag)}
+		}
+		switch f.Tag {
+		case "20":
+			statements = append(statements, bankStatement{ID: strings.TrimSpace(f.Value)})
+			statement, currency, last = &statements[len(statements)-1], "", nil
+		case "25":
+			statement.Account = strings.TrimSpace(f.Value)
+		case "28C":
+			statement.ID += "/" + strings.TrimSpace(f.Value)
+		case "60F", "60M":
+			// D or C, the date and then the currency
+			if v := strings.TrimSpace(f.Value); len(v) >= 10 {
+				currency = v[7:10]
+			}
+		case "61":
+			last = nil
+			first, _, _ := strings.Cut(f.Value, "\n")
+			m := mt940StatementLine.FindStringSubmatch(strings.TrimSpace(first))
+			if m == nil {
+				return nil, &importRowError{Line: f.Line, Message: "invalid :61: statement line"}
+			}
+			if m[3] != "C" {
+				statement.Skipped++
+				continue
+			}
+			date, err := time.Parse("060102", m[1])
+			if err != nil {
+				return nil, &importRowError{Line: f.Line, Message: "invalid value date " + m[1]}
+			}
+			amount, ok := statementAmount(m[4])
+			if !ok {
+				return nil, &importRowError{Line: f.Line, Message: "invalid amount " + m[4]}
+			}
+			// The account owner's reference, then // and the bank's reference
+			owner, bank, _ := strings.Cut(m[6], "//")
+			if strings.TrimSpace(owner) == "NONREF" {
+				owner = ""
+			}
+			statement.Entries = append(statement.Entries, bankEntry{
+				Reference:   strings.TrimSpace(cmp.Or(bank, owner)),
+				BookingDate: date,
+				Amount:      amount,
+				Currency:    currency,
+			})
+			last = &statement.Entries[len(statement.Entries)-1]
+		case "86":
+			if last != nil {
+				last.Remittance, last.Debtor = mt940Information(f.Value)
+				last = nil
+			}
+		}
+	}
+	if len(statements) == 0 {
+		return nil, &importRowError{Line: 1, Message: "the file has no MT940 statement"}
+	}
+	return statements, nil
+}
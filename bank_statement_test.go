@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMT940(t *testing.T) {
+	const statement = "{1:F01BANKTRISAXXX0000000000}{2:O9401200251015BANKTRISAXXXN}{4:\r\n" +
+		":20:STMT151025\r\n" +
+		":25:TR330006100519786457841326\r\n" +
+		":28C:00288/001\r\n" +
+		":60F:C251014TRY10000,00\r\n" +
+		":61:2510151015C1000,00NTRFNONREF//B5101500001\r\n" +
+		":86:166?00TRANSFER?20RF29 2207 0006 07?2110 1?32AYSE YILMAZ\r\n" +
+		":61:2510151015D50,00NCHGNONREF//B5101500002\r\n" +
+		":86:Bank charges\r\n" +
+		":61:2510151015C250,5NTRFREF123\r\n" +
+		":86:Tuition 22070006072 Fal\r\n" +
+		"l2025\r\n" +
+		":62F:C251015TRY11200,50\r\n" +
+		"-}"
+
+	format, statements, err := parseBankStatement([]byte(statement))
+	if err != nil || format != formatMT940 {
+		t.Fatalf("parseBankStatement = %q, %v", format, err)
+	}
+	day := time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC)
+	want := []bankStatement{{
+		ID:      "STMT151025/00288/001",
+		Account: "TR330006100519786457841326",
+		Entries: []bankEntry{
+			{Reference: "B5101500001", BookingDate: day, Amount: 1000, Currency: "TRY", Debtor: "AYSE YILMAZ", Remittance: "RF29 2207 0006 0710 1"},
+			{Reference: "REF123", BookingDate: day, Amount: 250.5, Currency: "TRY", Remittance: "Tuition 22070006072 Fall2025"},
+		},
+		Skipped: 1,
+	}}
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("statements = %+v", statements)
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	// Version 8 puts the status in a code element and the payer in a party
+	const statement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>S1</Id>
+      <Acct><Id><Othr><Id>12345678</Id></Othr></Id></Acct>
+      <Ntry>
+        <NtryRef>N1</NtryRef>
+        <Amt Ccy="EUR">300.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2025-10-15T09:30:00</DtTm></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">100.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Dbtr><Pty><Nm>First Payer</Nm></Pty></Dbtr></RltdPties>
+            <RmtInf><Ustrd>one</Ustrd></RmtInf>
+          </TxDtls>
+          <TxDtls>
+            <Refs><AcctSvcrRef>TX2</AcctSvcrRef></Refs>
+            <Amt Ccy="EUR">200.00</Amt>
+            <RmtInf><Ustrd>two</Ustrd><Ustrd>lines</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <RvslInd>true</RvslInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+	format, statements, err := parseBankStatement([]byte(statement))
+	if err != nil || format != formatCAMT053 {
+		t.Fatalf("parseBankStatement = %q, %v", format, err)
+	}
+	day := time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC)
+	want := []bankStatement{{
+		ID:      "S1",
+		Account: "12345678",
+		Entries: []bankEntry{
+			{Reference: "N1/1", BookingDate: day, Amount: 100, Currency: "EUR", Debtor: "First Payer", Remittance: "one"},
+			{Reference: "TX2", BookingDate: day, Amount: 200, Currency: "EUR", Remittance: "two lines"},
+		},
+		Skipped: 1,
+	}}
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("statements = %+v", statements)
+	}
+}
+
+func TestParseBankStatementErrors(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		line    int
+	}{
+		{"student_no,amount\n22070006071,100\n", 1},
+		{"<Document>\n<BkToCstmrStmt>\n</Stmt>", 3},
+		{"<Document><Other/></Document>", 1},
+		{":20:S1\n:25:ACC\n:61:not a statement line\n", 3},
+	} {
+		_, _, err := parseBankStatement([]byte(tt.content))
+		var fileErr *importRowError
+		if !errors.As(err, &fileErr) || fileErr.Line != tt.line {
+			t.Errorf("%q: got %v, want an error on line %d", tt.content, err, tt.line)
+		}
+	}
+}
+
+func TestReferenceCandidates(t *testing.T) {
+	words := remittanceWords("Tuition, ref: rf29 2207 0006 0710 1 Fall2025; RF18 5390 0754 7034 and RF00 1234")
+	if got := referenceCandidates(words); !reflect.DeepEqual(got, []string{"RF292207000607101", "RF18539007547034"}) {
+		t.Errorf("referenceCandidates = %q", got)
+	}
+}
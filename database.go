@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pool settings read from the environment, falling back to these defaults
+const (
+	defaultMaxConns          = 10
+	defaultMinConns          = 1
+	defaultMaxConnLifetime   = time.Hour
+	defaultMaxConnIdleTime   = 30 * time.Minute
+	defaultHealthCheckPeriod = 30 * time.Second
+	defaultConnectTimeout    = 5 * time.Second
+	defaultConnectRetries    = 5
+	defaultConnectBackoff    = time.Second
+	maxConnectBackoff        = 30 * time.Second
+)
+
+// newPoolConfig parses DATABASE_CONNECTION and applies the DB_* pool settings on top of it.
+func newPoolConfig() (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(os.Getenv("DATABASE_CONNECTION"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_CONNECTION: %w", err)
+	}
+
+	maxConns, err := envInt("DB_MAX_CONNS", defaultMaxConns)
+	if err != nil {
+		return nil, err
+	}
+	minConns, err := envInt("DB_MIN_CONNS", defaultMinConns)
+	if err != nil {
+		return nil, err
+	}
+	if minConns > maxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS (%d) cannot be greater than DB_MAX_CONNS (%d)", minConns, maxConns)
+	}
+	config.MaxConns = int32(maxConns)
+	config.MinConns = int32(minConns)
+
+	if config.MaxConnLifetime, err = envDuration("DB_MAX_CONN_LIFETIME", defaultMaxConnLifetime); err != nil {
+		return nil, err
+	}
+	if config.MaxConnIdleTime, err = envDuration("DB_MAX_CONN_IDLE_TIME", defaultMaxConnIdleTime); err != nil {
+		return nil, err
+	}
+	// Dead connections (e.g. after a database restart) are dropped by the health check
+	// and replaced on the next acquire, so the server recovers without a restart.
+	if config.HealthCheckPeriod, err = envDuration("DB_HEALTH_CHECK_PERIOD", defaultHealthCheckPeriod); err != nil {
+		return nil, err
+	}
+	if config.ConnConfig.ConnectTimeout, err = envDuration("DB_CONNECT_TIMEOUT", defaultConnectTimeout); err != nil {
+		return nil, err
+	}
+
+	config.ConnConfig.Tracer = queryTracer{}
+	return config, nil
+}
+
+// connectDatabase opens the connection pool and waits for the database to answer,
+// retrying with exponential backoff before giving up.
+func connectDatabase(ctx context.Context) (*pgxpool.Pool, error) {
+	config, err := newPoolConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	retries, err := envInt("DB_CONNECT_RETRIES", defaultConnectRetries)
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := envDuration("DB_CONNECT_BACKOFF", defaultConnectBackoff)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create connection pool: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = pool.Ping(ctx)
+		if err == nil {
+			return pool, nil
+		}
+		if attempt > retries {
+			pool.Close()
+			return nil, fmt.Errorf("database unreachable at %s:%d after %d attempts: %w",
+				config.ConnConfig.Host, config.ConnConfig.Port, attempt, err)
+		}
+
+		log.Printf("database not ready (attempt %d/%d): %v; retrying in %s", attempt, retries+1, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			pool.Close()
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+	}
+	return n, nil
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a duration such as 30s or 5m, got %q", key, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,137 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Querier interface {
+	AddBankPartner(ctx context.Context, arg AddBankPartnerParams) (BankPartner, error)
+	AddBankTransaction(ctx context.Context, arg AddBankTransactionParams) (BankTransaction, error)
+	AddImportJobError(ctx context.Context, arg AddImportJobErrorParams) error
+	AddImportJobFile(ctx context.Context, arg AddImportJobFileParams) error
+	AddNewStudent(ctx context.Context, arg AddNewStudentParams) error
+	AddPayment(ctx context.Context, arg AddPaymentParams) (Payment, error)
+	AddPaymentReference(ctx context.Context, arg AddPaymentReferenceParams) (PaymentReference, error)
+	AddReceipt(ctx context.Context, arg AddReceiptParams) (Receipt, error)
+	AddReceiptAllocation(ctx context.Context, arg AddReceiptAllocationParams) error
+	AddSettlement(ctx context.Context, arg AddSettlementParams) (Settlement, error)
+	AddStudentAccount(ctx context.Context, arg AddStudentAccountParams) error
+	AddTuitionChange(ctx context.Context, arg AddTuitionChangeParams) error
+	AddTuitionItem(ctx context.Context, arg AddTuitionItemParams) (TuitionItem, error)
+	AddTuitionToOneStudent(ctx context.Context, arg AddTuitionToOneStudentParams) (Tuition, error)
+	AmendTuition(ctx context.Context, arg AmendTuitionParams) error
+	// A queued job is cancelled right away; a running one stops at its next check.
+	CancelImportJob(ctx context.Context, jobID int32) error
+	CancelTuition(ctx context.Context, tuitionID int32) error
+	// Takes the oldest queued job, or a running one whose heartbeat is older than
+	// jobLease.
+	ClaimImportJob(ctx context.Context) (ImportJob, error)
+	ClearActiveTerm(ctx context.Context) error
+	// Resolves or dismisses a queued transaction; no row comes back when it is not queued.
+	CloseBankTransaction(ctx context.Context, arg CloseBankTransactionParams) (BankTransaction, error)
+	CountBankTransactions(ctx context.Context, status pgtype.Text) (int64, error)
+	CountBankTransactionsByKey(ctx context.Context, entryKey string) (int64, error)
+	CountImportJobs(ctx context.Context, status pgtype.Text) (int64, error)
+	CountPaymentReferences(ctx context.Context, studentNo string) (int64, error)
+	CountPaymentsForTerm(ctx context.Context, arg CountPaymentsForTermParams) (int64, error)
+	// Enrolled students a schedule covers who already have a tuition for the term.
+	CountScheduledBilled(ctx context.Context, term string) (int64, error)
+	CountSettlements(ctx context.Context, arg CountSettlementsParams) (int64, error)
+	CountStudents(ctx context.Context, arg CountStudentsParams) (int64, error)
+	CountTuitions(ctx context.Context, arg CountTuitionsParams) (int64, error)
+	CreateFeeSchedule(ctx context.Context, arg CreateFeeScheduleParams) (FeeSchedule, error)
+	CreateFeeType(ctx context.Context, arg CreateFeeTypeParams) (FeeType, error)
+	CreateImportJob(ctx context.Context, arg CreateImportJobParams) (ImportJob, error)
+	CreateTerm(ctx context.Context, arg CreateTermParams) (Term, error)
+	DeactivateStudent(ctx context.Context, studentNo string) error
+	DecreasePaymentLimit(ctx context.Context, studentNo string) error
+	DeleteFeeSchedule(ctx context.Context, scheduleID int32) error
+	DeleteImportJobFile(ctx context.Context, jobID int32) error
+	DeleteTerm(ctx context.Context, code string) error
+	FinishImportJob(ctx context.Context, arg FinishImportJobParams) error
+	GetAccountByStudentNo(ctx context.Context, studentNo string) (Account, error)
+	GetActiveTerm(ctx context.Context) (Term, error)
+	GetBankPartner(ctx context.Context, code string) (BankPartner, error)
+	GetBankTransaction(ctx context.Context, transactionID int32) (BankTransaction, error)
+	GetFeeSchedule(ctx context.Context, scheduleID int32) (FeeSchedule, error)
+	GetFeeType(ctx context.Context, code string) (FeeType, error)
+	GetImportJob(ctx context.Context, jobID int32) (ImportJob, error)
+	GetImportJobFile(ctx context.Context, jobID int32) ([]byte, error)
+	GetPaymentReference(ctx context.Context, reference string) (PaymentReference, error)
+	GetReceipt(ctx context.Context, receiptNo string) (GetReceiptRow, error)
+	GetSettlement(ctx context.Context, settlementID int32) (Settlement, error)
+	GetSettlementByDay(ctx context.Context, arg GetSettlementByDayParams) (Settlement, error)
+	GetStudent(ctx context.Context, studentNo string) (Student, error)
+	GetStudentById(ctx context.Context, studentNo string) (GetStudentByIdRow, error)
+	GetStudentDailyLimit(ctx context.Context, studentNo string) (int32, error)
+	GetStudentPaymentReference(ctx context.Context, arg GetStudentPaymentReferenceParams) (PaymentReference, error)
+	GetTerm(ctx context.Context, code string) (Term, error)
+	GetTuition(ctx context.Context, tuitionID int32) (Tuition, error)
+	GetTuitionByTerm(ctx context.Context, arg GetTuitionByTermParams) ([]GetTuitionByTermRow, error)
+	HeartbeatImportJob(ctx context.Context, jobID int32) error
+	ListBankPartners(ctx context.Context) ([]BankPartner, error)
+	ListBankTransactions(ctx context.Context, arg ListBankTransactionsParams) ([]BankTransaction, error)
+	// A null filter matches every schedule.
+	ListFeeSchedules(ctx context.Context, arg ListFeeSchedulesParams) ([]FeeSchedule, error)
+	ListFeeTypes(ctx context.Context) ([]FeeType, error)
+	ListImportJobErrors(ctx context.Context, jobID int32) ([]ImportJobError, error)
+	ListImportJobs(ctx context.Context, arg ListImportJobsParams) ([]ImportJob, error)
+	// The payments a partner took in [from_time, to_time), with their receipts.
+	ListPartnerPayments(ctx context.Context, arg ListPartnerPaymentsParams) ([]ListPartnerPaymentsRow, error)
+	ListPaymentsByStudent(ctx context.Context, studentNo string) ([]Payment, error)
+	ListReceiptAllocations(ctx context.Context, receiptNo string) ([]ReceiptAllocation, error)
+	// The scheduled items of every enrolled student not yet billed for the term,
+	// grouped by student in payment order.
+	ListScheduledCharges(ctx context.Context, term string) ([]ListScheduledChargesRow, error)
+	ListSettlements(ctx context.Context, arg ListSettlementsParams) ([]Settlement, error)
+	ListStudentTuitionChanges(ctx context.Context, studentNo string) ([]TuitionChange, error)
+	// Students whose number starts with prefix; a null filter matches every student.
+	ListStudents(ctx context.Context, arg ListStudentsParams) ([]Student, error)
+	// Terms in calendar order; terms without dates come last.
+	ListTerms(ctx context.Context) ([]Term, error)
+	// The students owing the most over all their active tuitions.
+	ListTopDebtors(ctx context.Context, limit int32) ([]ListTopDebtorsRow, error)
+	ListTuitionChanges(ctx context.Context, tuitionID int32) ([]TuitionChange, error)
+	// Items in the order payments settle them.
+	ListTuitionItems(ctx context.Context, tuitionID int32) ([]ListTuitionItemsRow, error)
+	// A null filter matches every tuition.
+	ListTuitions(ctx context.Context, arg ListTuitionsParams) ([]Tuition, error)
+	ListTuitionsByStudent(ctx context.Context, studentNo string) ([]Tuition, error)
+	// Active tuitions with an amount outstanding, in report order. Pages are keyed by
+	// the last row of the previous page, so an export only ever holds one page.
+	ListUnpaidReport(ctx context.Context, arg ListUnpaidReportParams) ([]ListUnpaidReportRow, error)
+	LockStudentBalance(ctx context.Context, studentNo string) (float64, error)
+	LockTuition(ctx context.Context, tuitionID int32) (Tuition, error)
+	ReactivateStudent(ctx context.Context, studentNo string) error
+	// Records the totals the partner reported for the day; reporting again replaces them.
+	ReportSettlement(ctx context.Context, arg ReportSettlementParams) (Settlement, error)
+	ResetTuitionTotal(ctx context.Context, arg ResetTuitionTotalParams) error
+	// Settling an item leaves its amount alone.
+	SetTuitionItemPaid(ctx context.Context, arg SetTuitionItemPaidParams) error
+	// A cancelled tuition is left alone and returns no rows.
+	SetTuitionOutstanding(ctx context.Context, arg SetTuitionOutstandingParams) (Tuition, error)
+	SummarizePaymentsByChannel(ctx context.Context, arg SummarizePaymentsByChannelParams) ([]SummarizePaymentsByChannelRow, error)
+	// Days are UTC days.
+	SummarizePaymentsByDay(ctx context.Context, arg SummarizePaymentsByDayParams) ([]SummarizePaymentsByDayRow, error)
+	// Students with an active tuition, and how many of them owe nothing.
+	SummarizeStudents(ctx context.Context) (SummarizeStudentsRow, error)
+	// Billing per term with the payments received for it in the range. A student is
+	// fully paid for a term once nothing of it is outstanding.
+	SummarizeTerms(ctx context.Context, arg SummarizeTermsParams) ([]SummarizeTermsRow, error)
+	UnpaidTuitions(ctx context.Context, arg UnpaidTuitionsParams) ([]UnpaidTuitionsRow, error)
+	UpdateBalance(ctx context.Context, arg UpdateBalanceParams) error
+	UpdateFeeSchedule(ctx context.Context, arg UpdateFeeScheduleParams) error
+	UpdateFeeType(ctx context.Context, arg UpdateFeeTypeParams) error
+	UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) error
+	UpdateStudent(ctx context.Context, arg UpdateStudentParams) error
+	UpdateTerm(ctx context.Context, arg UpdateTermParams) error
+	UpdateTuitionItem(ctx context.Context, arg UpdateTuitionItemParams) error
+}
+
+var _ Querier = (*Queries)(nil)
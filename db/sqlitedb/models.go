@@ -0,0 +1,207 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+
+package sqlitedb
+
+import (
+	pgxtype "github.com/jackc/pgx/v5/pgtype"
+)
+
+type Account struct {
+	AccountNo      int32
+	StudentNo      string
+	HashedPassword string
+}
+
+type BankPartner struct {
+	Code      string
+	Name      string
+	CreatedAt pgxtype.Timestamptz
+}
+
+type BankTransaction struct {
+	TransactionID int32
+	EntryKey      string
+	Format        string
+	StatementID   string
+	Account       string
+	BankReference string
+	BookingDate   pgxtype.Date
+	Amount        float64
+	Currency      string
+	Debtor        string
+	Remittance    string
+	Status        string
+	Issue         string
+	Detail        string
+	StudentNo     pgxtype.Text
+	Term          pgxtype.Text
+	PaymentID     pgxtype.Int4
+	ResolvedBy    string
+	CreatedAt     pgxtype.Timestamptz
+	ResolvedAt    pgxtype.Timestamptz
+}
+
+type FeeSchedule struct {
+	ScheduleID     int32
+	Program        string
+	EnrollmentYear int32
+	Term           string
+	FeeType        string
+	Amount         float64
+	CreatedAt      pgxtype.Timestamptz
+}
+
+type FeeType struct {
+	Code      string
+	Name      string
+	Priority  int32
+	CreatedAt pgxtype.Timestamptz
+}
+
+type ImportJob struct {
+	JobID           int32
+	Kind            string
+	Status          string
+	Filename        string
+	Format          string
+	Mode            string
+	DryRun          bool
+	Mapping         string
+	TotalRows       int32
+	ProcessedRows   int32
+	Imported        int32
+	Invalid         int32
+	Committed       bool
+	Failure         string
+	CancelRequested bool
+	CreatedBy       string
+	CreatedAt       pgxtype.Timestamptz
+	StartedAt       pgxtype.Timestamptz
+	HeartbeatAt     pgxtype.Timestamptz
+	FinishedAt      pgxtype.Timestamptz
+	Created         int32
+	Updated         int32
+	Skipped         int32
+}
+
+type ImportJobError struct {
+	ErrorID int32
+	JobID   int32
+	Line    int32
+	Field   string
+	Message string
+}
+
+type ImportJobFile struct {
+	JobID   int32
+	Payload []byte
+}
+
+type Payment struct {
+	PaymentID    int32
+	StudentNo    string
+	Term         string
+	Amount       float64
+	BalanceAfter float64
+	CreatedAt    pgxtype.Timestamptz
+	Channel      string
+	Partner      pgxtype.Text
+}
+
+type PaymentReference struct {
+	Reference string
+	StudentNo string
+	Term      string
+	CreatedAt pgxtype.Timestamptz
+}
+
+type Receipt struct {
+	ReceiptNo        string
+	PaymentID        int32
+	VerificationCode string
+	OutstandingAfter float64
+	CreatedAt        pgxtype.Timestamptz
+}
+
+type ReceiptAllocation struct {
+	AllocationID int32
+	ReceiptNo    string
+	FeeType      string
+	Description  string
+	Amount       float64
+}
+
+type Settlement struct {
+	SettlementID   int32
+	Partner        string
+	BusinessDate   pgxtype.Date
+	PaymentCount   int32
+	TotalAmount    float64
+	Status         string
+	ReportedCount  pgxtype.Int4
+	ReportedAmount pgxtype.Float8
+	ClosedBy       string
+	CreatedAt      pgxtype.Timestamptz
+	ReportedAt     pgxtype.Timestamptz
+}
+
+type Student struct {
+	StudentNo         string
+	Balance           float64
+	DailyPaymentLimit int32
+	DeactivatedAt     pgxtype.Timestamptz
+	FirstName         string
+	LastName          string
+	Email             string
+	Phone             string
+	Faculty           string
+	Department        string
+	Program           string
+	EnrollmentYear    pgxtype.Int4
+	EnrollmentStatus  string
+}
+
+type Term struct {
+	Code           string
+	Name           string
+	StartDate      pgxtype.Date
+	EndDate        pgxtype.Date
+	PaymentDueDate pgxtype.Date
+	Active         bool
+	CreatedAt      pgxtype.Timestamptz
+}
+
+type Tuition struct {
+	TuitionID    int32
+	StudentNo    string
+	Term         string
+	TuitionTotal float64
+	BilledTotal  float64
+	Status       string
+	CreatedAt    pgxtype.Timestamptz
+}
+
+type TuitionChange struct {
+	ChangeID          int32
+	TuitionID         int32
+	Action            string
+	OldTerm           string
+	NewTerm           string
+	OldAmount         float64
+	NewAmount         float64
+	BalanceAdjustment float64
+	Reason            string
+	ChangedBy         string
+	CreatedAt         pgxtype.Timestamptz
+}
+
+type TuitionItem struct {
+	ItemID      int32
+	TuitionID   int32
+	FeeType     string
+	Description string
+	Amount      float64
+	AmountPaid  float64
+}
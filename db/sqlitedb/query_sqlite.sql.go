@@ -0,0 +1,3163 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: query_sqlite.sql
+
+package sqlitedb
+
+import (
+	"context"
+
+	pgxtype "github.com/jackc/pgx/v5/pgtype"
+)
+
+const addBankPartner = `-- name: AddBankPartner :one
+INSERT INTO bank_partner (code, name)
+VALUES (?1, ?2)
+RETURNING code, name, created_at
+`
+
+type AddBankPartnerParams struct {
+	Code string
+	Name string
+}
+
+func (q *Queries) AddBankPartner(ctx context.Context, arg AddBankPartnerParams) (BankPartner, error) {
+	row := q.db.QueryRowContext(ctx, addBankPartner, arg.Code, arg.Name)
+	var i BankPartner
+	err := row.Scan(&i.Code, &i.Name, &i.CreatedAt)
+	return i, err
+}
+
+const addBankTransaction = `-- name: AddBankTransaction :one
+INSERT INTO bank_transaction (
+    entry_key, format, statement_id, account, bank_reference, booking_date, amount, currency,
+    debtor, remittance, status, issue, detail, student_no, term, payment_id
+)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16)
+RETURNING transaction_id, entry_key, format, statement_id, account, bank_reference, booking_date, amount, currency, debtor, remittance, status, issue, detail, student_no, term, payment_id, resolved_by, created_at, resolved_at
+`
+
+type AddBankTransactionParams struct {
+	EntryKey      string
+	Format        string
+	StatementID   string
+	Account       string
+	BankReference string
+	BookingDate   pgxtype.Date
+	Amount        float64
+	Currency      string
+	Debtor        string
+	Remittance    string
+	Status        string
+	Issue         string
+	Detail        string
+	StudentNo     pgxtype.Text
+	Term          pgxtype.Text
+	PaymentID     pgxtype.Int4
+}
+
+func (q *Queries) AddBankTransaction(ctx context.Context, arg AddBankTransactionParams) (BankTransaction, error) {
+	row := q.db.QueryRowContext(ctx, addBankTransaction,
+		arg.EntryKey,
+		arg.Format,
+		arg.StatementID,
+		arg.Account,
+		arg.BankReference,
+		arg.BookingDate,
+		arg.Amount,
+		arg.Currency,
+		arg.Debtor,
+		arg.Remittance,
+		arg.Status,
+		arg.Issue,
+		arg.Detail,
+		arg.StudentNo,
+		arg.Term,
+		arg.PaymentID,
+	)
+	var i BankTransaction
+	err := row.Scan(
+		&i.TransactionID,
+		&i.EntryKey,
+		&i.Format,
+		&i.StatementID,
+		&i.Account,
+		&i.BankReference,
+		&i.BookingDate,
+		&i.Amount,
+		&i.Currency,
+		&i.Debtor,
+		&i.Remittance,
+		&i.Status,
+		&i.Issue,
+		&i.Detail,
+		&i.StudentNo,
+		&i.Term,
+		&i.PaymentID,
+		&i.ResolvedBy,
+		&i.CreatedAt,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const addImportJobError = `-- name: AddImportJobError :exec
+INSERT INTO import_job_error (job_id, line, field, message)
+VALUES (?1, ?2, ?3, ?4)
+`
+
+type AddImportJobErrorParams struct {
+	JobID   int32
+	Line    int32
+	Field   string
+	Message string
+}
+
+func (q *Queries) AddImportJobError(ctx context.Context, arg AddImportJobErrorParams) error {
+	_, err := q.db.ExecContext(ctx, addImportJobError,
+		arg.JobID,
+		arg.Line,
+		arg.Field,
+		arg.Message,
+	)
+	return err
+}
+
+const addImportJobFile = `-- name: AddImportJobFile :exec
+INSERT INTO import_job_file (job_id, payload)
+VALUES (?1, ?2)
+`
+
+type AddImportJobFileParams struct {
+	JobID   int32
+	Payload []byte
+}
+
+func (q *Queries) AddImportJobFile(ctx context.Context, arg AddImportJobFileParams) error {
+	_, err := q.db.ExecContext(ctx, addImportJobFile, arg.JobID, arg.Payload)
+	return err
+}
+
+const addNewStudent = `-- name: AddNewStudent :exec
+INSERT INTO student(student_no,balance)
+VALUES (?1,?2)
+RETURNING student_no
+`
+
+type AddNewStudentParams struct {
+	StudentNo string
+	Balance   float64
+}
+
+func (q *Queries) AddNewStudent(ctx context.Context, arg AddNewStudentParams) error {
+	_, err := q.db.ExecContext(ctx, addNewStudent, arg.StudentNo, arg.Balance)
+	return err
+}
+
+const addPayment = `-- name: AddPayment :one
+INSERT INTO payment(student_no,term,amount,balance_after,channel,partner)
+VALUES (?1,?2,?3,?4,?5,?6)
+RETURNING payment_id, student_no, term, amount, balance_after, created_at, channel, partner
+`
+
+type AddPaymentParams struct {
+	StudentNo    string
+	Term         string
+	Amount       float64
+	BalanceAfter float64
+	Channel      string
+	Partner      pgxtype.Text
+}
+
+func (q *Queries) AddPayment(ctx context.Context, arg AddPaymentParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, addPayment,
+		arg.StudentNo,
+		arg.Term,
+		arg.Amount,
+		arg.BalanceAfter,
+		arg.Channel,
+		arg.Partner,
+	)
+	var i Payment
+	err := row.Scan(
+		&i.PaymentID,
+		&i.StudentNo,
+		&i.Term,
+		&i.Amount,
+		&i.BalanceAfter,
+		&i.CreatedAt,
+		&i.Channel,
+		&i.Partner,
+	)
+	return i, err
+}
+
+const addPaymentReference = `-- name: AddPaymentReference :one
+INSERT INTO payment_reference (reference, student_no, term)
+VALUES (?1, ?2, ?3)
+RETURNING reference, student_no, term, created_at
+`
+
+type AddPaymentReferenceParams struct {
+	Reference string
+	StudentNo string
+	Term      string
+}
+
+func (q *Queries) AddPaymentReference(ctx context.Context, arg AddPaymentReferenceParams) (PaymentReference, error) {
+	row := q.db.QueryRowContext(ctx, addPaymentReference, arg.Reference, arg.StudentNo, arg.Term)
+	var i PaymentReference
+	err := row.Scan(
+		&i.Reference,
+		&i.StudentNo,
+		&i.Term,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const addReceipt = `-- name: AddReceipt :one
+INSERT INTO receipt (receipt_no, payment_id, verification_code, outstanding_after)
+VALUES (?1, ?2, ?3, ?4)
+RETURNING receipt_no, payment_id, verification_code, outstanding_after, created_at
+`
+
+type AddReceiptParams struct {
+	ReceiptNo        string
+	PaymentID        int32
+	VerificationCode string
+	OutstandingAfter float64
+}
+
+func (q *Queries) AddReceipt(ctx context.Context, arg AddReceiptParams) (Receipt, error) {
+	row := q.db.QueryRowContext(ctx, addReceipt,
+		arg.ReceiptNo,
+		arg.PaymentID,
+		arg.VerificationCode,
+		arg.OutstandingAfter,
+	)
+	var i Receipt
+	err := row.Scan(
+		&i.ReceiptNo,
+		&i.PaymentID,
+		&i.VerificationCode,
+		&i.OutstandingAfter,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const addReceiptAllocation = `-- name: AddReceiptAllocation :exec
+INSERT INTO receipt_allocation (receipt_no, fee_type, description, amount)
+VALUES (?1, ?2, ?3, ?4)
+`
+
+type AddReceiptAllocationParams struct {
+	ReceiptNo   string
+	FeeType     string
+	Description string
+	Amount      float64
+}
+
+func (q *Queries) AddReceiptAllocation(ctx context.Context, arg AddReceiptAllocationParams) error {
+	_, err := q.db.ExecContext(ctx, addReceiptAllocation,
+		arg.ReceiptNo,
+		arg.FeeType,
+		arg.Description,
+		arg.Amount,
+	)
+	return err
+}
+
+const addSettlement = `-- name: AddSettlement :one
+INSERT INTO settlement (partner, business_date, payment_count, total_amount, closed_by)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING settlement_id, partner, business_date, payment_count, total_amount, status, reported_count, reported_amount, closed_by, created_at, reported_at
+`
+
+type AddSettlementParams struct {
+	Partner      string
+	BusinessDate pgxtype.Date
+	PaymentCount int32
+	TotalAmount  float64
+	ClosedBy     string
+}
+
+func (q *Queries) AddSettlement(ctx context.Context, arg AddSettlementParams) (Settlement, error) {
+	row := q.db.QueryRowContext(ctx, addSettlement,
+		arg.Partner,
+		arg.BusinessDate,
+		arg.PaymentCount,
+		arg.TotalAmount,
+		arg.ClosedBy,
+	)
+	var i Settlement
+	err := row.Scan(
+		&i.SettlementID,
+		&i.Partner,
+		&i.BusinessDate,
+		&i.PaymentCount,
+		&i.TotalAmount,
+		&i.Status,
+		&i.ReportedCount,
+		&i.ReportedAmount,
+		&i.ClosedBy,
+		&i.CreatedAt,
+		&i.ReportedAt,
+	)
+	return i, err
+}
+
+const addStudentAccount = `-- name: AddStudentAccount :exec
+INSERT INTO account(student_no,hashed_password)
+VALUES (?1,?2)
+`
+
+type AddStudentAccountParams struct {
+	StudentNo      string
+	HashedPassword string
+}
+
+func (q *Queries) AddStudentAccount(ctx context.Context, arg AddStudentAccountParams) error {
+	_, err := q.db.ExecContext(ctx, addStudentAccount, arg.StudentNo, arg.HashedPassword)
+	return err
+}
+
+const addTuitionChange = `-- name: AddTuitionChange :exec
+INSERT INTO tuition_change(tuition_id,action,old_term,new_term,old_amount,new_amount,balance_adjustment,reason,changed_by)
+VALUES (?1,?2,?3,?4,?5,?6,?7,?8,?9)
+`
+
+type AddTuitionChangeParams struct {
+	TuitionID         int32
+	Action            string
+	OldTerm           string
+	NewTerm           string
+	OldAmount         float64
+	NewAmount         float64
+	BalanceAdjustment float64
+	Reason            string
+	ChangedBy         string
+}
+
+func (q *Queries) AddTuitionChange(ctx context.Context, arg AddTuitionChangeParams) error {
+	_, err := q.db.ExecContext(ctx, addTuitionChange,
+		arg.TuitionID,
+		arg.Action,
+		arg.OldTerm,
+		arg.NewTerm,
+		arg.OldAmount,
+		arg.NewAmount,
+		arg.BalanceAdjustment,
+		arg.Reason,
+		arg.ChangedBy,
+	)
+	return err
+}
+
+const addTuitionItem = `-- name: AddTuitionItem :one
+INSERT INTO tuition_item (tuition_id, fee_type, description, amount, amount_paid)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING item_id, tuition_id, fee_type, description, amount, amount_paid
+`
+
+type AddTuitionItemParams struct {
+	TuitionID   int32
+	FeeType     string
+	Description string
+	Amount      float64
+	AmountPaid  float64
+}
+
+func (q *Queries) AddTuitionItem(ctx context.Context, arg AddTuitionItemParams) (TuitionItem, error) {
+	row := q.db.QueryRowContext(ctx, addTuitionItem,
+		arg.TuitionID,
+		arg.FeeType,
+		arg.Description,
+		arg.Amount,
+		arg.AmountPaid,
+	)
+	var i TuitionItem
+	err := row.Scan(
+		&i.ItemID,
+		&i.TuitionID,
+		&i.FeeType,
+		&i.Description,
+		&i.Amount,
+		&i.AmountPaid,
+	)
+	return i, err
+}
+
+const addTuitionToOneStudent = `-- name: AddTuitionToOneStudent :one
+INSERT INTO tuition(student_no,term,tuition_total,billed_total,created_at)
+VALUES (?1,?2,?3,?3,strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+RETURNING tuition_id, student_no, term, tuition_total, billed_total, status, created_at
+`
+
+type AddTuitionToOneStudentParams struct {
+	StudentNo    string
+	Term         string
+	TuitionTotal float64
+}
+
+func (q *Queries) AddTuitionToOneStudent(ctx context.Context, arg AddTuitionToOneStudentParams) (Tuition, error) {
+	row := q.db.QueryRowContext(ctx, addTuitionToOneStudent, arg.StudentNo, arg.Term, arg.TuitionTotal)
+	var i Tuition
+	err := row.Scan(
+		&i.TuitionID,
+		&i.StudentNo,
+		&i.Term,
+		&i.TuitionTotal,
+		&i.BilledTotal,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const amendTuition = `-- name: AmendTuition :exec
+UPDATE tuition
+SET term = ?2,
+    billed_total = ?3,
+    tuition_total = ?4
+WHERE tuition_id = ?1
+`
+
+type AmendTuitionParams struct {
+	TuitionID    int32
+	Term         string
+	BilledTotal  float64
+	TuitionTotal float64
+}
+
+func (q *Queries) AmendTuition(ctx context.Context, arg AmendTuitionParams) error {
+	_, err := q.db.ExecContext(ctx, amendTuition,
+		arg.TuitionID,
+		arg.Term,
+		arg.BilledTotal,
+		arg.TuitionTotal,
+	)
+	return err
+}
+
+const cancelImportJob = `-- name: CancelImportJob :exec
+UPDATE import_job
+SET cancel_requested = TRUE,
+    status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+    finished_at = CASE WHEN status = 'queued' THEN strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') ELSE finished_at END
+WHERE job_id = ?1
+AND status IN ('queued', 'running')
+`
+
+// A queued job is cancelled right away; a running one stops at its next check.
+func (q *Queries) CancelImportJob(ctx context.Context, jobID int32) error {
+	_, err := q.db.ExecContext(ctx, cancelImportJob, jobID)
+	return err
+}
+
+const cancelTuition = `-- name: CancelTuition :exec
+UPDATE tuition
+SET status = 'cancelled',
+    tuition_total = 0
+WHERE tuition_id = ?1
+`
+
+func (q *Queries) CancelTuition(ctx context.Context, tuitionID int32) error {
+	_, err := q.db.ExecContext(ctx, cancelTuition, tuitionID)
+	return err
+}
+
+const claimImportJob = `-- name: ClaimImportJob :one
+UPDATE import_job
+SET status = 'running',
+    started_at = coalesce(started_at, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    heartbeat_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE job_id = (
+    SELECT job_id FROM import_job
+    WHERE status = 'queued'
+    OR (status = 'running' AND heartbeat_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 minute'))
+    ORDER BY job_id
+    LIMIT 1
+)
+RETURNING job_id, kind, status, filename, format, mode, dry_run, mapping, total_rows, processed_rows, imported, invalid, committed, failure, cancel_requested, created_by, created_at, started_at, heartbeat_at, finished_at, created, updated, skipped
+`
+
+// Takes the oldest queued job, or a running one whose heartbeat is older than
+// jobLease.
+func (q *Queries) ClaimImportJob(ctx context.Context) (ImportJob, error) {
+	row := q.db.QueryRowContext(ctx, claimImportJob)
+	var i ImportJob
+	err := row.Scan(
+		&i.JobID,
+		&i.Kind,
+		&i.Status,
+		&i.Filename,
+		&i.Format,
+		&i.Mode,
+		&i.DryRun,
+		&i.Mapping,
+		&i.TotalRows,
+		&i.ProcessedRows,
+		&i.Imported,
+		&i.Invalid,
+		&i.Committed,
+		&i.Failure,
+		&i.CancelRequested,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.HeartbeatAt,
+		&i.FinishedAt,
+		&i.Created,
+		&i.Updated,
+		&i.Skipped,
+	)
+	return i, err
+}
+
+const clearActiveTerm = `-- name: ClearActiveTerm :exec
+UPDATE term
+SET active = FALSE
+WHERE active
+`
+
+func (q *Queries) ClearActiveTerm(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, clearActiveTerm)
+	return err
+}
+
+const closeBankTransaction = `-- name: CloseBankTransaction :one
+UPDATE bank_transaction
+SET status = ?2,
+    detail = ?3,
+    student_no = ?4,
+    term = ?5,
+    payment_id = ?6,
+    resolved_by = ?7,
+    resolved_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE transaction_id = ?1
+AND status = 'queued'
+RETURNING transaction_id, entry_key, format, statement_id, account, bank_reference, booking_date, amount, currency, debtor, remittance, status, issue, detail, student_no, term, payment_id, resolved_by, created_at, resolved_at
+`
+
+type CloseBankTransactionParams struct {
+	TransactionID int32
+	Status        string
+	Detail        string
+	StudentNo     pgxtype.Text
+	Term          pgxtype.Text
+	PaymentID     pgxtype.Int4
+	ResolvedBy    string
+}
+
+// Resolves or dismisses a queued transaction; no row comes back when it is not queued.
+func (q *Queries) CloseBankTransaction(ctx context.Context, arg CloseBankTransactionParams) (BankTransaction, error) {
+	row := q.db.QueryRowContext(ctx, closeBankTransaction,
+		arg.TransactionID,
+		arg.Status,
+		arg.Detail,
+		arg.StudentNo,
+		arg.Term,
+		arg.PaymentID,
+		arg.ResolvedBy,
+	)
+	var i BankTransaction
+	err := row.Scan(
+		&i.TransactionID,
+		&i.EntryKey,
+		&i.Format,
+		&i.StatementID,
+		&i.Account,
+		&i.BankReference,
+		&i.BookingDate,
+		&i.Amount,
+		&i.Currency,
+		&i.Debtor,
+		&i.Remittance,
+		&i.Status,
+		&i.Issue,
+		&i.Detail,
+		&i.StudentNo,
+		&i.Term,
+		&i.PaymentID,
+		&i.ResolvedBy,
+		&i.CreatedAt,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const countBankTransactions = `-- name: CountBankTransactions :one
+SELECT count(*) FROM bank_transaction
+WHERE coalesce(status = CAST(?1 AS TEXT), TRUE)
+`
+
+func (q *Queries) CountBankTransactions(ctx context.Context, status pgxtype.Text) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBankTransactions, status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBankTransactionsByKey = `-- name: CountBankTransactionsByKey :one
+SELECT count(*) FROM bank_transaction
+WHERE entry_key = ?1
+`
+
+func (q *Queries) CountBankTransactionsByKey(ctx context.Context, entryKey string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBankTransactionsByKey, entryKey)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countImportJobs = `-- name: CountImportJobs :one
+SELECT count(*) FROM import_job
+WHERE coalesce(status = CAST(?1 AS TEXT), TRUE)
+`
+
+func (q *Queries) CountImportJobs(ctx context.Context, status pgxtype.Text) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countImportJobs, status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPaymentReferences = `-- name: CountPaymentReferences :one
+SELECT count(*) FROM payment_reference
+WHERE student_no = ?1
+`
+
+func (q *Queries) CountPaymentReferences(ctx context.Context, studentNo string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPaymentReferences, studentNo)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPaymentsForTerm = `-- name: CountPaymentsForTerm :one
+SELECT count(*) FROM payment
+WHERE student_no = ?1
+AND term = ?2
+`
+
+type CountPaymentsForTermParams struct {
+	StudentNo string
+	Term      string
+}
+
+func (q *Queries) CountPaymentsForTerm(ctx context.Context, arg CountPaymentsForTermParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPaymentsForTerm, arg.StudentNo, arg.Term)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countScheduledBilled = `-- name: CountScheduledBilled :one
+SELECT count(DISTINCT student.student_no)
+FROM fee_schedule
+INNER JOIN student
+ON student.program = fee_schedule.program
+AND student.enrollment_year = fee_schedule.enrollment_year
+WHERE fee_schedule.term = ?1
+AND student.deactivated_at IS NULL
+AND student.enrollment_status = 'enrolled'
+AND EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.term = fee_schedule.term
+    AND tuition.status = 'active'
+)
+`
+
+// Enrolled students a schedule covers who already have a tuition for the term.
+func (q *Queries) CountScheduledBilled(ctx context.Context, term string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countScheduledBilled, term)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSettlements = `-- name: CountSettlements :one
+SELECT count(*) FROM settlement
+WHERE coalesce(partner = CAST(?1 AS TEXT), TRUE)
+AND coalesce(status = CAST(?2 AS TEXT), TRUE)
+`
+
+type CountSettlementsParams struct {
+	Partner pgxtype.Text
+	Status  pgxtype.Text
+}
+
+func (q *Queries) CountSettlements(ctx context.Context, arg CountSettlementsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSettlements, arg.Partner, arg.Status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countStudents = `-- name: CountStudents :one
+SELECT count(*) FROM student
+WHERE substr(student_no, 1, length(CAST(?1 AS TEXT))) = CAST(?1 AS TEXT)
+AND coalesce(faculty = CAST(?2 AS TEXT), TRUE)
+AND coalesce(department = CAST(?3 AS TEXT), TRUE)
+AND coalesce(program = CAST(?4 AS TEXT), TRUE)
+AND coalesce(enrollment_status = CAST(?5 AS TEXT), TRUE)
+AND coalesce(enrollment_year = CAST(?6 AS INTEGER), TRUE)
+AND coalesce((deactivated_at IS NULL) = CAST(?7 AS BOOLEAN), TRUE)
+AND coalesce(EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.tuition_total > 0
+) = CAST(?8 AS BOOLEAN), TRUE)
+`
+
+type CountStudentsParams struct {
+	Prefix           string
+	Faculty          pgxtype.Text
+	Department       pgxtype.Text
+	Program          pgxtype.Text
+	EnrollmentStatus pgxtype.Text
+	EnrollmentYear   pgxtype.Int4
+	Active           pgxtype.Bool
+	HasUnpaid        pgxtype.Bool
+}
+
+func (q *Queries) CountStudents(ctx context.Context, arg CountStudentsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countStudents,
+		arg.Prefix,
+		arg.Faculty,
+		arg.Department,
+		arg.Program,
+		arg.EnrollmentStatus,
+		arg.EnrollmentYear,
+		arg.Active,
+		arg.HasUnpaid,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTuitions = `-- name: CountTuitions :one
+SELECT count(*) FROM tuition
+WHERE coalesce(student_no = CAST(?1 AS TEXT), TRUE)
+AND coalesce(term = CAST(?2 AS TEXT), TRUE)
+AND coalesce(status = CAST(?3 AS TEXT), TRUE)
+AND coalesce((tuition_total > 0) = CAST(?4 AS BOOLEAN), TRUE)
+`
+
+type CountTuitionsParams struct {
+	StudentNo pgxtype.Text
+	Term      pgxtype.Text
+	Status    pgxtype.Text
+	Unpaid    pgxtype.Bool
+}
+
+func (q *Queries) CountTuitions(ctx context.Context, arg CountTuitionsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTuitions,
+		arg.StudentNo,
+		arg.Term,
+		arg.Status,
+		arg.Unpaid,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createFeeSchedule = `-- name: CreateFeeSchedule :one
+INSERT INTO fee_schedule (program, enrollment_year, term, fee_type, amount)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING schedule_id, program, enrollment_year, term, fee_type, amount, created_at
+`
+
+type CreateFeeScheduleParams struct {
+	Program        string
+	EnrollmentYear int32
+	Term           string
+	FeeType        string
+	Amount         float64
+}
+
+func (q *Queries) CreateFeeSchedule(ctx context.Context, arg CreateFeeScheduleParams) (FeeSchedule, error) {
+	row := q.db.QueryRowContext(ctx, createFeeSchedule,
+		arg.Program,
+		arg.EnrollmentYear,
+		arg.Term,
+		arg.FeeType,
+		arg.Amount,
+	)
+	var i FeeSchedule
+	err := row.Scan(
+		&i.ScheduleID,
+		&i.Program,
+		&i.EnrollmentYear,
+		&i.Term,
+		&i.FeeType,
+		&i.Amount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createFeeType = `-- name: CreateFeeType :one
+INSERT INTO fee_type (code, name, priority)
+VALUES (?1, ?2, ?3)
+RETURNING code, name, priority, created_at
+`
+
+type CreateFeeTypeParams struct {
+	Code     string
+	Name     string
+	Priority int32
+}
+
+func (q *Queries) CreateFeeType(ctx context.Context, arg CreateFeeTypeParams) (FeeType, error) {
+	row := q.db.QueryRowContext(ctx, createFeeType, arg.Code, arg.Name, arg.Priority)
+	var i FeeType
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.Priority,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createImportJob = `-- name: CreateImportJob :one
+INSERT INTO import_job (kind, filename, format, mode, dry_run, mapping, total_rows, created_by)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+RETURNING job_id, kind, status, filename, format, mode, dry_run, mapping, total_rows, processed_rows, imported, invalid, committed, failure, cancel_requested, created_by, created_at, started_at, heartbeat_at, finished_at, created, updated, skipped
+`
+
+type CreateImportJobParams struct {
+	Kind      string
+	Filename  string
+	Format    string
+	Mode      string
+	DryRun    bool
+	Mapping   string
+	TotalRows int32
+	CreatedBy string
+}
+
+func (q *Queries) CreateImportJob(ctx context.Context, arg CreateImportJobParams) (ImportJob, error) {
+	row := q.db.QueryRowContext(ctx, createImportJob,
+		arg.Kind,
+		arg.Filename,
+		arg.Format,
+		arg.Mode,
+		arg.DryRun,
+		arg.Mapping,
+		arg.TotalRows,
+		arg.CreatedBy,
+	)
+	var i ImportJob
+	err := row.Scan(
+		&i.JobID,
+		&i.Kind,
+		&i.Status,
+		&i.Filename,
+		&i.Format,
+		&i.Mode,
+		&i.DryRun,
+		&i.Mapping,
+		&i.TotalRows,
+		&i.ProcessedRows,
+		&i.Imported,
+		&i.Invalid,
+		&i.Committed,
+		&i.Failure,
+		&i.CancelRequested,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.HeartbeatAt,
+		&i.FinishedAt,
+		&i.Created,
+		&i.Updated,
+		&i.Skipped,
+	)
+	return i, err
+}
+
+const createTerm = `-- name: CreateTerm :one
+INSERT INTO term (code, name, start_date, end_date, payment_due_date, active)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+RETURNING code, name, start_date, end_date, payment_due_date, active, created_at
+`
+
+type CreateTermParams struct {
+	Code           string
+	Name           string
+	StartDate      pgxtype.Date
+	EndDate        pgxtype.Date
+	PaymentDueDate pgxtype.Date
+	Active         bool
+}
+
+func (q *Queries) CreateTerm(ctx context.Context, arg CreateTermParams) (Term, error) {
+	row := q.db.QueryRowContext(ctx, createTerm,
+		arg.Code,
+		arg.Name,
+		arg.StartDate,
+		arg.EndDate,
+		arg.PaymentDueDate,
+		arg.Active,
+	)
+	var i Term
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.StartDate,
+		&i.EndDate,
+		&i.PaymentDueDate,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deactivateStudent = `-- name: DeactivateStudent :exec
+UPDATE student
+SET deactivated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE student_no = ?1
+AND deactivated_at IS NULL
+`
+
+func (q *Queries) DeactivateStudent(ctx context.Context, studentNo string) error {
+	_, err := q.db.ExecContext(ctx, deactivateStudent, studentNo)
+	return err
+}
+
+const decreasePaymentLimit = `-- name: DecreasePaymentLimit :exec
+UPDATE student
+SET daily_payment_limit = daily_payment_limit-1
+WHERE student_no = ?1
+`
+
+func (q *Queries) DecreasePaymentLimit(ctx context.Context, studentNo string) error {
+	_, err := q.db.ExecContext(ctx, decreasePaymentLimit, studentNo)
+	return err
+}
+
+const deleteFeeSchedule = `-- name: DeleteFeeSchedule :exec
+DELETE FROM fee_schedule
+WHERE schedule_id = ?1
+`
+
+func (q *Queries) DeleteFeeSchedule(ctx context.Context, scheduleID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteFeeSchedule, scheduleID)
+	return err
+}
+
+const deleteImportJobFile = `-- name: DeleteImportJobFile :exec
+DELETE FROM import_job_file
+WHERE job_id = ?1
+`
+
+func (q *Queries) DeleteImportJobFile(ctx context.Context, jobID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteImportJobFile, jobID)
+	return err
+}
+
+const deleteTerm = `-- name: DeleteTerm :exec
+DELETE FROM term
+WHERE code = ?1
+`
+
+func (q *Queries) DeleteTerm(ctx context.Context, code string) error {
+	_, err := q.db.ExecContext(ctx, deleteTerm, code)
+	return err
+}
+
+const finishImportJob = `-- name: FinishImportJob :exec
+UPDATE import_job
+SET status = ?2,
+    processed_rows = ?3,
+    imported = ?4,
+    invalid = ?5,
+    committed = ?6,
+    failure = ?7,
+    created = ?8,
+    updated = ?9,
+    skipped = ?10,
+    finished_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE job_id = ?1
+`
+
+type FinishImportJobParams struct {
+	JobID         int32
+	Status        string
+	ProcessedRows int32
+	Imported      int32
+	Invalid       int32
+	Committed     bool
+	Failure       string
+	Created       int32
+	Updated       int32
+	Skipped       int32
+}
+
+func (q *Queries) FinishImportJob(ctx context.Context, arg FinishImportJobParams) error {
+	_, err := q.db.ExecContext(ctx, finishImportJob,
+		arg.JobID,
+		arg.Status,
+		arg.ProcessedRows,
+		arg.Imported,
+		arg.Invalid,
+		arg.Committed,
+		arg.Failure,
+		arg.Created,
+		arg.Updated,
+		arg.Skipped,
+	)
+	return err
+}
+
+const getAccountByStudentNo = `-- name: GetAccountByStudentNo :one
+SELECT account_no, student_no, hashed_password FROM account
+WHERE student_no = ?1
+`
+
+func (q *Queries) GetAccountByStudentNo(ctx context.Context, studentNo string) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByStudentNo, studentNo)
+	var i Account
+	err := row.Scan(&i.AccountNo, &i.StudentNo, &i.HashedPassword)
+	return i, err
+}
+
+const getActiveTerm = `-- name: GetActiveTerm :one
+SELECT code, name, start_date, end_date, payment_due_date, active, created_at FROM term
+WHERE active
+`
+
+func (q *Queries) GetActiveTerm(ctx context.Context) (Term, error) {
+	row := q.db.QueryRowContext(ctx, getActiveTerm)
+	var i Term
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.StartDate,
+		&i.EndDate,
+		&i.PaymentDueDate,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getBankPartner = `-- name: GetBankPartner :one
+SELECT code, name, created_at FROM bank_partner
+WHERE code = ?1
+`
+
+func (q *Queries) GetBankPartner(ctx context.Context, code string) (BankPartner, error) {
+	row := q.db.QueryRowContext(ctx, getBankPartner, code)
+	var i BankPartner
+	err := row.Scan(&i.Code, &i.Name, &i.CreatedAt)
+	return i, err
+}
+
+const getBankTransaction = `-- name: GetBankTransaction :one
+SELECT transaction_id, entry_key, format, statement_id, account, bank_reference, booking_date, amount, currency, debtor, remittance, status, issue, detail, student_no, term, payment_id, resolved_by, created_at, resolved_at FROM bank_transaction
+WHERE transaction_id = ?1
+`
+
+func (q *Queries) GetBankTransaction(ctx context.Context, transactionID int32) (BankTransaction, error) {
+	row := q.db.QueryRowContext(ctx, getBankTransaction, transactionID)
+	var i BankTransaction
+	err := row.Scan(
+		&i.TransactionID,
+		&i.EntryKey,
+		&i.Format,
+		&i.StatementID,
+		&i.Account,
+		&i.BankReference,
+		&i.BookingDate,
+		&i.Amount,
+		&i.Currency,
+		&i.Debtor,
+		&i.Remittance,
+		&i.Status,
+		&i.Issue,
+		&i.Detail,
+		&i.StudentNo,
+		&i.Term,
+		&i.PaymentID,
+		&i.ResolvedBy,
+		&i.CreatedAt,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const getFeeSchedule = `-- name: GetFeeSchedule :one
+SELECT schedule_id, program, enrollment_year, term, fee_type, amount, created_at FROM fee_schedule
+WHERE schedule_id = ?1
+`
+
+func (q *Queries) GetFeeSchedule(ctx context.Context, scheduleID int32) (FeeSchedule, error) {
+	row := q.db.QueryRowContext(ctx, getFeeSchedule, scheduleID)
+	var i FeeSchedule
+	err := row.Scan(
+		&i.ScheduleID,
+		&i.Program,
+		&i.EnrollmentYear,
+		&i.Term,
+		&i.FeeType,
+		&i.Amount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getFeeType = `-- name: GetFeeType :one
+SELECT code, name, priority, created_at FROM fee_type
+WHERE code = ?1
+`
+
+func (q *Queries) GetFeeType(ctx context.Context, code string) (FeeType, error) {
+	row := q.db.QueryRowContext(ctx, getFeeType, code)
+	var i FeeType
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.Priority,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getImportJob = `-- name: GetImportJob :one
+SELECT job_id, kind, status, filename, format, mode, dry_run, mapping, total_rows, processed_rows, imported, invalid, committed, failure, cancel_requested, created_by, created_at, started_at, heartbeat_at, finished_at, created, updated, skipped FROM import_job
+WHERE job_id = ?1
+`
+
+func (q *Queries) GetImportJob(ctx context.Context, jobID int32) (ImportJob, error) {
+	row := q.db.QueryRowContext(ctx, getImportJob, jobID)
+	var i ImportJob
+	err := row.Scan(
+		&i.JobID,
+		&i.Kind,
+		&i.Status,
+		&i.Filename,
+		&i.Format,
+		&i.Mode,
+		&i.DryRun,
+		&i.Mapping,
+		&i.TotalRows,
+		&i.ProcessedRows,
+		&i.Imported,
+		&i.Invalid,
+		&i.Committed,
+		&i.Failure,
+		&i.CancelRequested,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.HeartbeatAt,
+		&i.FinishedAt,
+		&i.Created,
+		&i.Updated,
+		&i.Skipped,
+	)
+	return i, err
+}
+
+const getImportJobFile = `-- name: GetImportJobFile :one
+SELECT payload FROM import_job_file
+WHERE job_id = ?1
+`
+
+func (q *Queries) GetImportJobFile(ctx context.Context, jobID int32) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getImportJobFile, jobID)
+	var payload []byte
+	err := row.Scan(&payload)
+	return payload, err
+}
+
+const getPaymentReference = `-- name: GetPaymentReference :one
+SELECT reference, student_no, term, created_at FROM payment_reference
+WHERE reference = ?1
+`
+
+func (q *Queries) GetPaymentReference(ctx context.Context, reference string) (PaymentReference, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentReference, reference)
+	var i PaymentReference
+	err := row.Scan(
+		&i.Reference,
+		&i.StudentNo,
+		&i.Term,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getReceipt = `-- name: GetReceipt :one
+SELECT receipt.receipt_no, receipt.payment_id, receipt.verification_code, receipt.outstanding_after, receipt.created_at, payment.student_no, payment.term, payment.amount, payment.balance_after, payment.channel,
+       payment.partner,
+       student.first_name, student.last_name, student.faculty, student.program
+FROM receipt
+INNER JOIN payment
+ON payment.payment_id = receipt.payment_id
+INNER JOIN student
+ON student.student_no = payment.student_no
+WHERE receipt.receipt_no = ?1
+`
+
+type GetReceiptRow struct {
+	ReceiptNo        string
+	PaymentID        int32
+	VerificationCode string
+	OutstandingAfter float64
+	CreatedAt        pgxtype.Timestamptz
+	StudentNo        string
+	Term             string
+	Amount           float64
+	BalanceAfter     float64
+	Channel          string
+	Partner          pgxtype.Text
+	FirstName        string
+	LastName         string
+	Faculty          string
+	Program          string
+}
+
+func (q *Queries) GetReceipt(ctx context.Context, receiptNo string) (GetReceiptRow, error) {
+	row := q.db.QueryRowContext(ctx, getReceipt, receiptNo)
+	var i GetReceiptRow
+	err := row.Scan(
+		&i.ReceiptNo,
+		&i.PaymentID,
+		&i.VerificationCode,
+		&i.OutstandingAfter,
+		&i.CreatedAt,
+		&i.StudentNo,
+		&i.Term,
+		&i.Amount,
+		&i.BalanceAfter,
+		&i.Channel,
+		&i.Partner,
+		&i.FirstName,
+		&i.LastName,
+		&i.Faculty,
+		&i.Program,
+	)
+	return i, err
+}
+
+const getSettlement = `-- name: GetSettlement :one
+SELECT settlement_id, partner, business_date, payment_count, total_amount, status, reported_count, reported_amount, closed_by, created_at, reported_at FROM settlement
+WHERE settlement_id = ?1
+`
+
+func (q *Queries) GetSettlement(ctx context.Context, settlementID int32) (Settlement, error) {
+	row := q.db.QueryRowContext(ctx, getSettlement, settlementID)
+	var i Settlement
+	err := row.Scan(
+		&i.SettlementID,
+		&i.Partner,
+		&i.BusinessDate,
+		&i.PaymentCount,
+		&i.TotalAmount,
+		&i.Status,
+		&i.ReportedCount,
+		&i.ReportedAmount,
+		&i.ClosedBy,
+		&i.CreatedAt,
+		&i.ReportedAt,
+	)
+	return i, err
+}
+
+const getSettlementByDay = `-- name: GetSettlementByDay :one
+SELECT settlement_id, partner, business_date, payment_count, total_amount, status, reported_count, reported_amount, closed_by, created_at, reported_at FROM settlement
+WHERE partner = ?1 AND business_date = ?2
+`
+
+type GetSettlementByDayParams struct {
+	Partner      string
+	BusinessDate pgxtype.Date
+}
+
+func (q *Queries) GetSettlementByDay(ctx context.Context, arg GetSettlementByDayParams) (Settlement, error) {
+	row := q.db.QueryRowContext(ctx, getSettlementByDay, arg.Partner, arg.BusinessDate)
+	var i Settlement
+	err := row.Scan(
+		&i.SettlementID,
+		&i.Partner,
+		&i.BusinessDate,
+		&i.PaymentCount,
+		&i.TotalAmount,
+		&i.Status,
+		&i.ReportedCount,
+		&i.ReportedAmount,
+		&i.ClosedBy,
+		&i.CreatedAt,
+		&i.ReportedAt,
+	)
+	return i, err
+}
+
+const getStudent = `-- name: GetStudent :one
+SELECT student_no, balance, daily_payment_limit, deactivated_at, first_name, last_name, email, phone, faculty, department, program, enrollment_year, enrollment_status FROM student
+WHERE student_no = ?1
+`
+
+func (q *Queries) GetStudent(ctx context.Context, studentNo string) (Student, error) {
+	row := q.db.QueryRowContext(ctx, getStudent, studentNo)
+	var i Student
+	err := row.Scan(
+		&i.StudentNo,
+		&i.Balance,
+		&i.DailyPaymentLimit,
+		&i.DeactivatedAt,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Faculty,
+		&i.Department,
+		&i.Program,
+		&i.EnrollmentYear,
+		&i.EnrollmentStatus,
+	)
+	return i, err
+}
+
+const getStudentById = `-- name: GetStudentById :one
+SELECT student.student_no, balance, daily_payment_limit, deactivated_at, first_name, last_name, email, phone, faculty, department, program, enrollment_year, enrollment_status, tuition_id, tuition.student_no, term, tuition_total, billed_total, status, created_at FROM student
+LEFT JOIN tuition
+ON student.student_no = tuition.student_no
+WHERE student.student_no = ?1
+`
+
+type GetStudentByIdRow struct {
+	StudentNo         string
+	Balance           float64
+	DailyPaymentLimit int32
+	DeactivatedAt     pgxtype.Timestamptz
+	FirstName         string
+	LastName          string
+	Email             string
+	Phone             string
+	Faculty           string
+	Department        string
+	Program           string
+	EnrollmentYear    pgxtype.Int4
+	EnrollmentStatus  string
+	TuitionID         pgxtype.Int4
+	StudentNo_2       pgxtype.Text
+	Term              pgxtype.Text
+	TuitionTotal      pgxtype.Float8
+	BilledTotal       pgxtype.Float8
+	Status            pgxtype.Text
+	CreatedAt         pgxtype.Timestamptz
+}
+
+func (q *Queries) GetStudentById(ctx context.Context, studentNo string) (GetStudentByIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getStudentById, studentNo)
+	var i GetStudentByIdRow
+	err := row.Scan(
+		&i.StudentNo,
+		&i.Balance,
+		&i.DailyPaymentLimit,
+		&i.DeactivatedAt,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Faculty,
+		&i.Department,
+		&i.Program,
+		&i.EnrollmentYear,
+		&i.EnrollmentStatus,
+		&i.TuitionID,
+		&i.StudentNo_2,
+		&i.Term,
+		&i.TuitionTotal,
+		&i.BilledTotal,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getStudentDailyLimit = `-- name: GetStudentDailyLimit :one
+SELECT daily_payment_limit 
+FROM student
+WHERE student_no = ?1
+`
+
+func (q *Queries) GetStudentDailyLimit(ctx context.Context, studentNo string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getStudentDailyLimit, studentNo)
+	var daily_payment_limit int32
+	err := row.Scan(&daily_payment_limit)
+	return daily_payment_limit, err
+}
+
+const getStudentPaymentReference = `-- name: GetStudentPaymentReference :one
+SELECT reference, student_no, term, created_at FROM payment_reference
+WHERE student_no = ?1
+AND term = ?2
+`
+
+type GetStudentPaymentReferenceParams struct {
+	StudentNo string
+	Term      string
+}
+
+func (q *Queries) GetStudentPaymentReference(ctx context.Context, arg GetStudentPaymentReferenceParams) (PaymentReference, error) {
+	row := q.db.QueryRowContext(ctx, getStudentPaymentReference, arg.StudentNo, arg.Term)
+	var i PaymentReference
+	err := row.Scan(
+		&i.Reference,
+		&i.StudentNo,
+		&i.Term,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTerm = `-- name: GetTerm :one
+SELECT code, name, start_date, end_date, payment_due_date, active, created_at FROM term
+WHERE code = ?1
+`
+
+func (q *Queries) GetTerm(ctx context.Context, code string) (Term, error) {
+	row := q.db.QueryRowContext(ctx, getTerm, code)
+	var i Term
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.StartDate,
+		&i.EndDate,
+		&i.PaymentDueDate,
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTuition = `-- name: GetTuition :one
+SELECT tuition_id, student_no, term, tuition_total, billed_total, status, created_at FROM tuition
+WHERE tuition_id = ?1
+`
+
+func (q *Queries) GetTuition(ctx context.Context, tuitionID int32) (Tuition, error) {
+	row := q.db.QueryRowContext(ctx, getTuition, tuitionID)
+	var i Tuition
+	err := row.Scan(
+		&i.TuitionID,
+		&i.StudentNo,
+		&i.Term,
+		&i.TuitionTotal,
+		&i.BilledTotal,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTuitionByTerm = `-- name: GetTuitionByTerm :many
+SELECT student.student_no, balance, daily_payment_limit, deactivated_at, first_name, last_name, email, phone, faculty, department, program, enrollment_year, enrollment_status, tuition_id, tuition.student_no, term, tuition_total, billed_total, status, created_at FROM student
+INNER JOIN tuition
+ON student.student_no = tuition.student_no
+WHERE student.student_no = ?1
+AND tuition.term = ?2
+AND tuition.status = 'active'
+`
+
+type GetTuitionByTermParams struct {
+	StudentNo string
+	Term      string
+}
+
+type GetTuitionByTermRow struct {
+	StudentNo         string
+	Balance           float64
+	DailyPaymentLimit int32
+	DeactivatedAt     pgxtype.Timestamptz
+	FirstName         string
+	LastName          string
+	Email             string
+	Phone             string
+	Faculty           string
+	Department        string
+	Program           string
+	EnrollmentYear    pgxtype.Int4
+	EnrollmentStatus  string
+	TuitionID         int32
+	StudentNo_2       string
+	Term              string
+	TuitionTotal      float64
+	BilledTotal       float64
+	Status            string
+	CreatedAt         pgxtype.Timestamptz
+}
+
+func (q *Queries) GetTuitionByTerm(ctx context.Context, arg GetTuitionByTermParams) ([]GetTuitionByTermRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTuitionByTerm, arg.StudentNo, arg.Term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTuitionByTermRow
+	for rows.Next() {
+		var i GetTuitionByTermRow
+		if err := rows.Scan(
+			&i.StudentNo,
+			&i.Balance,
+			&i.DailyPaymentLimit,
+			&i.DeactivatedAt,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.Faculty,
+			&i.Department,
+			&i.Program,
+			&i.EnrollmentYear,
+			&i.EnrollmentStatus,
+			&i.TuitionID,
+			&i.StudentNo_2,
+			&i.Term,
+			&i.TuitionTotal,
+			&i.BilledTotal,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const heartbeatImportJob = `-- name: HeartbeatImportJob :exec
+UPDATE import_job
+SET heartbeat_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE job_id = ?1
+AND status = 'running'
+`
+
+func (q *Queries) HeartbeatImportJob(ctx context.Context, jobID int32) error {
+	_, err := q.db.ExecContext(ctx, heartbeatImportJob, jobID)
+	return err
+}
+
+const listBankPartners = `-- name: ListBankPartners :many
+SELECT code, name, created_at FROM bank_partner
+ORDER BY code
+`
+
+func (q *Queries) ListBankPartners(ctx context.Context) ([]BankPartner, error) {
+	rows, err := q.db.QueryContext(ctx, listBankPartners)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BankPartner
+	for rows.Next() {
+		var i BankPartner
+		if err := rows.Scan(&i.Code, &i.Name, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBankTransactions = `-- name: ListBankTransactions :many
+SELECT transaction_id, entry_key, format, statement_id, account, bank_reference, booking_date, amount, currency, debtor, remittance, status, issue, detail, student_no, term, payment_id, resolved_by, created_at, resolved_at FROM bank_transaction
+WHERE coalesce(status = CAST(?1 AS TEXT), TRUE)
+ORDER BY transaction_id
+LIMIT ?3 OFFSET ?2
+`
+
+type ListBankTransactionsParams struct {
+	Status    pgxtype.Text
+	RowOffset int64
+	RowLimit  int64
+}
+
+func (q *Queries) ListBankTransactions(ctx context.Context, arg ListBankTransactionsParams) ([]BankTransaction, error) {
+	rows, err := q.db.QueryContext(ctx, listBankTransactions, arg.Status, arg.RowOffset, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BankTransaction
+	for rows.Next() {
+		var i BankTransaction
+		if err := rows.Scan(
+			&i.TransactionID,
+			&i.EntryKey,
+			&i.Format,
+			&i.StatementID,
+			&i.Account,
+			&i.BankReference,
+			&i.BookingDate,
+			&i.Amount,
+			&i.Currency,
+			&i.Debtor,
+			&i.Remittance,
+			&i.Status,
+			&i.Issue,
+			&i.Detail,
+			&i.StudentNo,
+			&i.Term,
+			&i.PaymentID,
+			&i.ResolvedBy,
+			&i.CreatedAt,
+			&i.ResolvedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeeSchedules = `-- name: ListFeeSchedules :many
+SELECT schedule_id, program, enrollment_year, term, fee_type, amount, created_at FROM fee_schedule
+WHERE coalesce(term = CAST(?1 AS TEXT), TRUE)
+AND coalesce(program = CAST(?2 AS TEXT), TRUE)
+ORDER BY term, program, enrollment_year, schedule_id
+`
+
+type ListFeeSchedulesParams struct {
+	Term    pgxtype.Text
+	Program pgxtype.Text
+}
+
+// A null filter matches every schedule.
+func (q *Queries) ListFeeSchedules(ctx context.Context, arg ListFeeSchedulesParams) ([]FeeSchedule, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeSchedules, arg.Term, arg.Program)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeeSchedule
+	for rows.Next() {
+		var i FeeSchedule
+		if err := rows.Scan(
+			&i.ScheduleID,
+			&i.Program,
+			&i.EnrollmentYear,
+			&i.Term,
+			&i.FeeType,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeeTypes = `-- name: ListFeeTypes :many
+SELECT code, name, priority, created_at FROM fee_type
+ORDER BY priority, code
+`
+
+func (q *Queries) ListFeeTypes(ctx context.Context) ([]FeeType, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeeType
+	for rows.Next() {
+		var i FeeType
+		if err := rows.Scan(
+			&i.Code,
+			&i.Name,
+			&i.Priority,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImportJobErrors = `-- name: ListImportJobErrors :many
+SELECT error_id, job_id, line, field, message FROM import_job_error
+WHERE job_id = ?1
+ORDER BY line, error_id
+`
+
+func (q *Queries) ListImportJobErrors(ctx context.Context, jobID int32) ([]ImportJobError, error) {
+	rows, err := q.db.QueryContext(ctx, listImportJobErrors, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportJobError
+	for rows.Next() {
+		var i ImportJobError
+		if err := rows.Scan(
+			&i.ErrorID,
+			&i.JobID,
+			&i.Line,
+			&i.Field,
+			&i.Message,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImportJobs = `-- name: ListImportJobs :many
+SELECT job_id, kind, status, filename, format, mode, dry_run, mapping, total_rows, processed_rows, imported, invalid, committed, failure, cancel_requested, created_by, created_at, started_at, heartbeat_at, finished_at, created, updated, skipped FROM import_job
+WHERE coalesce(status = CAST(?1 AS TEXT), TRUE)
+ORDER BY job_id DESC
+LIMIT ?3 OFFSET ?2
+`
+
+type ListImportJobsParams struct {
+	Status    pgxtype.Text
+	RowOffset int64
+	RowLimit  int64
+}
+
+func (q *Queries) ListImportJobs(ctx context.Context, arg ListImportJobsParams) ([]ImportJob, error) {
+	rows, err := q.db.QueryContext(ctx, listImportJobs, arg.Status, arg.RowOffset, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportJob
+	for rows.Next() {
+		var i ImportJob
+		if err := rows.Scan(
+			&i.JobID,
+			&i.Kind,
+			&i.Status,
+			&i.Filename,
+			&i.Format,
+			&i.Mode,
+			&i.DryRun,
+			&i.Mapping,
+			&i.TotalRows,
+			&i.ProcessedRows,
+			&i.Imported,
+			&i.Invalid,
+			&i.Committed,
+			&i.Failure,
+			&i.CancelRequested,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.StartedAt,
+			&i.HeartbeatAt,
+			&i.FinishedAt,
+			&i.Created,
+			&i.Updated,
+			&i.Skipped,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPartnerPayments = `-- name: ListPartnerPayments :many
+SELECT payment.payment_id, payment.student_no, payment.term, payment.amount,
+       payment.created_at, CAST(coalesce(receipt.receipt_no, '') AS TEXT) AS receipt_no
+FROM payment
+LEFT JOIN receipt ON receipt.payment_id = payment.payment_id
+WHERE payment.partner = CAST(?1 AS TEXT)
+AND payment.created_at >= CAST(?2 AS TEXT)
+AND payment.created_at < CAST(?3 AS TEXT)
+ORDER BY payment.created_at, payment.payment_id
+`
+
+type ListPartnerPaymentsParams struct {
+	Partner  string
+	FromTime string
+	ToTime   string
+}
+
+type ListPartnerPaymentsRow struct {
+	PaymentID int32
+	StudentNo string
+	Term      string
+	Amount    float64
+	CreatedAt pgxtype.Timestamptz
+	ReceiptNo string
+}
+
+// The payments a partner took in [from_time, to_time), with their receipts.
+func (q *Queries) ListPartnerPayments(ctx context.Context, arg ListPartnerPaymentsParams) ([]ListPartnerPaymentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPartnerPayments, arg.Partner, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPartnerPaymentsRow
+	for rows.Next() {
+		var i ListPartnerPaymentsRow
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.StudentNo,
+			&i.Term,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ReceiptNo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPaymentsByStudent = `-- name: ListPaymentsByStudent :many
+SELECT payment_id, student_no, term, amount, balance_after, created_at, channel, partner FROM payment
+WHERE student_no = ?1
+ORDER BY created_at, payment_id
+`
+
+func (q *Queries) ListPaymentsByStudent(ctx context.Context, studentNo string) ([]Payment, error) {
+	rows, err := q.db.QueryContext(ctx, listPaymentsByStudent, studentNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Payment
+	for rows.Next() {
+		var i Payment
+		if err := rows.Scan(
+			&i.PaymentID,
+			&i.StudentNo,
+			&i.Term,
+			&i.Amount,
+			&i.BalanceAfter,
+			&i.CreatedAt,
+			&i.Channel,
+			&i.Partner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReceiptAllocations = `-- name: ListReceiptAllocations :many
+SELECT allocation_id, receipt_no, fee_type, description, amount FROM receipt_allocation
+WHERE receipt_no = ?1
+ORDER BY allocation_id
+`
+
+func (q *Queries) ListReceiptAllocations(ctx context.Context, receiptNo string) ([]ReceiptAllocation, error) {
+	rows, err := q.db.QueryContext(ctx, listReceiptAllocations, receiptNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReceiptAllocation
+	for rows.Next() {
+		var i ReceiptAllocation
+		if err := rows.Scan(
+			&i.AllocationID,
+			&i.ReceiptNo,
+			&i.FeeType,
+			&i.Description,
+			&i.Amount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledCharges = `-- name: ListScheduledCharges :many
+SELECT student.student_no, fee_schedule.program, fee_schedule.enrollment_year, fee_schedule.fee_type, fee_schedule.amount
+FROM fee_schedule
+INNER JOIN student
+ON student.program = fee_schedule.program
+AND student.enrollment_year = fee_schedule.enrollment_year
+INNER JOIN fee_type
+ON fee_type.code = fee_schedule.fee_type
+WHERE fee_schedule.term = ?1
+AND student.deactivated_at IS NULL
+AND student.enrollment_status = 'enrolled'
+AND NOT EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.term = fee_schedule.term
+    AND tuition.status = 'active'
+)
+ORDER BY student.student_no, fee_type.priority, fee_schedule.fee_type
+`
+
+type ListScheduledChargesRow struct {
+	StudentNo      string
+	Program        string
+	EnrollmentYear int32
+	FeeType        string
+	Amount         float64
+}
+
+// The scheduled items of every enrolled student not yet billed for the term,
+// grouped by student in payment order.
+func (q *Queries) ListScheduledCharges(ctx context.Context, term string) ([]ListScheduledChargesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledCharges, term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListScheduledChargesRow
+	for rows.Next() {
+		var i ListScheduledChargesRow
+		if err := rows.Scan(
+			&i.StudentNo,
+			&i.Program,
+			&i.EnrollmentYear,
+			&i.FeeType,
+			&i.Amount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSettlements = `-- name: ListSettlements :many
+SELECT settlement_id, partner, business_date, payment_count, total_amount, status, reported_count, reported_amount, closed_by, created_at, reported_at FROM settlement
+WHERE coalesce(partner = CAST(?1 AS TEXT), TRUE)
+AND coalesce(status = CAST(?2 AS TEXT), TRUE)
+ORDER BY business_date DESC, partner
+LIMIT ?4 OFFSET ?3
+`
+
+type ListSettlementsParams struct {
+	Partner   pgxtype.Text
+	Status    pgxtype.Text
+	RowOffset int64
+	RowLimit  int64
+}
+
+func (q *Queries) ListSettlements(ctx context.Context, arg ListSettlementsParams) ([]Settlement, error) {
+	rows, err := q.db.QueryContext(ctx, listSettlements,
+		arg.Partner,
+		arg.Status,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Settlement
+	for rows.Next() {
+		var i Settlement
+		if err := rows.Scan(
+			&i.SettlementID,
+			&i.Partner,
+			&i.BusinessDate,
+			&i.PaymentCount,
+			&i.TotalAmount,
+			&i.Status,
+			&i.ReportedCount,
+			&i.ReportedAmount,
+			&i.ClosedBy,
+			&i.CreatedAt,
+			&i.ReportedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStudentTuitionChanges = `-- name: ListStudentTuitionChanges :many
+SELECT tuition_change.change_id, tuition_change.tuition_id, tuition_change."action", tuition_change.old_term, tuition_change.new_term, tuition_change.old_amount, tuition_change.new_amount, tuition_change.balance_adjustment, tuition_change.reason, tuition_change.changed_by, tuition_change.created_at FROM tuition_change
+INNER JOIN tuition
+ON tuition.tuition_id = tuition_change.tuition_id
+WHERE tuition.student_no = ?1
+ORDER BY tuition_change.created_at, tuition_change.change_id
+`
+
+func (q *Queries) ListStudentTuitionChanges(ctx context.Context, studentNo string) ([]TuitionChange, error) {
+	rows, err := q.db.QueryContext(ctx, listStudentTuitionChanges, studentNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TuitionChange
+	for rows.Next() {
+		var i TuitionChange
+		if err := rows.Scan(
+			&i.ChangeID,
+			&i.TuitionID,
+			&i.Action,
+			&i.OldTerm,
+			&i.NewTerm,
+			&i.OldAmount,
+			&i.NewAmount,
+			&i.BalanceAdjustment,
+			&i.Reason,
+			&i.ChangedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStudents = `-- name: ListStudents :many
+SELECT student_no, balance, daily_payment_limit, deactivated_at, first_name, last_name, email, phone, faculty, department, program, enrollment_year, enrollment_status FROM student
+WHERE substr(student_no, 1, length(CAST(?1 AS TEXT))) = CAST(?1 AS TEXT)
+AND coalesce(faculty = CAST(?2 AS TEXT), TRUE)
+AND coalesce(department = CAST(?3 AS TEXT), TRUE)
+AND coalesce(program = CAST(?4 AS TEXT), TRUE)
+AND coalesce(enrollment_status = CAST(?5 AS TEXT), TRUE)
+AND coalesce(enrollment_year = CAST(?6 AS INTEGER), TRUE)
+AND coalesce((deactivated_at IS NULL) = CAST(?7 AS BOOLEAN), TRUE)
+AND coalesce(EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.tuition_total > 0
+) = CAST(?8 AS BOOLEAN), TRUE)
+ORDER BY student_no
+LIMIT ?10 OFFSET ?9
+`
+
+type ListStudentsParams struct {
+	Prefix           string
+	Faculty          pgxtype.Text
+	Department       pgxtype.Text
+	Program          pgxtype.Text
+	EnrollmentStatus pgxtype.Text
+	EnrollmentYear   pgxtype.Int4
+	Active           pgxtype.Bool
+	HasUnpaid        pgxtype.Bool
+	RowOffset        int64
+	RowLimit         int64
+}
+
+// Students whose number starts with prefix; a null filter matches every student.
+func (q *Queries) ListStudents(ctx context.Context, arg ListStudentsParams) ([]Student, error) {
+	rows, err := q.db.QueryContext(ctx, listStudents,
+		arg.Prefix,
+		arg.Faculty,
+		arg.Department,
+		arg.Program,
+		arg.EnrollmentStatus,
+		arg.EnrollmentYear,
+		arg.Active,
+		arg.HasUnpaid,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Student
+	for rows.Next() {
+		var i Student
+		if err := rows.Scan(
+			&i.StudentNo,
+			&i.Balance,
+			&i.DailyPaymentLimit,
+			&i.DeactivatedAt,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.Faculty,
+			&i.Department,
+			&i.Program,
+			&i.EnrollmentYear,
+			&i.EnrollmentStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTerms = `-- name: ListTerms :many
+SELECT code, name, start_date, end_date, payment_due_date, active, created_at FROM term
+ORDER BY start_date IS NULL, start_date, code
+`
+
+// Terms in calendar order; terms without dates come last.
+func (q *Queries) ListTerms(ctx context.Context) ([]Term, error) {
+	rows, err := q.db.QueryContext(ctx, listTerms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Term
+	for rows.Next() {
+		var i Term
+		if err := rows.Scan(
+			&i.Code,
+			&i.Name,
+			&i.StartDate,
+			&i.EndDate,
+			&i.PaymentDueDate,
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTopDebtors = `-- name: ListTopDebtors :many
+SELECT student.student_no, student.first_name, student.last_name, student.faculty,
+       CAST(count(*) AS INTEGER) AS tuitions,
+       CAST(sum(tuition.tuition_total) AS REAL) AS outstanding
+FROM tuition
+INNER JOIN student
+ON student.student_no = tuition.student_no
+WHERE tuition.status = 'active'
+AND tuition.tuition_total > 0
+GROUP BY student.student_no
+ORDER BY outstanding DESC, student.student_no
+LIMIT ?1
+`
+
+type ListTopDebtorsRow struct {
+	StudentNo   string
+	FirstName   string
+	LastName    string
+	Faculty     string
+	Tuitions    int64
+	Outstanding float64
+}
+
+// The students owing the most over all their active tuitions.
+func (q *Queries) ListTopDebtors(ctx context.Context, limit int64) ([]ListTopDebtorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTopDebtors, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTopDebtorsRow
+	for rows.Next() {
+		var i ListTopDebtorsRow
+		if err := rows.Scan(
+			&i.StudentNo,
+			&i.FirstName,
+			&i.LastName,
+			&i.Faculty,
+			&i.Tuitions,
+			&i.Outstanding,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTuitionChanges = `-- name: ListTuitionChanges :many
+SELECT change_id, tuition_id, "action", old_term, new_term, old_amount, new_amount, balance_adjustment, reason, changed_by, created_at FROM tuition_change
+WHERE tuition_id = ?1
+ORDER BY created_at, change_id
+`
+
+func (q *Queries) ListTuitionChanges(ctx context.Context, tuitionID int32) ([]TuitionChange, error) {
+	rows, err := q.db.QueryContext(ctx, listTuitionChanges, tuitionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TuitionChange
+	for rows.Next() {
+		var i TuitionChange
+		if err := rows.Scan(
+			&i.ChangeID,
+			&i.TuitionID,
+			&i.Action,
+			&i.OldTerm,
+			&i.NewTerm,
+			&i.OldAmount,
+			&i.NewAmount,
+			&i.BalanceAdjustment,
+			&i.Reason,
+			&i.ChangedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTuitionItems = `-- name: ListTuitionItems :many
+SELECT tuition_item.item_id, tuition_item.tuition_id, tuition_item.fee_type, tuition_item.description, tuition_item.amount, tuition_item.amount_paid, fee_type.name AS fee_type_name, fee_type.priority
+FROM tuition_item
+INNER JOIN fee_type
+ON fee_type.code = tuition_item.fee_type
+WHERE tuition_item.tuition_id = ?1
+ORDER BY fee_type.priority, tuition_item.item_id
+`
+
+type ListTuitionItemsRow struct {
+	ItemID      int32
+	TuitionID   int32
+	FeeType     string
+	Description string
+	Amount      float64
+	AmountPaid  float64
+	FeeTypeName string
+	Priority    int32
+}
+
+// Items in the order payments settle them.
+func (q *Queries) ListTuitionItems(ctx context.Context, tuitionID int32) ([]ListTuitionItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTuitionItems, tuitionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTuitionItemsRow
+	for rows.Next() {
+		var i ListTuitionItemsRow
+		if err := rows.Scan(
+			&i.ItemID,
+			&i.TuitionID,
+			&i.FeeType,
+			&i.Description,
+			&i.Amount,
+			&i.AmountPaid,
+			&i.FeeTypeName,
+			&i.Priority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTuitions = `-- name: ListTuitions :many
+SELECT tuition_id, student_no, term, tuition_total, billed_total, status, created_at FROM tuition
+WHERE coalesce(student_no = CAST(?1 AS TEXT), TRUE)
+AND coalesce(term = CAST(?2 AS TEXT), TRUE)
+AND coalesce(status = CAST(?3 AS TEXT), TRUE)
+AND coalesce((tuition_total > 0) = CAST(?4 AS BOOLEAN), TRUE)
+ORDER BY tuition_id
+LIMIT ?6 OFFSET ?5
+`
+
+type ListTuitionsParams struct {
+	StudentNo pgxtype.Text
+	Term      pgxtype.Text
+	Status    pgxtype.Text
+	Unpaid    pgxtype.Bool
+	RowOffset int64
+	RowLimit  int64
+}
+
+// A null filter matches every tuition.
+func (q *Queries) ListTuitions(ctx context.Context, arg ListTuitionsParams) ([]Tuition, error) {
+	rows, err := q.db.QueryContext(ctx, listTuitions,
+		arg.StudentNo,
+		arg.Term,
+		arg.Status,
+		arg.Unpaid,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tuition
+	for rows.Next() {
+		var i Tuition
+		if err := rows.Scan(
+			&i.TuitionID,
+			&i.StudentNo,
+			&i.Term,
+			&i.TuitionTotal,
+			&i.BilledTotal,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTuitionsByStudent = `-- name: ListTuitionsByStudent :many
+SELECT tuition_id, student_no, term, tuition_total, billed_total, status, created_at FROM tuition
+WHERE student_no = ?1
+ORDER BY tuition_id
+`
+
+func (q *Queries) ListTuitionsByStudent(ctx context.Context, studentNo string) ([]Tuition, error) {
+	rows, err := q.db.QueryContext(ctx, listTuitionsByStudent, studentNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tuition
+	for rows.Next() {
+		var i Tuition
+		if err := rows.Scan(
+			&i.TuitionID,
+			&i.StudentNo,
+			&i.Term,
+			&i.TuitionTotal,
+			&i.BilledTotal,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpaidReport = `-- name: ListUnpaidReport :many
+SELECT tuition.tuition_id, tuition.term, term.payment_due_date,
+       tuition.billed_total, tuition.tuition_total,
+       student.student_no, student.first_name, student.last_name, student.email, student.phone,
+       student.faculty, student.department, student.program, student.enrollment_year, student.enrollment_status
+FROM tuition
+INNER JOIN student
+ON student.student_no = tuition.student_no
+LEFT JOIN term
+ON term.code = tuition.term
+WHERE tuition.status = 'active'
+AND tuition.tuition_total > 0
+AND coalesce(tuition.term = CAST(?1 AS TEXT), TRUE)
+AND coalesce(student.faculty = CAST(?2 AS TEXT), TRUE)
+AND coalesce(student.program = CAST(?3 AS TEXT), TRUE)
+AND (tuition.term, student.faculty, student.student_no, tuition.tuition_id)
+    > (CAST(?4 AS TEXT), CAST(?5 AS TEXT), CAST(?6 AS TEXT), CAST(?7 AS INTEGER))
+ORDER BY tuition.term, student.faculty, student.student_no, tuition.tuition_id
+LIMIT ?8
+`
+
+type ListUnpaidReportParams struct {
+	Term           pgxtype.Text
+	Faculty        pgxtype.Text
+	Program        pgxtype.Text
+	AfterTerm      string
+	AfterFaculty   string
+	AfterStudentNo string
+	AfterTuitionID int64
+	RowLimit       int64
+}
+
+type ListUnpaidReportRow struct {
+	TuitionID        int32
+	Term             string
+	PaymentDueDate   pgxtype.Date
+	BilledTotal      float64
+	TuitionTotal     float64
+	StudentNo        string
+	FirstName        string
+	LastName         string
+	Email            string
+	Phone            string
+	Faculty          string
+	Department       string
+	Program          string
+	EnrollmentYear   pgxtype.Int4
+	EnrollmentStatus string
+}
+
+// Active tuitions with an amount outstanding, in report order. Pages are keyed by
+// the last row of the previous page, so an export only ever holds one page.
+func (q *Queries) ListUnpaidReport(ctx context.Context, arg ListUnpaidReportParams) ([]ListUnpaidReportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnpaidReport,
+		arg.Term,
+		arg.Faculty,
+		arg.Program,
+		arg.AfterTerm,
+		arg.AfterFaculty,
+		arg.AfterStudentNo,
+		arg.AfterTuitionID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnpaidReportRow
+	for rows.Next() {
+		var i ListUnpaidReportRow
+		if err := rows.Scan(
+			&i.TuitionID,
+			&i.Term,
+			&i.PaymentDueDate,
+			&i.BilledTotal,
+			&i.TuitionTotal,
+			&i.StudentNo,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.Faculty,
+			&i.Department,
+			&i.Program,
+			&i.EnrollmentYear,
+			&i.EnrollmentStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockStudentBalance = `-- name: LockStudentBalance :one
+SELECT balance
+FROM student
+WHERE student_no = ?1
+`
+
+// SQLite has no row locks; transactions start with BEGIN IMMEDIATE instead.
+func (q *Queries) LockStudentBalance(ctx context.Context, studentNo string) (float64, error) {
+	row := q.db.QueryRowContext(ctx, lockStudentBalance, studentNo)
+	var balance float64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
+const lockTuition = `-- name: LockTuition :one
+SELECT tuition_id, student_no, term, tuition_total, billed_total, status, created_at FROM tuition
+WHERE tuition_id = ?1
+`
+
+// SQLite has no row locks; transactions start with BEGIN IMMEDIATE instead.
+func (q *Queries) LockTuition(ctx context.Context, tuitionID int32) (Tuition, error) {
+	row := q.db.QueryRowContext(ctx, lockTuition, tuitionID)
+	var i Tuition
+	err := row.Scan(
+		&i.TuitionID,
+		&i.StudentNo,
+		&i.Term,
+		&i.TuitionTotal,
+		&i.BilledTotal,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const reactivateStudent = `-- name: ReactivateStudent :exec
+UPDATE student
+SET deactivated_at = NULL
+WHERE student_no = ?1
+`
+
+func (q *Queries) ReactivateStudent(ctx context.Context, studentNo string) error {
+	_, err := q.db.ExecContext(ctx, reactivateStudent, studentNo)
+	return err
+}
+
+const reportSettlement = `-- name: ReportSettlement :one
+UPDATE settlement
+SET reported_count = ?2,
+    reported_amount = ?3,
+    status = ?4,
+    reported_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE settlement_id = ?1
+RETURNING settlement_id, partner, business_date, payment_count, total_amount, status, reported_count, reported_amount, closed_by, created_at, reported_at
+`
+
+type ReportSettlementParams struct {
+	SettlementID   int32
+	ReportedCount  pgxtype.Int4
+	ReportedAmount pgxtype.Float8
+	Status         string
+}
+
+// Records the totals the partner reported for the day; reporting again replaces them.
+func (q *Queries) ReportSettlement(ctx context.Context, arg ReportSettlementParams) (Settlement, error) {
+	row := q.db.QueryRowContext(ctx, reportSettlement,
+		arg.SettlementID,
+		arg.ReportedCount,
+		arg.ReportedAmount,
+		arg.Status,
+	)
+	var i Settlement
+	err := row.Scan(
+		&i.SettlementID,
+		&i.Partner,
+		&i.BusinessDate,
+		&i.PaymentCount,
+		&i.TotalAmount,
+		&i.Status,
+		&i.ReportedCount,
+		&i.ReportedAmount,
+		&i.ClosedBy,
+		&i.CreatedAt,
+		&i.ReportedAt,
+	)
+	return i, err
+}
+
+const resetTuitionTotal = `-- name: ResetTuitionTotal :exec
+UPDATE tuition
+SET tuition_total = 0
+WHERE student_no = ?1 
+AND term = ?2
+`
+
+type ResetTuitionTotalParams struct {
+	StudentNo string
+	Term      string
+}
+
+func (q *Queries) ResetTuitionTotal(ctx context.Context, arg ResetTuitionTotalParams) error {
+	_, err := q.db.ExecContext(ctx, resetTuitionTotal, arg.StudentNo, arg.Term)
+	return err
+}
+
+const setTuitionItemPaid = `-- name: SetTuitionItemPaid :exec
+UPDATE tuition_item
+SET amount_paid = ?2
+WHERE item_id = ?1
+`
+
+type SetTuitionItemPaidParams struct {
+	ItemID     int32
+	AmountPaid float64
+}
+
+// Settling an item leaves its amount alone.
+func (q *Queries) SetTuitionItemPaid(ctx context.Context, arg SetTuitionItemPaidParams) error {
+	_, err := q.db.ExecContext(ctx, setTuitionItemPaid, arg.ItemID, arg.AmountPaid)
+	return err
+}
+
+const setTuitionOutstanding = `-- name: SetTuitionOutstanding :one
+UPDATE tuition
+SET tuition_total = ?2
+WHERE tuition_id = ?1
+AND status = 'active'
+RETURNING tuition_id, student_no, term, tuition_total, billed_total, status, created_at
+`
+
+type SetTuitionOutstandingParams struct {
+	TuitionID    int32
+	TuitionTotal float64
+}
+
+// A cancelled tuition is left alone and returns no rows.
+func (q *Queries) SetTuitionOutstanding(ctx context.Context, arg SetTuitionOutstandingParams) (Tuition, error) {
+	row := q.db.QueryRowContext(ctx, setTuitionOutstanding, arg.TuitionID, arg.TuitionTotal)
+	var i Tuition
+	err := row.Scan(
+		&i.TuitionID,
+		&i.StudentNo,
+		&i.Term,
+		&i.TuitionTotal,
+		&i.BilledTotal,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const summarizePaymentsByChannel = `-- name: SummarizePaymentsByChannel :many
+SELECT channel,
+       CAST(count(*) AS INTEGER) AS payments,
+       CAST(sum(amount) AS REAL) AS amount
+FROM payment
+WHERE coalesce(created_at >= CAST(?1 AS TEXT), TRUE)
+AND coalesce(created_at < CAST(?2 AS TEXT), TRUE)
+GROUP BY channel
+ORDER BY channel
+`
+
+type SummarizePaymentsByChannelParams struct {
+	FromTime pgxtype.Text
+	ToTime   pgxtype.Text
+}
+
+type SummarizePaymentsByChannelRow struct {
+	Channel  string
+	Payments int64
+	Amount   float64
+}
+
+func (q *Queries) SummarizePaymentsByChannel(ctx context.Context, arg SummarizePaymentsByChannelParams) ([]SummarizePaymentsByChannelRow, error) {
+	rows, err := q.db.QueryContext(ctx, summarizePaymentsByChannel, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SummarizePaymentsByChannelRow
+	for rows.Next() {
+		var i SummarizePaymentsByChannelRow
+		if err := rows.Scan(&i.Channel, &i.Payments, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const summarizePaymentsByDay = `-- name: SummarizePaymentsByDay :many
+SELECT CAST(strftime('%Y-%m-%d', created_at) AS TEXT) AS day,
+       CAST(count(*) AS INTEGER) AS payments,
+       CAST(sum(amount) AS REAL) AS amount
+FROM payment
+WHERE coalesce(created_at >= CAST(?1 AS TEXT), TRUE)
+AND coalesce(created_at < CAST(?2 AS TEXT), TRUE)
+GROUP BY day
+ORDER BY day
+`
+
+type SummarizePaymentsByDayParams struct {
+	FromTime pgxtype.Text
+	ToTime   pgxtype.Text
+}
+
+type SummarizePaymentsByDayRow struct {
+	Day      string
+	Payments int64
+	Amount   float64
+}
+
+// Days are UTC days.
+func (q *Queries) SummarizePaymentsByDay(ctx context.Context, arg SummarizePaymentsByDayParams) ([]SummarizePaymentsByDayRow, error) {
+	rows, err := q.db.QueryContext(ctx, summarizePaymentsByDay, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SummarizePaymentsByDayRow
+	for rows.Next() {
+		var i SummarizePaymentsByDayRow
+		if err := rows.Scan(&i.Day, &i.Payments, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const summarizeStudents = `-- name: SummarizeStudents :one
+SELECT CAST(count(*) AS INTEGER) AS students,
+       CAST(coalesce(sum(CASE WHEN outstanding <= 0 THEN 1 ELSE 0 END), 0) AS INTEGER) AS fully_paid
+FROM (
+    SELECT student_no, sum(tuition_total) AS outstanding
+    FROM tuition
+    WHERE status = 'active'
+    GROUP BY student_no
+) owed
+`
+
+type SummarizeStudentsRow struct {
+	Students  int64
+	FullyPaid int64
+}
+
+// Students with an active tuition, and how many of them owe nothing.
+func (q *Queries) SummarizeStudents(ctx context.Context) (SummarizeStudentsRow, error) {
+	row := q.db.QueryRowContext(ctx, summarizeStudents)
+	var i SummarizeStudentsRow
+	err := row.Scan(&i.Students, &i.FullyPaid)
+	return i, err
+}
+
+const summarizeTerms = `-- name: SummarizeTerms :many
+SELECT billed.term,
+       CAST(count(*) AS INTEGER) AS students,
+       CAST(sum(CASE WHEN billed.outstanding <= 0 THEN 1 ELSE 0 END) AS INTEGER) AS fully_paid,
+       CAST(sum(billed.billed) AS REAL) AS billed,
+       CAST(sum(billed.outstanding) AS REAL) AS outstanding,
+       CAST(coalesce(max(received.amount), 0) AS REAL) AS received
+FROM (
+    SELECT term, student_no, sum(billed_total) AS billed, sum(tuition_total) AS outstanding
+    FROM tuition
+    WHERE status = 'active'
+    GROUP BY term, student_no
+) billed
+LEFT JOIN (
+    SELECT term, sum(amount) AS amount
+    FROM payment
+    WHERE coalesce(created_at >= CAST(?1 AS TEXT), TRUE)
+    AND coalesce(created_at < CAST(?2 AS TEXT), TRUE)
+    GROUP BY term
+) received
+ON received.term = billed.term
+GROUP BY billed.term
+ORDER BY billed.term
+`
+
+type SummarizeTermsParams struct {
+	FromTime pgxtype.Text
+	ToTime   pgxtype.Text
+}
+
+type SummarizeTermsRow struct {
+	Term        string
+	Students    int64
+	FullyPaid   int64
+	Billed      float64
+	Outstanding float64
+	Received    float64
+}
+
+// Billing per term with the payments received for it in the range. A student is
+// fully paid for a term once nothing of it is outstanding.
+// The range bounds are timestamps in the stored text format.
+func (q *Queries) SummarizeTerms(ctx context.Context, arg SummarizeTermsParams) ([]SummarizeTermsRow, error) {
+	rows, err := q.db.QueryContext(ctx, summarizeTerms, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SummarizeTermsRow
+	for rows.Next() {
+		var i SummarizeTermsRow
+		if err := rows.Scan(
+			&i.Term,
+			&i.Students,
+			&i.FullyPaid,
+			&i.Billed,
+			&i.Outstanding,
+			&i.Received,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unpaidTuitions = `-- name: UnpaidTuitions :many
+SELECT student.student_no, balance, daily_payment_limit, deactivated_at, first_name, last_name, email, phone, faculty, department, program, enrollment_year, enrollment_status, tuition_id, tuition.student_no, term, tuition_total, billed_total, status, created_at
+FROM student
+INNER JOIN tuition
+ON student.student_no = tuition.student_no
+WHERE tuition.tuition_total > 0
+LIMIT ?1 OFFSET ?2
+`
+
+type UnpaidTuitionsParams struct {
+	Limit  int64
+	Offset int64
+}
+
+type UnpaidTuitionsRow struct {
+	StudentNo         string
+	Balance           float64
+	DailyPaymentLimit int32
+	DeactivatedAt     pgxtype.Timestamptz
+	FirstName         string
+	LastName          string
+	Email             string
+	Phone             string
+	Faculty           string
+	Department        string
+	Program           string
+	EnrollmentYear    pgxtype.Int4
+	EnrollmentStatus  string
+	TuitionID         int32
+	StudentNo_2       string
+	Term              string
+	TuitionTotal      float64
+	BilledTotal       float64
+	Status            string
+	CreatedAt         pgxtype.Timestamptz
+}
+
+func (q *Queries) UnpaidTuitions(ctx context.Context, arg UnpaidTuitionsParams) ([]UnpaidTuitionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, unpaidTuitions, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UnpaidTuitionsRow
+	for rows.Next() {
+		var i UnpaidTuitionsRow
+		if err := rows.Scan(
+			&i.StudentNo,
+			&i.Balance,
+			&i.DailyPaymentLimit,
+			&i.DeactivatedAt,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.Faculty,
+			&i.Department,
+			&i.Program,
+			&i.EnrollmentYear,
+			&i.EnrollmentStatus,
+			&i.TuitionID,
+			&i.StudentNo_2,
+			&i.Term,
+			&i.TuitionTotal,
+			&i.BilledTotal,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateBalance = `-- name: UpdateBalance :exec
+UPDATE student
+SET balance = ?2
+WHERE student_no = ?1
+`
+
+type UpdateBalanceParams struct {
+	StudentNo string
+	Balance   float64
+}
+
+func (q *Queries) UpdateBalance(ctx context.Context, arg UpdateBalanceParams) error {
+	_, err := q.db.ExecContext(ctx, updateBalance, arg.StudentNo, arg.Balance)
+	return err
+}
+
+const updateFeeSchedule = `-- name: UpdateFeeSchedule :exec
+UPDATE fee_schedule
+SET amount = ?2
+WHERE schedule_id = ?1
+`
+
+type UpdateFeeScheduleParams struct {
+	ScheduleID int32
+	Amount     float64
+}
+
+func (q *Queries) UpdateFeeSchedule(ctx context.Context, arg UpdateFeeScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeeSchedule, arg.ScheduleID, arg.Amount)
+	return err
+}
+
+const updateFeeType = `-- name: UpdateFeeType :exec
+UPDATE fee_type
+SET name = ?2,
+    priority = ?3
+WHERE code = ?1
+`
+
+type UpdateFeeTypeParams struct {
+	Code     string
+	Name     string
+	Priority int32
+}
+
+func (q *Queries) UpdateFeeType(ctx context.Context, arg UpdateFeeTypeParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeeType, arg.Code, arg.Name, arg.Priority)
+	return err
+}
+
+const updateImportJobProgress = `-- name: UpdateImportJobProgress :exec
+UPDATE import_job
+SET processed_rows = ?2,
+    imported = ?3,
+    invalid = ?4,
+    created = ?5,
+    updated = ?6,
+    skipped = ?7,
+    heartbeat_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE job_id = ?1
+`
+
+type UpdateImportJobProgressParams struct {
+	JobID         int32
+	ProcessedRows int32
+	Imported      int32
+	Invalid       int32
+	Created       int32
+	Updated       int32
+	Skipped       int32
+}
+
+func (q *Queries) UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) error {
+	_, err := q.db.ExecContext(ctx, updateImportJobProgress,
+		arg.JobID,
+		arg.ProcessedRows,
+		arg.Imported,
+		arg.Invalid,
+		arg.Created,
+		arg.Updated,
+		arg.Skipped,
+	)
+	return err
+}
+
+const updateStudent = `-- name: UpdateStudent :exec
+UPDATE student
+SET balance = ?2,
+    daily_payment_limit = ?3,
+    first_name = ?4,
+    last_name = ?5,
+    email = ?6,
+    phone = ?7,
+    faculty = ?8,
+    department = ?9,
+    program = ?10,
+    enrollment_year = ?11,
+    enrollment_status = ?12
+WHERE student_no = ?1
+`
+
+type UpdateStudentParams struct {
+	StudentNo         string
+	Balance           float64
+	DailyPaymentLimit int32
+	FirstName         string
+	LastName          string
+	Email             string
+	Phone             string
+	Faculty           string
+	Department        string
+	Program           string
+	EnrollmentYear    pgxtype.Int4
+	EnrollmentStatus  string
+}
+
+func (q *Queries) UpdateStudent(ctx context.Context, arg UpdateStudentParams) error {
+	_, err := q.db.ExecContext(ctx, updateStudent,
+		arg.StudentNo,
+		arg.Balance,
+		arg.DailyPaymentLimit,
+		arg.FirstName,
+		arg.LastName,
+		arg.Email,
+		arg.Phone,
+		arg.Faculty,
+		arg.Department,
+		arg.Program,
+		arg.EnrollmentYear,
+		arg.EnrollmentStatus,
+	)
+	return err
+}
+
+const updateTerm = `-- name: UpdateTerm :exec
+UPDATE term
+SET name = ?2,
+    start_date = ?3,
+    end_date = ?4,
+    payment_due_date = ?5,
+    active = ?6
+WHERE code = ?1
+`
+
+type UpdateTermParams struct {
+	Code           string
+	Name           string
+	StartDate      pgxtype.Date
+	EndDate        pgxtype.Date
+	PaymentDueDate pgxtype.Date
+	Active         bool
+}
+
+func (q *Queries) UpdateTerm(ctx context.Context, arg UpdateTermParams) error {
+	_, err := q.db.ExecContext(ctx, updateTerm,
+		arg.Code,
+		arg.Name,
+		arg.StartDate,
+		arg.EndDate,
+		arg.PaymentDueDate,
+		arg.Active,
+	)
+	return err
+}
+
+const updateTuitionItem = `-- name: UpdateTuitionItem :exec
+UPDATE tuition_item
+SET amount = ?2,
+    amount_paid = ?3
+WHERE item_id = ?1
+`
+
+type UpdateTuitionItemParams struct {
+	ItemID     int32
+	Amount     float64
+	AmountPaid float64
+}
+
+func (q *Queries) UpdateTuitionItem(ctx context.Context, arg UpdateTuitionItemParams) error {
+	_, err := q.db.ExecContext(ctx, updateTuitionItem, arg.ItemID, arg.Amount, arg.AmountPaid)
+	return err
+}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"dogukan-dev/tuition/db"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
 
 	"net/http"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -34,13 +37,22 @@ func (a *App) QueryTuitionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	studentNo := r.URL.Query().Get("student_no")
-	activeTerm := r.URL.Query().Get("active_term")
-	if studentNo == "" || activeTerm == "" {
-		http.Error(w, `{"error":"student_no and active_term parameters are required"}`, http.StatusBadRequest)
+	if studentNo == "" {
+		http.Error(w, `{"error":"student_no parameter is required"}`, http.StatusBadRequest)
+		return
+	}
+	// Without active_term the term marked active in the calendar is used
+	activeTerm, err := a.activeTerm(r, r.URL.Query().Get("active_term"))
+	if errors.Is(err, errTermNotFound) {
+		http.Error(w, `{"error":"active_term is required while no term is active"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Cannot query term"}`, http.StatusInternalServerError)
 		return
 	}
 
-	student, err := a.Queries.GetStudentById(a.Context, studentNo)
+	student, err := a.Store.GetStudentById(r.Context(), studentNo)
 	if err != nil {
 
 		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
@@ -59,7 +71,7 @@ func (a *App) QueryTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	term, err := a.Queries.GetTuitionByTerm(a.Context, db.GetTuitionByTermParams{
+	term, err := a.Store.GetTuitionByTerm(r.Context(), db.GetTuitionByTermParams{
 		StudentNo: studentNo,
 		Term:      activeTerm,
 	})
@@ -74,24 +86,119 @@ func (a *App) QueryTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	items, err := tuitionItems(r.Context(), a.Store, term[0].TuitionID)
+	if err != nil {
+		http.Error(w, `{"error":"Cannot query term"}`, http.StatusBadRequest)
+		return
+	}
+
+	reference, err := paymentReference(r.Context(), a.Store, student.StudentNo, term[0].Term)
+	if err != nil {
+		http.Error(w, `{"error":"Payment reference cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
 	type TuitionQueryResponse struct {
-		StudentNo    string
-		Term         string
-		TuitionTotal float64
-		Balance      float64
+		StudentNo        string
+		Term             string
+		TuitionTotal     float64
+		Balance          float64
+		Items            []tuitionItemResponse
+		PaymentReference string
 	}
 
 	response := TuitionQueryResponse{
-		StudentNo:    student.StudentNo,
-		TuitionTotal: term[0].TuitionTotal,
-		Term:         term[0].Term,
-		Balance:      student.Balance,
+		StudentNo:        student.StudentNo,
+		TuitionTotal:     term[0].TuitionTotal,
+		Term:             term[0].Term,
+		Balance:          student.Balance,
+		Items:            items,
+		PaymentReference: reference,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// Payment channels. Payments are only taken through /banking/pay; transfers are
+// posted from imported bank statements.
+const (
+	channelBanking      = "banking"
+	channelBankTransfer = "bank_transfer"
+)
+
+// paymentResult is what posting a payment did to the student's account.
+type paymentResult struct {
+	Payment     db.Payment
+	ReceiptNo   string
+	Settled     []db.ListTuitionItemsRow
+	Outstanding float64
+}
+
+// postPayment pays amount towards the student's tuition for term in one
+// transaction: fee items are settled in priority order, the rest stays on the
+// balance, and a receipt is issued. partner is the bank partner that took the
+// payment, if any. The student must exist; without an active tuition for the term
+// it returns pgx.ErrNoRows, and errTuitionCancelled when the tuition is cancelled
+// while the payment is posted.
+func postPayment(ctx context.Context, store Store, studentNo, term string, amount float64, channel, partner string) (paymentResult, error) {
+	var result paymentResult
+	err := store.WithTx(ctx, func(tx Store) error {
+		// Lock the student so concurrent payments can't overwrite each other's balance
+		balance, err := tx.LockStudentBalance(ctx, studentNo)
+		if err != nil {
+			return err
+		}
+		tuition, err := tx.GetTuitionByTerm(ctx, db.GetTuitionByTermParams{
+			StudentNo: studentNo,
+			Term:      term,
+		})
+		if err != nil {
+			return err
+		}
+		if len(tuition) == 0 {
+			return pgx.ErrNoRows
+		}
+		// Then the tuition, so an amend or cancel can't change it under the payment
+		locked, err := tx.LockTuition(ctx, tuition[0].TuitionID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != "active" {
+			return errTuitionCancelled
+		}
+
+		// Fee items are settled in priority order; the rest stays on the balance
+		var currentBalance float64
+		currentBalance, result.Outstanding, result.Settled, err = settleTuitionItems(ctx, tx, tuition[0].TuitionID, balance+amount)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UpdateBalance(ctx, db.UpdateBalanceParams{
+			StudentNo: studentNo,
+			Balance:   currentBalance,
+		})
+		if err != nil {
+			return err
+		}
+		result.Payment, err = tx.AddPayment(ctx, db.AddPaymentParams{
+			StudentNo:    studentNo,
+			Term:         term,
+			Amount:       amount,
+			BalanceAfter: currentBalance,
+			Channel:      channel,
+			Partner:      optionalText(partner),
+		})
+		if err != nil {
+			return err
+		}
+		result.ReceiptNo, err = issueReceipt(ctx, tx, result.Payment, result.Outstanding, result.Settled)
+		return err
+	})
+	return result, err
+}
+
 // Banking App - Pay Tuition (No Auth, No Paging)
 func (a *App) PayTuitionHandler(w http.ResponseWriter, r *http.Request) {
 	// TODO: rate limit on gateway level
@@ -115,24 +222,66 @@ func (a *App) PayTuitionHandler(w http.ResponseWriter, r *http.Request) {
 	amountStr := q.Get("amount")
 	if amountStr != "" {
 		amount, err := strconv.ParseFloat(amountStr, 64)
-		if err != nil {
+		if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
 			http.Error(w, `{"error":"Invalid amount"}`, http.StatusBadRequest)
 			return
 		}
 		req.Amount = amount
 	}
 
+	// A payment reference stands for the student and term it was made for
+	if ref := q.Get("reference"); ref != "" {
+		ref = normalizeReference(ref)
+		if !validReference(ref) {
+			http.Error(w, `{"error":"Invalid payment reference"}`, http.StatusBadRequest)
+			return
+		}
+		reference, err := a.Store.GetPaymentReference(r.Context(), ref)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, `{"error":"Unknown payment reference"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Payment reference cannot be checked"}`, http.StatusInternalServerError)
+			return
+		}
+		if (req.StudentNo != "" && req.StudentNo != reference.StudentNo) || (req.Term != "" && req.Term != reference.Term) {
+			http.Error(w, `{"error":"The payment reference is for another student or term"}`, http.StatusBadRequest)
+			return
+		}
+		req.StudentNo, req.Term = reference.StudentNo, reference.Term
+	}
+
 	if req.StudentNo == "" || req.Term == "" {
-		http.Error(w, `{"error":"student_no and term are required"}`, http.StatusBadRequest)
+		http.Error(w, `{"error":"student_no and term, or reference, are required"}`, http.StatusBadRequest)
 		return
 	}
 
+	// Payments made with a bank partner's token are settled with that partner
+	partner, isPartner := partnerFromContext(r.Context())
+	if code := r.Header.Get(partnerHeader); code != "" && code != partner {
+		http.Error(w, `{"error":"The token is not for this bank partner"}`, http.StatusForbidden)
+		return
+	}
+	if isPartner {
+		_, err := a.Store.GetBankPartner(r.Context(), partner)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, `{"error":"Unknown bank partner"}`, http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Bank partner cannot be checked"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	type PaymentResponse struct {
 		TransactionStatus
-		Balance float64 `json:"balance,omitempty"`
+		Balance   float64 `json:"balance,omitempty"`
+		ReceiptNo string  `json:"receipt_no,omitempty"`
 	}
 
-	student, err := a.Queries.GetStudentById(a.Context, req.StudentNo)
+	student, err := a.Store.GetStudentById(r.Context(), req.StudentNo)
 
 	if err != nil {
 		response := PaymentResponse{
@@ -148,6 +297,11 @@ func (a *App) PayTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if student.DeactivatedAt.Valid {
+		http.Error(w, `{"error":"Student is deactivated"}`, http.StatusForbidden)
+		return
+	}
+
 	if req.Amount <= 0 {
 		response := PaymentResponse{
 			TransactionStatus: TransactionStatus{
@@ -160,7 +314,7 @@ func (a *App) PayTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	term, err := a.Queries.GetTuitionByTerm(a.Context, db.GetTuitionByTermParams{
+	term, err := a.Store.GetTuitionByTerm(r.Context(), db.GetTuitionByTermParams{
 		StudentNo: req.StudentNo,
 		Term:      req.Term,
 	})
@@ -174,48 +328,57 @@ func (a *App) PayTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	balanceSum := student.Balance + req.Amount
-
-	if balanceSum < student.TuitionTotal.Float64 {
-
-		a.Queries.UpdateBalance(a.Context, db.UpdateBalanceParams{
-			StudentNo: student.StudentNo,
-			Balance:   balanceSum,
-		})
+	paid, err := postPayment(r.Context(), a.Store, student.StudentNo, req.Term, req.Amount, channelBanking, partner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Cancelled after the check above
+		http.Error(w, `{"error":"There is no tuition set for this term"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errTuitionCancelled) {
+		http.Error(w, `{"error":"The tuition was cancelled"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Payment could not be processed"}`, http.StatusInternalServerError)
+		return
+	}
+	currentBalance, outstanding, settled, receiptNo := paid.Payment.BalanceAfter, paid.Outstanding, paid.Settled, paid.ReceiptNo
 
+	if outstanding > 0 && len(settled) > 0 {
 		response := PaymentResponse{
 			TransactionStatus: TransactionStatus{
 				Status:  "Successful",
-				Message: fmt.Sprintf("Entered amount added to balance.Balance: %.2f", balanceSum),
+				Message: fmt.Sprintf("You paid %d fee item(s) of this term.Remaining: %.2f\n Balance: %.2f", len(settled), outstanding, currentBalance),
 			},
+			ReceiptNo: receiptNo,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 		return
 	}
-	if balanceSum >= student.TuitionTotal.Float64 {
-		currentBalance := balanceSum - student.TuitionTotal.Float64
-
-		a.Queries.UpdateBalance(a.Context, db.UpdateBalanceParams{
-			StudentNo: student.StudentNo,
-			Balance:   currentBalance,
-		})
-		a.Queries.ResetTuitionTotal(a.Context, db.ResetTuitionTotalParams{
-			StudentNo: student.StudentNo,
-			Term:      req.Term,
-		})
 
+	if outstanding > 0 {
 		response := PaymentResponse{
 			TransactionStatus: TransactionStatus{
 				Status:  "Successful",
-				Message: fmt.Sprintf("You paid this term's tuition.Any excess amount added to balance.\n Balance: %.2f", currentBalance),
+				Message: fmt.Sprintf("Entered amount added to balance.Balance: %.2f", currentBalance),
 			},
+			ReceiptNo: receiptNo,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
+	response := PaymentResponse{
+		TransactionStatus: TransactionStatus{
+			Status:  "Successful",
+			Message: fmt.Sprintf("You paid this term's tuition.Any excess amount added to balance.\n Balance: %.2f", currentBalance),
+		},
+		ReceiptNo: receiptNo,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 // User - Register To system
@@ -240,12 +403,17 @@ func (a *App) registerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if student, err := a.Store.GetStudent(r.Context(), req.StudentNo); err == nil && student.DeactivatedAt.Valid {
+		http.Error(w, `{"error":"Student is deactivated"}`, http.StatusForbidden)
+		return
+	}
+
 	hashedPassword, err := HashPassword(req.RawPassword)
 	if err != nil {
 		http.Error(w, `{"error":"cannot hash password"}`, http.StatusBadRequest)
 		return
 	}
-	err = a.Queries.AddStudentAccount(a.Context, db.AddStudentAccountParams{
+	err = a.Store.AddStudentAccount(r.Context(), db.AddStudentAccountParams{
 		StudentNo:      req.StudentNo,
 		HashedPassword: hashedPassword,
 	})
@@ -287,7 +455,7 @@ func (a *App) loginHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"student_no and password are required"}`, http.StatusBadRequest)
 		return
 	}
-	account, err := a.Queries.GetAccountByStudentNo(a.Context, req.StudentNo)
+	account, err := a.Store.GetAccountByStudentNo(r.Context(), req.StudentNo)
 	if err != nil {
 		http.Error(w, `{"error":"Cannot get account by student No"}`, http.StatusBadRequest)
 		return
@@ -300,6 +468,16 @@ func (a *App) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	student, err := a.Store.GetStudent(r.Context(), req.StudentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusBadRequest)
+		return
+	}
+	if student.DeactivatedAt.Valid {
+		http.Error(w, `{"error":"Student is deactivated"}`, http.StatusForbidden)
+		return
+	}
+
 	jwt, err := GenerateJWT(req.StudentNo)
 	setJWTCookie(w, jwt)
 
@@ -343,7 +521,7 @@ func (a *App) addStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := a.Queries.AddNewStudent(a.Context, db.AddNewStudentParams{
+	err := a.Store.AddNewStudent(r.Context(), db.AddNewStudentParams{
 		StudentNo: req.StudentNo,
 		Balance:   req.Balance,
 	})
@@ -392,13 +570,17 @@ func (a *App) addTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	student, err := a.Queries.GetStudentById(a.Context, req.StudentNo)
+	student, err := a.Store.GetStudentById(r.Context(), req.StudentNo)
 	if err != nil {
 		http.Error(w, `{"error":"There is no student with this number"}`, http.StatusBadRequest)
 		return
 	}
+	if _, err := a.Store.GetTerm(r.Context(), req.Term); err != nil {
+		http.Error(w, `{"error":"There is no term with this code"}`, http.StatusBadRequest)
+		return
+	}
 
-	term, err := a.Queries.GetTuitionByTerm(a.Context, db.GetTuitionByTermParams{
+	term, err := a.Store.GetTuitionByTerm(r.Context(), db.GetTuitionByTermParams{
 		StudentNo: req.StudentNo,
 		Term:      req.Term,
 	})
@@ -418,11 +600,18 @@ func (a *App) addTuitionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = a.Queries.AddTuitionToOneStudent(a.Context, db.AddTuitionToOneStudentParams{
-		StudentNo:    student.StudentNo,
-		Term:         req.Term,
-		TuitionTotal: req.TuitionAmount,
+	err = a.Store.WithTx(r.Context(), func(tx Store) error {
+		_, err := billTuition(r.Context(), tx, student.StudentNo, req.Term, []feeItem{{FeeType: baseFeeType, Amount: req.TuitionAmount}})
+		return err
 	})
+	if errors.Is(err, errTermTaken) {
+		http.Error(w, `{"error":"This student's tuition for this term is already set"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Tuition cannot be added"}`, http.StatusInternalServerError)
+		return
+	}
 
 	response := TransactionStatus{
 		Status:  "Success",
@@ -434,106 +623,78 @@ func (a *App) addTuitionHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// Admin - Add Tuition (Multiple)
-func (a *App) addTuitionBatchHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
+// tuitionImporter bills one single-item tuition per row.
+var tuitionImporter = importer{
+	Fields: []importField{
+		{Name: "student_no", Aliases: []string{"student_number", "student"}, Required: true, Example: "22070006071"},
+		{Name: "term", Required: true, Example: "Fall2025"},
+		{Name: "amount", Aliases: []string{"tuition_amount"}, Required: true, Example: "120000"},
+	},
+	Apply: importTuitionRow,
+}
 
-	type Tuition struct {
-		StudentNo     string
-		Term          string
-		TuitionAmount float64
+func importTuitionRow(ctx context.Context, tx Store, row importRow) (string, error) {
+	studentNo, err := requiredValue(row, "student_no")
+	if err != nil {
+		return "", err
 	}
-
-	file, _, err := r.FormFile("file")
+	term, err := requiredValue(row, "term")
 	if err != nil {
-		http.Error(w, "Error reading file: "+err.Error(), http.StatusBadRequest)
-		return
+		return "", err
 	}
-	defer file.Close()
-
-	// // Save file locally (optional)
-	// out, err := os.Create("./uploads/" + header.Filename)
-	// if err != nil {
-	// 	http.Error(w, "Cannot create file: "+err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
-	// defer out.Close()
-	//
-	// _, err = io.Copy(out, file)
-	// if err != nil {
-	// 	http.Error(w, "Cannot save file: "+err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-
+	amount, ok, err := numberValue(row, "amount")
 	if err != nil {
-		http.Error(w, `cannot read csv file`, http.StatusBadRequest)
-		panic(err)
+		return "", err
 	}
-	var tuitions []Tuition
-
-	// skip header
-	for _, row := range records[1:] {
-		amt, _ := strconv.ParseFloat(row[2], 64)
-
-		tuitions = append(tuitions, Tuition{
-			StudentNo:     row[0],
-			Term:          row[1],
-			TuitionAmount: amt,
-		})
+	if !ok {
+		return "", rowError("amount", "amount is required")
+	}
+	if amount <= 0 {
+		return "", rowError("amount", "amount must be positive")
 	}
 
-	for _, t := range tuitions {
-		student, err := a.Queries.GetStudentById(a.Context, t.StudentNo)
-		if err != nil {
-			http.Error(w, `{"error":"There is no student with this number"}`, http.StatusBadRequest)
-			return
-		}
-
-		term, err := a.Queries.GetTuitionByTerm(a.Context, db.GetTuitionByTermParams{
-			StudentNo: t.StudentNo,
-			Term:      t.Term,
-		})
-		if err != nil {
-			http.Error(w, `{"error":"Cannot get term info"}`, http.StatusBadRequest)
-			return
-		}
-
-		if len(term) > 0 { // If entered term is set already
-			response := TransactionStatus{
-				Status:  "Error",
-				Message: fmt.Sprintf("This student's tuition for this term is already set"),
-			}
-
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		err = a.Queries.AddTuitionToOneStudent(a.Context, db.AddTuitionToOneStudentParams{
-			StudentNo:    student.StudentNo,
-			Term:         t.Term,
-			TuitionTotal: t.TuitionAmount,
-		})
-
-		response := TransactionStatus{
-			Status:  "Success",
-			Message: fmt.Sprintf("Tuition of %.2f added for student %s, term %s  ", t.TuitionAmount, t.StudentNo, t.Term),
-		}
+	if _, err := tx.GetStudentById(ctx, studentNo); errors.Is(err, pgx.ErrNoRows) {
+		return "", rowError("student_no", "There is no student with this number")
+	} else if err != nil {
+		return "", err
+	}
+	if _, err := tx.GetTerm(ctx, term); errors.Is(err, pgx.ErrNoRows) {
+		return "", rowError("term", "There is no term with this code")
+	} else if err != nil {
+		return "", err
+	}
+	// Also catches a student and term repeated in the file
+	existing, err := tx.GetTuitionByTerm(ctx, db.GetTuitionByTermParams{StudentNo: studentNo, Term: term})
+	if err != nil {
+		return "", err
+	}
+	if len(existing) > 0 {
+		return "", rowError("term", "This student's tuition for this term is already set")
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	_, err = billTuition(ctx, tx, studentNo, term, []feeItem{{FeeType: baseFeeType, Amount: amount}})
+	if errors.Is(err, errTermTaken) {
+		return "", rowError("term", "This student's tuition for this term is already set")
+	}
+	return importCreated, err
+}
 
+// Admin - Add Tuition (Multiple) from a CSV, XLSX or JSON file with student_no, term and amount columns
+func (a *App) addTuitionBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
 	}
 
+	a.handleImport(w, r, "tuitions")
+}
+
+// Admin - Template file for the tuition batch import
+func (a *App) tuitionTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	writeImportTemplate(w, r, "tuitions")
 }
 
-// Admin - Unpaid Tuition Status
+// Admin - API Request Logs
 func (a *App) getLogsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
@@ -564,12 +725,23 @@ func (a *App) getLogsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// Admin - Unpaid Tuition Status
+// Admin - Unpaid Tuition Status. Downloads in other formats are written by
+// exportUnpaidTuitions.
 func (a *App) unpaidTuitionStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		return
 	}
+	format, ok := reportFormat(r)
+	if !ok {
+		http.Error(w, `{"error":"format must be json, csv, xlsx or pdf"}`, http.StatusBadRequest)
+		return
+	}
+	if format != formatJSON {
+		a.exportUnpaidTuitions(w, r, format)
+		return
+	}
+
 	limitInt := 10
 	offsetInt := 0
 
@@ -578,15 +750,17 @@ func (a *App) unpaidTuitionStatusHandler(w http.ResponseWriter, r *http.Request)
 
 	if limit != "" {
 		tmp, err := strconv.Atoi(limit)
-		if err != nil {
+		if err != nil || tmp < 0 {
 			http.Error(w, `Limit must be a number`, http.StatusBadRequest)
+			return
 		}
 		limitInt = tmp
 	}
 	if offset != "" {
 		tmp, err := strconv.Atoi(offset)
-		if err != nil {
+		if err != nil || tmp < 0 {
 			http.Error(w, `Offset must be a number`, http.StatusBadRequest)
+			return
 		}
 
 		offsetInt = tmp
@@ -598,7 +772,7 @@ func (a *App) unpaidTuitionStatusHandler(w http.ResponseWriter, r *http.Request)
 	}
 	var response []UnpaidStudent
 
-	unpaid, err := a.Queries.UnpaidTuitions(a.Context, db.UnpaidTuitionsParams{
+	unpaid, err := a.Store.UnpaidTuitions(r.Context(), db.UnpaidTuitionsParams{
 		Limit:  int32(limitInt),
 		Offset: int32(offsetInt),
 	})
@@ -619,7 +793,25 @@ func (a *App) unpaidTuitionStatusHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // Health check
-func healthHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"status":"healthy","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := a.Store.Ping(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"unhealthy","database":"unreachable","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
+		return
+	}
+
+	switch store := a.Store.(type) {
+	case *PostgresStore:
+		stat := store.pool.Stat()
+		w.Write([]byte(fmt.Sprintf(`{"status":"healthy","database":"up","open_connections":%d,"idle_connections":%d,"timestamp":"%s"}`,
+			stat.TotalConns(), stat.IdleConns(), time.Now().Format(time.RFC3339))))
+	case *SQLiteStore:
+		w.Write([]byte(`{"status":"healthy","database":"sqlite","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
+	default:
+		w.Write([]byte(`{"status":"healthy","database":"memory","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
+	}
 }
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type feeScheduleResponse struct {
+	ScheduleID     int32   `json:"schedule_id"`
+	Program        string  `json:"program"`
+	EnrollmentYear int32   `json:"enrollment_year"`
+	Term           string  `json:"term"`
+	FeeType        string  `json:"fee_type"`
+	Amount         float64 `json:"amount"`
+}
+
+// scheduledTuition is the tuition a generate run bills, or would bill, for one student.
+type scheduledTuition struct {
+	StudentNo      string    `json:"student_no"`
+	Program        string    `json:"program"`
+	EnrollmentYear int32     `json:"enrollment_year"`
+	Total          float64   `json:"total"`
+	Items          []feeItem `json:"items"`
+}
+
+type generateResult struct {
+	Term          string             `json:"term"`
+	DryRun        bool               `json:"dry_run"`
+	Created       int                `json:"created"`
+	AlreadyBilled int64              `json:"already_billed"`
+	TotalAmount   float64            `json:"total_amount"`
+	Tuitions      []scheduledTuition `json:"tuitions"`
+}
+
+func newFeeScheduleResponse(f db.FeeSchedule) feeScheduleResponse {
+	return feeScheduleResponse{
+		ScheduleID:     f.ScheduleID,
+		Program:        f.Program,
+		EnrollmentYear: f.EnrollmentYear,
+		Term:           f.Term,
+		FeeType:        f.FeeType,
+		Amount:         f.Amount,
+	}
+}
+
+func scheduleIDFromPath(r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(r.PathValue("schedule_id"), 10, 32)
+	return int32(id), err == nil && id > 0
+}
+
+// generateTuitions bills every enrolled student the fee schedules of term cover and
+// who has no active tuition for it yet, so running it again only picks up students
+// added since. A dry run reports the same tuitions without billing them.
+func generateTuitions(ctx context.Context, store Store, term string, dryRun bool) (generateResult, error) {
+	result := generateResult{Term: term, DryRun: dryRun, Tuitions: []scheduledTuition{}}
+	err := store.WithTx(ctx, func(tx Store) error {
+		if _, err := tx.GetTerm(ctx, term); errors.Is(err, pgx.ErrNoRows) {
+			return errTermNotFound
+		} else if err != nil {
+			return err
+		}
+		charges, err := tx.ListScheduledCharges(ctx, term)
+		if err != nil {
+			return err
+		}
+		result.AlreadyBilled, err = tx.CountScheduledBilled(ctx, term)
+		if err != nil {
+			return err
+		}
+
+		// Charges come grouped by student
+		var pending []scheduledTuition
+		for _, c := range charges {
+			if len(pending) == 0 || pending[len(pending)-1].StudentNo != c.StudentNo {
+				pending = append(pending, scheduledTuition{StudentNo: c.StudentNo, Program: c.Program, EnrollmentYear: c.EnrollmentYear})
+			}
+			t := &pending[len(pending)-1]
+			t.Items = append(t.Items, feeItem{FeeType: c.FeeType, Amount: c.Amount})
+			t.Total += c.Amount
+		}
+
+		for _, t := range pending {
+			if !dryRun {
+				// A concurrent run or admin may bill the student after the list above was
+				// read; the unique index turns that into errTermTaken. The savepoint keeps
+				// the violation from aborting the rest of the run on Postgres.
+				err := tx.WithTx(ctx, func(tx Store) error {
+					_, err := billTuition(ctx, tx, t.StudentNo, term, t.Items)
+					return err
+				})
+				if errors.Is(err, errTermTaken) {
+					result.AlreadyBilled++
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			result.Created++
+			result.TotalAmount += t.Total
+			result.Tuitions = append(result.Tuitions, t)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// runGenerateCommand is the command line version of the generate endpoint:
+// generate-tuitions [-dry-run] TERM
+func runGenerateCommand(ctx context.Context, store Store, args []string) error {
+	flags := flag.NewFlagSet("generate-tuitions", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "list the tuitions without billing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: generate-tuitions [-dry-run] TERM")
+	}
+
+	result, err := generateTuitions(ctx, store, flags.Arg(0), *dryRun)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STUDENT\tPROGRAM\tYEAR\tTOTAL")
+	for _, t := range result.Tuitions {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\n", t.StudentNo, t.Program, t.EnrollmentYear, t.Total)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	verb := "billed"
+	if result.DryRun {
+		verb = "would bill"
+	}
+	fmt.Printf("%s %d tuition(s) totalling %.2f for %s; %d student(s) were already billed\n",
+		verb, result.Created, result.TotalAmount, result.Term, result.AlreadyBilled)
+	return nil
+}
+
+// Admin - List Fee Schedules
+func (a *App) listFeeSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	schedules, err := a.Store.ListFeeSchedules(r.Context(), db.ListFeeSchedulesParams{
+		Term:    optionalText(q.Get("term")),
+		Program: optionalText(q.Get("program")),
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Fee schedules cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListFeeSchedulesResponse struct {
+		FeeSchedules []feeScheduleResponse `json:"fee_schedules"`
+	}
+	response := ListFeeSchedulesResponse{FeeSchedules: []feeScheduleResponse{}}
+	for _, f := range schedules {
+		response.FeeSchedules = append(response.FeeSchedules, newFeeScheduleResponse(f))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Create Fee Schedule
+func (a *App) createFeeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	type CreateFeeScheduleRequest struct {
+		Program        string  `json:"program"`
+		EnrollmentYear int32   `json:"enrollment_year"`
+		Term           string  `json:"term"`
+		FeeType        string  `json:"fee_type"`
+		Amount         float64 `json:"amount"`
+	}
+	var req CreateFeeScheduleRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Program = strings.TrimSpace(req.Program)
+	if req.Program == "" || len([]rune(req.Program)) > nameMaxLength {
+		http.Error(w, `{"error":"program must be between 1 and 100 characters"}`, http.StatusBadRequest)
+		return
+	}
+	if req.EnrollmentYear < minEnrollmentYear {
+		http.Error(w, `{"error":"enrollment_year is required"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Term == "" {
+		http.Error(w, `{"error":"term is required"}`, http.StatusBadRequest)
+		return
+	}
+	if req.FeeType == "" {
+		req.FeeType = baseFeeType
+	}
+	if req.Amount <= 0 || math.IsInf(req.Amount, 0) {
+		http.Error(w, `{"error":"amount must be positive"}`, http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := a.Store.CreateFeeSchedule(r.Context(), db.CreateFeeScheduleParams{
+		Program:        req.Program,
+		EnrollmentYear: req.EnrollmentYear,
+		Term:           req.Term,
+		FeeType:        req.FeeType,
+		Amount:         req.Amount,
+	})
+	switch {
+	case isConstraintError(err, pgForeignKeyViolation):
+		http.Error(w, `{"error":"Unknown term or fee_type"}`, http.StatusBadRequest)
+		return
+	case isConstraintError(err, pgUniqueViolation):
+		http.Error(w, `{"error":"This fee is already scheduled for the program, cohort and term"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Fee schedule cannot be created"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newFeeScheduleResponse(schedule))
+}
+
+// Admin - Update Fee Schedule amount. Tuitions already generated keep their amounts.
+func (a *App) updateFeeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid schedule id"}`, http.StatusBadRequest)
+		return
+	}
+
+	type UpdateFeeScheduleRequest struct {
+		Amount float64 `json:"amount"`
+	}
+	var req UpdateFeeScheduleRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Amount <= 0 || math.IsInf(req.Amount, 0) {
+		http.Error(w, `{"error":"amount must be positive"}`, http.StatusBadRequest)
+		return
+	}
+
+	var schedule db.FeeSchedule
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		if _, err := tx.GetFeeSchedule(r.Context(), scheduleID); err != nil {
+			return err
+		}
+		err := tx.UpdateFeeSchedule(r.Context(), db.UpdateFeeScheduleParams{ScheduleID: scheduleID, Amount: req.Amount})
+		if err != nil {
+			return err
+		}
+		schedule, err = tx.GetFeeSchedule(r.Context(), scheduleID)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Fee schedule not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Fee schedule cannot be updated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newFeeScheduleResponse(schedule))
+}
+
+// Admin - Delete Fee Schedule
+func (a *App) deleteFeeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid schedule id"}`, http.StatusBadRequest)
+		return
+	}
+
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		if _, err := tx.GetFeeSchedule(r.Context(), scheduleID); err != nil {
+			return err
+		}
+		return tx.DeleteFeeSchedule(r.Context(), scheduleID)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Fee schedule not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Fee schedule cannot be deleted"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Admin - Generate a term's tuitions from its fee schedules
+func (a *App) generateTuitionsHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseBoolFilter(r.URL.Query(), "dry_run")
+	if !ok {
+		http.Error(w, `{"error":"dry_run must be true or false"}`, http.StatusBadRequest)
+		return
+	}
+
+	result, err := generateTuitions(r.Context(), a.Store, r.PathValue("code"), dryRun.Bool)
+	if errors.Is(err, errTermNotFound) {
+		http.Error(w, `{"error":"Term not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be generated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
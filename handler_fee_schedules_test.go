@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func (ta *testApp) createFeeSchedule(body string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	return ta.do(http.MethodPost, "/api/v2/admin/fee-schedules", adminToken(ta.t), bytes.NewBufferString(body), nil)
+}
+
+func (ta *testApp) generate(term string, dryRun bool) generateResult {
+	ta.t.Helper()
+	rec := ta.do(http.MethodPost, fmt.Sprintf("/api/v2/admin/terms/%s/generate-tuitions?dry_run=%t", term, dryRun), adminToken(ta.t), nil, nil)
+	if rec.Code != http.StatusOK {
+		ta.t.Fatalf("generate %s: %d %s", term, rec.Code, rec.Body)
+	}
+	return decodeJSON[generateResult](ta.t, rec)
+}
+
+// enroll puts the student in a program and cohort.
+func (ta *testApp) enroll(studentNo, program string, year int, status string) {
+	ta.t.Helper()
+	body := fmt.Sprintf(`{"program": %q, "enrollment_year": %d, "enrollment_status": %q}`, program, year, status)
+	rec := ta.do(http.MethodPatch, "/api/v2/admin/students/"+studentNo, adminToken(ta.t), bytes.NewBufferString(body), nil)
+	if rec.Code != http.StatusOK {
+		ta.t.Fatalf("enroll %s: %d %s", studentNo, rec.Code, rec.Body)
+	}
+}
+
+func TestFeeSchedules(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addTerm("Fall2025")
+
+		rec := ta.createFeeSchedule(`{"program": "Computer Engineering", "enrollment_year": 2022, "term": "Fall2025", "amount": 120000}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		schedule := decodeJSON[feeScheduleResponse](t, rec)
+		if schedule.FeeType != "tuition" || schedule.Amount != 120000 {
+			t.Errorf("unexpected schedule %+v", schedule)
+		}
+
+		if rec := ta.createFeeSchedule(`{"program": "Computer Engineering", "enrollment_year": 2022, "term": "Fall2025", "amount": 1}`); rec.Code != http.StatusConflict {
+			t.Errorf("duplicate schedule: got %d, want 409", rec.Code)
+		}
+		for _, body := range []string{
+			`{"program": "", "enrollment_year": 2022, "term": "Fall2025", "amount": 1}`,
+			`{"program": "Physics", "term": "Fall2025", "amount": 1}`,
+			`{"program": "Physics", "enrollment_year": 2022, "term": "Fall2099", "amount": 1}`,
+			`{"program": "Physics", "enrollment_year": 2022, "term": "Fall2025", "fee_type": "parking", "amount": 1}`,
+			`{"program": "Physics", "enrollment_year": 2022, "term": "Fall2025", "amount": 0}`,
+		} {
+			if rec := ta.createFeeSchedule(body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+
+		path := fmt.Sprintf("/api/v2/admin/fee-schedules/%d", schedule.ScheduleID)
+		rec = ta.do(http.MethodPatch, path, adminToken(t), bytes.NewBufferString(`{"amount": 125000}`), nil)
+		if got := decodeJSON[feeScheduleResponse](t, rec); got.Amount != 125000 {
+			t.Errorf("update: %d %+v", rec.Code, got)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/fee-schedules?program=Computer+Engineering", adminToken(t), nil, nil)
+		list := decodeJSON[struct {
+			FeeSchedules []feeScheduleResponse `json:"fee_schedules"`
+		}](t, rec)
+		if len(list.FeeSchedules) != 1 {
+			t.Errorf("unexpected schedules %+v", list.FeeSchedules)
+		}
+
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/terms/Fall2025", adminToken(t), nil, nil); rec.Code != http.StatusConflict {
+			t.Errorf("deleting a scheduled term: got %d, want 409", rec.Code)
+		}
+		if rec := ta.do(http.MethodDelete, path, adminToken(t), nil, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("delete: got %d", rec.Code)
+		}
+		if rec := ta.do(http.MethodDelete, path, adminToken(t), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("delete again: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestGenerateTuitions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addTerm("Fall2025")
+		for _, body := range []string{
+			`{"program": "Computer Engineering", "enrollment_year": 2022, "term": "Fall2025", "amount": 120000}`,
+			`{"program": "Computer Engineering", "enrollment_year": 2022, "term": "Fall2025", "fee_type": "lab", "amount": 5000}`,
+			`{"program": "Computer Engineering", "enrollment_year": 2023, "term": "Fall2025", "amount": 130000}`,
+		} {
+			if rec := ta.createFeeSchedule(body); rec.Code != http.StatusCreated {
+				t.Fatalf("%s: %d %s", body, rec.Code, rec.Body)
+			}
+		}
+		for _, no := range []string{"22070006071", "22070006072", "22070006073", "22070006074", "22070006075"} {
+			ta.addStudent(no, 10)
+		}
+		ta.enroll("22070006071", "Computer Engineering", 2022, "enrolled")
+		ta.enroll("22070006072", "Computer Engineering", 2023, "enrolled")
+		ta.enroll("22070006073", "Computer Engineering", 2022, "graduated")
+		ta.enroll("22070006074", "Physics", 2022, "enrolled")
+		ta.enroll("22070006075", "Computer Engineering", 2022, "enrolled")
+		ta.addTuition("22070006075", "Fall2025", 1000)
+
+		preview := ta.generate("Fall2025", true)
+		if !preview.DryRun || preview.Created != 2 || preview.AlreadyBilled != 1 || preview.TotalAmount != 255000 {
+			t.Fatalf("unexpected preview %+v", preview)
+		}
+		first := preview.Tuitions[0]
+		if first.StudentNo != "22070006071" || first.Total != 125000 || len(first.Items) != 2 || first.Items[0].FeeType != "tuition" {
+			t.Errorf("unexpected preview tuition %+v", first)
+		}
+		rec := ta.do(http.MethodGet, "/api/v2/admin/tuitions?term=Fall2025", adminToken(t), nil, nil)
+		if got := decodeJSON[struct{ Total int64 }](t, rec); got.Total != 1 {
+			t.Errorf("dry run billed tuitions: %d tuitions", got.Total)
+		}
+
+		result := ta.generate("Fall2025", false)
+		if result.DryRun || result.Created != 2 || result.TotalAmount != 255000 {
+			t.Fatalf("unexpected result %+v", result)
+		}
+		if total := ta.tuitionTotal("22070006071", "Fall2025"); total != 125000 {
+			t.Errorf("generated tuition: %v", total)
+		}
+		if total := ta.tuitionTotal("22070006075", "Fall2025"); total != 1000 {
+			t.Errorf("already billed student was billed again: %v", total)
+		}
+
+		// Running it again bills nobody
+		if again := ta.generate("Fall2025", false); again.Created != 0 || again.AlreadyBilled != 3 || len(again.Tuitions) != 0 {
+			t.Errorf("second run: %+v", again)
+		}
+
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/terms/Fall2099/generate-tuitions", adminToken(t), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown term: got %d, want 404", rec.Code)
+		}
+
+		// Concurrent runs skip students another run billed instead of failing
+		ta.addTerm("Spring2026")
+		if rec := ta.createFeeSchedule(`{"program": "Computer Engineering", "enrollment_year": 2022, "term": "Spring2026", "amount": 120000}`); rec.Code != http.StatusCreated {
+			t.Fatalf("spring schedule: %d %s", rec.Code, rec.Body)
+		}
+		var wg sync.WaitGroup
+		var created atomic.Int32
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := ta.do(http.MethodPost, "/api/v2/admin/terms/Spring2026/generate-tuitions", adminToken(t), nil, nil)
+				if rec.Code != http.StatusOK {
+					t.Errorf("concurrent generate: %d %s", rec.Code, rec.Body)
+					return
+				}
+				created.Add(int32(decodeJSON[generateResult](t, rec).Created))
+			}()
+		}
+		wg.Wait()
+		if created.Load() != 2 {
+			t.Errorf("concurrent runs created %d tuitions, want 2", created.Load())
+		}
+	})
+}
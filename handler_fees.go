@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Fee type codes are stored on every item, e.g. health_insurance
+var feeTypeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// The fee type of tuitions billed as a single amount
+const baseFeeType = "tuition"
+
+var (
+	errFeeTypeNotFound = errors.New("fee type does not exist")
+	errItemized        = errors.New("tuition has more than one item")
+)
+
+type feeTypeResponse struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Priority int32  `json:"priority"`
+}
+
+type tuitionItemResponse struct {
+	ItemID      int32   `json:"item_id"`
+	FeeType     string  `json:"fee_type"`
+	FeeTypeName string  `json:"fee_type_name"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`
+	AmountPaid  float64 `json:"amount_paid"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// feeItem is one line of a tuition to be billed.
+type feeItem struct {
+	FeeType     string  `json:"fee_type"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+func newFeeTypeResponse(f db.FeeType) feeTypeResponse {
+	return feeTypeResponse{Code: f.Code, Name: f.Name, Priority: f.Priority}
+}
+
+func newTuitionItemResponse(i db.ListTuitionItemsRow) tuitionItemResponse {
+	return tuitionItemResponse{
+		ItemID:      i.ItemID,
+		FeeType:     i.FeeType,
+		FeeTypeName: i.FeeTypeName,
+		Description: i.Description,
+		Amount:      i.Amount,
+		AmountPaid:  i.AmountPaid,
+		Outstanding: i.Amount - i.AmountPaid,
+	}
+}
+
+// tuitionItems lists the items of a tuition in payment order for a response.
+func tuitionItems(ctx context.Context, store Store, tuitionID int32) ([]tuitionItemResponse, error) {
+	items, err := store.ListTuitionItems(ctx, tuitionID)
+	if err != nil {
+		return nil, err
+	}
+	out := []tuitionItemResponse{}
+	for _, i := range items {
+		out = append(out, newTuitionItemResponse(i))
+	}
+	return out, nil
+}
+
+// billTuition adds a tuition made of items for a student and term. The tuition
+// totals are the sums of the item amounts. It returns errTermTaken when the student
+// already has an active tuition for the term. Run it inside a transaction.
+func billTuition(ctx context.Context, tx Store, studentNo, term string, items []feeItem) (db.Tuition, error) {
+	var total float64
+	for _, item := range items {
+		total += item.Amount
+	}
+	tuition, err := tx.AddTuitionToOneStudent(ctx, db.AddTuitionToOneStudentParams{
+		StudentNo:    studentNo,
+		Term:         term,
+		TuitionTotal: total,
+	})
+	// Another request billed the student for the term first
+	if isConstraintError(err, pgUniqueViolation) {
+		return tuition, errTermTaken
+	}
+	if err != nil {
+		return tuition, err
+	}
+	for _, item := range items {
+		_, err := tx.AddTuitionItem(ctx, db.AddTuitionItemParams{
+			TuitionID:   tuition.TuitionID,
+			FeeType:     item.FeeType,
+			Description: item.Description,
+			Amount:      item.Amount,
+		})
+		if isConstraintError(err, pgForeignKeyViolation) {
+			return tuition, errFeeTypeNotFound
+		}
+		if err != nil {
+			return tuition, err
+		}
+	}
+	return tuition, nil
+}
+
+// settleTuitionItems charges the balance for the outstanding items of a tuition in
+// fee type priority order. Items are settled whole, and a lower priority item is
+// never settled before a higher priority one. It returns what is left of the
+// balance, what is still owed and the items that were settled, as they were before.
+func settleTuitionItems(ctx context.Context, tx Store, tuitionID int32, balance float64) (float64, float64, []db.ListTuitionItemsRow, error) {
+	items, err := tx.ListTuitionItems(ctx, tuitionID)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	var outstanding float64
+	var settled []db.ListTuitionItemsRow
+	blocked := false
+	for _, item := range items {
+		due := item.Amount - item.AmountPaid
+		if due <= 0 {
+			continue
+		}
+		if blocked || balance < due {
+			blocked = true
+			outstanding += due
+			continue
+		}
+		err := tx.SetTuitionItemPaid(ctx, db.SetTuitionItemPaidParams{
+			ItemID:     item.ItemID,
+			AmountPaid: item.Amount,
+		})
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		balance -= due
+		settled = append(settled, item)
+	}
+
+	_, err = tx.SetTuitionOutstanding(ctx, db.SetTuitionOutstandingParams{TuitionID: tuitionID, TuitionTotal: outstanding})
+	// Cancelled since the items were read
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, nil, errTuitionCancelled
+	}
+	return balance, outstanding, settled, err
+}
+
+// validateFeeItems checks the items of a new tuition and returns the error body
+// for the first invalid one.
+func validateFeeItems(items []feeItem) string {
+	if len(items) == 0 {
+		return `{"error":"at least one item is required"}`
+	}
+	for i := range items {
+		items[i].FeeType = strings.TrimSpace(items[i].FeeType)
+		items[i].Description = strings.TrimSpace(items[i].Description)
+		if items[i].FeeType == "" {
+			return `{"error":"every item needs a fee_type"}`
+		}
+		if items[i].Amount <= 0 || math.IsInf(items[i].Amount, 0) {
+			return `{"error":"item amounts must be positive"}`
+		}
+		if len([]rune(items[i].Description)) > descriptionMaxLength {
+			return `{"error":"item descriptions must be at most 200 characters"}`
+		}
+	}
+	return ""
+}
+
+// Admin - List Fee Types in payment priority order
+func (a *App) listFeeTypesHandler(w http.ResponseWriter, r *http.Request) {
+	feeTypes, err := a.Store.ListFeeTypes(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Fee types cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListFeeTypesResponse struct {
+		FeeTypes []feeTypeResponse `json:"fee_types"`
+	}
+	response := ListFeeTypesResponse{FeeTypes: []feeTypeResponse{}}
+	for _, f := range feeTypes {
+		response.FeeTypes = append(response.FeeTypes, newFeeTypeResponse(f))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Create Fee Type
+func (a *App) createFeeTypeHandler(w http.ResponseWriter, r *http.Request) {
+	type CreateFeeTypeRequest struct {
+		Code     string `json:"code"`
+		Name     string `json:"name"`
+		Priority int32  `json:"priority"`
+	}
+	var req CreateFeeTypeRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Code) > feeTypeMaxLength || !feeTypeCodePattern.MatchString(req.Code) {
+		http.Error(w, `{"error":"code must be at most 30 lowercase letters, digits or underscores"}`, http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > nameMaxLength {
+		http.Error(w, `{"error":"name must be between 1 and 100 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	feeType, err := a.Store.CreateFeeType(r.Context(), db.CreateFeeTypeParams{
+		Code:     req.Code,
+		Name:     req.Name,
+		Priority: req.Priority,
+	})
+	if isConstraintError(err, pgUniqueViolation) {
+		http.Error(w, `{"error":"A fee type with this code already exists"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Fee type cannot be created"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newFeeTypeResponse(feeType))
+}
+
+// Admin - Update Fee Type name or priority. A new priority applies to payments
+// made from now on.
+func (a *App) updateFeeTypeHandler(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	type UpdateFeeTypeRequest struct {
+		Name     *string `json:"name"`
+		Priority *int32  `json:"priority"`
+	}
+	var req UpdateFeeTypeRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil {
+		*req.Name = strings.TrimSpace(*req.Name)
+		if *req.Name == "" || len([]rune(*req.Name)) > nameMaxLength {
+			http.Error(w, `{"error":"name must be between 1 and 100 characters"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var feeType db.FeeType
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := tx.GetFeeType(r.Context(), code)
+		if err != nil {
+			return err
+		}
+		params := db.UpdateFeeTypeParams{Code: code, Name: current.Name, Priority: current.Priority}
+		if req.Name != nil {
+			params.Name = *req.Name
+		}
+		if req.Priority != nil {
+			params.Priority = *req.Priority
+		}
+		if err := tx.UpdateFeeType(r.Context(), params); err != nil {
+			return err
+		}
+		feeType, err = tx.GetFeeType(r.Context(), code)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Fee type not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Fee type cannot be updated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newFeeTypeResponse(feeType))
+}
+
+// Admin - Create an itemized Tuition
+func (a *App) createTuitionHandler(w http.ResponseWriter, r *http.Request) {
+	type CreateTuitionRequest struct {
+		StudentNo string    `json:"student_no"`
+		Term      string    `json:"term"`
+		Items     []feeItem `json:"items"`
+	}
+	var req CreateTuitionRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	if req.StudentNo == "" || req.Term == "" {
+		http.Error(w, `{"error":"student_no and term are required"}`, http.StatusBadRequest)
+		return
+	}
+	if msg := validateFeeItems(req.Items); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	var tuition db.Tuition
+	var items []tuitionItemResponse
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		if _, err := tx.GetStudent(r.Context(), req.StudentNo); err != nil {
+			return err
+		}
+		if _, err := tx.GetTerm(r.Context(), req.Term); errors.Is(err, pgx.ErrNoRows) {
+			return errTermNotFound
+		} else if err != nil {
+			return err
+		}
+		existing, err := tx.GetTuitionByTerm(r.Context(), db.GetTuitionByTermParams{StudentNo: req.StudentNo, Term: req.Term})
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return errTermTaken
+		}
+
+		tuition, err = billTuition(r.Context(), tx, req.StudentNo, req.Term, req.Items)
+		if err != nil {
+			return err
+		}
+		items, err = tuitionItems(r.Context(), tx, tuition.TuitionID)
+		return err
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"There is no student with this number"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, errTermNotFound):
+		http.Error(w, `{"error":"There is no term with this code"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, errFeeTypeNotFound):
+		http.Error(w, `{"error":"Unknown fee_type"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, errTermTaken):
+		http.Error(w, `{"error":"This student's tuition for this term is already set"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Tuition cannot be created"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type CreateTuitionResponse struct {
+		tuitionResponse
+		Items []tuitionItemResponse `json:"items"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateTuitionResponse{
+		tuitionResponse: newTuitionResponse(tuition),
+		Items:           items,
+	})
+}
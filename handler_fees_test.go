@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type itemizedTuition struct {
+	tuitionResponse
+	Items []tuitionItemResponse `json:"items"`
+}
+
+func (ta *testApp) createTuition(body string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	return ta.do(http.MethodPost, "/api/v2/admin/tuitions", adminToken(ta.t), bytes.NewBufferString(body), nil)
+}
+
+func TestFeeTypes(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		rec := ta.do(http.MethodGet, "/api/v2/admin/fee-types", adminToken(t), nil, nil)
+		list := decodeJSON[struct {
+			FeeTypes []feeTypeResponse `json:"fee_types"`
+		}](t, rec)
+		if len(list.FeeTypes) != 5 || list.FeeTypes[0].Code != "tuition" || list.FeeTypes[4].Code != "student_union" {
+			t.Fatalf("unexpected default fee types %+v", list.FeeTypes)
+		}
+
+		rec = ta.do(http.MethodPost, "/api/v2/admin/fee-types", adminToken(t), strings.NewReader(`{"code": "library", "name": "Library", "priority": 15}`), nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: got %d %s", rec.Code, rec.Body)
+		}
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/fee-types", adminToken(t), strings.NewReader(`{"code": "library", "name": "Library"}`), nil); rec.Code != http.StatusConflict {
+			t.Errorf("duplicate code: got %d, want 409", rec.Code)
+		}
+		for _, body := range []string{`{"code": "Library", "name": "Library"}`, `{"code": "books"}`, `{"code": "books", "name": "Books", "prio": 1}`} {
+			if rec := ta.do(http.MethodPost, "/api/v2/admin/fee-types", adminToken(t), strings.NewReader(body), nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+
+		rec = ta.do(http.MethodPatch, "/api/v2/admin/fee-types/library", adminToken(t), strings.NewReader(`{"priority": 5}`), nil)
+		if got := decodeJSON[feeTypeResponse](t, rec); got.Name != "Library" || got.Priority != 5 {
+			t.Errorf("update: %d %+v", rec.Code, got)
+		}
+		if rec := ta.do(http.MethodPatch, "/api/v2/admin/fee-types/parking", adminToken(t), strings.NewReader(`{"priority": 5}`), nil); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown fee type: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestCreateItemizedTuition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("2025-FALL")
+
+		rec := ta.createTuition(`{"student_no": "22070006071", "term": "2025-FALL", "items": [
+			{"fee_type": "dormitory", "amount": 300},
+			{"fee_type": "tuition", "amount": 1000},
+			{"fee_type": "lab", "description": "Chemistry lab", "amount": 50}
+		]}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		got := decodeJSON[itemizedTuition](t, rec)
+		if got.BilledTotal != 1350 || got.TuitionTotal != 1350 || len(got.Items) != 3 {
+			t.Fatalf("unexpected tuition %+v", got)
+		}
+		// Items come back in payment order
+		if got.Items[0].FeeType != "tuition" || got.Items[1].FeeType != "lab" || got.Items[2].FeeType != "dormitory" || got.Items[1].Description != "Chemistry lab" {
+			t.Errorf("unexpected items %+v", got.Items)
+		}
+
+		if rec := ta.createTuition(`{"student_no": "22070006071", "term": "2025-FALL", "items": [{"fee_type": "tuition", "amount": 10}]}`); rec.Code != http.StatusConflict {
+			t.Errorf("second tuition for term: got %d, want 409", rec.Code)
+		}
+		for _, body := range []string{
+			`{"student_no": "22070006071", "term": "2025-FALL", "items": []}`,
+			`{"student_no": "22070006071", "term": "2026-SPRING", "items": [{"fee_type": "tuition", "amount": 10}]}`,
+			`{"student_no": "22070009999", "term": "2025-FALL", "items": [{"fee_type": "tuition", "amount": 10}]}`,
+			`{"student_no": "22070006071", "term": "2025-FALL", "items": [{"fee_type": "tuition", "amount": -10}]}`,
+		} {
+			if rec := ta.createTuition(body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+		ta.addTerm("2026-SPRING")
+		if rec := ta.createTuition(`{"student_no": "22070006071", "term": "2026-SPRING", "items": [{"fee_type": "parking", "amount": 10}]}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown fee type: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestPaymentAllocation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("2025-FALL")
+		rec := ta.createTuition(`{"student_no": "22070006071", "term": "2025-FALL", "items": [
+			{"fee_type": "tuition", "amount": 1000},
+			{"fee_type": "health_insurance", "amount": 200},
+			{"fee_type": "dormitory", "amount": 300}
+		]}`)
+		tuition := decodeJSON[itemizedTuition](t, rec)
+		token := ta.register("22070006071", "secret")
+
+		// 1100 covers tuition; insurance stays due, and dormitory may not jump the queue
+		ta.pay(token, "22070006071", "2025-FALL", "1100")
+		if total, balance := ta.tuitionTotal("22070006071", "2025-FALL"), ta.balance("22070006071"); total != 500 || balance != 110 {
+			t.Errorf("after first payment: owed %v, balance %v", total, balance)
+		}
+
+		// Dormitory first from now on
+		ta.do(http.MethodPatch, "/api/v2/admin/fee-types/dormitory", adminToken(t), strings.NewReader(`{"priority": 1}`), nil)
+		ta.pay(token, "22070006071", "2025-FALL", "190")
+		if total, balance := ta.tuitionTotal("22070006071", "2025-FALL"), ta.balance("22070006071"); total != 200 || balance != 0 {
+			t.Errorf("after second payment: owed %v, balance %v", total, balance)
+		}
+
+		rec = ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/tuitions/%d", tuition.TuitionID), adminToken(t), nil, nil)
+		detail := decodeJSON[itemizedTuition](t, rec)
+		paid := map[string]float64{}
+		for _, item := range detail.Items {
+			paid[item.FeeType] = item.AmountPaid
+		}
+		if paid["tuition"] != 1000 || paid["dormitory"] != 300 || paid["health_insurance"] != 0 || detail.AmountPaid != 1300 {
+			t.Errorf("unexpected allocation %+v", detail)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/mobile/tuition?student_no=22070006071&active_term=2025-FALL", token, nil, nil)
+		query := decodeJSON[struct {
+			TuitionTotal float64
+			Items        []tuitionItemResponse
+		}](t, rec)
+		if query.TuitionTotal != 200 || len(query.Items) != 3 || query.Items[0].FeeType != "dormitory" {
+			t.Errorf("unexpected query response %+v", query)
+		}
+
+		if rec := ta.amend(tuition.TuitionID, `{"amount": 2000, "reason": "raise"}`); rec.Code != http.StatusConflict {
+			t.Errorf("amending an itemized amount: got %d, want 409", rec.Code)
+		}
+	})
+}
+
+func TestSettleCancelledTuition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("2025-FALL")
+		tuition := decodeJSON[itemizedTuition](t, ta.createTuition(`{"student_no": "22070006071", "term": "2025-FALL", "items": [
+			{"fee_type": "tuition", "amount": 1000}
+		]}`))
+		if rec := ta.cancel(tuition.TuitionID, `{"reason": "withdrew"}`); rec.Code != http.StatusOK {
+			t.Fatalf("cancel: %d %s", rec.Code, rec.Body)
+		}
+
+		// As a payment that read the tuition before it was cancelled would
+		ctx := context.Background()
+		err := ta.app.Store.WithTx(ctx, func(tx Store) error {
+			_, _, _, err := settleTuitionItems(ctx, tx, tuition.TuitionID, 1000)
+			return err
+		})
+		if !errors.Is(err, errTuitionCancelled) {
+			t.Fatalf("settle cancelled tuition: %v", err)
+		}
+		items, err := ta.app.Store.ListTuitionItems(ctx, tuition.TuitionID)
+		if err != nil || len(items) != 1 || items[0].AmountPaid != 0 || items[0].Amount != 1000 {
+			t.Fatalf("items: %v %+v", err, items)
+		}
+	})
+}
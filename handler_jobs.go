@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type importJobResponse struct {
+	JobID           int32            `json:"job_id"`
+	Kind            string           `json:"kind"`
+	Status          string           `json:"status"`
+	Filename        string           `json:"filename"`
+	Format          string           `json:"format"`
+	Mode            string           `json:"mode"`
+	DryRun          bool             `json:"dry_run"`
+	TotalRows       int32            `json:"total_rows"`
+	ProcessedRows   int32            `json:"processed_rows"`
+	Imported        int32            `json:"imported"`
+	Created         int32            `json:"created"`
+	Updated         int32            `json:"updated"`
+	Skipped         int32            `json:"skipped"`
+	Invalid         int32            `json:"invalid"`
+	Committed       bool             `json:"committed"`
+	CancelRequested bool             `json:"cancel_requested"`
+	Failure         string           `json:"failure,omitempty"`
+	CreatedBy       string           `json:"created_by"`
+	CreatedAt       time.Time        `json:"created_at"`
+	StartedAt       *time.Time       `json:"started_at,omitempty"`
+	FinishedAt      *time.Time       `json:"finished_at,omitempty"`
+	Errors          []importRowError `json:"errors,omitempty"`
+}
+
+func newImportJobResponse(j db.ImportJob) importJobResponse {
+	return importJobResponse{
+		JobID:           j.JobID,
+		Kind:            j.Kind,
+		Status:          j.Status,
+		Filename:        j.Filename,
+		Format:          j.Format,
+		Mode:            j.Mode,
+		DryRun:          j.DryRun,
+		TotalRows:       j.TotalRows,
+		ProcessedRows:   j.ProcessedRows,
+		Imported:        j.Imported,
+		Created:         j.Created,
+		Updated:         j.Updated,
+		Skipped:         j.Skipped,
+		Invalid:         j.Invalid,
+		Committed:       j.Committed,
+		CancelRequested: j.CancelRequested,
+		Failure:         j.Failure,
+		CreatedBy:       j.CreatedBy,
+		CreatedAt:       j.CreatedAt.Time,
+		StartedAt:       timePtr(j.StartedAt),
+		FinishedAt:      timePtr(j.FinishedAt),
+	}
+}
+
+func jobIDFromPath(r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(r.PathValue("job_id"), 10, 32)
+	return int32(id), err == nil && id > 0
+}
+
+// queueImport accepts an upload as a background job of the given kind. The file is
+// read once here, so one that cannot be imported at all is still rejected with 400.
+func (a *App) queueImport(w http.ResponseWriter, r *http.Request, kind, format, filename string, payload []byte, opts importOptions) {
+	imp := jobImporters[kind]
+	table, fileErrs := readImportFile(format, bytes.NewReader(payload), imp.Fields, opts.Mapping)
+	if len(fileErrs) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(importSummary{Mode: opts.Mode, DryRun: opts.DryRun, Invalid: len(fileErrs), Errors: fileErrs})
+		return
+	}
+	mapping, err := json.Marshal(opts.Mapping)
+	if err != nil {
+		http.Error(w, `{"error":"Import cannot be queued"}`, http.StatusInternalServerError)
+		return
+	}
+	if len(filename) > jobFilenameMaxLength {
+		filename = strings.ToValidUTF8(filename[:jobFilenameMaxLength], "")
+	}
+	createdBy, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+
+	var job db.ImportJob
+	err = a.Store.WithTx(r.Context(), func(tx Store) error {
+		var err error
+		job, err = tx.CreateImportJob(r.Context(), db.CreateImportJobParams{
+			Kind:      kind,
+			Filename:  filename,
+			Format:    format,
+			Mode:      opts.Mode,
+			DryRun:    opts.DryRun,
+			Mapping:   string(mapping),
+			TotalRows: int32(len(table.Rows) + len(table.Errors)),
+			CreatedBy: createdBy,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.AddImportJobFile(r.Context(), db.AddImportJobFileParams{JobID: job.JobID, Payload: payload})
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Import cannot be queued"}`, http.StatusInternalServerError)
+		return
+	}
+	a.Jobs.notify()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v2/admin/jobs/%d", job.JobID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newImportJobResponse(job))
+}
+
+// Admin - List Import Jobs
+func (a *App) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, offset, ok := parsePage(q)
+	if !ok {
+		http.Error(w, `{"error":"limit must be between 0 and 100 and offset must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+	status := q.Get("status")
+	if status != "" && !slices.Contains(jobStatuses, status) {
+		http.Error(w, `{"error":"status must be queued, running, completed, failed or cancelled"}`, http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := a.Store.ListImportJobs(r.Context(), db.ListImportJobsParams{
+		Status:    optionalText(status),
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Jobs cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	total, err := a.Store.CountImportJobs(r.Context(), optionalText(status))
+	if err != nil {
+		http.Error(w, `{"error":"Jobs cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListJobsResponse struct {
+		Jobs   []importJobResponse `json:"jobs"`
+		Total  int64               `json:"total"`
+		Limit  int32               `json:"limit"`
+		Offset int32               `json:"offset"`
+	}
+	response := ListJobsResponse{Jobs: []importJobResponse{}, Total: total, Limit: limit, Offset: offset}
+	for _, j := range jobs {
+		// Jobs running here report their live progress
+		if live, _, ok := a.Jobs.snapshot(j.JobID); ok {
+			j = live
+		}
+		response.Jobs = append(response.Jobs, newImportJobResponse(j))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// importJob reads a job with its error report. A job running in this process is
+// read from the runner, which does not wait on the transaction importing it.
+func (a *App) importJob(r *http.Request, jobID int32) (importJobResponse, error) {
+	if job, errs, ok := a.Jobs.snapshot(jobID); ok {
+		response := newImportJobResponse(job)
+		response.Errors = errs
+		return response, nil
+	}
+	job, err := a.Store.GetImportJob(r.Context(), jobID)
+	if err != nil {
+		return importJobResponse{}, err
+	}
+	errs, err := a.Store.ListImportJobErrors(r.Context(), jobID)
+	if err != nil {
+		return importJobResponse{}, err
+	}
+	response := newImportJobResponse(job)
+	for _, e := range errs {
+		response.Errors = append(response.Errors, importRowError{Line: int(e.Line), Field: e.Field, Message: e.Message})
+	}
+	return response, nil
+}
+
+// Admin - Get Import Job with its progress and error report
+func (a *App) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid job id"}`, http.StatusBadRequest)
+		return
+	}
+
+	response, err := a.importJob(r, jobID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Job not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Job cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Cancel Import Job. A queued job is cancelled at once; a running one stops
+// after the row it is importing, keeping the chunks a skip_invalid job committed.
+func (a *App) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid job id"}`, http.StatusBadRequest)
+		return
+	}
+
+	job, _, live := a.Jobs.snapshot(jobID)
+	if !live {
+		var err error
+		job, err = a.Store.GetImportJob(r.Context(), jobID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, `{"error":"Job not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Job cannot be cancelled"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	if job.Status != "queued" && job.Status != "running" {
+		http.Error(w, `{"error":"Job has already finished"}`, http.StatusConflict)
+		return
+	}
+
+	// Stop it here first, so the store is not held by its transaction
+	a.Jobs.cancel(jobID)
+	if err := a.Store.CancelImportJob(r.Context(), jobID); err != nil {
+		http.Error(w, `{"error":"Job cannot be cancelled"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response, err := a.importJob(r, jobID)
+	if err != nil {
+		http.Error(w, `{"error":"Job cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	response.CancelRequested = true
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Printed at the top of receipts unless UNIVERSITY_NAME is set
+const defaultUniversityName = "Tuition Payment System"
+
+// Verification codes leave out letters and digits that are easily mixed up
+const verificationAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// receiptNumber is unique because payment ids are; the year only helps people
+// reading it.
+func receiptNumber(payment db.Payment) string {
+	return fmt.Sprintf("RC-%d-%08d", payment.CreatedAt.Time.Year(), payment.PaymentID)
+}
+
+// verificationCode makes a random code like 7KQ4-MZ2X-H9PA.
+func verificationCode() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	var code strings.Builder
+	for i, c := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(verificationAlphabet[int(c)%len(verificationAlphabet)])
+	}
+	return code.String()
+}
+
+// issueReceipt records the receipt of a payment with the items it settled. Run it
+// in the payment's transaction.
+func issueReceipt(ctx context.Context, tx Store, payment db.Payment, outstanding float64, settled []db.ListTuitionItemsRow) (string, error) {
+	receipt, err := tx.AddReceipt(ctx, db.AddReceiptParams{
+		ReceiptNo:        receiptNumber(payment),
+		PaymentID:        payment.PaymentID,
+		VerificationCode: verificationCode(),
+		OutstandingAfter: outstanding,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, item := range settled {
+		description := item.Description
+		if description == "" {
+			description = item.FeeTypeName
+		}
+		err := tx.AddReceiptAllocation(ctx, db.AddReceiptAllocationParams{
+			ReceiptNo:   receipt.ReceiptNo,
+			FeeType:     item.FeeType,
+			Description: description,
+			Amount:      item.Amount - item.AmountPaid,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return receipt.ReceiptNo, nil
+}
+
+type receiptAllocationResponse struct {
+	FeeType     string  `json:"fee_type"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+type receiptResponse struct {
+	ReceiptNo        string                      `json:"receipt_no"`
+	IssuedAt         time.Time                   `json:"issued_at"`
+	StudentNo        string                      `json:"student_no"`
+	FirstName        string                      `json:"first_name"`
+	LastName         string                      `json:"last_name"`
+	Faculty          string                      `json:"faculty"`
+	Program          string                      `json:"program"`
+	Term             string                      `json:"term"`
+	Amount           float64                     `json:"amount"`
+	Channel          string                      `json:"channel"`
+	Allocations      []receiptAllocationResponse `json:"allocations"`
+	OutstandingAfter float64                     `json:"outstanding_after"`
+	BalanceAfter     float64                     `json:"balance_after"`
+	VerificationCode string                      `json:"verification_code"`
+}
+
+func newReceiptResponse(r db.GetReceiptRow, allocations []db.ReceiptAllocation) receiptResponse {
+	response := receiptResponse{
+		ReceiptNo:        r.ReceiptNo,
+		IssuedAt:         r.CreatedAt.Time.UTC(),
+		StudentNo:        r.StudentNo,
+		FirstName:        r.FirstName,
+		LastName:         r.LastName,
+		Faculty:          r.Faculty,
+		Program:          r.Program,
+		Term:             r.Term,
+		Amount:           r.Amount,
+		Channel:          r.Channel,
+		Allocations:      []receiptAllocationResponse{},
+		OutstandingAfter: r.OutstandingAfter,
+		BalanceAfter:     r.BalanceAfter,
+		VerificationCode: r.VerificationCode,
+	}
+	for _, a := range allocations {
+		response.Allocations = append(response.Allocations, receiptAllocationResponse{
+			FeeType:     a.FeeType,
+			Description: a.Description,
+			Amount:      a.Amount,
+		})
+	}
+	return response
+}
+
+// writeReceiptPDF lays a receipt out as a single page.
+func writeReceiptPDF(w http.ResponseWriter, receipt receiptResponse) error {
+	w.Header().Set("Content-Type", reportContentTypes[formatPDF])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%s.pdf"`, receipt.ReceiptNo))
+
+	university := os.Getenv("UNIVERSITY_NAME")
+	if university == "" {
+		university = defaultUniversityName
+	}
+	doc := newPDFDocument(w, "Receipt "+receipt.ReceiptNo)
+	doc.Line(university)
+	if address := os.Getenv("UNIVERSITY_ADDRESS"); address != "" {
+		doc.Line(address)
+	}
+	doc.Line("")
+	doc.Line("PAYMENT RECEIPT")
+	doc.Line("")
+	line := func(label string, value any) {
+		doc.Line(fmt.Sprintf("%-22s %s", label, reportCell(value)))
+	}
+	line("Receipt no", receipt.ReceiptNo)
+	line("Date", receipt.IssuedAt.Format("2006-01-02 15:04 UTC"))
+	line("Student no", receipt.StudentNo)
+	line("Name", strings.TrimSpace(receipt.FirstName+" "+receipt.LastName))
+	line("Faculty", receipt.Faculty)
+	line("Program", receipt.Program)
+	line("Term", receipt.Term)
+	line("Paid through", receipt.Channel)
+	line("Amount paid", receipt.Amount)
+	doc.Line("")
+	if len(receipt.Allocations) == 0 {
+		doc.Line("No fee items were settled; the amount was added to the balance.")
+	} else {
+		doc.Line("Settled fee items")
+		for _, a := range receipt.Allocations {
+			doc.Line(fmt.Sprintf("  %-60s %14s", a.Description, reportCell(a.Amount)))
+		}
+	}
+	doc.Line("")
+	line("Still owed for term", receipt.OutstandingAfter)
+	line("Balance after payment", receipt.BalanceAfter)
+	doc.Line("")
+	line("Verification code", receipt.VerificationCode)
+	doc.Line(fmt.Sprintf("Check this receipt at /api/v2/receipts/%s/verify?code=%s", receipt.ReceiptNo, receipt.VerificationCode))
+	return doc.Close()
+}
+
+// canSeeReceipt tells whether the signed-in token may fetch the receipt: the student
+// it is for, the bank partner that took the payment, or an admin.
+func canSeeReceipt(ctx context.Context, receipt db.GetReceiptRow) bool {
+	if partner, ok := partnerFromContext(ctx); ok {
+		return receipt.Partner.Valid && receipt.Partner.String == partner
+	}
+	subject, _ := ctx.Value("LOGGEDIN_STUDENT_NO").(string)
+	return subject == receipt.StudentNo || isAdminSubject(subject)
+}
+
+// Mobile / Banking - Receipt of one of the signed-in student's payments, or of one
+// a bank partner took, as PDF unless JSON is asked for.
+func (a *App) receiptHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	switch {
+	case format == "" && strings.Contains(r.Header.Get("Accept"), "application/json"):
+		format = formatJSON
+	case format == "":
+		format = formatPDF
+	case format != formatJSON && format != formatPDF:
+		http.Error(w, `{"error":"format must be pdf or json"}`, http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := a.Store.GetReceipt(r.Context(), r.PathValue("receipt_no"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Receipt not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Receipt cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	// Receipts others may not see are not found either
+	if !canSeeReceipt(r.Context(), receipt) {
+		http.Error(w, `{"error":"Receipt not found"}`, http.StatusNotFound)
+		return
+	}
+	allocations, err := a.Store.ListReceiptAllocations(r.Context(), receipt.ReceiptNo)
+	if err != nil {
+		http.Error(w, `{"error":"Receipt cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := newReceiptResponse(receipt, allocations)
+	if format == formatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	if err := writeReceiptPDF(w, response); err != nil {
+		log.Printf("receipt %s: %v", receipt.ReceiptNo, err)
+	}
+}
+
+// Public - Verify a receipt by the code printed on it. Without the right code
+// nothing is told about the receipt, not even whether it exists.
+func (a *App) verifyReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("code")))
+	if code == "" {
+		http.Error(w, `{"error":"code parameter is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	type VerifyReceiptResponse struct {
+		Valid     bool       `json:"valid"`
+		ReceiptNo string     `json:"receipt_no,omitempty"`
+		IssuedAt  *time.Time `json:"issued_at,omitempty"`
+		StudentNo string     `json:"student_no,omitempty"`
+		Term      string     `json:"term,omitempty"`
+		Amount    float64    `json:"amount,omitempty"`
+	}
+	receipt, err := a.Store.GetReceipt(r.Context(), r.PathValue("receipt_no"))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Receipt cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	response := VerifyReceiptResponse{}
+	if err == nil && subtle.ConstantTimeCompare([]byte(code), []byte(receipt.VerificationCode)) == 1 {
+		issuedAt := receipt.CreatedAt.Time.UTC()
+		response = VerifyReceiptResponse{
+			Valid:     true,
+			ReceiptNo: receipt.ReceiptNo,
+			IssuedAt:  &issuedAt,
+			StudentNo: receipt.StudentNo,
+			Term:      receipt.Term,
+			Amount:    receipt.Amount,
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReceipts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTerm("Fall2025")
+		token := ta.register("22070006071", "pw")
+		other := ta.register("22070006072", "pw")
+		ta.createTuition(`{"student_no": "22070006071", "term": "Fall2025", "items": [
+			{"fee_type": "tuition", "amount": 1000},
+			{"fee_type": "lab", "description": "Chemistry lab", "amount": 50}
+		]}`)
+
+		paid := decodeJSON[struct {
+			ReceiptNo string `json:"receipt_no"`
+		}](t, ta.pay(token, "22070006071", "Fall2025", "1000"))
+		if !regexp.MustCompile(`^RC-\d{4}-\d{8}$`).MatchString(paid.ReceiptNo) {
+			t.Fatalf("receipt_no = %q", paid.ReceiptNo)
+		}
+
+		rec := ta.do(http.MethodGet, "/api/v2/receipts/"+paid.ReceiptNo, token, nil, http.Header{"Accept": {"application/json"}})
+		got := decodeJSON[receiptResponse](t, rec)
+		if got.StudentNo != "22070006071" || got.Term != "Fall2025" || got.Amount != 1000 || got.Channel != channelBanking ||
+			got.OutstandingAfter != 50 || got.BalanceAfter != 10 {
+			t.Errorf("receipt = %+v", got)
+		}
+		if len(got.Allocations) != 1 || got.Allocations[0] != (receiptAllocationResponse{FeeType: "tuition", Description: "Tuition", Amount: 1000}) {
+			t.Errorf("allocations = %+v", got.Allocations)
+		}
+		if !regexp.MustCompile(`^[A-Z2-9]{4}-[A-Z2-9]{4}-[A-Z2-9]{4}$`).MatchString(got.VerificationCode) {
+			t.Errorf("verification code = %q", got.VerificationCode)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/receipts/"+paid.ReceiptNo, token, nil, nil)
+		pdf := rec.Body.Bytes()
+		if rec.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(pdf, []byte("%PDF-")) ||
+			!bytes.Contains(pdf, []byte(paid.ReceiptNo)) || !bytes.Contains(pdf, []byte(got.VerificationCode)) {
+			t.Errorf("pdf receipt: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+
+		// The next payment settles the lab fee and gets a receipt of its own
+		second := decodeJSON[struct {
+			ReceiptNo string `json:"receipt_no"`
+		}](t, ta.pay(token, "22070006071", "Fall2025", "50"))
+		rec = ta.do(http.MethodGet, "/api/v2/receipts/"+second.ReceiptNo+"?format=json", token, nil, nil)
+		if lab := decodeJSON[receiptResponse](t, rec); second.ReceiptNo == paid.ReceiptNo || lab.OutstandingAfter != 0 ||
+			len(lab.Allocations) != 1 || lab.Allocations[0].Description != "Chemistry lab" || lab.Allocations[0].Amount != 50 {
+			t.Errorf("second receipt %s = %+v", second.ReceiptNo, lab)
+		}
+
+		for _, tt := range []struct {
+			target string
+			token  string
+			code   int
+		}{
+			{"/api/v2/receipts/" + paid.ReceiptNo, other, http.StatusNotFound},
+			{"/api/v2/receipts/RC-2000-00000099", token, http.StatusNotFound},
+			{"/api/v2/receipts/" + paid.ReceiptNo, "", http.StatusUnauthorized},
+			{"/api/v2/receipts/" + paid.ReceiptNo + "?format=xml", token, http.StatusBadRequest},
+			{"/api/v2/receipts/" + paid.ReceiptNo + "/verify", "", http.StatusBadRequest},
+		} {
+			if rec := ta.do(http.MethodGet, tt.target, tt.token, nil, nil); rec.Code != tt.code {
+				t.Errorf("%s: got %d, want %d", tt.target, rec.Code, tt.code)
+			}
+		}
+
+		type verification struct {
+			Valid     bool    `json:"valid"`
+			ReceiptNo string  `json:"receipt_no"`
+			StudentNo string  `json:"student_no"`
+			Amount    float64 `json:"amount"`
+		}
+		// Anyone holding the receipt can check it, without signing in
+		rec = ta.do(http.MethodGet, "/api/v2/receipts/"+paid.ReceiptNo+"/verify?code="+strings.ToLower(got.VerificationCode), "", nil, nil)
+		if v := decodeJSON[verification](t, rec); !v.Valid || v.ReceiptNo != paid.ReceiptNo || v.StudentNo != "22070006071" || v.Amount != 1000 {
+			t.Errorf("verify = %+v", v)
+		}
+		for _, target := range []string{
+			"/api/v2/receipts/" + paid.ReceiptNo + "/verify?code=AAAA-AAAA-AAAA",
+			"/api/v2/receipts/RC-2000-00000099/verify?code=" + got.VerificationCode,
+		} {
+			if v := decodeJSON[verification](t, ta.do(http.MethodGet, target, "", nil, nil)); v != (verification{}) {
+				t.Errorf("%s: %+v", target, v)
+			}
+		}
+	})
+}
+
+func TestPartnerReceipts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		student := ta.register("22070006071", "pw")
+		ta.addPartner("ZIRAAT")
+		ta.addPartner("AKBANK")
+		ziraat := ta.partnerToken("ZIRAAT")
+
+		paid := decodeJSON[struct {
+			ReceiptNo string `json:"receipt_no"`
+		}](t, ta.partnerPay(ziraat, "", "22070006071", "Fall2025", "400"))
+		unsettled := decodeJSON[struct {
+			ReceiptNo string `json:"receipt_no"`
+		}](t, ta.pay(student, "22070006071", "Fall2025", "100"))
+
+		// The bank that took a payment gets its receipt, other banks don't
+		for _, tt := range []struct {
+			name, receiptNo, token string
+			code                   int
+		}{
+			{"partner", paid.ReceiptNo, ziraat, http.StatusOK},
+			{"student", paid.ReceiptNo, student, http.StatusOK},
+			{"admin", paid.ReceiptNo, adminToken(t), http.StatusOK},
+			{"other partner", paid.ReceiptNo, ta.partnerToken("AKBANK"), http.StatusNotFound},
+			{"payment without a partner", unsettled.ReceiptNo, ziraat, http.StatusNotFound},
+		} {
+			rec := ta.do(http.MethodGet, "/api/v2/receipts/"+tt.receiptNo+"?format=json", tt.token, nil, nil)
+			if rec.Code != tt.code {
+				t.Errorf("%s: got %d, want %d (%s)", tt.name, rec.Code, tt.code, rec.Body)
+			}
+		}
+	})
+}
@@ -0,0 +1,657 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"dogukan-dev/tuition/db"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Bank transaction statuses. Credits that cannot be posted are queued until an admin
+// resolves them by naming the tuition they pay, or dismisses them.
+var bankTransactionStatuses = []string{"posted", "queued", "resolved", "dismissed"}
+
+// Why a queued credit was not posted
+const (
+	issueUnmatched = "unmatched"
+	issueAmbiguous = "ambiguous"
+	issueCurrency  = "currency"
+)
+
+// Tuitions are billed in this currency; credits in any other are never posted
+const tuitionCurrency = "TRY"
+
+var (
+	errTransactionClosed = errors.New("bank transaction is not queued")
+	errForeignCurrency   = errors.New("bank transaction is not in the tuition currency")
+	errNoActiveTuition   = errors.New("no active tuition for the term")
+)
+
+// Student numbers are eleven digits
+const studentNoDigits = 11
+
+type bankTransactionResponse struct {
+	TransactionID int32       `json:"transaction_id,omitempty"`
+	Format        string      `json:"format"`
+	StatementID   string      `json:"statement_id"`
+	Account       string      `json:"account"`
+	BankReference string      `json:"bank_reference"`
+	BookingDate   pgtype.Date `json:"booking_date"`
+	Amount        float64     `json:"amount"`
+	Currency      string      `json:"currency"`
+	Debtor        string      `json:"debtor"`
+	Remittance    string      `json:"remittance"`
+	Status        string      `json:"status"`
+	Issue         string      `json:"issue,omitempty"`
+	Detail        string      `json:"detail,omitempty"`
+	StudentNo     string      `json:"student_no,omitempty"`
+	Term          string      `json:"term,omitempty"`
+	PaymentID     int32       `json:"payment_id,omitempty"`
+	ResolvedBy    string      `json:"resolved_by,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	ResolvedAt    *time.Time  `json:"resolved_at,omitempty"`
+}
+
+func newBankTransactionResponse(t db.BankTransaction) bankTransactionResponse {
+	return bankTransactionResponse{
+		TransactionID: t.TransactionID,
+		Format:        t.Format,
+		StatementID:   t.StatementID,
+		Account:       t.Account,
+		BankReference: t.BankReference,
+		BookingDate:   t.BookingDate,
+		Amount:        t.Amount,
+		Currency:      t.Currency,
+		Debtor:        t.Debtor,
+		Remittance:    t.Remittance,
+		Status:        t.Status,
+		Issue:         t.Issue,
+		Detail:        t.Detail,
+		StudentNo:     t.StudentNo.String,
+		Term:          t.Term.String,
+		PaymentID:     t.PaymentID.Int32,
+		ResolvedBy:    t.ResolvedBy,
+		CreatedAt:     t.CreatedAt.Time,
+		ResolvedAt:    timePtr(t.ResolvedAt),
+	}
+}
+
+// entryKey identifies a statement entry across imports: by the bank's reference,
+// or by its place in the statement when it has none.
+func entryKey(statement bankStatement, n int) string {
+	e := statement.Entries[n]
+	id := e.Reference
+	if id == "" {
+		id = fmt.Sprintf("%s#%d", statement.ID, n+1)
+	}
+	sum := sha256.Sum256([]byte(statement.Account + "\x00" + id))
+	return hex.EncodeToString(sum[:])
+}
+
+// remittanceWords splits remittance information into runs of letters and digits,
+// in upper case.
+func remittanceWords(remittance string) []string {
+	return strings.FieldsFunc(strings.ToUpper(remittance), func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+}
+
+// referenceCandidates finds the payment references in remittance information,
+// written in one piece or in groups of four.
+func referenceCandidates(words []string) []string {
+	var refs []string
+	for i, w := range words {
+		if !strings.HasPrefix(w, referencePrefix) {
+			continue
+		}
+		ref := ""
+		for _, next := range words[i:] {
+			ref += next
+			if len(ref) > referenceMaxLength {
+				break
+			}
+			if validReference(ref) && !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// bankMatch is the tuition a credit pays.
+type bankMatch struct {
+	StudentNo string
+	Term      string
+}
+
+// matchCredit finds the tuition a credit pays from the payment references and
+// student numbers in its remittance information; a known reference wins over
+// student numbers. A student number alone picks the term named in the remittance
+// information, or else the only term with anything owed. When there is no single
+// payable tuition it returns the issue and a detail for the queue, with the
+// student when one was found.
+func matchCredit(ctx context.Context, store Store, remittance string) (match bankMatch, issue, detail string, err error) {
+	words := remittanceWords(remittance)
+
+	var matches []bankMatch
+	var unknown []string
+	for _, ref := range referenceCandidates(words) {
+		reference, err := store.GetPaymentReference(ctx, ref)
+		if errors.Is(err, pgx.ErrNoRows) {
+			unknown = append(unknown, ref)
+			continue
+		}
+		if err != nil {
+			return match, "", "", err
+		}
+		if m := (bankMatch{reference.StudentNo, reference.Term}); !slices.Contains(matches, m) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) > 1 {
+		return match, issueAmbiguous, fmt.Sprintf("the payment references are for %d different tuitions", len(matches)), nil
+	}
+
+	if len(matches) == 0 {
+		var students []string
+		for _, w := range words {
+			if len(w) != studentNoDigits || strings.Trim(w, "0123456789") != "" || slices.Contains(students, w) {
+				continue
+			}
+			_, err := store.GetStudentById(ctx, w)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return match, "", "", err
+			}
+			students = append(students, w)
+		}
+		switch {
+		case len(students) > 1:
+			return match, issueAmbiguous, "several student numbers: " + strings.Join(students, ", "), nil
+		case len(students) == 0 && len(unknown) > 0:
+			return match, issueUnmatched, "unknown payment reference " + unknown[0], nil
+		case len(students) == 0:
+			return match, issueUnmatched, "no payment reference or student number found", nil
+		}
+
+		match.StudentNo = students[0]
+		tuitions, err := store.ListTuitionsByStudent(ctx, match.StudentNo)
+		if err != nil {
+			return match, "", "", err
+		}
+		text := " " + strings.Join(words, " ") + " "
+		var named, owed []string
+		for _, t := range tuitions {
+			if t.Status != "active" {
+				continue
+			}
+			if strings.Contains(text, " "+strings.Join(remittanceWords(t.Term), " ")+" ") {
+				named = append(named, t.Term)
+			}
+			if t.TuitionTotal > 0 {
+				owed = append(owed, t.Term)
+			}
+		}
+		switch {
+		case len(named) == 1:
+			match.Term = named[0]
+		case len(named) > 1:
+			return match, issueAmbiguous, "several terms named: " + strings.Join(named, ", "), nil
+		case len(owed) == 1:
+			match.Term = owed[0]
+		case len(owed) > 1:
+			return match, issueAmbiguous, "the student owes for several terms: " + strings.Join(owed, ", "), nil
+		default:
+			return match, issueUnmatched, "the student owes nothing and no term is named", nil
+		}
+	} else {
+		match = matches[0]
+	}
+
+	issue, detail, err = checkPayable(ctx, store, match)
+	return match, issue, detail, err
+}
+
+// checkPayable tells why a payment cannot be posted to a tuition, if it cannot.
+func checkPayable(ctx context.Context, store Store, match bankMatch) (issue, detail string, err error) {
+	student, err := store.GetStudentById(ctx, match.StudentNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return issueUnmatched, "student " + match.StudentNo + " does not exist", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if student.DeactivatedAt.Valid {
+		return issueUnmatched, "student " + match.StudentNo + " is deactivated", nil
+	}
+	tuition, err := store.GetTuitionByTerm(ctx, db.GetTuitionByTermParams{StudentNo: match.StudentNo, Term: match.Term})
+	if err != nil {
+		return "", "", err
+	}
+	if len(tuition) == 0 {
+		return issueUnmatched, fmt.Sprintf("student %s has no active tuition for %s", match.StudentNo, match.Term), nil
+	}
+	return "", "", nil
+}
+
+type reconciliationSummary struct {
+	Format     string `json:"format"`
+	DryRun     bool   `json:"dry_run"`
+	Committed  bool   `json:"committed"`
+	Statements int    `json:"statements"`
+	// Credits read from the statements, duplicates included
+	Credits      int     `json:"credits"`
+	Posted       int     `json:"posted"`
+	PostedAmount float64 `json:"posted_amount"`
+	Queued       int     `json:"queued"`
+	QueuedAmount float64 `json:"queued_amount"`
+	// Credits already imported with an earlier statement
+	Duplicates int `json:"duplicates"`
+	// Debits, reversals and entries not booked yet
+	Skipped      int                       `json:"skipped"`
+	Transactions []bankTransactionResponse `json:"transactions"`
+	Errors       []importRowError          `json:"errors,omitempty"`
+}
+
+// reconcileStatements posts the credits of statements that match a tuition and
+// queues the rest, in one transaction. Credits imported before are left alone.
+func reconcileStatements(ctx context.Context, store Store, format string, statements []bankStatement, dryRun bool) (reconciliationSummary, error) {
+	summary := reconciliationSummary{Format: format, DryRun: dryRun, Statements: len(statements), Transactions: []bankTransactionResponse{}}
+	err := store.WithTx(ctx, func(tx Store) error {
+		for _, statement := range statements {
+			summary.Skipped += statement.Skipped
+			for n, e := range statement.Entries {
+				summary.Credits++
+				key := entryKey(statement, n)
+				count, err := tx.CountBankTransactionsByKey(ctx, key)
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					summary.Duplicates++
+					continue
+				}
+
+				match, issue, detail, err := matchCredit(ctx, tx, e.Remittance)
+				if err != nil {
+					return err
+				}
+				// Still matched, so the queue shows whom a foreign credit was meant for
+				if !strings.EqualFold(e.Currency, tuitionCurrency) {
+					issue = issueCurrency
+					detail = fmt.Sprintf("credit in %s, tuitions are billed in %s", cmp.Or(e.Currency, "no currency"), tuitionCurrency)
+				}
+				params := db.AddBankTransactionParams{
+					EntryKey:      key,
+					Format:        format,
+					StatementID:   statementText(statement.ID, statementIDMaxLength),
+					Account:       statementText(statement.Account, accountMaxLength),
+					BankReference: statementText(e.Reference, bankReferenceMaxLength),
+					BookingDate:   pgtype.Date{Time: e.BookingDate, Valid: !e.BookingDate.IsZero()},
+					Amount:        e.Amount,
+					Currency:      statementText(e.Currency, currencyMaxLength),
+					Debtor:        statementText(e.Debtor, debtorMaxLength),
+					Remittance:    statementText(e.Remittance, remittanceMaxLength),
+					Status:        "queued",
+					Issue:         issue,
+					Detail:        statementText(detail, detailMaxLength),
+					StudentNo:     optionalText(match.StudentNo),
+					Term:          optionalText(match.Term),
+				}
+				if issue == "" {
+					paid, err := postPayment(ctx, tx, match.StudentNo, match.Term, e.Amount, channelBankTransfer, "")
+					if err != nil {
+						return err
+					}
+					params.Status = "posted"
+					params.PaymentID = pgtype.Int4{Int32: paid.Payment.PaymentID, Valid: true}
+				}
+				t, err := tx.AddBankTransaction(ctx, params)
+				if err != nil {
+					return err
+				}
+
+				if t.Status == "posted" {
+					summary.Posted++
+					summary.PostedAmount += t.Amount
+				} else {
+					summary.Queued++
+					summary.QueuedAmount += t.Amount
+				}
+				response := newBankTransactionResponse(t)
+				// Nothing of a dry run is kept
+				if dryRun {
+					response.TransactionID, response.PaymentID = 0, 0
+				}
+				summary.Transactions = append(summary.Transactions, response)
+			}
+		}
+		if dryRun {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, errImportRolledBack) {
+		return summary, nil
+	}
+	summary.Committed = err == nil
+	return summary, err
+}
+
+// Admin - Import a CAMT.053 or MT940 bank statement. Credits are matched to
+// tuitions by payment reference or student number and posted as payments; the
+// rest go to the reconciliation queue.
+func (a *App) importStatementHandler(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, `{"error":"a CAMT.053 or MT940 statement is required in the file field"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	dryRun, ok := parseBoolFilter(r.Form, "dry_run")
+	if !ok {
+		http.Error(w, `{"error":"dry_run must be true or false"}`, http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, `{"error":"the file cannot be read"}`, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	format, statements, err := parseBankStatement(data)
+	var fileErr *importRowError
+	if errors.As(err, &fileErr) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(reconciliationSummary{
+			Format:       format,
+			DryRun:       dryRun.Bool,
+			Transactions: []bankTransactionResponse{},
+			Errors:       []importRowError{*fileErr},
+		})
+		return
+	}
+
+	summary, err := reconcileStatements(r.Context(), a.Store, format, statements, dryRun.Bool)
+	if err != nil {
+		http.Error(w, `{"error":"Statement could not be reconciled"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(summary)
+}
+
+func bankTransactionIDFromPath(r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(r.PathValue("transaction_id"), 10, 32)
+	return int32(id), err == nil
+}
+
+// Admin - Bank transactions read from statements, oldest first. status=queued is
+// the reconciliation queue.
+func (a *App) listBankTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, offset, ok := parsePage(q)
+	if !ok {
+		http.Error(w, `{"error":"limit must be between 0 and 100 and offset must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+	status := q.Get("status")
+	if status != "" && !slices.Contains(bankTransactionStatuses, status) {
+		http.Error(w, `{"error":"status must be posted, queued, resolved or dismissed"}`, http.StatusBadRequest)
+		return
+	}
+
+	transactions, err := a.Store.ListBankTransactions(r.Context(), db.ListBankTransactionsParams{
+		Status:    optionalText(status),
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Bank transactions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	total, err := a.Store.CountBankTransactions(r.Context(), optionalText(status))
+	if err != nil {
+		http.Error(w, `{"error":"Bank transactions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListBankTransactionsResponse struct {
+		Transactions []bankTransactionResponse `json:"transactions"`
+		Total        int64                     `json:"total"`
+		Limit        int32                     `json:"limit"`
+		Offset       int32                     `json:"offset"`
+	}
+	response := ListBankTransactionsResponse{Transactions: []bankTransactionResponse{}, Total: total, Limit: limit, Offset: offset}
+	for _, t := range transactions {
+		response.Transactions = append(response.Transactions, newBankTransactionResponse(t))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - A single bank transaction, with what matching found
+func (a *App) getBankTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	transactionID, ok := bankTransactionIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid transaction id"}`, http.StatusBadRequest)
+		return
+	}
+	t, err := a.Store.GetBankTransaction(r.Context(), transactionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Bank transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Bank transaction cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newBankTransactionResponse(t))
+}
+
+// Admin - Post a queued credit to the tuition it pays, named by student_no and
+// term or by a payment reference.
+func (a *App) resolveBankTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	transactionID, ok := bankTransactionIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid transaction id"}`, http.StatusBadRequest)
+		return
+	}
+
+	type ResolveBankTransactionRequest struct {
+		StudentNo string `json:"student_no"`
+		Term      string `json:"term"`
+		Reference string `json:"reference"`
+		Note      string `json:"note"`
+	}
+	var req ResolveBankTransactionRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len(req.Note) > maxReasonLength {
+		http.Error(w, `{"error":"note must be at most 500 characters"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Reference != "" {
+		ref := normalizeReference(req.Reference)
+		if !validReference(ref) {
+			http.Error(w, `{"error":"Invalid payment reference"}`, http.StatusBadRequest)
+			return
+		}
+		reference, err := a.Store.GetPaymentReference(r.Context(), ref)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, `{"error":"Unknown payment reference"}`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error":"Payment reference cannot be checked"}`, http.StatusInternalServerError)
+			return
+		}
+		if (req.StudentNo != "" && req.StudentNo != reference.StudentNo) || (req.Term != "" && req.Term != reference.Term) {
+			http.Error(w, `{"error":"The payment reference is for another student or term"}`, http.StatusBadRequest)
+			return
+		}
+		req.StudentNo, req.Term = reference.StudentNo, reference.Term
+	}
+	if req.StudentNo == "" || req.Term == "" {
+		http.Error(w, `{"error":"student_no and term, or reference, are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	student, err := a.Store.GetStudentById(r.Context(), req.StudentNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Bank transaction cannot be resolved"}`, http.StatusInternalServerError)
+		return
+	}
+	if student.DeactivatedAt.Valid {
+		http.Error(w, `{"error":"Student is deactivated"}`, http.StatusForbidden)
+		return
+	}
+
+	resolvedBy, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+	var t db.BankTransaction
+	err = a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := tx.GetBankTransaction(r.Context(), transactionID)
+		if err != nil {
+			return err
+		}
+		if current.Status != "queued" {
+			return errTransactionClosed
+		}
+		if current.Issue == issueCurrency {
+			return errForeignCurrency
+		}
+
+		paid, err := postPayment(r.Context(), tx, req.StudentNo, req.Term, current.Amount, channelBankTransfer, "")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errNoActiveTuition
+		}
+		if err != nil {
+			return err
+		}
+		t, err = tx.CloseBankTransaction(r.Context(), db.CloseBankTransactionParams{
+			TransactionID: transactionID,
+			Status:        "resolved",
+			Detail:        cmp.Or(req.Note, current.Detail),
+			StudentNo:     optionalText(req.StudentNo),
+			Term:          optionalText(req.Term),
+			PaymentID:     pgtype.Int4{Int32: paid.Payment.PaymentID, Valid: true},
+			ResolvedBy:    resolvedBy,
+		})
+		// Resolved or dismissed by someone else meanwhile
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errTransactionClosed
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"Bank transaction not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errTransactionClosed):
+		http.Error(w, `{"error":"Bank transaction is not queued"}`, http.StatusConflict)
+		return
+	case errors.Is(err, errForeignCurrency):
+		http.Error(w, `{"error":"Bank transaction is not in the tuition currency, dismiss it instead"}`, http.StatusConflict)
+		return
+	case errors.Is(err, errTuitionCancelled):
+		http.Error(w, `{"error":"The tuition was cancelled"}`, http.StatusConflict)
+		return
+	case errors.Is(err, errNoActiveTuition):
+		http.Error(w, `{"error":"There is no tuition set for this term"}`, http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Bank transaction cannot be resolved"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newBankTransactionResponse(t))
+}
+
+// Admin - Take a queued credit off the queue without posting it, e.g. when it was
+// sent back to the payer.
+func (a *App) dismissBankTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	transactionID, ok := bankTransactionIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid transaction id"}`, http.StatusBadRequest)
+		return
+	}
+
+	type DismissBankTransactionRequest struct {
+		Reason string `json:"reason"`
+	}
+	var req DismissBankTransactionRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxReasonLength {
+		http.Error(w, `{"error":"a reason of at most 500 characters is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	resolvedBy, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+	var t db.BankTransaction
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := tx.GetBankTransaction(r.Context(), transactionID)
+		if err != nil {
+			return err
+		}
+		t, err = tx.CloseBankTransaction(r.Context(), db.CloseBankTransactionParams{
+			TransactionID: transactionID,
+			Status:        "dismissed",
+			Detail:        req.Reason,
+			StudentNo:     current.StudentNo,
+			Term:          current.Term,
+			ResolvedBy:    resolvedBy,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errTransactionClosed
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"Bank transaction not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errTransactionClosed):
+		http.Error(w, `{"error":"Bank transaction is not queued"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Bank transaction cannot be dismissed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newBankTransactionResponse(t))
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// camtFile is a CAMT.053 statement of the given Ntry elements.
+func camtFile(entries ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-20251015</MsgId><CreDtTm>2025-10-15T18:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT-20251015</Id>
+      <Acct><Id><IBAN>TR330006100519786457841326</IBAN></Id></Acct>
+` + strings.Join(entries, "\n") + `
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+}
+
+// camtNtry is an Ntry element; details are its TxDtls elements.
+func camtNtry(ref, indicator, status, amount string, details ...string) string {
+	return fmt.Sprintf(`<Ntry>
+  <Amt Ccy="TRY">%s</Amt><CdtDbtInd>%s</CdtDbtInd><Sts>%s</Sts>
+  <BookgDt><Dt>2025-10-15</Dt></BookgDt><AcctSvcrRef>%s</AcctSvcrRef>
+  <NtryDtls>%s</NtryDtls>
+</Ntry>`, amount, indicator, status, ref, strings.Join(details, ""))
+}
+
+func (ta *testApp) importStatement(query, content string) (int, reconciliationSummary) {
+	ta.t.Helper()
+	body, header := multipartFile(ta.t, "file", "statement.xml", content)
+	rec := ta.do(http.MethodPost, "/api/v2/admin/reconciliation/statements"+query, adminToken(ta.t), body, header)
+	return rec.Code, decodeJSON[reconciliationSummary](ta.t, rec)
+}
+
+func TestReconcileStatement(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTerm("Fall2025")
+		ta.addTerm("Spring2026")
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 1000)
+		ta.addTuition("22070006072", "Fall2025", 500)
+		ctx := context.Background()
+		fall, err := paymentReference(ctx, ta.app.Store, "22070006071", "Fall2025")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statement := camtFile(
+			// Paid by reference
+			camtNtry("BANK-1", "CRDT", "BOOK", "1000.00",
+				`<TxDtls><RltdPties><Dbtr><Nm>Ayse Yilmaz</Nm></Dbtr></RltdPties>
+				<RmtInf><Strd><CdtrRefInf><Ref>`+fall+`</Ref></CdtrRefInf></Strd></RmtInf></TxDtls>`),
+			// Paid by student number; the student owes for one term only
+			camtNtry("BANK-2", "CRDT", "BOOK", "500.00", `<TxDtls><RmtInf><Ustrd>Tuition fee 22070006072</Ustrd></RmtInf></TxDtls>`),
+			camtNtry("BANK-3", "DBIT", "BOOK", "20.00"),
+			camtNtry("BANK-4", "CRDT", "PDNG", "40.00"),
+			camtNtry("BANK-5", "CRDT", "BOOK", "75.00", `<TxDtls><RmtInf><Ustrd>Donation</Ustrd></RmtInf></TxDtls>`),
+			// Two transfers booked together
+			camtNtry("BANK-6", "CRDT", "BOOK", "300.00",
+				`<TxDtls><Refs><AcctSvcrRef>BANK-6A</AcctSvcrRef></Refs><Amt Ccy="TRY">100.00</Amt>
+				<RmtInf><Ustrd>22070006071 and 22070006072</Ustrd></RmtInf></TxDtls>`,
+				`<TxDtls><Refs><AcctSvcrRef>BANK-6B</AcctSvcrRef></Refs><Amt Ccy="TRY">200.00</Amt>
+				<RmtInf><Ustrd>Student 22070006071</Ustrd></RmtInf></TxDtls>`),
+		)
+
+		// A dry run tells what would be posted and keeps nothing
+		code, summary := ta.importStatement("?dry_run=true", statement)
+		if code != http.StatusOK || summary.Committed || summary.Posted != 3 || summary.Queued != 2 {
+			t.Fatalf("dry run: %d %+v", code, summary)
+		}
+		if ta.tuitionTotal("22070006071", "Fall2025") != 1000 {
+			t.Fatal("a dry run posted a payment")
+		}
+
+		code, summary = ta.importStatement("", statement)
+		if code != http.StatusOK || !summary.Committed || summary.Format != formatCAMT053 || summary.Statements != 1 ||
+			summary.Credits != 5 || summary.Posted != 3 || summary.PostedAmount != 1700 || summary.Queued != 2 ||
+			summary.QueuedAmount != 175 || summary.Skipped != 2 || summary.Duplicates != 0 {
+			t.Fatalf("import: %d %+v", code, summary)
+		}
+		byRef := map[string]bankTransactionResponse{}
+		for _, tx := range summary.Transactions {
+			byRef[tx.BankReference] = tx
+		}
+		for ref, want := range map[string]bankTransactionResponse{
+			"BANK-1":  {Status: "posted", StudentNo: "22070006071", Term: "Fall2025", Debtor: "Ayse Yilmaz"},
+			"BANK-2":  {Status: "posted", StudentNo: "22070006072", Term: "Fall2025"},
+			"BANK-5":  {Status: "queued", Issue: issueUnmatched},
+			"BANK-6A": {Status: "queued", Issue: issueAmbiguous},
+			"BANK-6B": {Status: "posted", StudentNo: "22070006071", Term: "Spring2026"},
+		} {
+			got := byRef[ref]
+			if got.Status != want.Status || got.Issue != want.Issue || got.StudentNo != want.StudentNo || got.Term != want.Term ||
+				got.Debtor != want.Debtor || (got.Status == "posted") != (got.PaymentID != 0) {
+				t.Errorf("%s = %+v", ref, got)
+			}
+		}
+		if got := ta.tuitionTotal("22070006071", "Fall2025"); got != 0 {
+			t.Errorf("Fall2025 tuition total of 22070006071 = %v", got)
+		}
+		if got := ta.tuitionTotal("22070006072", "Fall2025"); got != 0 {
+			t.Errorf("Fall2025 tuition total of 22070006072 = %v", got)
+		}
+		// 200 is not enough for the Spring2026 tuition and stays on the balance
+		if got := ta.balance("22070006071"); got != 210 {
+			t.Errorf("balance = %v", got)
+		}
+		payments, err := ta.app.Store.ListPaymentsByStudent(ctx, "22070006071")
+		if err != nil || len(payments) != 2 || payments[0].Channel != channelBankTransfer {
+			t.Errorf("payments = %+v, %v", payments, err)
+		}
+
+		// Importing the statement again posts nothing twice
+		code, summary = ta.importStatement("", statement)
+		if code != http.StatusOK || summary.Duplicates != 5 || summary.Posted != 0 || summary.Queued != 0 {
+			t.Fatalf("second import: %d %+v", code, summary)
+		}
+
+		if code, summary := ta.importStatement("", "student_no,amount\n22070006071,100\n"); code != http.StatusBadRequest || len(summary.Errors) != 1 {
+			t.Errorf("not a statement: %d %+v", code, summary)
+		}
+	})
+}
+
+func TestReconciliationQueue(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTerm("Fall2025")
+		ta.addTerm("Spring2026")
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 1000)
+		spring, err := paymentReference(context.Background(), ta.app.Store, "22070006071", "Spring2026")
+		if err != nil {
+			t.Fatal(err)
+		}
+		admin := adminToken(t)
+
+		// The student owes for two terms and names neither
+		code, summary := ta.importStatement("", camtFile(
+			camtNtry("BANK-1", "CRDT", "BOOK", "1000.00", `<TxDtls><RmtInf><Ustrd>22070006071</Ustrd></RmtInf></TxDtls>`),
+			camtNtry("BANK-2", "CRDT", "BOOK", "50.00", `<TxDtls><RmtInf><Ustrd>Unknown</Ustrd></RmtInf></TxDtls>`),
+		))
+		if code != http.StatusOK || summary.Queued != 2 || summary.Transactions[0].Issue != issueAmbiguous ||
+			summary.Transactions[0].StudentNo != "22070006071" {
+			t.Fatalf("import: %d %+v", code, summary)
+		}
+		owed, unknown := summary.Transactions[0].TransactionID, summary.Transactions[1].TransactionID
+
+		type queue struct {
+			Transactions []bankTransactionResponse `json:"transactions"`
+			Total        int64                     `json:"total"`
+		}
+		rec := ta.do(http.MethodGet, "/api/v2/admin/reconciliation/transactions?status=queued", admin, nil, nil)
+		if q := decodeJSON[queue](t, rec); q.Total != 2 || len(q.Transactions) != 2 {
+			t.Errorf("queue = %+v", q)
+		}
+
+		post := func(target, body string) int {
+			return ta.do(http.MethodPost, target, admin, strings.NewReader(body), nil).Code
+		}
+		base := "/api/v2/admin/reconciliation/transactions/"
+		for _, tt := range []struct {
+			target, body string
+			code         int
+		}{
+			{base + "999/resolve", `{"student_no": "22070006071", "term": "Fall2025"}`, http.StatusNotFound},
+			{base + "x/resolve", `{}`, http.StatusBadRequest},
+			{fmt.Sprintf("%s%d/resolve", base, unknown), `{"term": "Fall2025"}`, http.StatusBadRequest},
+			{fmt.Sprintf("%s%d/resolve", base, unknown), `{"student_no": "22070009999", "term": "Fall2025"}`, http.StatusNotFound},
+			{fmt.Sprintf("%s%d/resolve", base, unknown), `{"student_no": "22070006072", "term": "Fall2025"}`, http.StatusBadRequest},
+			{fmt.Sprintf("%s%d/resolve", base, unknown), `{"reference": "RF00 1234"}`, http.StatusBadRequest},
+			{fmt.Sprintf("%s%d/dismiss", base, unknown), `{"reason": " "}`, http.StatusBadRequest},
+		} {
+			if code := post(tt.target, tt.body); code != tt.code {
+				t.Errorf("%s %s: got %d, want %d", tt.target, tt.body, code, tt.code)
+			}
+		}
+
+		rec = ta.do(http.MethodPost, fmt.Sprintf("%s%d/resolve", base, owed), admin, strings.NewReader(`{"reference": "`+spring+`", "note": "Spring per phone call"}`), nil)
+		resolved := decodeJSON[bankTransactionResponse](t, rec)
+		if rec.Code != http.StatusOK || resolved.Status != "resolved" || resolved.Term != "Spring2026" || resolved.PaymentID == 0 ||
+			resolved.ResolvedBy != "admin" || resolved.ResolvedAt == nil || resolved.Detail != "Spring per phone call" {
+			t.Errorf("resolve: %d %+v", rec.Code, resolved)
+		}
+		if got := ta.tuitionTotal("22070006071", "Spring2026"); got != 0 {
+			t.Errorf("Spring2026 tuition total = %v", got)
+		}
+
+		rec = ta.do(http.MethodPost, fmt.Sprintf("%s%d/dismiss", base, unknown), admin, strings.NewReader(`{"reason": "Returned to the payer"}`), nil)
+		if dismissed := decodeJSON[bankTransactionResponse](t, rec); rec.Code != http.StatusOK || dismissed.Status != "dismissed" || dismissed.PaymentID != 0 {
+			t.Errorf("dismiss: %d %+v", rec.Code, dismissed)
+		}
+
+		// Neither can be closed twice
+		for _, target := range []string{fmt.Sprintf("%s%d/resolve", base, owed), fmt.Sprintf("%s%d/resolve", base, unknown)} {
+			if code := post(target, `{"student_no": "22070006071", "term": "Fall2025"}`); code != http.StatusConflict {
+				t.Errorf("%s: got %d, want 409", target, code)
+			}
+		}
+		if code := post(fmt.Sprintf("%s%d/dismiss", base, owed), `{"reason": "again"}`); code != http.StatusConflict {
+			t.Errorf("dismiss resolved: got %d, want 409", code)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/reconciliation/transactions?status=queued", admin, nil, nil)
+		if q := decodeJSON[queue](t, rec); q.Total != 0 {
+			t.Errorf("queue after closing = %+v", q)
+		}
+		rec = ta.do(http.MethodGet, fmt.Sprintf("%s%d", base, owed), admin, nil, nil)
+		if got := decodeJSON[bankTransactionResponse](t, rec); got.Status != "resolved" || got.BankReference != "BANK-1" {
+			t.Errorf("get = %+v", got)
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/reconciliation/transactions?status=open", admin, nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("bad status: got %d", rec.Code)
+		}
+	})
+}
+
+func TestReconcileForeignCurrency(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("Fall2025")
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		fall, err := paymentReference(context.Background(), ta.app.Store, "22070006071", "Fall2025")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A valid reference, but 1000 EUR is not 1000 TRY
+		euro := strings.Replace(camtNtry("BANK-1", "CRDT", "BOOK", "1000.00",
+			`<TxDtls><RmtInf><Ustrd>`+fall+`</Ustrd></RmtInf></TxDtls>`), `Ccy="TRY"`, `Ccy="EUR"`, 1)
+		code, summary := ta.importStatement("", camtFile(euro))
+		if code != http.StatusOK || summary.Posted != 0 || summary.Queued != 1 {
+			t.Fatalf("import: %d %+v", code, summary)
+		}
+		queued := summary.Transactions[0]
+		if queued.Issue != issueCurrency || queued.Currency != "EUR" || queued.StudentNo != "22070006071" {
+			t.Fatalf("queued: %+v", queued)
+		}
+		if got := ta.tuitionTotal("22070006071", "Fall2025"); got != 1000 {
+			t.Fatalf("tuition total = %v", got)
+		}
+
+		target := fmt.Sprintf("/api/v2/admin/reconciliation/transactions/%d/resolve", queued.TransactionID)
+		rec := ta.do(http.MethodPost, target, adminToken(t), strings.NewReader(`{"reference": "`+fall+`"}`), nil)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("resolve: %d %s", rec.Code, rec.Body)
+		}
+		if ta.balance("22070006071") != 10 || ta.tuitionTotal("22070006071", "Fall2025") != 1000 {
+			t.Fatal("a foreign currency credit was posted")
+		}
+	})
+}
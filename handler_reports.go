@@ -0,0 +1,603 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var unpaidReportColumns = []reportColumn{
+	{Name: "term", Width: 10},
+	{Name: "payment_due_date", Width: 10},
+	{Name: "student_no", Width: 11},
+	{Name: "first_name", Width: 14},
+	{Name: "last_name", Width: 14},
+	{Name: "email"},
+	{Name: "phone"},
+	{Name: "faculty", Width: 14},
+	{Name: "department", Width: 12},
+	{Name: "program", Width: 12},
+	{Name: "enrollment_year", Width: 4, Numeric: true},
+	{Name: "enrollment_status"},
+	{Name: "billed", Width: 11, Numeric: true},
+	{Name: "paid", Width: 11, Numeric: true},
+	{Name: "outstanding", Width: 11, Numeric: true},
+}
+
+var unpaidTotalColumns = []reportColumn{
+	{Name: "term", Width: 14},
+	{Name: "faculty", Width: 20},
+	{Name: "tuitions", Width: 8, Numeric: true},
+	{Name: "billed", Width: 14, Numeric: true},
+	{Name: "paid", Width: 14, Numeric: true},
+	{Name: "outstanding", Width: 14, Numeric: true},
+}
+
+// unpaidTotal sums the unpaid tuitions of a term and faculty.
+type unpaidTotal struct {
+	Term        string
+	Faculty     string
+	Tuitions    int
+	Billed      float64
+	Outstanding float64
+}
+
+func (t *unpaidTotal) add(o unpaidTotal) {
+	t.Tuitions += o.Tuitions
+	t.Billed += o.Billed
+	t.Outstanding += o.Outstanding
+}
+
+// unpaidTotalRows adds a total after each term's faculties and a grand total at
+// the end. The faculty totals are in report order, so a term's are together.
+func unpaidTotalRows(faculties []unpaidTotal) []unpaidTotal {
+	var rows []unpaidTotal
+	all := unpaidTotal{Term: "All terms", Faculty: "All faculties"}
+	var term unpaidTotal
+	for i, f := range faculties {
+		if i == 0 || faculties[i-1].Term != f.Term {
+			term = unpaidTotal{Term: f.Term, Faculty: "All faculties"}
+		}
+		rows = append(rows, f)
+		term.add(f)
+		all.add(f)
+		if i == len(faculties)-1 || faculties[i+1].Term != f.Term {
+			rows = append(rows, term)
+		}
+	}
+	return append(rows, all)
+}
+
+// unpaidCursor reads the unpaid tuition report a page at a time, so a report over
+// every tuition only ever holds one page of them.
+type unpaidCursor struct {
+	store  Store
+	params db.ListUnpaidReportParams
+	done   bool
+}
+
+func newUnpaidCursor(store Store, params db.ListUnpaidReportParams) *unpaidCursor {
+	params.RowLimit = reportPageSize
+	return &unpaidCursor{store: store, params: params}
+}
+
+// next returns the next page, which is empty after the last one.
+func (c *unpaidCursor) next(ctx context.Context) ([]db.ListUnpaidReportRow, error) {
+	if c.done {
+		return nil, nil
+	}
+	page, err := c.store.ListUnpaidReport(ctx, c.params)
+	if err != nil {
+		return nil, err
+	}
+	if len(page) < int(c.params.RowLimit) {
+		c.done = true
+	} else {
+		last := page[len(page)-1]
+		c.params.AfterTerm, c.params.AfterFaculty, c.params.AfterStudentNo, c.params.AfterTuitionID = last.Term, last.Faculty, last.StudentNo, last.TuitionID
+	}
+	return page, nil
+}
+
+// abortReport cuts a download short when a page cannot be read after it started;
+// there is no way left to report the error.
+func abortReport(name string, err error) {
+	log.Printf("%s export: %v", name, err)
+	panic(http.ErrAbortHandler)
+}
+
+// exportUnpaidTuitions writes the unpaid tuitions with the students' profiles as a
+// download, followed by totals per term and faculty.
+func (a *App) exportUnpaidTuitions(w http.ResponseWriter, r *http.Request, format string) {
+	q := r.URL.Query()
+	cursor := newUnpaidCursor(a.Store, db.ListUnpaidReportParams{
+		Term:    optionalText(q.Get("term")),
+		Faculty: optionalText(q.Get("faculty")),
+	})
+	page, err := cursor.next(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Unpaid tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	report := newReportWriter(w, format, "unpaid-tuitions")
+	report.Table("Unpaid tuitions", unpaidReportColumns)
+	var faculties []unpaidTotal
+	for len(page) > 0 {
+		for _, t := range page {
+			report.Row(t.Term, reportDate(t.PaymentDueDate), t.StudentNo, t.FirstName, t.LastName, t.Email, t.Phone,
+				t.Faculty, t.Department, t.Program, reportYear(t.EnrollmentYear), t.EnrollmentStatus,
+				t.BilledTotal, t.BilledTotal-t.TuitionTotal, t.TuitionTotal)
+
+			if n := len(faculties); n == 0 || faculties[n-1].Term != t.Term || faculties[n-1].Faculty != t.Faculty {
+				faculties = append(faculties, unpaidTotal{Term: t.Term, Faculty: t.Faculty})
+			}
+			faculties[len(faculties)-1].add(unpaidTotal{Tuitions: 1, Billed: t.BilledTotal, Outstanding: t.TuitionTotal})
+		}
+		if page, err = cursor.next(r.Context()); err != nil {
+			abortReport("unpaid tuitions", err)
+		}
+	}
+
+	report.Table("Totals", unpaidTotalColumns)
+	for _, t := range unpaidTotalRows(faculties) {
+		report.Row(t.Term, t.Faculty, t.Tuitions, t.Billed, t.Billed-t.Outstanding, t.Outstanding)
+	}
+	if err := report.Close(); err != nil {
+		log.Printf("unpaid tuitions export: %v", err)
+	}
+}
+
+// Groupings of the aging report. Grouping by student lists every unpaid tuition,
+// which drills down into a group when filtered by it.
+var agingGroups = []string{"term", "faculty", "program", "student"}
+
+// agingAmounts splits what is outstanding by how long it is past due. Tuitions of
+// a term with no due date, or one still to come, are not due.
+type agingAmounts struct {
+	Tuitions    int     `json:"tuitions"`
+	NotDue      float64 `json:"not_due"`
+	Days0To30   float64 `json:"days_0_30"`
+	Days31To60  float64 `json:"days_31_60"`
+	Days61To90  float64 `json:"days_61_90"`
+	DaysOver90  float64 `json:"days_over_90"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// agingDays is how many days the tuition is past due on asOf, or nil without a
+// due date. It is negative while the due date is still to come.
+func agingDays(due pgtype.Date, asOf time.Time) *int {
+	if !due.Valid {
+		return nil
+	}
+	days := int(asOf.Sub(due.Time).Hours() / 24)
+	return &days
+}
+
+func (a *agingAmounts) add(days *int, amount float64) {
+	a.Tuitions++
+	a.Outstanding += amount
+	switch {
+	case days == nil || *days < 0:
+		a.NotDue += amount
+	case *days <= 30:
+		a.Days0To30 += amount
+	case *days <= 60:
+		a.Days31To60 += amount
+	case *days <= 90:
+		a.Days61To90 += amount
+	default:
+		a.DaysOver90 += amount
+	}
+}
+
+func (a agingAmounts) sum(b agingAmounts) agingAmounts {
+	return agingAmounts{
+		Tuitions:    a.Tuitions + b.Tuitions,
+		NotDue:      a.NotDue + b.NotDue,
+		Days0To30:   a.Days0To30 + b.Days0To30,
+		Days31To60:  a.Days31To60 + b.Days31To60,
+		Days61To90:  a.Days61To90 + b.Days61To90,
+		DaysOver90:  a.DaysOver90 + b.DaysOver90,
+		Outstanding: a.Outstanding + b.Outstanding,
+	}
+}
+
+// row gives the amounts in the order of agingColumns.
+func (a agingAmounts) row() []any {
+	return []any{a.Tuitions, a.NotDue, a.Days0To30, a.Days31To60, a.Days61To90, a.DaysOver90, a.Outstanding}
+}
+
+func agingColumns(width int) []reportColumn {
+	var columns []reportColumn
+	for _, name := range []string{"tuitions", "not_due", "days_0_30", "days_31_60", "days_61_90", "days_over_90", "outstanding"} {
+		columns = append(columns, reportColumn{Name: name, Width: width, Numeric: true})
+	}
+	return columns
+}
+
+type agingGroup struct {
+	Group string `json:"group"`
+	agingAmounts
+}
+
+type agingStudent struct {
+	Term           string      `json:"term"`
+	PaymentDueDate pgtype.Date `json:"payment_due_date"`
+	DaysPastDue    *int        `json:"days_past_due"`
+	StudentNo      string      `json:"student_no"`
+	FirstName      string      `json:"first_name"`
+	LastName       string      `json:"last_name"`
+	Faculty        string      `json:"faculty"`
+	Program        string      `json:"program"`
+	agingAmounts
+}
+
+// Admin - Receivables Aging, grouped by term, faculty or program, or by student to
+// drill down. The term, faculty and program parameters filter the tuitions.
+func (a *App) agingReportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	format, ok := reportFormat(r)
+	if !ok {
+		http.Error(w, `{"error":"format must be json, csv, xlsx or pdf"}`, http.StatusBadRequest)
+		return
+	}
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = "term"
+	}
+	if !slices.Contains(agingGroups, groupBy) {
+		http.Error(w, `{"error":"group_by must be term, faculty, program or student"}`, http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	asOf := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := q.Get("as_of"); v != "" {
+		var err error
+		if asOf, err = time.Parse(time.DateOnly, v); err != nil {
+			http.Error(w, `{"error":"as_of must be a date like 2025-10-15"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	cursor := newUnpaidCursor(a.Store, db.ListUnpaidReportParams{
+		Term:    optionalText(q.Get("term")),
+		Faculty: optionalText(q.Get("faculty")),
+		Program: optionalText(q.Get("program")),
+	})
+	if groupBy == "student" {
+		a.agingStudents(w, r, cursor, format, asOf)
+		return
+	}
+
+	// There are few groups, so they are summed up before anything is written
+	sums := map[string]agingAmounts{}
+	for {
+		page, err := cursor.next(r.Context())
+		if err != nil {
+			http.Error(w, `{"error":"Aging report cannot be queried"}`, http.StatusInternalServerError)
+			return
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, t := range page {
+			key := t.Term
+			switch groupBy {
+			case "faculty":
+				key = t.Faculty
+			case "program":
+				key = t.Program
+			}
+			amounts := sums[key]
+			amounts.add(agingDays(t.PaymentDueDate, asOf), t.TuitionTotal)
+			sums[key] = amounts
+		}
+	}
+	var totals agingAmounts
+	groups := []agingGroup{}
+	for _, key := range slices.Sorted(maps.Keys(sums)) {
+		groups = append(groups, agingGroup{Group: key, agingAmounts: sums[key]})
+		totals = totals.sum(sums[key])
+	}
+
+	if format != formatJSON {
+		report := newReportWriter(w, format, "aging-by-"+groupBy)
+		report.Table("Aging by "+groupBy, append([]reportColumn{{Name: groupBy, Width: 24}}, agingColumns(13)...))
+		for _, g := range groups {
+			report.Row(append([]any{g.Group}, g.row()...)...)
+		}
+		report.Row(append([]any{"All"}, totals.row()...)...)
+		if err := report.Close(); err != nil {
+			log.Printf("aging export: %v", err)
+		}
+		return
+	}
+
+	type AgingResponse struct {
+		AsOf    string       `json:"as_of"`
+		GroupBy string       `json:"group_by"`
+		Groups  []agingGroup `json:"groups"`
+		Totals  agingAmounts `json:"totals"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AgingResponse{AsOf: asOf.Format(time.DateOnly), GroupBy: groupBy, Groups: groups, Totals: totals})
+}
+
+var agingStudentColumns = append([]reportColumn{
+	{Name: "term", Width: 10},
+	{Name: "payment_due_date", Width: 10},
+	{Name: "days_past_due", Width: 5, Numeric: true},
+	{Name: "student_no", Width: 11},
+	{Name: "first_name", Width: 12},
+	{Name: "last_name", Width: 12},
+	{Name: "faculty", Width: 12},
+	{Name: "program", Width: 10},
+	// Always 1, so left out of the PDF
+	{Name: "tuitions", Numeric: true},
+}, agingColumns(10)[1:]...)
+
+// agingStudents writes every unpaid tuition with its amount in its bucket, followed
+// by the totals. Rows are written as they are read, in JSON as well.
+func (a *App) agingStudents(w http.ResponseWriter, r *http.Request, cursor *unpaidCursor, format string, asOf time.Time) {
+	page, err := cursor.next(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Aging report cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var report reportWriter
+	var enc *json.Encoder
+	if format == formatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"as_of":%q,"group_by":"student","students":[`, asOf.Format(time.DateOnly))
+		enc = json.NewEncoder(w)
+	} else {
+		report = newReportWriter(w, format, "aging-by-student")
+		report.Table("Aging by student", agingStudentColumns)
+	}
+
+	var totals agingAmounts
+	rows := 0
+	for len(page) > 0 {
+		for _, t := range page {
+			days := agingDays(t.PaymentDueDate, asOf)
+			s := agingStudent{
+				Term:           t.Term,
+				PaymentDueDate: t.PaymentDueDate,
+				DaysPastDue:    days,
+				StudentNo:      t.StudentNo,
+				FirstName:      t.FirstName,
+				LastName:       t.LastName,
+				Faculty:        t.Faculty,
+				Program:        t.Program,
+			}
+			s.add(days, t.TuitionTotal)
+			totals.add(days, t.TuitionTotal)
+
+			if enc != nil {
+				if rows > 0 {
+					io.WriteString(w, ",")
+				}
+				enc.Encode(s)
+			} else {
+				pastDue := any("")
+				if days != nil {
+					pastDue = *days
+				}
+				report.Row(append([]any{s.Term, reportDate(s.PaymentDueDate), pastDue, s.StudentNo, s.FirstName, s.LastName, s.Faculty, s.Program}, s.row()...)...)
+			}
+			rows++
+		}
+		if page, err = cursor.next(r.Context()); err != nil {
+			abortReport("aging", err)
+		}
+	}
+
+	if enc != nil {
+		io.WriteString(w, `],"totals":`)
+		enc.Encode(totals)
+		io.WriteString(w, "}\n")
+		return
+	}
+	report.Table("Totals", agingColumns(13))
+	report.Row(totals.row()...)
+	if err := report.Close(); err != nil {
+		log.Printf("aging export: %v", err)
+	}
+}
+
+// Students listed as top debtors by default, and at most
+const (
+	defaultTopDebtors = 10
+	maxTopDebtors     = 100
+)
+
+type summaryTerm struct {
+	Term             string  `json:"term"`
+	Students         int32   `json:"students"`
+	FullyPaid        int32   `json:"fully_paid"`
+	FullyPaidPercent float64 `json:"fully_paid_percent"`
+	Billed           float64 `json:"billed"`
+	Collected        float64 `json:"collected"`
+	Outstanding      float64 `json:"outstanding"`
+	Received         float64 `json:"received"`
+}
+
+type summaryPayments struct {
+	Day      string  `json:"day,omitempty"`
+	Channel  string  `json:"channel,omitempty"`
+	Payments int32   `json:"payments"`
+	Amount   float64 `json:"amount"`
+}
+
+type summaryDebtor struct {
+	StudentNo   string  `json:"student_no"`
+	FirstName   string  `json:"first_name"`
+	LastName    string  `json:"last_name"`
+	Faculty     string  `json:"faculty"`
+	Tuitions    int32   `json:"tuitions"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+type summaryResponse struct {
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Students    struct {
+		Students         int32   `json:"students"`
+		FullyPaid        int32   `json:"fully_paid"`
+		FullyPaidPercent float64 `json:"fully_paid_percent"`
+	} `json:"students"`
+	Terms             []summaryTerm     `json:"terms"`
+	PaymentsByDay     []summaryPayments `json:"payments_by_day"`
+	PaymentsByChannel []summaryPayments `json:"payments_by_channel"`
+	TopDebtors        []summaryDebtor   `json:"top_debtors"`
+}
+
+// percent is part of whole as a percentage with two decimals.
+func percent(part, whole int32) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(whole)) / 100
+}
+
+// summarize runs the aggregates of the collections summary. The range is of payment
+// days; billing is summed over all active tuitions.
+func (a *App) summarize(ctx context.Context, from, to pgtype.Timestamptz, top int32) (summaryResponse, error) {
+	response := summaryResponse{
+		GeneratedAt:       time.Now().UTC(),
+		Terms:             []summaryTerm{},
+		PaymentsByDay:     []summaryPayments{},
+		PaymentsByChannel: []summaryPayments{},
+		TopDebtors:        []summaryDebtor{},
+	}
+
+	students, err := a.Store.SummarizeStudents(ctx)
+	if err != nil {
+		return response, err
+	}
+	response.Students.Students = students.Students
+	response.Students.FullyPaid = students.FullyPaid
+	response.Students.FullyPaidPercent = percent(students.FullyPaid, students.Students)
+
+	terms, err := a.Store.SummarizeTerms(ctx, db.SummarizeTermsParams{FromTime: from, ToTime: to})
+	if err != nil {
+		return response, err
+	}
+	for _, t := range terms {
+		response.Terms = append(response.Terms, summaryTerm{
+			Term:             t.Term,
+			Students:         t.Students,
+			FullyPaid:        t.FullyPaid,
+			FullyPaidPercent: percent(t.FullyPaid, t.Students),
+			Billed:           t.Billed,
+			Collected:        t.Billed - t.Outstanding,
+			Outstanding:      t.Outstanding,
+			Received:         t.Received,
+		})
+	}
+
+	days, err := a.Store.SummarizePaymentsByDay(ctx, db.SummarizePaymentsByDayParams{FromTime: from, ToTime: to})
+	if err != nil {
+		return response, err
+	}
+	for _, d := range days {
+		response.PaymentsByDay = append(response.PaymentsByDay, summaryPayments{Day: d.Day, Payments: d.Payments, Amount: d.Amount})
+	}
+
+	channels, err := a.Store.SummarizePaymentsByChannel(ctx, db.SummarizePaymentsByChannelParams{FromTime: from, ToTime: to})
+	if err != nil {
+		return response, err
+	}
+	for _, c := range channels {
+		response.PaymentsByChannel = append(response.PaymentsByChannel, summaryPayments{Channel: c.Channel, Payments: c.Payments, Amount: c.Amount})
+	}
+
+	debtors, err := a.Store.ListTopDebtors(ctx, top)
+	if err != nil {
+		return response, err
+	}
+	for _, d := range debtors {
+		response.TopDebtors = append(response.TopDebtors, summaryDebtor(d))
+	}
+	return response, nil
+}
+
+// dateRange reads the from and to date parameters of a report. The range includes
+// the whole of the to day, so to is returned as the start of the next one. Either
+// end may be unset; msg is the error body for an invalid range.
+func dateRange(r *http.Request) (from, to pgtype.Timestamptz, msg string) {
+	q := r.URL.Query()
+	if v := q.Get("from"); v != "" {
+		day, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return from, to, `{"error":"from must be a date like 2025-10-15"}`
+		}
+		from = pgtype.Timestamptz{Time: day, Valid: true}
+	}
+	if v := q.Get("to"); v != "" {
+		day, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return from, to, `{"error":"to must be a date like 2025-10-15"}`
+		}
+		to = pgtype.Timestamptz{Time: day.AddDate(0, 0, 1), Valid: true}
+	}
+	if from.Valid && to.Valid && !from.Time.Before(to.Time) {
+		return from, to, `{"error":"to must not be before from"}`
+	}
+	return from, to, ""
+}
+
+// Admin - Collections Summary. A summary is cached for REPORT_CACHE_SECONDS per
+// range; refresh=true computes it again.
+func (a *App) summaryReportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	from, to, msg := dateRange(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	top := defaultTopDebtors
+	if v := q.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTopDebtors {
+			http.Error(w, `{"error":"top must be between 1 and 100"}`, http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	key := fmt.Sprintf("summary|%s|%s|%d", q.Get("from"), q.Get("to"), top)
+	body, ok := a.Reports.get(key)
+	if !ok || q.Get("refresh") == "true" {
+		response, err := a.summarize(r.Context(), from, to, int32(top))
+		if err != nil {
+			http.Error(w, `{"error":"Summary cannot be queried"}`, http.StatusInternalServerError)
+			return
+		}
+		response.From, response.To = q.Get("from"), q.Get("to")
+		if body, err = json.Marshal(response); err != nil {
+			http.Error(w, `{"error":"Summary cannot be queried"}`, http.StatusInternalServerError)
+			return
+		}
+		a.Reports.put(key, body)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/csv"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/xuri/excelize/v2"
+)
+
+// addUnpaidReportData bills four students of two faculties, one of whom has paid
+// their Fall2025 tuition.
+func (ta *testApp) addUnpaidReportData() {
+	ta.t.Helper()
+	body, header := multipartFile(ta.t, "file", "students.csv", "student_no,first_name,last_name,faculty,program,enrollment_year,balance\n"+
+		"22070006071,Şule,Yılmaz,Engineering,CS,2022,5000\n"+
+		"22070006072,Ali,Demir,Engineering,EE,2022,5000\n"+
+		"22070006073,Ayşe,Kaya,Medicine,MED,2021,5000\n"+
+		"22070006074,Can,Öz,Medicine,MED,2021,0\n")
+	if rec := ta.do(http.MethodPost, "/api/v2/admin/students/import", adminToken(ta.t), body, header); rec.Code != http.StatusOK {
+		ta.t.Fatalf("import students: %d %s", rec.Code, rec.Body)
+	}
+	due := pgtype.Date{Time: time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC), Valid: true}
+	if _, err := ta.app.Store.CreateTerm(context.Background(), db.CreateTermParams{Code: "Fall2025", Name: "Fall 2025", PaymentDueDate: due}); err != nil {
+		ta.t.Fatal(err)
+	}
+	ta.addTuition("22070006072", "Fall2025", 2000)
+	ta.addTuition("22070006071", "Fall2025", 1000)
+	ta.addTuition("22070006073", "Fall2025", 3000)
+	ta.addTuition("22070006071", "Spring2026", 1000)
+	ta.addTuition("22070006074", "Fall2025", 500)
+	if rec := ta.pay(ta.register("22070006074", "password"), "22070006074", "Fall2025", "500"); rec.Code != http.StatusOK {
+		ta.t.Fatalf("pay: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUnpaidTuitionExport(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addUnpaidReportData()
+
+		rec := ta.do(http.MethodGet, "/api/v2/admin/unpaid-status?format=csv", adminToken(t), nil, nil)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+			t.Fatalf("csv export: %d %q %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+		if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="unpaid-tuitions.csv"` {
+			t.Errorf("Content-Disposition = %q", got)
+		}
+		r := csv.NewReader(rec.Body)
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{
+			{"term", "payment_due_date", "student_no", "first_name", "last_name", "email", "phone", "faculty", "department", "program", "enrollment_year", "enrollment_status", "billed", "paid", "outstanding"},
+			{"Fall2025", "2025-10-15", "22070006071", "Şule", "Yılmaz", "", "", "Engineering", "", "CS", "2022", "enrolled", "1000.00", "0.00", "1000.00"},
+			{"Fall2025", "2025-10-15", "22070006072", "Ali", "Demir", "", "", "Engineering", "", "EE", "2022", "enrolled", "2000.00", "0.00", "2000.00"},
+			{"Fall2025", "2025-10-15", "22070006073", "Ayşe", "Kaya", "", "", "Medicine", "", "MED", "2021", "enrolled", "3000.00", "0.00", "3000.00"},
+			{"Spring2026", "", "22070006071", "Şule", "Yılmaz", "", "", "Engineering", "", "CS", "2022", "enrolled", "1000.00", "0.00", "1000.00"},
+			{"term", "faculty", "tuitions", "billed", "paid", "outstanding"},
+			{"Fall2025", "Engineering", "2", "3000.00", "0.00", "3000.00"},
+			{"Fall2025", "Medicine", "1", "3000.00", "0.00", "3000.00"},
+			{"Fall2025", "All faculties", "3", "6000.00", "0.00", "6000.00"},
+			{"Spring2026", "Engineering", "1", "1000.00", "0.00", "1000.00"},
+			{"Spring2026", "All faculties", "1", "1000.00", "0.00", "1000.00"},
+			{"All terms", "All faculties", "4", "7000.00", "0.00", "7000.00"},
+		}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("csv export:\n got %q\nwant %q", records, want)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/unpaid-status?term=Fall2025&faculty=Medicine", adminToken(t), nil,
+			http.Header{"Accept": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}})
+		f, err := excelize.OpenReader(rec.Body)
+		if err != nil {
+			t.Fatalf("xlsx export: %v", err)
+		}
+		defer f.Close()
+		if sheets := f.GetSheetList(); len(sheets) != 2 || sheets[0] != "Unpaid tuitions" || sheets[1] != "Totals" {
+			t.Fatalf("sheets = %q", sheets)
+		}
+		rows, _ := f.GetRows("Unpaid tuitions")
+		if len(rows) != 2 || rows[1][2] != "22070006073" || rows[1][14] != "3000" {
+			t.Errorf("unpaid sheet = %q", rows)
+		}
+		if rows, _ := f.GetRows("Totals"); len(rows) != 4 || rows[3][0] != "All terms" {
+			t.Errorf("totals sheet = %q", rows)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/unpaid-status", adminToken(t), nil, http.Header{"Accept": {"application/pdf"}})
+		pdf := rec.Body.Bytes()
+		if rec.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+			t.Errorf("pdf export: %q %q", rec.Header().Get("Content-Type"), pdf)
+		}
+		// Turkish letters are shown in the font's Windows-1254 encoding
+		if !bytes.Contains(pdf, []byte("\xdeule")) || !bytes.Contains(pdf, []byte("Y\xfdlmaz")) {
+			t.Error("pdf export is missing the student names")
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/unpaid-status?format=xml", adminToken(t), nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown format: got %d, want 400", rec.Code)
+		}
+		rec = ta.do(http.MethodGet, "/api/v2/admin/unpaid-status?limit=2", adminToken(t), nil, http.Header{"Accept": {"text/html, */*"}})
+		if unpaid := decodeJSON[[]map[string]string](t, rec); len(unpaid) != 2 {
+			t.Errorf("json status: %v", unpaid)
+		}
+	})
+}
+
+func TestListUnpaidReportPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addUnpaidReportData()
+
+		// Reading a row at a time gives the rows of a single page, in the same order
+		ctx := context.Background()
+		all, err := ta.app.Store.ListUnpaidReport(ctx, db.ListUnpaidReportParams{RowLimit: 10})
+		if err != nil || len(all) != 4 {
+			t.Fatalf("got %d rows: %v", len(all), err)
+		}
+		params := db.ListUnpaidReportParams{RowLimit: 1}
+		for i := range all {
+			page, err := ta.app.Store.ListUnpaidReport(ctx, params)
+			if err != nil || len(page) != 1 || page[0] != all[i] {
+				t.Fatalf("page %d = %+v, %v; want %+v", i, page, err, all[i])
+			}
+			params.AfterTerm, params.AfterFaculty, params.AfterStudentNo, params.AfterTuitionID = page[0].Term, page[0].Faculty, page[0].StudentNo, page[0].TuitionID
+		}
+		if page, _ := ta.app.Store.ListUnpaidReport(ctx, params); len(page) != 0 {
+			t.Errorf("read past the last row: %+v", page)
+		}
+	})
+}
+
+func TestAgingReport(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addUnpaidReportData()
+		due := pgtype.Date{Time: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+		if _, err := ta.app.Store.CreateTerm(context.Background(), db.CreateTermParams{Code: "Summer2025", Name: "Summer 2025", PaymentDueDate: due}); err != nil {
+			t.Fatal(err)
+		}
+		ta.addTuition("22070006073", "Summer2025", 800)
+
+		// On 2025-11-20 Fall2025 is 36 days past due, Summer2025 142 and Spring2026 has no due date
+		type group struct {
+			Group string `json:"group"`
+			agingAmounts
+		}
+		rec := ta.do(http.MethodGet, "/api/v2/admin/reports/aging?group_by=faculty&as_of=2025-11-20", adminToken(t), nil, nil)
+		summary := decodeJSON[struct {
+			AsOf    string       `json:"as_of"`
+			GroupBy string       `json:"group_by"`
+			Groups  []group      `json:"groups"`
+			Totals  agingAmounts `json:"totals"`
+		}](t, rec)
+		want := []group{
+			{"Engineering", agingAmounts{Tuitions: 3, NotDue: 1000, Days31To60: 3000, Outstanding: 4000}},
+			{"Medicine", agingAmounts{Tuitions: 2, Days31To60: 3000, DaysOver90: 800, Outstanding: 3800}},
+		}
+		if summary.AsOf != "2025-11-20" || summary.GroupBy != "faculty" || !reflect.DeepEqual(summary.Groups, want) {
+			t.Errorf("aging by faculty = %+v", summary)
+		}
+		if summary.Totals != (agingAmounts{Tuitions: 5, NotDue: 1000, Days31To60: 6000, DaysOver90: 800, Outstanding: 7800}) {
+			t.Errorf("totals = %+v", summary.Totals)
+		}
+
+		// Drilling down into Medicine
+		rec = ta.do(http.MethodGet, "/api/v2/admin/reports/aging?group_by=student&faculty=Medicine&as_of=2025-11-20", adminToken(t), nil, nil)
+		students := decodeJSON[struct {
+			Students []agingStudent `json:"students"`
+			Totals   agingAmounts   `json:"totals"`
+		}](t, rec)
+		if len(students.Students) != 2 || students.Totals.Outstanding != 3800 {
+			t.Fatalf("aging by student = %+v", students)
+		}
+		if s := students.Students[1]; s.Term != "Summer2025" || s.StudentNo != "22070006073" || *s.DaysPastDue != 142 || s.DaysOver90 != 800 {
+			t.Errorf("drill-down row = %+v", s)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/reports/aging?format=csv&as_of=2025-11-20", adminToken(t), nil, nil)
+		if got, want := rec.Body.String(), "term,tuitions,not_due,days_0_30,days_31_60,days_61_90,days_over_90,outstanding\n"+
+			"Fall2025,3,0.00,0.00,6000.00,0.00,0.00,6000.00\n"+
+			"Spring2026,1,1000.00,0.00,0.00,0.00,0.00,1000.00\n"+
+			"Summer2025,1,0.00,0.00,0.00,0.00,800.00,800.00\n"+
+			"All,5,1000.00,0.00,6000.00,0.00,800.00,7800.00\n"; got != want {
+			t.Errorf("aging csv:\n%s\nwant:\n%s", got, want)
+		}
+		rec = ta.do(http.MethodGet, "/api/v2/admin/reports/aging?group_by=student&program=CS", adminToken(t), nil, http.Header{"Accept": {"application/pdf"}})
+		if !strings.HasPrefix(rec.Body.String(), "%PDF-") || rec.Header().Get("Content-Disposition") != `attachment; filename="aging-by-student.pdf"` {
+			t.Errorf("aging pdf: %q", rec.Header())
+		}
+
+		for _, query := range []string{"group_by=department", "as_of=20251120", "format=doc"} {
+			if rec := ta.do(http.MethodGet, "/api/v2/admin/reports/aging?"+query, adminToken(t), nil, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestAgingBuckets(t *testing.T) {
+	var got agingAmounts
+	for _, days := range []int{-1, 0, 30, 31, 60, 61, 90, 91} {
+		got.add(&days, 1)
+	}
+	got.add(nil, 1)
+	want := agingAmounts{Tuitions: 9, NotDue: 2, Days0To30: 2, Days31To60: 2, Days61To90: 2, DaysOver90: 1, Outstanding: 9}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSummaryReport(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addUnpaidReportData()
+		ta.app.Reports = newReportCache(time.Minute)
+		token := ta.register("22070006071", "password")
+		if rec := ta.pay(token, "22070006071", "Fall2025", "1000"); rec.Code != http.StatusOK {
+			t.Fatalf("pay: %d %s", rec.Code, rec.Body)
+		}
+
+		summary := func(query string) summaryResponse {
+			t.Helper()
+			rec := ta.do(http.MethodGet, "/api/v2/admin/reports/summary?"+query, adminToken(t), nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("summary %s: %d %s", query, rec.Code, rec.Body)
+			}
+			return decodeJSON[summaryResponse](t, rec)
+		}
+
+		got := summary("top=2")
+		if s := got.Students; s.Students != 4 || s.FullyPaid != 1 || s.FullyPaidPercent != 25 {
+			t.Errorf("students = %+v", s)
+		}
+		wantTerms := []summaryTerm{
+			{Term: "Fall2025", Students: 4, FullyPaid: 2, FullyPaidPercent: 50, Billed: 6500, Collected: 1500, Outstanding: 5000, Received: 1500},
+			{Term: "Spring2026", Students: 1, Billed: 1000, Outstanding: 1000},
+		}
+		if !reflect.DeepEqual(got.Terms, wantTerms) {
+			t.Errorf("terms = %+v", got.Terms)
+		}
+		today := time.Now().UTC().Format(time.DateOnly)
+		if !reflect.DeepEqual(got.PaymentsByDay, []summaryPayments{{Day: today, Payments: 2, Amount: 1500}}) {
+			t.Errorf("payments by day = %+v", got.PaymentsByDay)
+		}
+		if !reflect.DeepEqual(got.PaymentsByChannel, []summaryPayments{{Channel: "banking", Payments: 2, Amount: 1500}}) {
+			t.Errorf("payments by channel = %+v", got.PaymentsByChannel)
+		}
+		if len(got.TopDebtors) != 2 || got.TopDebtors[0].StudentNo != "22070006073" || got.TopDebtors[1].Outstanding != 2000 {
+			t.Errorf("top debtors = %+v", got.TopDebtors)
+		}
+
+		// A range without payments
+		yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+		ranged := summary("from=" + yesterday + "&to=" + yesterday)
+		if len(ranged.PaymentsByDay) != 0 || len(ranged.PaymentsByChannel) != 0 || ranged.Terms[0].Received != 0 || ranged.Terms[0].Billed != 6500 {
+			t.Errorf("summary of %s = %+v", yesterday, ranged)
+		}
+
+		// Cached until refreshed
+		ta.pay(ta.register("22070006072", "password"), "22070006072", "Fall2025", "2000")
+		if cached := summary("top=2"); !cached.GeneratedAt.Equal(got.GeneratedAt) || cached.Terms[0].FullyPaid != 2 {
+			t.Errorf("summary was not cached: %+v", cached)
+		}
+		if fresh := summary("top=2&refresh=true"); fresh.Terms[0].FullyPaid != 3 {
+			t.Errorf("refreshed summary = %+v", fresh.Terms)
+		}
+
+		for _, query := range []string{"from=yesterday", "top=0", "top=101", "from=2025-10-02&to=2025-10-01"} {
+			if rec := ta.do(http.MethodGet, "/api/v2/admin/reports/summary?"+query, adminToken(t), nil, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// A bank partner pays with a token whose subject is its code after this prefix,
+// so the partner a payment is settled with is the one that signed in.
+const partnerSubjectPrefix = "partner:"
+
+// Partner tokens are for servers, so they last longer than a student's
+const partnerTokenTTL = 30 * 24 * time.Hour
+
+// Optional on a partner's payments; a code other than the token's is rejected
+const partnerHeader = "X-Partner-Code"
+
+// Partner codes are agreed with the bank, e.g. ZIRAAT
+var partnerCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]*$`)
+
+// Settlement statuses. A day is closed until the partner's totals are in, and then
+// matched or discrepancy.
+var settlementStatuses = []string{"closed", "matched", "discrepancy"}
+
+// Reported amounts within half a cent of ours match
+const settlementTolerance = 0.005
+
+var (
+	errDayNotOver    = errors.New("business day is not over")
+	errDayClosed     = errors.New("business day already closed")
+	errNoSuchPartner = errors.New("no such bank partner")
+)
+
+// partnerFromContext returns the bank partner the signed-in token was issued to.
+func partnerFromContext(ctx context.Context) (string, bool) {
+	subject, _ := ctx.Value("LOGGEDIN_STUDENT_NO").(string)
+	if partner, ok := strings.CutPrefix(subject, partnerSubjectPrefix); ok {
+		return partner, true
+	}
+	return "", false
+}
+
+type bankPartnerResponse struct {
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newBankPartnerResponse(p db.BankPartner) bankPartnerResponse {
+	return bankPartnerResponse{Code: p.Code, Name: p.Name, CreatedAt: p.CreatedAt.Time}
+}
+
+// settlementResponse shows the differences once the partner's totals are in, as
+// reported minus ours.
+type settlementResponse struct {
+	SettlementID     int32       `json:"settlement_id"`
+	Partner          string      `json:"partner"`
+	BusinessDate     pgtype.Date `json:"business_date"`
+	PaymentCount     int32       `json:"payment_count"`
+	TotalAmount      float64     `json:"total_amount"`
+	Status           string      `json:"status"`
+	ReportedCount    *int32      `json:"reported_count,omitempty"`
+	ReportedAmount   *float64    `json:"reported_amount,omitempty"`
+	CountDifference  *int32      `json:"count_difference,omitempty"`
+	AmountDifference *float64    `json:"amount_difference,omitempty"`
+	ClosedBy         string      `json:"closed_by,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+	ReportedAt       *time.Time  `json:"reported_at,omitempty"`
+}
+
+func newSettlementResponse(s db.Settlement) settlementResponse {
+	response := settlementResponse{
+		SettlementID: s.SettlementID,
+		Partner:      s.Partner,
+		BusinessDate: s.BusinessDate,
+		PaymentCount: s.PaymentCount,
+		TotalAmount:  s.TotalAmount,
+		Status:       s.Status,
+		ClosedBy:     s.ClosedBy,
+		CreatedAt:    s.CreatedAt.Time,
+		ReportedAt:   timePtr(s.ReportedAt),
+	}
+	if s.ReportedCount.Valid && s.ReportedAmount.Valid {
+		count := s.ReportedCount.Int32 - s.PaymentCount
+		amount := math.Round((s.ReportedAmount.Float64-s.TotalAmount)*100) / 100
+		response.ReportedCount, response.ReportedAmount = &s.ReportedCount.Int32, &s.ReportedAmount.Float64
+		response.CountDifference, response.AmountDifference = &count, &amount
+	}
+	return response
+}
+
+// settlementStatus compares the totals a partner reported with the settlement.
+func settlementStatus(s db.Settlement, count int32, amount float64) string {
+	if count == s.PaymentCount && math.Abs(amount-s.TotalAmount) < settlementTolerance {
+		return "matched"
+	}
+	return "discrepancy"
+}
+
+// closeSettlement closes a partner's business day with the count and sum of the
+// payments it took that day.
+func closeSettlement(ctx context.Context, store Store, partner string, day time.Time, closedBy string) (db.Settlement, error) {
+	date := pgtype.Date{Time: day, Valid: true}
+	var settlement db.Settlement
+	err := store.WithTx(ctx, func(tx Store) error {
+		_, err := tx.GetSettlementByDay(ctx, db.GetSettlementByDayParams{Partner: partner, BusinessDate: date})
+		if err == nil {
+			return errDayClosed
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		payments, err := tx.ListPartnerPayments(ctx, db.ListPartnerPaymentsParams{
+			Partner:  partner,
+			FromTime: pgtype.Timestamptz{Time: day, Valid: true},
+			ToTime:   pgtype.Timestamptz{Time: day.AddDate(0, 0, 1), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		var total float64
+		for _, p := range payments {
+			total += p.Amount
+		}
+		settlement, err = tx.AddSettlement(ctx, db.AddSettlementParams{
+			Partner:      partner,
+			BusinessDate: date,
+			PaymentCount: int32(len(payments)),
+			TotalAmount:  math.Round(total*100) / 100,
+			ClosedBy:     closedBy,
+		})
+		return err
+	})
+	// Closed by another request or instance meanwhile
+	if isConstraintError(err, pgUniqueViolation) {
+		return settlement, errDayClosed
+	}
+	return settlement, err
+}
+
+// settlementClose is what closing a business day did.
+type settlementClose struct {
+	BusinessDate  string               `json:"business_date"`
+	Settlements   []settlementResponse `json:"settlements"`
+	AlreadyClosed []string             `json:"already_closed"`
+}
+
+// closeSettlements closes a business day for one partner or, when partner is
+// empty, for every partner whose day is not closed yet. A day can only be closed
+// once it is over in UTC.
+func closeSettlements(ctx context.Context, store Store, day time.Time, partner, closedBy string) (settlementClose, error) {
+	result := settlementClose{BusinessDate: day.Format(time.DateOnly), Settlements: []settlementResponse{}, AlreadyClosed: []string{}}
+	if day.AddDate(0, 0, 1).After(time.Now().UTC()) {
+		return result, errDayNotOver
+	}
+
+	var partners []string
+	if partner != "" {
+		_, err := store.GetBankPartner(ctx, partner)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return result, errNoSuchPartner
+		}
+		if err != nil {
+			return result, err
+		}
+		partners = []string{partner}
+	} else {
+		all, err := store.ListBankPartners(ctx)
+		if err != nil {
+			return result, err
+		}
+		for _, p := range all {
+			partners = append(partners, p.Code)
+		}
+	}
+
+	for _, code := range partners {
+		settlement, err := closeSettlement(ctx, store, code, day, closedBy)
+		if errors.Is(err, errDayClosed) && partner == "" {
+			result.AlreadyClosed = append(result.AlreadyClosed, code)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		result.Settlements = append(result.Settlements, newSettlementResponse(settlement))
+	}
+	return result, nil
+}
+
+// parseBusinessDate reads a business day, yesterday (UTC) when v is empty.
+func parseBusinessDate(v string) (time.Time, bool) {
+	if v == "" {
+		now := time.Now().UTC()
+		return time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC), true
+	}
+	day, err := time.Parse(time.DateOnly, v)
+	return day, err == nil
+}
+
+// runCloseSettlementsCommand is the command line version of the close endpoint,
+// meant to run daily: close-settlements [-date YYYY-MM-DD] [-partner CODE]
+func runCloseSettlementsCommand(ctx context.Context, store Store, args []string) error {
+	flags := flag.NewFlagSet("close-settlements", flag.ContinueOnError)
+	date := flags.String("date", "", "the business day to close; yesterday by default")
+	partner := flags.String("partner", "", "only close the day of this partner")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: close-settlements [-date YYYY-MM-DD] [-partner CODE]")
+	}
+	day, ok := parseBusinessDate(*date)
+	if !ok {
+		return errors.New("date must be a date like 2025-10-15")
+	}
+
+	result, err := closeSettlements(ctx, store, day, *partner, "")
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PARTNER\tPAYMENTS\tTOTAL")
+	for _, s := range result.Settlements {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\n", s.Partner, s.PaymentCount, s.TotalAmount)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("closed %s for %d partner(s); %d were already closed\n",
+		result.BusinessDate, len(result.Settlements), len(result.AlreadyClosed))
+	return nil
+}
+
+// Admin - List Bank Partners
+func (a *App) listBankPartnersHandler(w http.ResponseWriter, r *http.Request) {
+	partners, err := a.Store.ListBankPartners(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Bank partners cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListBankPartnersResponse struct {
+		Partners []bankPartnerResponse `json:"partners"`
+	}
+	response := ListBankPartnersResponse{Partners: []bankPartnerResponse{}}
+	for _, p := range partners {
+		response.Partners = append(response.Partners, newBankPartnerResponse(p))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Onboard a Bank Partner. Its payments through /banking/pay are settled
+// with it once it pays with a token from issuePartnerTokenHandler.
+func (a *App) createBankPartnerHandler(w http.ResponseWriter, r *http.Request) {
+	type CreateBankPartnerRequest struct {
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	var req CreateBankPartnerRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Code) > partnerCodeMaxLength || !partnerCodePattern.MatchString(req.Code) {
+		http.Error(w, `{"error":"code must be at most 30 uppercase letters, digits, dashes or underscores"}`, http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > nameMaxLength {
+		http.Error(w, `{"error":"name must be between 1 and 100 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	partner, err := a.Store.AddBankPartner(r.Context(), db.AddBankPartnerParams{Code: req.Code, Name: req.Name})
+	if isConstraintError(err, pgUniqueViolation) {
+		http.Error(w, `{"error":"A bank partner with this code already exists"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Bank partner cannot be created"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newBankPartnerResponse(partner))
+}
+
+// Admin - Issue a token for a bank partner. Payments made with it are settled
+// with that partner.
+func (a *App) issuePartnerTokenHandler(w http.ResponseWriter, r *http.Request) {
+	partner, err := a.Store.GetBankPartner(r.Context(), r.PathValue("code"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Bank partner not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Bank partner cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(partnerTokenTTL).UTC()
+	token, err := generateJWT(partnerSubjectPrefix+partner.Code, partnerTokenTTL)
+	if err != nil {
+		http.Error(w, `{"error":"Token cannot be issued"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type PartnerTokenResponse struct {
+		Partner   string    `json:"partner"`
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PartnerTokenResponse{Partner: partner.Code, Token: token, ExpiresAt: expiresAt})
+}
+
+// Admin - Close a business day, for one partner or for all of them
+func (a *App) closeSettlementsHandler(w http.ResponseWriter, r *http.Request) {
+	type CloseSettlementsRequest struct {
+		Date    string `json:"date"`
+		Partner string `json:"partner"`
+	}
+	var req CloseSettlementsRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	// Every field is optional, so an empty body closes yesterday for all partners
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	day, ok := parseBusinessDate(req.Date)
+	if !ok {
+		http.Error(w, `{"error":"date must be a date like 2025-10-15"}`, http.StatusBadRequest)
+		return
+	}
+
+	closedBy, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+	result, err := closeSettlements(r.Context(), a.Store, day, req.Partner, closedBy)
+	switch {
+	case errors.Is(err, errDayNotOver):
+		http.Error(w, `{"error":"The business day is not over yet"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, errNoSuchPartner):
+		http.Error(w, `{"error":"Bank partner not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errDayClosed):
+		http.Error(w, `{"error":"The business day is already closed for this partner"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Business day cannot be closed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func settlementIDFromPath(r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(r.PathValue("settlement_id"), 10, 32)
+	return int32(id), err == nil
+}
+
+// Admin - Settlements, latest business day first
+func (a *App) listSettlementsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, offset, ok := parsePage(q)
+	if !ok {
+		http.Error(w, `{"error":"limit must be between 0 and 100 and offset must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+	status := q.Get("status")
+	if status != "" && !slices.Contains(settlementStatuses, status) {
+		http.Error(w, `{"error":"status must be closed, matched or discrepancy"}`, http.StatusBadRequest)
+		return
+	}
+	partner := optionalText(q.Get("partner"))
+
+	settlements, err := a.Store.ListSettlements(r.Context(), db.ListSettlementsParams{
+		Partner:   partner,
+		Status:    optionalText(status),
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Settlements cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	total, err := a.Store.CountSettlements(r.Context(), db.CountSettlementsParams{Partner: partner, Status: optionalText(status)})
+	if err != nil {
+		http.Error(w, `{"error":"Settlements cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListSettlementsResponse struct {
+		Settlements []settlementResponse `json:"settlements"`
+		Total       int64                `json:"total"`
+		Limit       int32                `json:"limit"`
+		Offset      int32                `json:"offset"`
+	}
+	response := ListSettlementsResponse{Settlements: []settlementResponse{}, Total: total, Limit: limit, Offset: offset}
+	for _, s := range settlements {
+		response.Settlements = append(response.Settlements, newSettlementResponse(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// settlementFromPath loads the settlement named in the path, responding with the
+// error itself when it cannot.
+func (a *App) settlementFromPath(w http.ResponseWriter, r *http.Request) (db.Settlement, bool) {
+	settlementID, ok := settlementIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid settlement id"}`, http.StatusBadRequest)
+		return db.Settlement{}, false
+	}
+	settlement, err := a.Store.GetSettlement(r.Context(), settlementID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Settlement not found"}`, http.StatusNotFound)
+		return settlement, false
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Settlement cannot be queried"}`, http.StatusInternalServerError)
+		return settlement, false
+	}
+	return settlement, true
+}
+
+// Admin - A single settlement with its reported totals and differences
+func (a *App) getSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	settlement, ok := a.settlementFromPath(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newSettlementResponse(settlement))
+}
+
+type settlementPayment struct {
+	PaymentID int32     `json:"payment_id"`
+	CreatedAt time.Time `json:"created_at"`
+	StudentNo string    `json:"student_no"`
+	Term      string    `json:"term"`
+	Amount    float64   `json:"amount"`
+	ReceiptNo string    `json:"receipt_no,omitempty"`
+}
+
+// Admin - Settlement File: the day's totals and every payment the partner took
+// that day, as JSON or, with format=csv (or xlsx or pdf), a download.
+func (a *App) settlementFileHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := reportFormat(r)
+	if !ok {
+		http.Error(w, `{"error":"format must be json, csv, xlsx or pdf"}`, http.StatusBadRequest)
+		return
+	}
+	settlement, ok := a.settlementFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	day := settlement.BusinessDate.Time
+	rows, err := a.Store.ListPartnerPayments(r.Context(), db.ListPartnerPaymentsParams{
+		Partner:  settlement.Partner,
+		FromTime: pgtype.Timestamptz{Time: day, Valid: true},
+		ToTime:   pgtype.Timestamptz{Time: day.AddDate(0, 0, 1), Valid: true},
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Settlement cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	payments := []settlementPayment{}
+	for _, p := range rows {
+		payments = append(payments, settlementPayment{
+			PaymentID: p.PaymentID,
+			CreatedAt: p.CreatedAt.Time,
+			StudentNo: p.StudentNo,
+			Term:      p.Term,
+			Amount:    p.Amount,
+			ReceiptNo: p.ReceiptNo,
+		})
+	}
+
+	response := newSettlementResponse(settlement)
+	if format == formatJSON {
+		type SettlementFileResponse struct {
+			Settlement settlementResponse  `json:"settlement"`
+			Payments   []settlementPayment `json:"payments"`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SettlementFileResponse{Settlement: response, Payments: payments})
+		return
+	}
+
+	// Reported totals and differences are blank until the partner's are in
+	var reported []any
+	if response.ReportedCount != nil {
+		reported = []any{int(*response.ReportedCount), *response.ReportedAmount, int(*response.CountDifference), *response.AmountDifference}
+	} else {
+		reported = []any{"", "", "", ""}
+	}
+	report := newReportWriter(w, format, fmt.Sprintf("settlement-%s-%s", settlement.Partner, reportDate(settlement.BusinessDate)))
+	report.Table("Settlement", []reportColumn{
+		{Name: "partner", Width: 12},
+		{Name: "business_date", Width: 13},
+		{Name: "payment_count", Width: 13, Numeric: true},
+		{Name: "total_amount", Width: 14, Numeric: true},
+		{Name: "status", Width: 11},
+		{Name: "reported_count", Width: 14, Numeric: true},
+		{Name: "reported_amount", Width: 15, Numeric: true},
+		{Name: "count_difference"},
+		{Name: "amount_difference"},
+	})
+	report.Row(append([]any{settlement.Partner, reportDate(settlement.BusinessDate), int(settlement.PaymentCount), settlement.TotalAmount, settlement.Status}, reported...)...)
+	report.Table("Payments", []reportColumn{
+		{Name: "payment_id", Width: 10, Numeric: true},
+		{Name: "created_at", Width: 20},
+		{Name: "student_no", Width: 11},
+		{Name: "term", Width: 14},
+		{Name: "amount", Width: 12, Numeric: true},
+		{Name: "receipt_no", Width: 20},
+	})
+	for _, p := range payments {
+		report.Row(int(p.PaymentID), p.CreatedAt.UTC().Format(time.RFC3339), p.StudentNo, p.Term, p.Amount, p.ReceiptNo)
+	}
+	if err := report.Close(); err != nil {
+		log.Printf("settlement file: %v", err)
+	}
+}
+
+// reportSettlement records the totals a partner reported for a closed day.
+func reportSettlement(ctx context.Context, store Store, settlement db.Settlement, count int32, amount float64) (db.Settlement, error) {
+	return store.ReportSettlement(ctx, db.ReportSettlementParams{
+		SettlementID:   settlement.SettlementID,
+		ReportedCount:  pgtype.Int4{Int32: count, Valid: true},
+		ReportedAmount: pgtype.Float8{Float64: amount, Valid: true},
+		Status:         settlementStatus(settlement, count, amount),
+	})
+}
+
+// Admin - Post the totals a partner reported for a closed day. Posting again
+// replaces them.
+func (a *App) reportSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	type ReportSettlementRequest struct {
+		PaymentCount *int32   `json:"payment_count"`
+		TotalAmount  *float64 `json:"total_amount"`
+	}
+	var req ReportSettlementRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	if req.PaymentCount == nil || *req.PaymentCount < 0 || req.TotalAmount == nil || *req.TotalAmount < 0 {
+		http.Error(w, `{"error":"payment_count and total_amount are required and must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	settlement, ok := a.settlementFromPath(w, r)
+	if !ok {
+		return
+	}
+	settlement, err := reportSettlement(r.Context(), a.Store, settlement, *req.PaymentCount, *req.TotalAmount)
+	if err != nil {
+		http.Error(w, `{"error":"Reported totals cannot be recorded"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newSettlementResponse(settlement))
+}
+
+// settlementTotalsImporter records the totals partners report, one closed day per
+// row. A row for a day that is not closed is rejected.
+var settlementTotalsImporter = importer{
+	Fields: []importField{
+		{Name: "partner", Aliases: []string{"partner_code", "bank"}, Required: true, Example: "ZIRAAT"},
+		{Name: "date", Aliases: []string{"business_date", "settlement_date"}, Required: true, Example: "2025-10-15"},
+		{Name: "payment_count", Aliases: []string{"count", "payments"}, Required: true, Example: "42"},
+		{Name: "total_amount", Aliases: []string{"amount", "total"}, Required: true, Example: "125000.00"},
+	},
+	Apply: importSettlementTotalsRow,
+}
+
+func importSettlementTotalsRow(ctx context.Context, tx Store, row importRow) (string, error) {
+	partner, err := requiredValue(row, "partner")
+	if err != nil {
+		return "", err
+	}
+	date, err := requiredValue(row, "date")
+	if err != nil {
+		return "", err
+	}
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return "", rowError("date", "date must be a date like 2025-10-15")
+	}
+	count, ok, err := integerValue(row, "payment_count")
+	if err != nil {
+		return "", err
+	}
+	if !ok || count < 0 {
+		return "", rowError("payment_count", "payment_count is required and must not be negative")
+	}
+	amount, ok, err := numberValue(row, "total_amount")
+	if err != nil {
+		return "", err
+	}
+	if !ok || amount < 0 {
+		return "", rowError("total_amount", "total_amount is required and must not be negative")
+	}
+
+	settlement, err := tx.GetSettlementByDay(ctx, db.GetSettlementByDayParams{
+		Partner:      partner,
+		BusinessDate: pgtype.Date{Time: day, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", rowError("date", "the business day is not closed for this partner")
+	}
+	if err != nil {
+		return "", err
+	}
+	if settlement.ReportedCount == (pgtype.Int4{Int32: count, Valid: true}) &&
+		settlement.ReportedAmount == (pgtype.Float8{Float64: amount, Valid: true}) {
+		return importSkipped, nil
+	}
+	if _, err := reportSettlement(ctx, tx, settlement, count, amount); err != nil {
+		return "", err
+	}
+	return importUpdated, nil
+}
+
+// Admin - Upload the totals partners reported (CSV, XLSX or JSON), with the options
+// of the other imports
+func (a *App) importSettlementTotalsHandler(w http.ResponseWriter, r *http.Request) {
+	a.handleImport(w, r, "settlement_totals")
+}
+
+// Admin - Template file for the reported totals upload
+func (a *App) settlementTotalsTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	writeImportTemplate(w, r, "settlement_totals")
+}
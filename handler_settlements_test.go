@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (ta *testApp) addPartner(code string) {
+	ta.t.Helper()
+	rec := ta.do(http.MethodPost, "/api/v2/admin/partners", adminToken(ta.t),
+		strings.NewReader(fmt.Sprintf(`{"code":%q,"name":"Bank %s"}`, code, code)), nil)
+	if rec.Code != http.StatusCreated {
+		ta.t.Fatalf("add partner %s: %d %s", code, rec.Code, rec.Body)
+	}
+}
+
+func (ta *testApp) partnerToken(code string) string {
+	ta.t.Helper()
+	rec := ta.do(http.MethodPost, "/api/v2/admin/partners/"+code+"/token", adminToken(ta.t), nil, nil)
+	type tokenResponse struct {
+		Token string `json:"token"`
+	}
+	token := decodeJSON[tokenResponse](ta.t, rec).Token
+	if rec.Code != http.StatusOK || token == "" {
+		ta.t.Fatalf("partner token %s: %d %s", code, rec.Code, rec.Body)
+	}
+	return token
+}
+
+// partnerPay pays with token, sending partner in the partner header unless it is empty.
+func (ta *testApp) partnerPay(token, partner, studentNo, term, amount string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	q := url.Values{"student_no": {studentNo}, "term": {term}, "amount": {amount}}
+	var header http.Header
+	if partner != "" {
+		header = http.Header{partnerHeader: {partner}}
+	}
+	return ta.do(http.MethodPost, "/api/v2/banking/pay?"+q.Encode(), token, nil, header)
+}
+
+func TestPartnerPayments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		token := ta.register("22070006071", "secret")
+		ta.addPartner("ZIRAAT")
+		ta.addPartner("AKBANK")
+		ziraat := ta.partnerToken("ZIRAAT")
+		unknown, err := generateJWT(partnerSubjectPrefix+"NOPE", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The partner comes from the token, so a header can't book payments to another one
+		for _, tt := range []struct {
+			name, token, partner string
+		}{
+			{"student naming a partner", token, "ZIRAAT"},
+			{"another partner", ziraat, "AKBANK"},
+			{"unknown partner", unknown, ""},
+		} {
+			if rec := ta.partnerPay(tt.token, tt.partner, "22070006071", "Fall2025", "100"); rec.Code != http.StatusForbidden {
+				t.Fatalf("%s: %d %s", tt.name, rec.Code, rec.Body)
+			}
+		}
+		if ta.balance("22070006071") != 10 {
+			t.Fatal("a rejected partner payment was posted")
+		}
+		if rec := ta.partnerPay(ziraat, "", "22070006071", "Fall2025", "60"); rec.Code != http.StatusOK {
+			t.Fatalf("pay: %d %s", rec.Code, rec.Body)
+		}
+		if rec := ta.partnerPay(ziraat, "ZIRAAT", "22070006071", "Fall2025", "40"); rec.Code != http.StatusOK {
+			t.Fatalf("pay with header: %d %s", rec.Code, rec.Body)
+		}
+		// Payments without a partner code are not settled with anyone
+		if rec := ta.pay(token, "22070006071", "Fall2025", "50"); rec.Code != http.StatusOK {
+			t.Fatalf("pay: %d %s", rec.Code, rec.Body)
+		}
+
+		payments, err := ta.app.Store.ListPaymentsByStudent(context.Background(), "22070006071")
+		if err != nil || len(payments) != 3 {
+			t.Fatalf("payments: %v %+v", err, payments)
+		}
+		if payments[0].Partner.String != "ZIRAAT" || payments[1].Partner.String != "ZIRAAT" || payments[2].Partner.Valid {
+			t.Fatalf("partners: %+v", payments)
+		}
+
+		rec := ta.do(http.MethodPost, "/api/v2/admin/partners/NOPE/token", adminToken(t), nil, nil)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("token for unknown partner: %d %s", rec.Code, rec.Body)
+		}
+		rec = ta.do(http.MethodPost, "/api/v2/admin/partners/ZIRAAT/token", ziraat, nil, nil)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("partner issuing tokens: %d %s", rec.Code, rec.Body)
+		}
+
+		rec = ta.do(http.MethodPost, "/api/v2/admin/partners", adminToken(t), strings.NewReader(`{"code":"ZIRAAT","name":"Again"}`), nil)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("duplicate partner: %d %s", rec.Code, rec.Body)
+		}
+		rec = ta.do(http.MethodPost, "/api/v2/admin/partners", adminToken(t), strings.NewReader(`{"code":"zi raat","name":"Bad"}`), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("invalid code: %d %s", rec.Code, rec.Body)
+		}
+	})
+}
+
+func TestCloseSettlements(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addPartner("AKBANK")
+		ta.addPartner("ZIRAAT")
+		admin := adminToken(t)
+
+		today := time.Now().UTC().Format(time.DateOnly)
+		rec := ta.do(http.MethodPost, "/api/v2/admin/settlements", admin, strings.NewReader(`{"date":"`+today+`"}`), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("close today: %d %s", rec.Code, rec.Body)
+		}
+
+		// Yesterday by default, for every partner; the body can be left out
+		rec = ta.do(http.MethodPost, "/api/v2/admin/settlements", admin, nil, nil)
+		closed := decodeJSON[settlementClose](t, rec)
+		yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+		if rec.Code != http.StatusOK || closed.BusinessDate != yesterday || len(closed.Settlements) != 2 || len(closed.AlreadyClosed) != 0 {
+			t.Fatalf("close: %d %+v", rec.Code, closed)
+		}
+		if s := closed.Settlements[0]; s.Partner != "AKBANK" || s.PaymentCount != 0 || s.Status != "closed" || s.ClosedBy != "admin" {
+			t.Fatalf("settlement: %+v", s)
+		}
+
+		rec = ta.do(http.MethodPost, "/api/v2/admin/settlements", admin, strings.NewReader(`{}`), nil)
+		if closed := decodeJSON[settlementClose](t, rec); len(closed.Settlements) != 0 || len(closed.AlreadyClosed) != 2 {
+			t.Fatalf("close again: %+v", closed)
+		}
+		rec = ta.do(http.MethodPost, "/api/v2/admin/settlements", admin, strings.NewReader(`{"partner":"ZIRAAT"}`), nil)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("close partner again: %d %s", rec.Code, rec.Body)
+		}
+		rec = ta.do(http.MethodPost, "/api/v2/admin/settlements", admin, strings.NewReader(`{"partner":"NOPE","date":"2025-10-15"}`), nil)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("unknown partner: %d %s", rec.Code, rec.Body)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/settlements?partner=ZIRAAT", admin, nil, nil)
+		type listResponse struct {
+			Settlements []settlementResponse `json:"settlements"`
+			Total       int64                `json:"total"`
+		}
+		if list := decodeJSON[listResponse](t, rec); list.Total != 1 || list.Settlements[0].Partner != "ZIRAAT" {
+			t.Fatalf("list: %+v", list)
+		}
+	})
+}
+
+func TestSettlementDiscrepancies(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006072", "Fall2025", 1000)
+		ta.addPartner("ZIRAAT")
+		admin := adminToken(t)
+
+		ziraat := ta.partnerToken("ZIRAAT")
+		ta.partnerPay(ziraat, "", "22070006071", "Fall2025", "400")
+		ta.partnerPay(ziraat, "", "22070006072", "Fall2025", "250.50")
+
+		// Today's payments, closed as if the day were over
+		now := time.Now().UTC()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		settlement, err := closeSettlement(context.Background(), ta.app.Store, "ZIRAAT", day, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settlement.PaymentCount != 2 || settlement.TotalAmount != 650.5 {
+			t.Fatalf("settlement: %+v", settlement)
+		}
+		path := fmt.Sprintf("/api/v2/admin/settlements/%d", settlement.SettlementID)
+
+		rec := ta.do(http.MethodGet, path+"/file?format=csv", admin, nil, nil)
+		reader := csv.NewReader(strings.NewReader(rec.Body.String()))
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("csv: %d %v %s", rec.Code, err, rec.Body)
+		}
+		// Settlement header and row, then payments header and two rows
+		if len(records) != 5 || records[1][0] != "ZIRAAT" || records[1][2] != "2" || records[1][3] != "650.50" ||
+			records[3][2] != "22070006071" || records[3][5] == "" {
+			t.Fatalf("csv: %q", records)
+		}
+
+		type fileResponse struct {
+			Settlement settlementResponse  `json:"settlement"`
+			Payments   []settlementPayment `json:"payments"`
+		}
+		rec = ta.do(http.MethodGet, path+"/file", admin, nil, nil)
+		if file := decodeJSON[fileResponse](t, rec); len(file.Payments) != 2 || file.Payments[1].Amount != 250.5 {
+			t.Fatalf("json: %+v", file)
+		}
+
+		rec = ta.do(http.MethodPost, path+"/reported", admin, strings.NewReader(`{"payment_count":2,"total_amount":650.5}`), nil)
+		if s := decodeJSON[settlementResponse](t, rec); s.Status != "matched" || *s.CountDifference != 0 || *s.AmountDifference != 0 {
+			t.Fatalf("matched: %+v", s)
+		}
+		rec = ta.do(http.MethodPost, path+"/reported", admin, strings.NewReader(`{"payment_count":3}`), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("missing amount: %d %s", rec.Code, rec.Body)
+		}
+
+		// Uploaded totals replace the posted ones
+		upload := "partner,date,payment_count,total_amount\n" +
+			"ZIRAAT," + day.Format(time.DateOnly) + ",3,700.50\n" +
+			"ZIRAAT,2020-01-01,1,10\n"
+		body, header := multipartFile(t, "file", "totals.csv", upload)
+		rec = ta.do(http.MethodPost, "/api/v2/admin/settlements/import?mode=skip_invalid", admin, body, header)
+		summary := decodeJSON[importSummary](t, rec)
+		if rec.Code != http.StatusOK || summary.Updated != 1 || summary.Invalid != 1 || summary.Errors[0].Line != 3 {
+			t.Fatalf("import: %d %+v", rec.Code, summary)
+		}
+
+		rec = ta.do(http.MethodGet, path, admin, nil, nil)
+		s := decodeJSON[settlementResponse](t, rec)
+		if s.Status != "discrepancy" || *s.ReportedCount != 3 || *s.CountDifference != 1 || *s.AmountDifference != 50 {
+			t.Fatalf("discrepancy: %+v", s)
+		}
+		rec = ta.do(http.MethodGet, "/api/v2/admin/settlements?status=discrepancy", admin, nil, nil)
+		if !strings.Contains(rec.Body.String(), `"total":1`) {
+			t.Fatalf("list: %s", rec.Body)
+		}
+	})
+}
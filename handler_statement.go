@@ -0,0 +1,228 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of statement entries. A credit is a cancelled tuition and an adjustment an
+// amended one.
+const (
+	entryCharge     = "charge"
+	entryPayment    = "payment"
+	entryCredit     = "credit"
+	entryAdjustment = "adjustment"
+)
+
+// Entries at the same time are listed in this order, so a tuition is billed before
+// it is paid.
+var entryOrder = map[string]int{entryCharge: 0, entryAdjustment: 1, entryCredit: 2, entryPayment: 3}
+
+// statementEntry is a line of a statement. Amount is what it adds to what the
+// student owes, so payments and credits are negative. Balance is what was billed
+// less what was paid up to it; a negative balance is paid ahead. Credit an admin
+// puts on the student directly is not part of the statement.
+type statementEntry struct {
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Term        string    `json:"term"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Balance     float64   `json:"balance"`
+}
+
+// statementResponse sums up the entries of a date range: the closing balance is
+// the opening balance plus charges and adjustments, less payments and credits.
+type statementResponse struct {
+	StudentNo      string           `json:"student_no"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	OpeningBalance float64          `json:"opening_balance"`
+	Charges        float64          `json:"charges"`
+	Payments       float64          `json:"payments"`
+	Credits        float64          `json:"credits"`
+	Adjustments    float64          `json:"adjustments"`
+	ClosingBalance float64          `json:"closing_balance"`
+	Entries        []statementEntry `json:"entries"`
+}
+
+var statementSummaryColumns = []reportColumn{
+	{Name: "student_no", Width: 11},
+	{Name: "from", Width: 10},
+	{Name: "to", Width: 10},
+	{Name: "opening_balance", Width: 15, Numeric: true},
+	{Name: "charges", Width: 14, Numeric: true},
+	{Name: "payments", Width: 14, Numeric: true},
+	{Name: "credits", Width: 14, Numeric: true},
+	{Name: "adjustments", Width: 14, Numeric: true},
+	{Name: "closing_balance", Width: 15, Numeric: true},
+}
+
+var statementEntryColumns = []reportColumn{
+	{Name: "date", Width: 16},
+	{Name: "type", Width: 10},
+	{Name: "term", Width: 12},
+	{Name: "description", Width: 80},
+	{Name: "amount", Width: 14, Numeric: true},
+	{Name: "balance", Width: 14, Numeric: true},
+}
+
+// studentEntries lists everything that changed what a student owes, oldest first.
+// Tuitions are charged at what they were first billed, with later amendments and
+// cancellations as entries of their own.
+func studentEntries(ctx context.Context, store Store, studentNo string) ([]statementEntry, error) {
+	tuitions, err := store.ListTuitionsByStudent(ctx, studentNo)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := store.ListStudentTuitionChanges(ctx, studentNo)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := store.ListPaymentsByStudent(ctx, studentNo)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []statementEntry
+	for _, t := range tuitions {
+		billed, term := t.BilledTotal, t.Term
+		// Changes are oldest first, and the first one knows what was billed
+		if i := slices.IndexFunc(changes, func(c db.TuitionChange) bool { return c.TuitionID == t.TuitionID }); i >= 0 {
+			billed, term = changes[i].OldAmount, changes[i].OldTerm
+		}
+		entries = append(entries, statementEntry{
+			Date:        t.CreatedAt.Time,
+			Type:        entryCharge,
+			Term:        term,
+			Description: "Tuition billed",
+			Amount:      billed,
+		})
+	}
+	for _, c := range changes {
+		entry := statementEntry{Date: c.CreatedAt.Time, Term: c.NewTerm}
+		switch {
+		case c.Action == "cancel":
+			entry.Type, entry.Amount = entryCredit, -c.OldAmount
+			entry.Description = "Tuition cancelled: " + c.Reason
+		case c.OldTerm != c.NewTerm:
+			entry.Type, entry.Amount = entryAdjustment, c.NewAmount-c.OldAmount
+			entry.Description = fmt.Sprintf("Tuition moved from %s: %s", c.OldTerm, c.Reason)
+		default:
+			entry.Type, entry.Amount = entryAdjustment, c.NewAmount-c.OldAmount
+			entry.Description = "Tuition amended: " + c.Reason
+		}
+		entries = append(entries, entry)
+	}
+	for _, p := range payments {
+		entries = append(entries, statementEntry{
+			Date:        p.CreatedAt.Time,
+			Type:        entryPayment,
+			Term:        p.Term,
+			Description: "Payment via " + p.Channel,
+			Amount:      -p.Amount,
+		})
+	}
+
+	slices.SortStableFunc(entries, func(a, b statementEntry) int {
+		return cmp.Or(a.Date.Compare(b.Date), cmp.Compare(entryOrder[a.Type], entryOrder[b.Type]))
+	})
+	return entries, nil
+}
+
+// statement gives the entries between from and to with running balances. Entries
+// before from make up the opening balance.
+func statement(entries []statementEntry, from, to pgtype.Timestamptz) statementResponse {
+	response := statementResponse{Entries: []statementEntry{}}
+	balance := 0.0
+	for _, e := range entries {
+		if to.Valid && !e.Date.Before(to.Time) {
+			break
+		}
+		balance += e.Amount
+		if from.Valid && e.Date.Before(from.Time) {
+			response.OpeningBalance = balance
+			continue
+		}
+		switch e.Type {
+		case entryCharge:
+			response.Charges += e.Amount
+		case entryPayment:
+			response.Payments -= e.Amount
+		case entryCredit:
+			response.Credits -= e.Amount
+		case entryAdjustment:
+			response.Adjustments += e.Amount
+		}
+		e.Date = e.Date.UTC()
+		e.Balance = balance
+		response.Entries = append(response.Entries, e)
+	}
+	response.ClosingBalance = balance
+	return response
+}
+
+// Mobile / Banking - Statement. Lists a student's charges, payments, credits and
+// adjustments over a date range with running balances, as JSON or a download.
+func (a *App) statementHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format, ok := reportFormat(r)
+	if !ok {
+		http.Error(w, `{"error":"format must be json, csv, xlsx or pdf"}`, http.StatusBadRequest)
+		return
+	}
+	studentNo := q.Get("student_no")
+	if studentNo == "" {
+		http.Error(w, `{"error":"student_no parameter is required"}`, http.StatusBadRequest)
+		return
+	}
+	from, to, msg := dateRange(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	student, err := a.Store.GetStudentById(r.Context(), studentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if r.Context().Value("LOGGEDIN_STUDENT_NO") != student.StudentNo {
+		http.Error(w, `{"error":"Each student only can see their own statement"}`, http.StatusNotFound)
+		return
+	}
+
+	entries, err := studentEntries(r.Context(), a.Store, student.StudentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Statement cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	response := statement(entries, from, to)
+	response.StudentNo, response.From, response.To = student.StudentNo, q.Get("from"), q.Get("to")
+
+	if format == formatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	report := newReportWriter(w, format, "statement-"+student.StudentNo)
+	report.Table("Statement", statementSummaryColumns)
+	report.Row(response.StudentNo, response.From, response.To, response.OpeningBalance, response.Charges,
+		response.Payments, response.Credits, response.Adjustments, response.ClosingBalance)
+	report.Table("Entries", statementEntryColumns)
+	for _, e := range response.Entries {
+		report.Row(e.Date.Format("2006-01-02 15:04"), e.Type, e.Term, e.Description, e.Amount, e.Balance)
+	}
+	if err := report.Close(); err != nil {
+		log.Printf("statement export: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestStatement(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		token := ta.register("22070006071", "password")
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 800)
+		ta.addTuition("22070006071", "Summer2026", 400)
+		if rec := ta.pay(token, "22070006071", "Fall2025", "1000"); rec.Code != http.StatusOK {
+			t.Fatalf("pay: %d %s", rec.Code, rec.Body)
+		}
+		ta.amend(ta.tuitionID("22070006071", "Spring2026"), `{"amount": 600, "reason": "scholarship"}`)
+		ta.cancel(ta.tuitionID("22070006071", "Summer2026"), `{"reason":"withdrew"}`)
+
+		rec := ta.do(http.MethodGet, "/api/v2/banking/statement?student_no=22070006071", token, nil, nil)
+		got := decodeJSON[statementResponse](t, rec)
+		if len(got.Entries) != 6 {
+			t.Fatalf("entries = %+v", got.Entries)
+		}
+		for i, e := range got.Entries[:3] {
+			if e.Type != entryCharge {
+				t.Errorf("entry %d = %+v, want a charge", i, e)
+			}
+		}
+		balance := 0.0
+		for _, e := range got.Entries {
+			balance += e.Amount
+			if e.Balance != balance {
+				t.Errorf("running balance of %+v, want %v", e, balance)
+			}
+		}
+		want := statementResponse{StudentNo: "22070006071", Charges: 2200, Payments: 1000, Credits: 400, Adjustments: -200, ClosingBalance: 600}
+		got.Entries = nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("statement = %+v, want %+v", got, want)
+		}
+		if owed := ta.tuitionTotal("22070006071", "Spring2026"); owed != got.ClosingBalance {
+			t.Errorf("owed = %v, want the closing balance", owed)
+		}
+
+		tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+		rec = ta.do(http.MethodGet, "/api/v2/mobile/statement?student_no=22070006071&from="+tomorrow, token, nil, nil)
+		if got := decodeJSON[statementResponse](t, rec); got.OpeningBalance != 600 || got.ClosingBalance != 600 || len(got.Entries) != 0 {
+			t.Errorf("statement from tomorrow = %+v", got)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/banking/statement?student_no=22070006071&format=pdf", token, nil, nil)
+		pdf := rec.Body.Bytes()
+		if rec.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf, []byte("Tuition cancelled: withdrew")) {
+			t.Errorf("pdf statement: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="statement-22070006071.pdf"` {
+			t.Errorf("Content-Disposition = %q", got)
+		}
+
+		tests := []struct {
+			query string
+			token string
+			code  int
+		}{
+			{"", token, http.StatusBadRequest},
+			{"student_no=22070006071&from=15.10.2025", token, http.StatusBadRequest},
+			{"student_no=22070006071&from=2025-10-15&to=2025-10-14", token, http.StatusBadRequest},
+			{"student_no=22070006071&format=xml", token, http.StatusBadRequest},
+			{"student_no=22070006072", token, http.StatusNotFound},
+			{"student_no=22070006071", "", http.StatusUnauthorized},
+		}
+		for _, tt := range tests {
+			if rec := ta.do(http.MethodGet, "/api/v2/banking/statement?"+tt.query, tt.token, nil, nil); rec.Code != tt.code {
+				t.Errorf("%q: got %d, want %d", tt.query, rec.Code, tt.code)
+			}
+		}
+	})
+}
+
+func TestStatementRange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 10, d, 12, 0, 0, 0, time.UTC) }
+	entries := []statementEntry{
+		{Date: day(1), Type: entryCharge, Amount: 1000},
+		{Date: day(5), Type: entryPayment, Amount: -400},
+		{Date: day(10), Type: entryAdjustment, Amount: 100},
+		{Date: day(20), Type: entryPayment, Amount: -700},
+	}
+	from := pgtype.Timestamptz{Time: time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC), Valid: true}
+	to := pgtype.Timestamptz{Time: time.Date(2025, 10, 11, 0, 0, 0, 0, time.UTC), Valid: true}
+
+	got := statement(entries, from, to)
+	if got.OpeningBalance != 1000 || got.Payments != 400 || got.Adjustments != 100 || got.ClosingBalance != 700 {
+		t.Errorf("statement = %+v", got)
+	}
+	if len(got.Entries) != 2 || got.Entries[0].Balance != 600 || got.Entries[1].Balance != 700 {
+		t.Errorf("entries = %+v", got.Entries)
+	}
+}
@@ -0,0 +1,740 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultPageSize   = 10
+	maxPageSize       = 100
+	minEnrollmentYear = 1900
+)
+
+// enrollmentStatuses is the academic status of a student. It is separate from
+// deactivation, which only controls access to the system.
+var enrollmentStatuses = []string{"enrolled", "on_leave", "graduated", "withdrawn"}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]*$`)
+
+type studentResponse struct {
+	StudentNo         string     `json:"student_no"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	Email             string     `json:"email"`
+	Phone             string     `json:"phone"`
+	Faculty           string     `json:"faculty"`
+	Department        string     `json:"department"`
+	Program           string     `json:"program"`
+	EnrollmentYear    *int32     `json:"enrollment_year"`
+	EnrollmentStatus  string     `json:"enrollment_status"`
+	Balance           float64    `json:"balance"`
+	DailyPaymentLimit int32      `json:"daily_payment_limit"`
+	Active            bool       `json:"active"`
+	DeactivatedAt     *time.Time `json:"deactivated_at,omitempty"`
+}
+
+type paymentResponse struct {
+	PaymentID    int32     `json:"payment_id"`
+	Term         string    `json:"term"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	Channel      string    `json:"channel"`
+	Partner      string    `json:"partner,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newStudentResponse(s db.Student) studentResponse {
+	var enrollmentYear *int32
+	if s.EnrollmentYear.Valid {
+		enrollmentYear = &s.EnrollmentYear.Int32
+	}
+	return studentResponse{
+		StudentNo:         s.StudentNo,
+		FirstName:         s.FirstName,
+		LastName:          s.LastName,
+		Email:             s.Email,
+		Phone:             s.Phone,
+		Faculty:           s.Faculty,
+		Department:        s.Department,
+		Program:           s.Program,
+		EnrollmentYear:    enrollmentYear,
+		EnrollmentStatus:  s.EnrollmentStatus,
+		Balance:           s.Balance,
+		DailyPaymentLimit: s.DailyPaymentLimit,
+		Active:            !s.DeactivatedAt.Valid,
+		DeactivatedAt:     timePtr(s.DeactivatedAt),
+	}
+}
+
+func newPaymentResponse(p db.Payment) paymentResponse {
+	return paymentResponse{
+		PaymentID:    p.PaymentID,
+		Term:         p.Term,
+		Amount:       p.Amount,
+		BalanceAfter: p.BalanceAfter,
+		Channel:      p.Channel,
+		Partner:      p.Partner.String,
+		CreatedAt:    p.CreatedAt.Time,
+	}
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// parsePage reads limit and offset query parameters for the admin list endpoints.
+func parsePage(q url.Values) (limit, offset int32, ok bool) {
+	limit = defaultPageSize
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > maxPageSize {
+			return 0, 0, false
+		}
+		limit = int32(n)
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > math.MaxInt32 {
+			return 0, 0, false
+		}
+		offset = int32(n)
+	}
+	return limit, offset, true
+}
+
+// parseBoolFilter reads an optional true/false query parameter; absent means no filter.
+func parseBoolFilter(q url.Values, key string) (pgtype.Bool, bool) {
+	s := q.Get(key)
+	if s == "" {
+		return pgtype.Bool{}, true
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return pgtype.Bool{}, false
+	}
+	return pgtype.Bool{Bool: b, Valid: true}, true
+}
+
+// studentProfile holds the profile fields of a student update. Only the fields
+// present in the request are changed.
+type studentProfile struct {
+	FirstName        *string `json:"first_name"`
+	LastName         *string `json:"last_name"`
+	Email            *string `json:"email"`
+	Phone            *string `json:"phone"`
+	Faculty          *string `json:"faculty"`
+	Department       *string `json:"department"`
+	Program          *string `json:"program"`
+	EnrollmentYear   *int32  `json:"enrollment_year"`
+	EnrollmentStatus *string `json:"enrollment_status"`
+}
+
+// validate trims the text fields and returns the error body for the first invalid one.
+func (p *studentProfile) validate() string {
+	if _, msg := p.check(); msg != "" {
+		return `{"error":"` + msg + `"}`
+	}
+	return ""
+}
+
+// check trims the text fields and returns the first invalid field with the reason.
+func (p *studentProfile) check() (field, message string) {
+	for _, f := range []struct {
+		name  string
+		value *string
+		max   int
+	}{
+		{"first_name", p.FirstName, nameMaxLength},
+		{"last_name", p.LastName, nameMaxLength},
+		{"email", p.Email, emailMaxLength},
+		{"phone", p.Phone, phoneMaxLength},
+		{"faculty", p.Faculty, nameMaxLength},
+		{"department", p.Department, nameMaxLength},
+		{"program", p.Program, nameMaxLength},
+	} {
+		if f.value == nil {
+			continue
+		}
+		*f.value = strings.TrimSpace(*f.value)
+		if len([]rune(*f.value)) > f.max {
+			return f.name, f.name + " is too long"
+		}
+	}
+	if p.Email != nil && *p.Email != "" {
+		if addr, err := mail.ParseAddress(*p.Email); err != nil || addr.Address != *p.Email {
+			return "email", "email is not a valid address"
+		}
+	}
+	if p.Phone != nil && *p.Phone != "" && !phonePattern.MatchString(*p.Phone) {
+		return "phone", "phone may only contain digits, spaces, parentheses, dashes and a leading +"
+	}
+	if p.EnrollmentYear != nil && (*p.EnrollmentYear < minEnrollmentYear || int(*p.EnrollmentYear) > time.Now().Year()+1) {
+		return "enrollment_year", "enrollment_year is out of range"
+	}
+	if p.EnrollmentStatus != nil && !slices.Contains(enrollmentStatuses, *p.EnrollmentStatus) {
+		return "enrollment_status", "enrollment_status must be one of enrolled, on_leave, graduated, withdrawn"
+	}
+	return "", ""
+}
+
+// apply copies the fields present in p onto params.
+func (p *studentProfile) apply(params *db.UpdateStudentParams) {
+	for _, f := range []struct {
+		src *string
+		dst *string
+	}{
+		{p.FirstName, &params.FirstName},
+		{p.LastName, &params.LastName},
+		{p.Email, &params.Email},
+		{p.Phone, &params.Phone},
+		{p.Faculty, &params.Faculty},
+		{p.Department, &params.Department},
+		{p.Program, &params.Program},
+		{p.EnrollmentStatus, &params.EnrollmentStatus},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+	if p.EnrollmentYear != nil {
+		params.EnrollmentYear = pgtype.Int4{Int32: *p.EnrollmentYear, Valid: true}
+	}
+}
+
+// studentUpdateParams is an update that leaves s as it is.
+func studentUpdateParams(s db.Student) db.UpdateStudentParams {
+	return db.UpdateStudentParams{
+		StudentNo:         s.StudentNo,
+		Balance:           s.Balance,
+		DailyPaymentLimit: s.DailyPaymentLimit,
+		FirstName:         s.FirstName,
+		LastName:          s.LastName,
+		Email:             s.Email,
+		Phone:             s.Phone,
+		Faculty:           s.Faculty,
+		Department:        s.Department,
+		Program:           s.Program,
+		EnrollmentYear:    s.EnrollmentYear,
+		EnrollmentStatus:  s.EnrollmentStatus,
+	}
+}
+
+// studentImporter creates or updates one student per row. An empty cell leaves the
+// field as it is, and balance is only the opening balance of a student created.
+var studentImporter = importer{
+	Fields: []importField{
+		{Name: "student_no", Aliases: []string{"student_number", "student"}, Required: true, Example: "22070006071"},
+		{Name: "first_name", Example: "Ayse"},
+		{Name: "last_name", Example: "Yilmaz"},
+		{Name: "email", Example: "ayse.yilmaz@example.edu"},
+		{Name: "phone", Example: "+90 555 123 45 67"},
+		{Name: "faculty", Example: "Engineering"},
+		{Name: "department", Example: "Computer Engineering"},
+		{Name: "program", Example: "Computer Engineering"},
+		{Name: "enrollment_year", Example: "2022"},
+		{Name: "enrollment_status", Example: "enrolled"},
+		{Name: "balance", Aliases: []string{"initial_balance"}, Example: "1000"},
+		{Name: "daily_payment_limit", Aliases: []string{"daily_limit"}, Example: "3"},
+	},
+	Apply: importStudentRow,
+}
+
+func importStudentRow(ctx context.Context, tx Store, row importRow) (string, error) {
+	studentNo, err := requiredValue(row, "student_no")
+	if err != nil {
+		return "", err
+	}
+	if len([]rune(studentNo)) > studentNoMaxLength {
+		return "", rowError("student_no", "student_no is too long")
+	}
+
+	var profile studentProfile
+	for _, f := range []struct {
+		name string
+		dst  **string
+	}{
+		{"first_name", &profile.FirstName},
+		{"last_name", &profile.LastName},
+		{"email", &profile.Email},
+		{"phone", &profile.Phone},
+		{"faculty", &profile.Faculty},
+		{"department", &profile.Department},
+		{"program", &profile.Program},
+		{"enrollment_status", &profile.EnrollmentStatus},
+	} {
+		if v := row.Values[f.name]; v != "" {
+			*f.dst = &v
+		}
+	}
+	year, ok, err := integerValue(row, "enrollment_year")
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		profile.EnrollmentYear = &year
+	}
+	if field, msg := profile.check(); msg != "" {
+		return "", rowError(field, msg)
+	}
+	balance, _, err := numberValue(row, "balance")
+	if err != nil {
+		return "", err
+	}
+	if balance < 0 {
+		return "", rowError("balance", "balance must not be negative")
+	}
+	limit, hasLimit, err := integerValue(row, "daily_payment_limit")
+	if err != nil {
+		return "", err
+	}
+	if limit < 0 {
+		return "", rowError("daily_payment_limit", "daily_payment_limit must not be negative")
+	}
+
+	outcome := importUpdated
+	current, err := tx.GetStudent(ctx, studentNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.AddNewStudent(ctx, db.AddNewStudentParams{StudentNo: studentNo, Balance: balance})
+		if err != nil {
+			return "", err
+		}
+		current, err = tx.GetStudent(ctx, studentNo)
+		outcome = importCreated
+	}
+	if err != nil {
+		return "", err
+	}
+
+	params := studentUpdateParams(current)
+	profile.apply(&params)
+	if hasLimit {
+		params.DailyPaymentLimit = limit
+	}
+	if outcome == importUpdated && params == studentUpdateParams(current) {
+		return importSkipped, nil
+	}
+	return outcome, tx.UpdateStudent(ctx, params)
+}
+
+// Admin - Import Students from a CSV, XLSX or JSON file, creating or updating them
+func (a *App) importStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	a.handleImport(w, r, "students")
+}
+
+// Admin - Template file for the student import
+func (a *App) studentTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	writeImportTemplate(w, r, "students")
+}
+
+// Admin - List Students
+func (a *App) listStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, offset, ok := parsePage(q)
+	if !ok {
+		http.Error(w, `{"error":"limit must be between 0 and 100 and offset must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	var active pgtype.Bool
+	switch q.Get("status") {
+	case "":
+	case "active":
+		active = pgtype.Bool{Bool: true, Valid: true}
+	case "deactivated":
+		active = pgtype.Bool{Bool: false, Valid: true}
+	default:
+		http.Error(w, `{"error":"status must be active or deactivated"}`, http.StatusBadRequest)
+		return
+	}
+
+	hasUnpaid, ok := parseBoolFilter(q, "has_unpaid")
+	if !ok {
+		http.Error(w, `{"error":"has_unpaid must be true or false"}`, http.StatusBadRequest)
+		return
+	}
+
+	enrollmentStatus := optionalText(q.Get("enrollment_status"))
+	if enrollmentStatus.Valid && !slices.Contains(enrollmentStatuses, enrollmentStatus.String) {
+		http.Error(w, `{"error":"enrollment_status must be one of enrolled, on_leave, graduated, withdrawn"}`, http.StatusBadRequest)
+		return
+	}
+
+	var enrollmentYear pgtype.Int4
+	if s := q.Get("enrollment_year"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			http.Error(w, `{"error":"enrollment_year must be a year"}`, http.StatusBadRequest)
+			return
+		}
+		enrollmentYear = pgtype.Int4{Int32: int32(n), Valid: true}
+	}
+
+	filter := db.CountStudentsParams{
+		Prefix:           q.Get("student_no"),
+		Faculty:          optionalText(q.Get("faculty")),
+		Department:       optionalText(q.Get("department")),
+		Program:          optionalText(q.Get("program")),
+		EnrollmentStatus: enrollmentStatus,
+		EnrollmentYear:   enrollmentYear,
+		Active:           active,
+		HasUnpaid:        hasUnpaid,
+	}
+	students, err := a.Store.ListStudents(r.Context(), db.ListStudentsParams{
+		Prefix:           filter.Prefix,
+		Faculty:          filter.Faculty,
+		Department:       filter.Department,
+		Program:          filter.Program,
+		EnrollmentStatus: filter.EnrollmentStatus,
+		EnrollmentYear:   filter.EnrollmentYear,
+		Active:           filter.Active,
+		HasUnpaid:        filter.HasUnpaid,
+		RowLimit:         limit,
+		RowOffset:        offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Students cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	total, err := a.Store.CountStudents(r.Context(), filter)
+	if err != nil {
+		http.Error(w, `{"error":"Students cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListStudentsResponse struct {
+		Students []studentResponse `json:"students"`
+		Total    int64             `json:"total"`
+		Limit    int32             `json:"limit"`
+		Offset   int32             `json:"offset"`
+	}
+	response := ListStudentsResponse{
+		Students: []studentResponse{},
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, s := range students {
+		response.Students = append(response.Students, newStudentResponse(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Get Student with tuitions and payments
+func (a *App) getStudentHandler(w http.ResponseWriter, r *http.Request) {
+	studentNo := r.PathValue("student_no")
+
+	student, err := a.Store.GetStudent(r.Context(), studentNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Student cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tuitions, err := a.Store.ListTuitionsByStudent(r.Context(), studentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	payments, err := a.Store.ListPaymentsByStudent(r.Context(), studentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Payments cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type StudentDetailResponse struct {
+		studentResponse
+		Tuitions []tuitionResponse `json:"tuitions"`
+		Payments []paymentResponse `json:"payments"`
+	}
+	response := StudentDetailResponse{
+		studentResponse: newStudentResponse(student),
+		Tuitions:        []tuitionResponse{},
+		Payments:        []paymentResponse{},
+	}
+	for _, t := range tuitions {
+		response.Tuitions = append(response.Tuitions, newTuitionResponse(t))
+	}
+	for _, p := range payments {
+		response.Payments = append(response.Payments, newPaymentResponse(p))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Update Student. Only the fields present in the body are changed.
+func (a *App) updateStudentHandler(w http.ResponseWriter, r *http.Request) {
+	studentNo := r.PathValue("student_no")
+
+	type UpdateStudentRequest struct {
+		studentProfile
+		Balance           *float64 `json:"balance"`
+		DailyPaymentLimit *int32   `json:"daily_payment_limit"`
+		Active            *bool    `json:"active"`
+	}
+	var req UpdateStudentRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Balance != nil && *req.Balance < 0 {
+		http.Error(w, `{"error":"balance must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+	if req.DailyPaymentLimit != nil && *req.DailyPaymentLimit < 0 {
+		http.Error(w, `{"error":"daily_payment_limit must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	var student db.Student
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := tx.GetStudent(r.Context(), studentNo)
+		if err != nil {
+			return err
+		}
+
+		params := studentUpdateParams(current)
+		req.apply(&params)
+		if req.Balance != nil {
+			params.Balance = *req.Balance
+		}
+		if req.DailyPaymentLimit != nil {
+			params.DailyPaymentLimit = *req.DailyPaymentLimit
+		}
+		if err := tx.UpdateStudent(r.Context(), params); err != nil {
+			return err
+		}
+
+		if req.Active != nil && *req.Active {
+			err = tx.ReactivateStudent(r.Context(), studentNo)
+		} else if req.Active != nil {
+			err = tx.DeactivateStudent(r.Context(), studentNo)
+		}
+		if err != nil {
+			return err
+		}
+
+		student, err = tx.GetStudent(r.Context(), studentNo)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Student cannot be updated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newStudentResponse(student))
+}
+
+// Admin - Deactivate Student. Records are kept; the student can no longer log in or pay.
+func (a *App) deactivateStudentHandler(w http.ResponseWriter, r *http.Request) {
+	studentNo := r.PathValue("student_no")
+
+	var student db.Student
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		if err := tx.DeactivateStudent(r.Context(), studentNo); err != nil {
+			return err
+		}
+		var err error
+		student, err = tx.GetStudent(r.Context(), studentNo)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Student cannot be deactivated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newStudentResponse(student))
+}
+
+// Student - Own profile with balance and outstanding tuitions
+func (a *App) meHandler(w http.ResponseWriter, r *http.Request) {
+	studentNo, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+
+	student, err := a.Store.GetStudent(r.Context(), studentNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Student cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	tuitions, err := a.Store.ListTuitionsByStudent(r.Context(), studentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type MeResponse struct {
+		studentResponse
+		OutstandingTuitions []tuitionResponse `json:"outstanding_tuitions"`
+		TotalOutstanding    float64           `json:"total_outstanding"`
+	}
+	response := MeResponse{
+		studentResponse:     newStudentResponse(student),
+		OutstandingTuitions: []tuitionResponse{},
+	}
+	for _, t := range tuitions {
+		if t.Status != "active" || t.TuitionTotal <= 0 {
+			continue
+		}
+		response.OutstandingTuitions = append(response.OutstandingTuitions, newTuitionResponse(t))
+		response.TotalOutstanding += t.TuitionTotal
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Statuses of a student's tuition for a term. A tuition past its term's payment
+// due date with anything still owed is overdue, however much was paid.
+var termStatuses = []string{"paid", "partially_paid", "unpaid", "overdue", "cancelled"}
+
+type studentTermResponse struct {
+	TuitionID      int32       `json:"tuition_id"`
+	Term           string      `json:"term"`
+	TermName       string      `json:"term_name"`
+	PaymentDueDate pgtype.Date `json:"payment_due_date"`
+	Billed         float64     `json:"billed"`
+	Paid           float64     `json:"paid"`
+	Outstanding    float64     `json:"outstanding"`
+	Status         string      `json:"status"`
+	// Quoted on bank transfers; cancelled tuitions have none
+	PaymentReference string `json:"payment_reference,omitempty"`
+}
+
+func termStatus(t db.Tuition, due pgtype.Date, today time.Time) string {
+	switch {
+	case t.Status == "cancelled":
+		return "cancelled"
+	case t.TuitionTotal <= 0:
+		return "paid"
+	case due.Valid && due.Time.Before(today):
+		return "overdue"
+	case tuitionPaid(t) > 0:
+		return "partially_paid"
+	}
+	return "unpaid"
+}
+
+// Mobile / Banking - Every term of the signed-in student with what was billed,
+// paid and is outstanding, in calendar order. Totals leave cancelled tuitions out.
+func (a *App) studentTermsHandler(w http.ResponseWriter, r *http.Request) {
+	studentNo, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(termStatuses, status) {
+		http.Error(w, `{"error":"status must be paid, partially_paid, unpaid, overdue or cancelled"}`, http.StatusBadRequest)
+		return
+	}
+
+	student, err := a.Store.GetStudent(r.Context(), studentNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Student not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Student cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	tuitions, err := a.Store.ListTuitionsByStudent(r.Context(), studentNo)
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	terms, err := a.Store.ListTerms(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type StudentTermsResponse struct {
+		StudentNo        string                `json:"student_no"`
+		CreditBalance    float64               `json:"credit_balance"`
+		Terms            []studentTermResponse `json:"terms"`
+		TotalBilled      float64               `json:"total_billed"`
+		TotalPaid        float64               `json:"total_paid"`
+		TotalOutstanding float64               `json:"total_outstanding"`
+	}
+	response := StudentTermsResponse{
+		StudentNo:     student.StudentNo,
+		CreditBalance: student.Balance,
+		Terms:         []studentTermResponse{},
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, term := range terms {
+		for _, t := range tuitions {
+			if t.Term != term.Code {
+				continue
+			}
+			termResponse := studentTermResponse{
+				TuitionID:      t.TuitionID,
+				Term:           t.Term,
+				TermName:       term.Name,
+				PaymentDueDate: term.PaymentDueDate,
+				Billed:         t.BilledTotal,
+				Paid:           tuitionPaid(t),
+				Outstanding:    t.TuitionTotal,
+				Status:         termStatus(t, term.PaymentDueDate, today),
+			}
+			if status != "" && termResponse.Status != status {
+				continue
+			}
+			if termResponse.Status != "cancelled" {
+				termResponse.PaymentReference, err = paymentReference(r.Context(), a.Store, t.StudentNo, t.Term)
+				if err != nil {
+					http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+					return
+				}
+			}
+			response.Terms = append(response.Terms, termResponse)
+			if termResponse.Status != "cancelled" {
+				response.TotalBilled += termResponse.Billed
+				response.TotalPaid += termResponse.Paid
+				response.TotalOutstanding += termResponse.Outstanding
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
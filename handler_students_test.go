@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dogukan-dev/tuition/db"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type studentList struct {
+	Students []studentResponse `json:"students"`
+	Total    int64             `json:"total"`
+}
+
+func TestListStudents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		for _, no := range []string{"22070006071", "22070006072", "22070006073", "21070006001"} {
+			ta.addStudent(no, 100)
+		}
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006072", "Fall2025", 50)
+		ta.pay(token, "22070006072", "Fall2025", "1")
+		ta.do(http.MethodDelete, "/api/v2/admin/students/22070006073", token, nil, nil)
+
+		tests := []struct {
+			query string
+			want  []string
+			total int64
+		}{
+			{"", []string{"21070006001", "22070006071", "22070006072", "22070006073"}, 4},
+			{"student_no=2207", []string{"22070006071", "22070006072", "22070006073"}, 3},
+			{"student_no=2207&limit=2", []string{"22070006071", "22070006072"}, 3},
+			{"student_no=2207&limit=2&offset=2", []string{"22070006073"}, 3},
+			{"status=active", []string{"21070006001", "22070006071", "22070006072"}, 3},
+			{"status=deactivated", []string{"22070006073"}, 1},
+			{"has_unpaid=true", []string{"22070006071"}, 1},
+			{"has_unpaid=false&status=active", []string{"21070006001", "22070006072"}, 2},
+			{"student_no=9", nil, 0},
+		}
+		for _, tt := range tests {
+			rec := ta.do(http.MethodGet, "/api/v2/admin/students?"+tt.query, token, nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%q: %d %s", tt.query, rec.Code, rec.Body)
+			}
+			got := decodeJSON[studentList](t, rec)
+			var nos []string
+			for _, s := range got.Students {
+				nos = append(nos, s.StudentNo)
+			}
+			if len(nos) != len(tt.want) || got.Total != tt.total {
+				t.Errorf("%q: got %v (total %d), want %v (total %d)", tt.query, nos, got.Total, tt.want, tt.total)
+				continue
+			}
+			for i := range nos {
+				if nos[i] != tt.want[i] {
+					t.Errorf("%q: got %v, want %v", tt.query, nos, tt.want)
+					break
+				}
+			}
+		}
+
+		for _, query := range []string{"limit=101", "offset=-1", "status=gone", "has_unpaid=maybe"} {
+			if rec := ta.do(http.MethodGet, "/api/v2/admin/students?"+query, token, nil, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", query, rec.Code)
+			}
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/students", "", nil, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("without token: got %d, want 401", rec.Code)
+		}
+	})
+}
+
+func TestGetStudent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 500)
+		ta.pay(token, "22070006071", "Fall2025", "300")
+		ta.pay(token, "22070006071", "Fall2025", "600")
+
+		rec := ta.do(http.MethodGet, "/api/v2/admin/students/22070006071", token, nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		got := decodeJSON[struct {
+			studentResponse
+			Tuitions []tuitionResponse `json:"tuitions"`
+			Payments []paymentResponse `json:"payments"`
+		}](t, rec)
+
+		if got.StudentNo != "22070006071" || got.Balance != 0 || !got.Active {
+			t.Errorf("unexpected student %+v", got.studentResponse)
+		}
+		if len(got.Tuitions) != 2 || got.Tuitions[0].Term != "Fall2025" || got.Tuitions[1].TuitionTotal != 500 {
+			t.Errorf("unexpected tuitions %+v", got.Tuitions)
+		}
+		if len(got.Payments) != 2 {
+			t.Fatalf("got %d payments, want 2", len(got.Payments))
+		}
+		if p := got.Payments[0]; p.Amount != 300 || p.BalanceAfter != 400 || p.CreatedAt.IsZero() {
+			t.Errorf("unexpected first payment %+v", p)
+		}
+		if p := got.Payments[1]; p.Amount != 600 || p.BalanceAfter != 0 {
+			t.Errorf("unexpected second payment %+v", p)
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/students/22070009999", token, nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown student: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestUpdateStudent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 100)
+
+		patch := func(body string) *http.Response {
+			rec := ta.do(http.MethodPatch, "/api/v2/admin/students/22070006071", token, bytes.NewBufferString(body), nil)
+			return rec.Result()
+		}
+
+		res := patch(`{"balance": 250.5}`)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got %d", res.StatusCode)
+		}
+		if got := ta.balance("22070006071"); got != 250.5 {
+			t.Errorf("balance = %v, want 250.5", got)
+		}
+
+		res = patch(`{"daily_payment_limit": 7}`)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got %d", res.StatusCode)
+		}
+		rec := ta.do(http.MethodGet, "/api/v2/admin/students/22070006071", token, nil, nil)
+		got := decodeJSON[studentResponse](t, rec)
+		if got.Balance != 250.5 || got.DailyPaymentLimit != 7 {
+			t.Errorf("partial update changed other fields: %+v", got)
+		}
+
+		for _, body := range []string{`{"balance": -1}`, `{"daily_payment_limit": -1}`, `{"balanse": 1}`, `{`} {
+			if res := patch(body); res.StatusCode != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, res.StatusCode)
+			}
+		}
+
+		rec = ta.do(http.MethodPatch, "/api/v2/admin/students/22070009999", token, bytes.NewBufferString(`{"balance": 1}`), nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("unknown student: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestUpdateStudentProfile(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 100)
+		ta.addStudent("22070006072", 100)
+
+		patch := func(studentNo, body string) *httptest.ResponseRecorder {
+			return ta.do(http.MethodPatch, "/api/v2/admin/students/"+studentNo, token, bytes.NewBufferString(body), nil)
+		}
+
+		rec := ta.do(http.MethodGet, "/api/v2/admin/students/22070006071", token, nil, nil)
+		if got := decodeJSON[studentResponse](t, rec); got.EnrollmentStatus != "enrolled" || got.EnrollmentYear != nil || got.FirstName != "" {
+			t.Errorf("unexpected default profile %+v", got)
+		}
+
+		rec = patch("22070006071", `{"first_name": " Ayşe ", "last_name": "Yılmaz", "email": "ayse@example.edu", "phone": "+90 (532) 111-2233",
+			"faculty": "Engineering", "department": "Computer Engineering", "program": "BSc", "enrollment_year": 2022}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		got := decodeJSON[studentResponse](t, rec)
+		if got.FirstName != "Ayşe" || got.Email != "ayse@example.edu" || got.Department != "Computer Engineering" ||
+			got.EnrollmentYear == nil || *got.EnrollmentYear != 2022 || got.Balance != 100 {
+			t.Errorf("unexpected profile %+v", got)
+		}
+
+		// Profile updates leave the other fields alone
+		rec = patch("22070006071", `{"enrollment_status": "on_leave", "balance": 5}`)
+		got = decodeJSON[studentResponse](t, rec)
+		if got.EnrollmentStatus != "on_leave" || got.Balance != 5 || got.LastName != "Yılmaz" || got.Program != "BSc" {
+			t.Errorf("unexpected profile after partial update %+v", got)
+		}
+		patch("22070006072", `{"faculty": "Law", "program": "LLB", "enrollment_year": 2023}`)
+
+		for _, body := range []string{
+			`{"email": "not an email"}`,
+			`{"email": "Ayşe <ayse@example.edu>"}`,
+			`{"phone": "call me"}`,
+			`{"enrollment_year": 1850}`,
+			`{"enrollment_status": "expelled"}`,
+			`{"first_name": "` + strings.Repeat("a", 101) + `"}`,
+		} {
+			if rec := patch("22070006071", body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+
+		tests := []struct {
+			query string
+			total int64
+		}{
+			{"faculty=Engineering", 1},
+			{"faculty=Law&program=LLB", 1},
+			{"department=Computer%20Engineering", 1},
+			{"enrollment_year=2023", 1},
+			{"enrollment_status=on_leave", 1},
+			{"enrollment_status=enrolled", 1},
+			{"faculty=Medicine", 0},
+		}
+		for _, tt := range tests {
+			rec := ta.do(http.MethodGet, "/api/v2/admin/students?"+tt.query, token, nil, nil)
+			if got := decodeJSON[studentList](t, rec); got.Total != tt.total || len(got.Students) != int(tt.total) {
+				t.Errorf("%q: got %d students (total %d), want %d", tt.query, len(got.Students), got.Total, tt.total)
+			}
+		}
+		for _, query := range []string{"enrollment_status=expelled", "enrollment_year=soon"} {
+			if rec := ta.do(http.MethodGet, "/api/v2/admin/students?"+query, token, nil, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestMe(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 400)
+		ta.addTuition("22070006071", "Fall2026", 700)
+		token := ta.register("22070006071", "pw")
+		ta.pay(token, "22070006071", "Spring2026", "300")
+		ta.cancel(ta.tuitionID("22070006071", "Fall2026"), `{"reason":"withdrew"}`)
+		ta.do(http.MethodPatch, "/api/v2/admin/students/22070006071", adminToken(t), bytes.NewBufferString(`{"first_name": "Ayşe", "faculty": "Engineering"}`), nil)
+
+		rec := ta.do(http.MethodGet, "/api/v2/me", token, nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		got := decodeJSON[struct {
+			studentResponse
+			OutstandingTuitions []tuitionResponse `json:"outstanding_tuitions"`
+			TotalOutstanding    float64           `json:"total_outstanding"`
+		}](t, rec)
+		if got.StudentNo != "22070006071" || got.FirstName != "Ayşe" || got.Faculty != "Engineering" || got.Balance != 0 {
+			t.Errorf("unexpected profile %+v", got.studentResponse)
+		}
+		if len(got.OutstandingTuitions) != 1 || got.OutstandingTuitions[0].Term != "Fall2025" || got.TotalOutstanding != 1000 {
+			t.Errorf("unexpected outstanding tuitions %+v (total %v)", got.OutstandingTuitions, got.TotalOutstanding)
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/me", "", nil, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("without token: got %d, want 401", rec.Code)
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/me", adminToken(t), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("token without a student: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestStudentTerms(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		date := func(s string) pgtype.Date {
+			d, _ := time.Parse(time.DateOnly, s)
+			return pgtype.Date{Time: d, Valid: true}
+		}
+		for _, term := range []db.CreateTermParams{
+			{Code: "Fall2025", Name: "Fall 2025", StartDate: date("2025-09-01"), PaymentDueDate: date("2025-10-15")},
+			{Code: "Spring2026", Name: "Spring 2026", StartDate: date("2026-02-01"), PaymentDueDate: date("2099-03-15")},
+			{Code: "Fall2026", Name: "Fall 2026", StartDate: date("2026-09-01")},
+			{Code: "Spring2027", Name: "Spring 2027", StartDate: date("2027-02-01")},
+		} {
+			if _, err := ta.app.Store.CreateTerm(context.Background(), term); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ta.addStudent("22070006071", 10)
+		token := ta.register("22070006071", "pw")
+		ta.addTuition("22070006071", "Summer2026", 300)
+		ta.pay(token, "22070006071", "Summer2026", "300")
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.createTuition(`{"student_no": "22070006071", "term": "Spring2026", "items": [
+			{"fee_type": "tuition", "amount": 1000},
+			{"fee_type": "lab", "amount": 50}
+		]}`)
+		ta.pay(token, "22070006071", "Spring2026", "1000")
+		ta.addTuition("22070006071", "Fall2026", 700)
+		ta.cancel(ta.tuitionID("22070006071", "Fall2026"), `{"reason":"withdrew"}`)
+		ta.addTuition("22070006071", "Spring2027", 200)
+
+		type termsResponse struct {
+			StudentNo        string                `json:"student_no"`
+			CreditBalance    float64               `json:"credit_balance"`
+			Terms            []studentTermResponse `json:"terms"`
+			TotalBilled      float64               `json:"total_billed"`
+			TotalPaid        float64               `json:"total_paid"`
+			TotalOutstanding float64               `json:"total_outstanding"`
+		}
+		rec := ta.do(http.MethodGet, "/api/v2/banking/tuitions", token, nil, nil)
+		got := decodeJSON[termsResponse](t, rec)
+		var statuses []string
+		for _, term := range got.Terms {
+			statuses = append(statuses, term.Term+" "+term.Status)
+		}
+		// Terms in calendar order, those without dates last
+		want := []string{"Fall2025 overdue", "Spring2026 partially_paid", "Fall2026 cancelled", "Spring2027 unpaid", "Summer2026 paid"}
+		if !slices.Equal(statuses, want) {
+			t.Errorf("terms = %q, want %q", statuses, want)
+		}
+		if got.StudentNo != "22070006071" || got.CreditBalance != 10 || got.TotalBilled != 2550 || got.TotalPaid != 1300 || got.TotalOutstanding != 1250 {
+			t.Errorf("totals = %+v", got)
+		}
+		if spring := got.Terms[1]; spring.TermName != "Spring 2026" || spring.PaymentDueDate != date("2099-03-15") || spring.Billed != 1050 || spring.Paid != 1000 || spring.Outstanding != 50 {
+			t.Errorf("spring = %+v", spring)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/mobile/tuitions?status=overdue", token, nil, nil)
+		if got := decodeJSON[termsResponse](t, rec); len(got.Terms) != 1 || got.Terms[0].Term != "Fall2025" || got.TotalOutstanding != 1000 {
+			t.Errorf("overdue terms = %+v", got)
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/banking/tuitions?status=late", token, nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown status: got %d, want 400", rec.Code)
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/banking/tuitions", adminToken(t), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("token without a student: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestDeactivateStudent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 100)
+		ta.addStudent("22070006072", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.register("22070006071", "pw")
+
+		rec := ta.do(http.MethodDelete, "/api/v2/admin/students/22070006071", token, nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		if got := decodeJSON[studentResponse](t, rec); got.Active || got.DeactivatedAt == nil {
+			t.Errorf("student still active: %+v", got)
+		}
+
+		// Deactivating again is a no-op
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/students/22070006071", token, nil, nil); rec.Code != http.StatusOK {
+			t.Errorf("second deactivation: got %d", rec.Code)
+		}
+
+		if rec := ta.postJSON("/api/v2/login", map[string]string{"student_no": "22070006071", "password": "pw"}); rec.Code != http.StatusForbidden {
+			t.Errorf("login: got %d, want 403", rec.Code)
+		}
+		if rec := ta.pay(token, "22070006071", "Fall2025", "100"); rec.Code != http.StatusForbidden {
+			t.Errorf("pay: got %d, want 403", rec.Code)
+		}
+		if got := ta.balance("22070006071"); got != 100 {
+			t.Errorf("balance changed to %v", got)
+		}
+
+		ta.do(http.MethodDelete, "/api/v2/admin/students/22070006072", token, nil, nil)
+		if rec := ta.postJSON("/api/v2/register", map[string]string{"student_no": "22070006072", "password": "pw"}); rec.Code != http.StatusForbidden {
+			t.Errorf("register: got %d, want 403", rec.Code)
+		}
+
+		// Reactivation lifts the block
+		rec = ta.do(http.MethodPatch, "/api/v2/admin/students/22070006071", token, bytes.NewBufferString(`{"active": true}`), nil)
+		if got := decodeJSON[studentResponse](t, rec); !got.Active || got.DeactivatedAt != nil {
+			t.Errorf("student not reactivated: %+v", got)
+		}
+		if rec := ta.postJSON("/api/v2/login", map[string]string{"student_no": "22070006071", "password": "pw"}); rec.Code != http.StatusOK {
+			t.Errorf("login after reactivation: got %d", rec.Code)
+		}
+		if rec := ta.pay(token, "22070006071", "Fall2025", "100"); rec.Code != http.StatusOK {
+			t.Errorf("pay after reactivation: got %d", rec.Code)
+		}
+
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/students/22070009999", token, nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown student: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestImportStudents(t *testing.T) {
+	// Line 3 changes nothing, line 5 has a bad email and line 6 a negative balance
+	const content = "student_no,first_name,last_name,email,program,enrollment_year,balance,daily_limit\n" +
+		"22070006071,Ayse,Yilmaz,ayse@example.edu,Computer Engineering,2022,1000,5\n" +
+		"22070006072,,,,,,,\n" +
+		"22070006073,Mehmet,,,,,,\n" +
+		"22070006074,Can,,not-an-email,,,,\n" +
+		"22070006075,Ece,,,,,-5,\n"
+
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006072", 10)
+		ta.addStudent("22070006073", 10)
+
+		body, header := multipartFile(t, "file", "students.csv", content)
+		rec := ta.do(http.MethodPost, "/api/v2/admin/students/import?mode=skip_invalid", adminToken(t), body, header)
+		got := decodeJSON[importSummary](t, rec)
+		if rec.Code != http.StatusOK || got.Created != 1 || got.Updated != 1 || got.Skipped != 1 || got.Imported != 2 || got.Invalid != 2 {
+			t.Fatalf("import: %d %+v", rec.Code, got)
+		}
+		if got.Errors[0].Line != 5 || got.Errors[0].Field != "email" || got.Errors[1].Field != "balance" {
+			t.Errorf("unexpected errors %+v", got.Errors)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/students/22070006071", adminToken(t), nil, nil)
+		created := decodeJSON[studentResponse](t, rec)
+		if created.FirstName != "Ayse" || created.Program != "Computer Engineering" || created.EnrollmentYear == nil || *created.EnrollmentYear != 2022 ||
+			created.Balance != 1000 || created.DailyPaymentLimit != 5 {
+			t.Errorf("unexpected created student %+v", created)
+		}
+		rec = ta.do(http.MethodGet, "/api/v2/admin/students/22070006073", adminToken(t), nil, nil)
+		if updated := decodeJSON[studentResponse](t, rec); updated.FirstName != "Mehmet" || updated.Balance != 10 {
+			t.Errorf("unexpected updated student %+v", updated)
+		}
+
+		// Importing the same file again only skips
+		body, header = multipartFile(t, "file", "students.csv", content)
+		rec = ta.do(http.MethodPost, "/api/v2/admin/students/import?mode=skip_invalid", adminToken(t), body, header)
+		if got := decodeJSON[importSummary](t, rec); got.Created != 0 || got.Updated != 0 || got.Skipped != 3 {
+			t.Errorf("second import: %+v", got)
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/students/import/template?format=xlsx", adminToken(t), nil, nil)
+		body, header = multipartFile(t, "file", "students.xlsx", rec.Body.String())
+		rec = ta.do(http.MethodPost, "/api/v2/admin/students/import?dry_run=true", adminToken(t), body, header)
+		if got := decodeJSON[importSummary](t, rec); got.Invalid != 0 || got.Updated != 1 {
+			t.Errorf("template import: %d %+v", rec.Code, got)
+		}
+	})
+}
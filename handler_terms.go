@@ -0,0 +1,274 @@
+package main
+
+import (
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Term codes are what students and banks type, e.g. Fall2025
+var termCodePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+var errTermNotFound = errors.New("term does not exist")
+
+type termResponse struct {
+	Code           string      `json:"code"`
+	Name           string      `json:"name"`
+	StartDate      pgtype.Date `json:"start_date"`
+	EndDate        pgtype.Date `json:"end_date"`
+	PaymentDueDate pgtype.Date `json:"payment_due_date"`
+	Active         bool        `json:"active"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+func newTermResponse(t db.Term) termResponse {
+	return termResponse{
+		Code:           t.Code,
+		Name:           t.Name,
+		StartDate:      t.StartDate,
+		EndDate:        t.EndDate,
+		PaymentDueDate: t.PaymentDueDate,
+		Active:         t.Active,
+		CreatedAt:      t.CreatedAt.Time,
+	}
+}
+
+// validateTerm checks the fields shared by create and update and returns the
+// error body for the first invalid one.
+func validateTerm(name string, start, end pgtype.Date) string {
+	if name == "" || len([]rune(name)) > nameMaxLength {
+		return `{"error":"name must be between 1 and 100 characters"}`
+	}
+	if start.Valid && end.Valid && end.Time.Before(start.Time) {
+		return `{"error":"end_date must not be before start_date"}`
+	}
+	return ""
+}
+
+// activeTerm resolves the term a student-facing request refers to: the given
+// code, or the active term when it is omitted.
+func (a *App) activeTerm(r *http.Request, code string) (string, error) {
+	if code != "" {
+		return code, nil
+	}
+	term, err := a.Store.GetActiveTerm(r.Context())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", errTermNotFound
+	}
+	return term.Code, err
+}
+
+// Admin - List Terms in calendar order
+func (a *App) listTermsHandler(w http.ResponseWriter, r *http.Request) {
+	terms, err := a.Store.ListTerms(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"Terms cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListTermsResponse struct {
+		Terms []termResponse `json:"terms"`
+	}
+	response := ListTermsResponse{Terms: []termResponse{}}
+	for _, t := range terms {
+		response.Terms = append(response.Terms, newTermResponse(t))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Create Term. Making it active deactivates the previous active term.
+func (a *App) createTermHandler(w http.ResponseWriter, r *http.Request) {
+	type CreateTermRequest struct {
+		Code           string      `json:"code"`
+		Name           string      `json:"name"`
+		StartDate      pgtype.Date `json:"start_date"`
+		EndDate        pgtype.Date `json:"end_date"`
+		PaymentDueDate pgtype.Date `json:"payment_due_date"`
+		Active         bool        `json:"active"`
+	}
+	var req CreateTermRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json; dates are YYYY-MM-DD"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Code = strings.TrimSpace(req.Code)
+	if len(req.Code) > termMaxLength || !termCodePattern.MatchString(req.Code) {
+		http.Error(w, `{"error":"code must be at most 50 letters, digits, dashes or underscores"}`, http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = req.Code
+	}
+	if msg := validateTerm(req.Name, req.StartDate, req.EndDate); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	var term db.Term
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		if req.Active {
+			if err := tx.ClearActiveTerm(r.Context()); err != nil {
+				return err
+			}
+		}
+		var err error
+		term, err = tx.CreateTerm(r.Context(), db.CreateTermParams{
+			Code:           req.Code,
+			Name:           req.Name,
+			StartDate:      req.StartDate,
+			EndDate:        req.EndDate,
+			PaymentDueDate: req.PaymentDueDate,
+			Active:         req.Active,
+		})
+		return err
+	})
+	if isConstraintError(err, pgUniqueViolation) {
+		http.Error(w, `{"error":"A term with this code already exists"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Term cannot be created"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newTermResponse(term))
+}
+
+// Admin - Get Term
+func (a *App) getTermHandler(w http.ResponseWriter, r *http.Request) {
+	term, err := a.Store.GetTerm(r.Context(), r.PathValue("code"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Term not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Term cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTermResponse(term))
+}
+
+// Admin - Update Term. Only the fields present in the body are changed; the code
+// is fixed because tuitions and payments refer to it.
+func (a *App) updateTermHandler(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	type UpdateTermRequest struct {
+		Name           *string      `json:"name"`
+		StartDate      *pgtype.Date `json:"start_date"`
+		EndDate        *pgtype.Date `json:"end_date"`
+		PaymentDueDate *pgtype.Date `json:"payment_due_date"`
+		Active         *bool        `json:"active"`
+	}
+	var req UpdateTermRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json; dates are YYYY-MM-DD"}`, http.StatusBadRequest)
+		return
+	}
+
+	var term db.Term
+	var invalid string
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := tx.GetTerm(r.Context(), code)
+		if err != nil {
+			return err
+		}
+
+		params := db.UpdateTermParams{
+			Code:           code,
+			Name:           current.Name,
+			StartDate:      current.StartDate,
+			EndDate:        current.EndDate,
+			PaymentDueDate: current.PaymentDueDate,
+			Active:         current.Active,
+		}
+		if req.Name != nil {
+			params.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.StartDate != nil {
+			params.StartDate = *req.StartDate
+		}
+		if req.EndDate != nil {
+			params.EndDate = *req.EndDate
+		}
+		if req.PaymentDueDate != nil {
+			params.PaymentDueDate = *req.PaymentDueDate
+		}
+		if req.Active != nil {
+			params.Active = *req.Active
+		}
+		// Dates are checked against the stored ones, so this happens in the transaction
+		if invalid = validateTerm(params.Name, params.StartDate, params.EndDate); invalid != "" {
+			return errors.New(invalid)
+		}
+
+		if params.Active && !current.Active {
+			if err := tx.ClearActiveTerm(r.Context()); err != nil {
+				return err
+			}
+		}
+		if err := tx.UpdateTerm(r.Context(), params); err != nil {
+			return err
+		}
+		term, err = tx.GetTerm(r.Context(), code)
+		return err
+	})
+	switch {
+	case invalid != "":
+		http.Error(w, invalid, http.StatusBadRequest)
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"Term not found"}`, http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Term cannot be updated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTermResponse(term))
+}
+
+// Admin - Delete Term. Only terms no tuition was ever billed for can be deleted.
+func (a *App) deleteTermHandler(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		if _, err := tx.GetTerm(r.Context(), code); err != nil {
+			return err
+		}
+		return tx.DeleteTerm(r.Context(), code)
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"Term not found"}`, http.StatusNotFound)
+		return
+	case isConstraintError(err, pgForeignKeyViolation):
+		http.Error(w, `{"error":"Tuitions or fee schedules use this term; it cannot be deleted"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Term cannot be deleted"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func (ta *testApp) createTerm(body string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	return ta.do(http.MethodPost, "/api/v2/admin/terms", adminToken(ta.t), bytes.NewBufferString(body), nil)
+}
+
+func TestCreateTerm(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		rec := ta.createTerm(`{"code": "Fall2025", "name": "Fall 2025", "start_date": "2025-09-15", "end_date": "2026-01-20", "payment_due_date": "2025-10-01"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		got := decodeJSON[termResponse](t, rec)
+		if got.Code != "Fall2025" || got.Name != "Fall 2025" || got.Active || got.PaymentDueDate.Time.Format("2006-01-02") != "2025-10-01" {
+			t.Errorf("unexpected term %+v", got)
+		}
+
+		// The name defaults to the code and dates are optional
+		rec = ta.createTerm(`{"code": "Summer2025"}`)
+		if got := decodeJSON[termResponse](t, rec); rec.Code != http.StatusCreated || got.Name != "Summer2025" || got.StartDate.Valid {
+			t.Errorf("minimal term: %d %+v", rec.Code, got)
+		}
+
+		if rec := ta.createTerm(`{"code": "Fall2025"}`); rec.Code != http.StatusConflict {
+			t.Errorf("duplicate code: got %d, want 409", rec.Code)
+		}
+		for _, body := range []string{
+			`{}`,
+			`{"code": "Fall 2025"}`,
+			`{"code": "Fall2026", "start_date": "15/09/2026"}`,
+			`{"code": "Fall2026", "start_date": "2026-09-15", "end_date": "2026-01-20"}`,
+			`{"code": "Fall2026", "activ": true}`,
+		} {
+			if rec := ta.createTerm(body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+
+		rec = ta.do(http.MethodGet, "/api/v2/admin/terms", adminToken(t), nil, nil)
+		list := decodeJSON[struct {
+			Terms []termResponse `json:"terms"`
+		}](t, rec)
+		if len(list.Terms) != 2 || list.Terms[0].Code != "Fall2025" || list.Terms[1].Code != "Summer2025" {
+			t.Errorf("unexpected terms %+v", list.Terms)
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/terms/Fall2025", adminToken(t), nil, nil); rec.Code != http.StatusOK {
+			t.Errorf("get: got %d", rec.Code)
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/terms/Fall2099", adminToken(t), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown term: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestActiveTerm(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.createTerm(`{"code": "Fall2025", "active": true}`)
+		ta.createTerm(`{"code": "Spring2026"}`)
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 2000)
+		own := ta.register("22070006071", "pw")
+
+		query := func() (string, float64) {
+			rec := ta.do(http.MethodGet, "/api/v2/banking/tuition?student_no=22070006071", own, nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("query: %d %s", rec.Code, rec.Body)
+			}
+			got := decodeJSON[struct {
+				Term         string
+				TuitionTotal float64
+			}](t, rec)
+			return got.Term, got.TuitionTotal
+		}
+		if term, total := query(); term != "Fall2025" || total != 1000 {
+			t.Errorf("default term: got %s %v, want Fall2025 1000", term, total)
+		}
+
+		// Activating another term deactivates the previous one
+		rec := ta.do(http.MethodPatch, "/api/v2/admin/terms/Spring2026", token, bytes.NewBufferString(`{"active": true, "payment_due_date": "2026-03-01"}`), nil)
+		if got := decodeJSON[termResponse](t, rec); rec.Code != http.StatusOK || !got.Active || !got.PaymentDueDate.Valid {
+			t.Fatalf("activate: %d %+v", rec.Code, got)
+		}
+		if term, total := query(); term != "Spring2026" || total != 2000 {
+			t.Errorf("default term: got %s %v, want Spring2026 2000", term, total)
+		}
+		rec = ta.do(http.MethodGet, "/api/v2/admin/terms/Fall2025", token, nil, nil)
+		if got := decodeJSON[termResponse](t, rec); got.Active {
+			t.Errorf("previous term still active")
+		}
+
+		// An explicit active_term still wins
+		rec = ta.do(http.MethodGet, "/api/v2/banking/tuition?student_no=22070006071&active_term=Fall2025", own, nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("explicit term: got %d", rec.Code)
+		}
+
+		ta.do(http.MethodPatch, "/api/v2/admin/terms/Spring2026", token, bytes.NewBufferString(`{"active": false}`), nil)
+		if rec := ta.do(http.MethodGet, "/api/v2/banking/tuition?student_no=22070006071", own, nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("no active term: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestUpdateAndDeleteTerm(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.createTerm(`{"code": "Fall2025", "start_date": "2025-09-15", "end_date": "2026-01-20"}`)
+		ta.createTerm(`{"code": "Spring2026"}`)
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+
+		patch := func(code, body string) *httptest.ResponseRecorder {
+			return ta.do(http.MethodPatch, "/api/v2/admin/terms/"+code, token, bytes.NewBufferString(body), nil)
+		}
+		rec := patch("Fall2025", `{"name": "Fall semester 2025"}`)
+		if got := decodeJSON[termResponse](t, rec); got.Name != "Fall semester 2025" || got.StartDate.Time.Format("2006-01-02") != "2025-09-15" {
+			t.Errorf("unexpected term %+v", got)
+		}
+		// The end date is checked against the stored start date
+		if rec := patch("Fall2025", `{"end_date": "2025-01-01"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("end before start: got %d, want 400", rec.Code)
+		}
+		for _, body := range []string{`{"name": ""}`, `{"code": "Fall2024"}`} {
+			if rec := patch("Fall2025", body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+		if rec := patch("Fall2099", `{"name": "x"}`); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown term: got %d, want 404", rec.Code)
+		}
+
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/terms/Fall2025", token, nil, nil); rec.Code != http.StatusConflict {
+			t.Errorf("delete billed term: got %d, want 409", rec.Code)
+		}
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/terms/Spring2026", token, nil, nil); rec.Code != http.StatusNoContent {
+			t.Errorf("delete: got %d, want 204", rec.Code)
+		}
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/terms/Spring2026", token, nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("delete twice: got %d, want 404", rec.Code)
+		}
+	})
+}
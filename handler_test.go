@@ -0,0 +1,412 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		for _, version := range []string{"v1", "v2"} {
+			rec := ta.do(http.MethodGet, "/api/"+version+"/health", "", nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s health: %d %s", version, rec.Code, rec.Body)
+			}
+			body := decodeJSON[map[string]any](t, rec)
+			if body["status"] != "healthy" {
+				t.Errorf("%s health status = %v", version, body["status"])
+			}
+		}
+	})
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+
+		t.Run("unknown student cannot register", func(t *testing.T) {
+			rec := ta.postJSON("/api/v2/register", map[string]string{"student_no": "99999999999", "password": "pw"})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+
+		t.Run("missing fields", func(t *testing.T) {
+			for _, path := range []string{"/api/v2/register", "/api/v2/login"} {
+				rec := ta.postJSON(path, map[string]string{"student_no": "22070006071"})
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("%s: got %d, want 400", path, rec.Code)
+				}
+			}
+		})
+
+		t.Run("invalid json", func(t *testing.T) {
+			rec := ta.do(http.MethodPost, "/api/v2/register", "", strings.NewReader("{"), nil)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+
+		t.Run("method not allowed", func(t *testing.T) {
+			for _, path := range []string{"/api/v2/register", "/api/v2/login"} {
+				rec := ta.do(http.MethodGet, path, "", nil, nil)
+				if rec.Code != http.StatusMethodNotAllowed {
+					t.Errorf("%s: got %d, want 405", path, rec.Code)
+				}
+			}
+		})
+
+		token := ta.register("22070006071", "s3cret")
+		if token == "" {
+			t.Fatal("register returned no token")
+		}
+
+		t.Run("duplicate registration", func(t *testing.T) {
+			rec := ta.postJSON("/api/v2/register", map[string]string{"student_no": "22070006071", "password": "other"})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+
+		t.Run("wrong password", func(t *testing.T) {
+			rec := ta.postJSON("/api/v2/login", map[string]string{"student_no": "22070006071", "password": "nope"})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+
+		t.Run("unknown account", func(t *testing.T) {
+			rec := ta.postJSON("/api/v2/login", map[string]string{"student_no": "22070006072", "password": "s3cret"})
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+
+		for _, version := range []string{"v1", "v2"} {
+			t.Run("login "+version, func(t *testing.T) {
+				rec := ta.postJSON("/api/"+version+"/login", map[string]string{"student_no": "22070006071", "password": "s3cret"})
+				if rec.Code != http.StatusOK {
+					t.Fatalf("got %d %s", rec.Code, rec.Body)
+				}
+				var cookie *http.Cookie
+				for _, c := range rec.Result().Cookies() {
+					if c.Name == "jwt" {
+						cookie = c
+					}
+				}
+				if cookie == nil || !cookie.HttpOnly {
+					t.Fatalf("expected an HttpOnly jwt cookie, got %+v", cookie)
+				}
+				status := decodeJSON[TransactionStatus](t, rec)
+				if !strings.Contains(status.Message, cookie.Value) {
+					t.Errorf("token missing from response message %q", status.Message)
+				}
+			})
+		}
+	})
+}
+
+func TestAddStudentValidation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		tests := []struct {
+			name  string
+			query string
+			want  int
+		}{
+			{"missing student_no", "balance=10", http.StatusBadRequest},
+			{"missing balance", "student_no=22070006071", http.StatusBadRequest},
+			{"balance too small", "student_no=22070006071&balance=1", http.StatusBadRequest},
+			{"invalid balance", "student_no=22070006071&balance=ten", http.StatusBadRequest},
+			{"student number too long", "student_no=220700060711234&balance=10", http.StatusBadRequest},
+			{"ok", "student_no=22070006071&balance=10", http.StatusOK},
+			{"duplicate", "student_no=22070006071&balance=10", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			rec := ta.do(http.MethodPost, "/api/v2/admin/add-student?"+tt.query, adminToken(t), nil, nil)
+			if rec.Code != tt.want {
+				t.Errorf("%s: got %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+			}
+		}
+	})
+}
+
+func TestAddTuition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addTerm("Fall2025")
+		token := adminToken(t)
+
+		tests := []struct {
+			name       string
+			query      string
+			wantCode   int
+			wantStatus string
+		}{
+			{"missing term", "student_no=22070006071&tuition_amount=1000", http.StatusBadRequest, ""},
+			{"term not in calendar", "student_no=22070006071&term=Fall2052&tuition_amount=1000", http.StatusBadRequest, ""},
+			{"invalid amount", "student_no=22070006071&term=Fall2025&tuition_amount=abc", http.StatusBadRequest, ""},
+			{"unknown student", "student_no=22070006099&term=Fall2025&tuition_amount=1000", http.StatusBadRequest, ""},
+			{"ok", "student_no=22070006071&term=Fall2025&tuition_amount=1000", http.StatusOK, "Success"},
+			{"already set", "student_no=22070006071&term=Fall2025&tuition_amount=1000", http.StatusOK, "Error"},
+		}
+		for _, tt := range tests {
+			rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition?"+tt.query, token, nil, nil)
+			if rec.Code != tt.wantCode {
+				t.Errorf("%s: got %d, want %d (%s)", tt.name, rec.Code, tt.wantCode, rec.Body)
+				continue
+			}
+			if tt.wantStatus != "" {
+				if status := decodeJSON[TransactionStatus](t, rec); status.Status != tt.wantStatus {
+					t.Errorf("%s: status %q, want %q", tt.name, status.Status, tt.wantStatus)
+				}
+			}
+		}
+		if got := ta.tuitionTotal("22070006071", "Fall2025"); got != 1000 {
+			t.Errorf("tuition total = %v, want 1000", got)
+		}
+	})
+}
+
+func TestQueryTuitionAuthorization(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addStudent("22070006072", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006072", "Fall2025", 2000)
+		own := ta.register("22070006071", "pw")
+
+		for _, path := range []string{"/api/v2/mobile/tuition", "/api/v2/banking/tuition"} {
+			t.Run(path, func(t *testing.T) {
+				rec := ta.do(http.MethodGet, path+"?student_no=22070006071&active_term=Fall2025", own, nil, nil)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("own tuition: %d %s", rec.Code, rec.Body)
+				}
+				got := decodeJSON[struct {
+					StudentNo    string
+					Term         string
+					TuitionTotal float64
+					Balance      float64
+				}](t, rec)
+				if got.StudentNo != "22070006071" || got.Term != "Fall2025" || got.TuitionTotal != 1000 || got.Balance != 100 {
+					t.Errorf("unexpected response %+v", got)
+				}
+
+				rec = ta.do(http.MethodGet, path+"?student_no=22070006072&active_term=Fall2025", own, nil, nil)
+				if rec.Code != http.StatusNotFound {
+					t.Errorf("other student's tuition: got %d, want 404", rec.Code)
+				}
+
+				rec = ta.do(http.MethodGet, path+"?student_no=22070006071", own, nil, nil)
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("missing active_term with no active term: got %d, want 400", rec.Code)
+				}
+
+				rec = ta.do(http.MethodGet, path+"?student_no=22070006071&active_term=Spring2026", own, nil, nil)
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("term without tuition: got %d, want 400", rec.Code)
+				}
+
+				rec = ta.do(http.MethodGet, path+"?student_no=22070006071&active_term=Fall2025", "", nil, nil)
+				if rec.Code != http.StatusUnauthorized {
+					t.Errorf("no token: got %d, want 401", rec.Code)
+				}
+			})
+		}
+	})
+}
+
+func TestPayTuition(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     float64
+		tuition     float64
+		amount      string
+		wantCode    int
+		wantStatus  string
+		wantBalance float64
+		wantTuition float64
+	}{
+		{"partial payment goes to balance", 100, 1000, "400", http.StatusOK, "Successful", 500, 1000},
+		{"exact payment settles the term", 100, 1000, "900", http.StatusOK, "Successful", 0, 0},
+		{"overpayment keeps the excess", 100, 1000, "1500.50", http.StatusOK, "Successful", 600.50, 0},
+		{"zero amount", 100, 1000, "0", http.StatusOK, "Error", 100, 1000},
+		{"negative amount", 100, 1000, "-50", http.StatusOK, "Error", 100, 1000},
+		{"missing amount", 100, 1000, "", http.StatusOK, "Error", 100, 1000},
+		{"invalid amount", 100, 1000, "12abc", http.StatusBadRequest, "", 100, 1000},
+		{"NaN amount", 100, 1000, "NaN", http.StatusBadRequest, "", 100, 1000},
+		{"infinite amount", 100, 1000, "+Inf", http.StatusBadRequest, "", 100, 1000},
+	}
+
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		for i, tt := range tests {
+			studentNo := fmt.Sprintf("220700061%02d", i)
+			ta.addStudent(studentNo, tt.balance)
+			ta.addTuition(studentNo, "Fall2025", tt.tuition)
+
+			rec := ta.pay(token, studentNo, "Fall2025", tt.amount)
+			if rec.Code != tt.wantCode {
+				t.Errorf("%s: got %d, want %d (%s)", tt.name, rec.Code, tt.wantCode, rec.Body)
+				continue
+			}
+			if tt.wantStatus != "" {
+				if status := decodeJSON[TransactionStatus](t, rec); status.Status != tt.wantStatus {
+					t.Errorf("%s: status %q, want %q", tt.name, status.Status, tt.wantStatus)
+				}
+			}
+			if got := ta.balance(studentNo); got != tt.wantBalance {
+				t.Errorf("%s: balance = %v, want %v", tt.name, got, tt.wantBalance)
+			}
+			if got := ta.tuitionTotal(studentNo, "Fall2025"); got != tt.wantTuition {
+				t.Errorf("%s: tuition = %v, want %v", tt.name, got, tt.wantTuition)
+			}
+		}
+
+		t.Run("unknown student", func(t *testing.T) {
+			rec := ta.pay(token, "22070009999", "Fall2025", "100")
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("got %d, want 404", rec.Code)
+			}
+		})
+
+		t.Run("term without tuition", func(t *testing.T) {
+			rec := ta.pay(token, "22070006100", "Spring2030", "100")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+
+		// Only the requested term is settled
+		{
+			ta.addStudent("22070006075", 10)
+			ta.addTuition("22070006075", "Fall2025", 5000)
+			ta.addTuition("22070006075", "Spring2026", 1000)
+
+			if rec := ta.pay(token, "22070006075", "Spring2026", "990"); rec.Code != http.StatusOK {
+				t.Fatalf("got %d %s", rec.Code, rec.Body)
+			}
+			if got := ta.tuitionTotal("22070006075", "Spring2026"); got != 0 {
+				t.Errorf("Spring2026 tuition = %v, want 0", got)
+			}
+			if got := ta.tuitionTotal("22070006075", "Fall2025"); got != 5000 {
+				t.Errorf("Fall2025 tuition = %v, want 5000", got)
+			}
+			if got := ta.balance("22070006075"); got != 0 {
+				t.Errorf("balance = %v, want 0", got)
+			}
+		}
+
+		t.Run("requires a token", func(t *testing.T) {
+			if rec := ta.pay("", "22070006075", "Fall2025", "10"); rec.Code != http.StatusUnauthorized {
+				t.Errorf("got %d, want 401", rec.Code)
+			}
+		})
+	})
+}
+
+func TestAddTuitionBatch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addStudent("22070006072", 100)
+		ta.addTerm("Fall2025")
+		ta.addTerm("Spring2025")
+		token := adminToken(t)
+
+		csv := "student_number,term,amount\n22070006071,Fall2025,10000\n22070006072,Spring2025,12000\n"
+		body, header := multipartFile(t, "file", "tuitions.csv", csv)
+		rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch", token, body, header)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		if got := ta.tuitionTotal("22070006071", "Fall2025"); got != 10000 {
+			t.Errorf("22070006071 tuition = %v, want 10000", got)
+		}
+		if got := ta.tuitionTotal("22070006072", "Spring2025"); got != 12000 {
+			t.Errorf("22070006072 tuition = %v, want 12000", got)
+		}
+
+		t.Run("unknown student", func(t *testing.T) {
+			body, header := multipartFile(t, "file", "tuitions.csv", "student_number,term,amount\n22070009999,Fall2025,1\n")
+			rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch", token, body, header)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got %d, want 422", rec.Code)
+			}
+		})
+
+		t.Run("missing file", func(t *testing.T) {
+			rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch", token, nil, nil)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", rec.Code)
+			}
+		})
+	})
+}
+
+func TestUnpaidTuitionPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		for i := range 6 {
+			studentNo := fmt.Sprintf("2207000610%d", i)
+			ta.addStudent(studentNo, 10)
+			ta.addTuition(studentNo, "Fall2025", 1000)
+		}
+		// Settle one of them; it must not be listed
+		if rec := ta.pay(token, "22070006105", "Fall2025", "990"); rec.Code != http.StatusOK {
+			t.Fatalf("pay: %d %s", rec.Code, rec.Body)
+		}
+
+		type unpaid struct {
+			StudentNumber string
+			Term          string
+		}
+		seen := map[string]bool{}
+		for offset, want := range map[int]int{0: 2, 2: 2, 4: 1, 6: 0} {
+			rec := ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/unpaid-status?limit=2&offset=%d", offset), token, nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("offset %d: %d %s", offset, rec.Code, rec.Body)
+			}
+			page := decodeJSON[[]unpaid](t, rec)
+			if len(page) != want {
+				t.Errorf("offset %d: %d rows, want %d", offset, len(page), want)
+			}
+			for _, row := range page {
+				seen[row.StudentNumber] = true
+			}
+		}
+		if len(seen) != 5 || seen["22070006105"] {
+			t.Errorf("pages covered %v, want the 5 unpaid students", seen)
+		}
+
+		rec := ta.do(http.MethodGet, "/api/v2/admin/unpaid-status", token, nil, nil)
+		if got := decodeJSON[[]unpaid](t, rec); len(got) != 5 {
+			t.Errorf("default page has %d rows, want 5", len(got))
+		}
+
+		for _, query := range []string{"limit=abc", "offset=x", "limit=-1"} {
+			rec := ta.do(http.MethodGet, "/api/v2/admin/unpaid-status?"+query, token, nil, nil)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestGetLogs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		rec := ta.do(http.MethodGet, "/api/v2/admin/logs", adminToken(t), nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		var lines []string
+		if err := json.NewDecoder(rec.Body).Decode(&lines); err != nil {
+			t.Fatal(err)
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/logs", "", nil, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("without token: got %d, want 401", rec.Code)
+		}
+	})
+}
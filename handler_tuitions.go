@@ -0,0 +1,506 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const maxReasonLength = 500
+
+// Conflicts detected inside the amend and cancel transactions
+var (
+	errTuitionCancelled = errors.New("tuition is cancelled")
+	errTermTaken        = errors.New("student already has a tuition for the term")
+	errTermHasPayments  = errors.New("payments were made for the term")
+)
+
+// tuitionResponse reports tuition_total (what is still owed) next to what was billed.
+type tuitionResponse struct {
+	TuitionID    int32   `json:"tuition_id"`
+	StudentNo    string  `json:"student_no"`
+	Term         string  `json:"term"`
+	TuitionTotal float64 `json:"tuition_total"`
+	BilledTotal  float64 `json:"billed_total"`
+	AmountPaid   float64 `json:"amount_paid"`
+	Status       string  `json:"status"`
+}
+
+type tuitionChangeResponse struct {
+	ChangeID          int32     `json:"change_id"`
+	Action            string    `json:"action"`
+	OldTerm           string    `json:"old_term"`
+	NewTerm           string    `json:"new_term"`
+	OldAmount         float64   `json:"old_amount"`
+	NewAmount         float64   `json:"new_amount"`
+	BalanceAdjustment float64   `json:"balance_adjustment"`
+	Reason            string    `json:"reason"`
+	ChangedBy         string    `json:"changed_by"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newTuitionResponse(t db.Tuition) tuitionResponse {
+	return tuitionResponse{
+		TuitionID:    t.TuitionID,
+		StudentNo:    t.StudentNo,
+		Term:         t.Term,
+		TuitionTotal: t.TuitionTotal,
+		BilledTotal:  t.BilledTotal,
+		AmountPaid:   tuitionPaid(t),
+		Status:       t.Status,
+	}
+}
+
+func newTuitionChangeResponse(c db.TuitionChange) tuitionChangeResponse {
+	return tuitionChangeResponse{
+		ChangeID:          c.ChangeID,
+		Action:            c.Action,
+		OldTerm:           c.OldTerm,
+		NewTerm:           c.NewTerm,
+		OldAmount:         c.OldAmount,
+		NewAmount:         c.NewAmount,
+		BalanceAdjustment: c.BalanceAdjustment,
+		Reason:            c.Reason,
+		ChangedBy:         c.ChangedBy,
+		CreatedAt:         c.CreatedAt.Time,
+	}
+}
+
+// tuitionPaid is how much of the billed amount has already been charged to the student.
+func tuitionPaid(t db.Tuition) float64 {
+	if t.Status == "cancelled" {
+		return 0
+	}
+	return t.BilledTotal - t.TuitionTotal
+}
+
+func tuitionIDFromPath(r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(r.PathValue("tuition_id"), 10, 32)
+	return int32(id), err == nil && id > 0
+}
+
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// Admin - List Tuitions
+func (a *App) listTuitionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, offset, ok := parsePage(q)
+	if !ok {
+		http.Error(w, `{"error":"limit must be between 0 and 100 and offset must not be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	status := q.Get("status")
+	if status != "" && status != "active" && status != "cancelled" {
+		http.Error(w, `{"error":"status must be active or cancelled"}`, http.StatusBadRequest)
+		return
+	}
+
+	unpaid, ok := parseBoolFilter(q, "unpaid")
+	if !ok {
+		http.Error(w, `{"error":"unpaid must be true or false"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := db.CountTuitionsParams{
+		StudentNo: optionalText(q.Get("student_no")),
+		Term:      optionalText(q.Get("term")),
+		Status:    optionalText(status),
+		Unpaid:    unpaid,
+	}
+	tuitions, err := a.Store.ListTuitions(r.Context(), db.ListTuitionsParams{
+		StudentNo: filter.StudentNo,
+		Term:      filter.Term,
+		Status:    filter.Status,
+		Unpaid:    filter.Unpaid,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	total, err := a.Store.CountTuitions(r.Context(), filter)
+	if err != nil {
+		http.Error(w, `{"error":"Tuitions cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type ListTuitionsResponse struct {
+		Tuitions []tuitionResponse `json:"tuitions"`
+		Total    int64             `json:"total"`
+		Limit    int32             `json:"limit"`
+		Offset   int32             `json:"offset"`
+	}
+	response := ListTuitionsResponse{
+		Tuitions: []tuitionResponse{},
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, t := range tuitions {
+		response.Tuitions = append(response.Tuitions, newTuitionResponse(t))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Get Tuition with its change history
+func (a *App) getTuitionHandler(w http.ResponseWriter, r *http.Request) {
+	tuitionID, ok := tuitionIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid tuition id"}`, http.StatusBadRequest)
+		return
+	}
+
+	tuition, err := a.Store.GetTuition(r.Context(), tuitionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, `{"error":"Tuition not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error":"Tuition cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	changes, err := a.Store.ListTuitionChanges(r.Context(), tuitionID)
+	if err != nil {
+		http.Error(w, `{"error":"Tuition history cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+	items, err := tuitionItems(r.Context(), a.Store, tuitionID)
+	if err != nil {
+		http.Error(w, `{"error":"Tuition items cannot be queried"}`, http.StatusInternalServerError)
+		return
+	}
+
+	type TuitionDetailResponse struct {
+		tuitionResponse
+		Items   []tuitionItemResponse   `json:"items"`
+		Changes []tuitionChangeResponse `json:"changes"`
+	}
+	response := TuitionDetailResponse{
+		tuitionResponse: newTuitionResponse(tuition),
+		Items:           items,
+		Changes:         []tuitionChangeResponse{},
+	}
+	for _, c := range changes {
+		response.Changes = append(response.Changes, newTuitionChangeResponse(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Admin - Amend Tuition amount and/or term.
+//
+// The part of the tuition already charged to the student stays charged. If the new
+// amount is above it the rest becomes outstanding again; if it is below, the
+// difference is credited back to the student's balance as an adjustment.
+func (a *App) amendTuitionHandler(w http.ResponseWriter, r *http.Request) {
+	tuitionID, ok := tuitionIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid tuition id"}`, http.StatusBadRequest)
+		return
+	}
+
+	type AmendTuitionRequest struct {
+		Amount *float64 `json:"amount"`
+		Term   *string  `json:"term"`
+		Reason string   `json:"reason"`
+	}
+	var req AmendTuitionRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxReasonLength {
+		http.Error(w, `{"error":"a reason of at most 500 characters is required"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Amount == nil && req.Term == nil {
+		http.Error(w, `{"error":"amount or term is required"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Amount != nil && (*req.Amount <= 0 || math.IsInf(*req.Amount, 0)) {
+		http.Error(w, `{"error":"amount must be positive"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Term != nil && strings.TrimSpace(*req.Term) == "" {
+		http.Error(w, `{"error":"term must not be empty"}`, http.StatusBadRequest)
+		return
+	}
+
+	changedBy, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+
+	var tuition db.Tuition
+	var adjustment float64
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := lockTuition(r.Context(), tx, tuitionID)
+		if err != nil {
+			return err
+		}
+		if current.Status == "cancelled" {
+			return errTuitionCancelled
+		}
+
+		term, amount := current.Term, current.BilledTotal
+		if req.Term != nil {
+			term = strings.TrimSpace(*req.Term)
+		}
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+
+		if term != current.Term {
+			if _, err := tx.GetTerm(r.Context(), term); errors.Is(err, pgx.ErrNoRows) {
+				return errTermNotFound
+			} else if err != nil {
+				return err
+			}
+			existing, err := tx.GetTuitionByTerm(r.Context(), db.GetTuitionByTermParams{StudentNo: current.StudentNo, Term: term})
+			if err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				return errTermTaken
+			}
+			// Payments name their term, so moving the tuition would orphan them
+			payments, err := tx.CountPaymentsForTerm(r.Context(), db.CountPaymentsForTermParams{StudentNo: current.StudentNo, Term: current.Term})
+			if err != nil {
+				return err
+			}
+			if payments > 0 {
+				return errTermHasPayments
+			}
+		}
+
+		// A single item follows the amount; itemized bills have no one amount to change
+		var items []db.ListTuitionItemsRow
+		if amount != current.BilledTotal {
+			items, err = tx.ListTuitionItems(r.Context(), tuitionID)
+			if err != nil {
+				return err
+			}
+			if len(items) != 1 {
+				return errItemized
+			}
+		}
+
+		outstanding := amount - tuitionPaid(current)
+		if outstanding < 0 {
+			adjustment = -outstanding
+			outstanding = 0
+		}
+		if len(items) == 1 {
+			err = tx.UpdateTuitionItem(r.Context(), db.UpdateTuitionItemParams{
+				ItemID:     items[0].ItemID,
+				Amount:     amount,
+				AmountPaid: amount - outstanding,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if adjustment > 0 {
+			balance, err := tx.LockStudentBalance(r.Context(), current.StudentNo)
+			if err != nil {
+				return err
+			}
+			err = tx.UpdateBalance(r.Context(), db.UpdateBalanceParams{StudentNo: current.StudentNo, Balance: balance + adjustment})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.AmendTuition(r.Context(), db.AmendTuitionParams{
+			TuitionID:    tuitionID,
+			Term:         term,
+			BilledTotal:  amount,
+			TuitionTotal: outstanding,
+		})
+		// Another request billed the student for the new term first
+		if isConstraintError(err, pgUniqueViolation) {
+			return errTermTaken
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.AddTuitionChange(r.Context(), db.AddTuitionChangeParams{
+			TuitionID:         tuitionID,
+			Action:            "amend",
+			OldTerm:           current.Term,
+			NewTerm:           term,
+			OldAmount:         current.BilledTotal,
+			NewAmount:         amount,
+			BalanceAdjustment: adjustment,
+			Reason:            req.Reason,
+			ChangedBy:         changedBy,
+		})
+		if err != nil {
+			return err
+		}
+
+		tuition, err = tx.GetTuition(r.Context(), tuitionID)
+		return err
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"Tuition not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errTuitionCancelled):
+		http.Error(w, `{"error":"Cancelled tuitions cannot be amended"}`, http.StatusConflict)
+		return
+	case errors.Is(err, errTermTaken):
+		http.Error(w, `{"error":"This student's tuition for this term is already set"}`, http.StatusConflict)
+		return
+	case errors.Is(err, errTermNotFound):
+		http.Error(w, `{"error":"There is no term with this code"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, errTermHasPayments):
+		http.Error(w, `{"error":"Payments were already made for this term; cancel the tuition and add a new one instead"}`, http.StatusConflict)
+		return
+	case errors.Is(err, errItemized):
+		http.Error(w, `{"error":"Itemized tuitions cannot be amended by amount; cancel the tuition and bill it again"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Tuition cannot be amended"}`, http.StatusBadRequest)
+		return
+	}
+
+	type AmendTuitionResponse struct {
+		tuitionResponse
+		BalanceAdjustment float64 `json:"balance_adjustment"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AmendTuitionResponse{
+		tuitionResponse:   newTuitionResponse(tuition),
+		BalanceAdjustment: adjustment,
+	})
+}
+
+// lockTuition locks the student's balance and then the tuition, the order postPayment
+// takes them in, so amends, cancels and payments wait for each other instead of
+// deadlocking.
+func lockTuition(ctx context.Context, tx Store, tuitionID int32) (db.Tuition, error) {
+	tuition, err := tx.GetTuition(ctx, tuitionID)
+	if err != nil {
+		return tuition, err
+	}
+	if _, err := tx.LockStudentBalance(ctx, tuition.StudentNo); err != nil {
+		return tuition, err
+	}
+	return tx.LockTuition(ctx, tuitionID)
+}
+
+// Admin - Cancel Tuition. Whatever was already charged for it is credited back to the
+// student's balance.
+func (a *App) cancelTuitionHandler(w http.ResponseWriter, r *http.Request) {
+	tuitionID, ok := tuitionIDFromPath(r)
+	if !ok {
+		http.Error(w, `{"error":"Invalid tuition id"}`, http.StatusBadRequest)
+		return
+	}
+
+	type CancelTuitionRequest struct {
+		Reason string `json:"reason"`
+	}
+	var req CancelTuitionRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid json"}`, http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxReasonLength {
+		http.Error(w, `{"error":"a reason of at most 500 characters is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	changedBy, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+
+	var tuition db.Tuition
+	var adjustment float64
+	err := a.Store.WithTx(r.Context(), func(tx Store) error {
+		current, err := lockTuition(r.Context(), tx, tuitionID)
+		if err != nil {
+			return err
+		}
+		if current.Status == "cancelled" {
+			return errTuitionCancelled
+		}
+
+		adjustment = tuitionPaid(current)
+		if adjustment > 0 {
+			balance, err := tx.LockStudentBalance(r.Context(), current.StudentNo)
+			if err != nil {
+				return err
+			}
+			err = tx.UpdateBalance(r.Context(), db.UpdateBalanceParams{StudentNo: current.StudentNo, Balance: balance + adjustment})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.CancelTuition(r.Context(), tuitionID); err != nil {
+			return err
+		}
+		err = tx.AddTuitionChange(r.Context(), db.AddTuitionChangeParams{
+			TuitionID:         tuitionID,
+			Action:            "cancel",
+			OldTerm:           current.Term,
+			NewTerm:           current.Term,
+			OldAmount:         current.BilledTotal,
+			NewAmount:         0,
+			BalanceAdjustment: adjustment,
+			Reason:            req.Reason,
+			ChangedBy:         changedBy,
+		})
+		if err != nil {
+			return err
+		}
+
+		tuition, err = tx.GetTuition(r.Context(), tuitionID)
+		return err
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"Tuition not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errTuitionCancelled):
+		http.Error(w, `{"error":"Tuition is already cancelled"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error":"Tuition cannot be cancelled"}`, http.StatusBadRequest)
+		return
+	}
+
+	type CancelTuitionResponse struct {
+		tuitionResponse
+		BalanceAdjustment float64 `json:"balance_adjustment"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CancelTuitionResponse{
+		tuitionResponse:   newTuitionResponse(tuition),
+		BalanceAdjustment: adjustment,
+	})
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dogukan-dev/tuition/db"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type tuitionDetail struct {
+	tuitionResponse
+	Changes []tuitionChangeResponse `json:"changes"`
+}
+
+// tuitionID looks up the id of the student's active tuition for term.
+func (ta *testApp) tuitionID(studentNo, term string) int32 {
+	ta.t.Helper()
+	rec := ta.do(http.MethodGet, "/api/v2/admin/tuitions?status=active&student_no="+studentNo+"&term="+term, adminToken(ta.t), nil, nil)
+	got := decodeJSON[struct {
+		Tuitions []tuitionResponse `json:"tuitions"`
+	}](ta.t, rec)
+	if len(got.Tuitions) != 1 {
+		ta.t.Fatalf("tuition %s/%s: found %d", studentNo, term, len(got.Tuitions))
+	}
+	return got.Tuitions[0].TuitionID
+}
+
+func (ta *testApp) amend(id int32, body string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	return ta.do(http.MethodPatch, fmt.Sprintf("/api/v2/admin/tuitions/%d", id), adminToken(ta.t), bytes.NewBufferString(body), nil)
+}
+
+func (ta *testApp) cancel(id int32, body string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	return ta.do(http.MethodPost, fmt.Sprintf("/api/v2/admin/tuitions/%d/cancel", id), adminToken(ta.t), bytes.NewBufferString(body), nil)
+}
+
+func TestListTuitions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 1000)
+		ta.addTuition("22070006072", "Fall2025", 500)
+		ta.pay(token, "22070006072", "Fall2025", "490")
+		ta.cancel(ta.tuitionID("22070006071", "Spring2026"), `{"reason":"withdrew"}`)
+
+		tests := []struct {
+			query string
+			total int64
+		}{
+			{"", 3},
+			{"student_no=22070006071", 2},
+			{"term=Fall2025", 2},
+			{"status=cancelled", 1},
+			{"unpaid=true", 1},
+			{"unpaid=false&status=active", 1},
+			{"limit=1&offset=2", 3},
+		}
+		for _, tt := range tests {
+			rec := ta.do(http.MethodGet, "/api/v2/admin/tuitions?"+tt.query, token, nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%q: %d %s", tt.query, rec.Code, rec.Body)
+			}
+			got := decodeJSON[struct {
+				Tuitions []tuitionResponse `json:"tuitions"`
+				Total    int64             `json:"total"`
+			}](t, rec)
+			if got.Total != tt.total {
+				t.Errorf("%q: total %d, want %d", tt.query, got.Total, tt.total)
+			}
+		}
+
+		rec := ta.do(http.MethodGet, "/api/v2/admin/tuitions?student_no=22070006072", token, nil, nil)
+		got := decodeJSON[struct {
+			Tuitions []tuitionResponse `json:"tuitions"`
+		}](t, rec)
+		if len(got.Tuitions) != 1 || got.Tuitions[0].AmountPaid != 500 || got.Tuitions[0].BilledTotal != 500 || got.Tuitions[0].TuitionTotal != 0 {
+			t.Errorf("unexpected paid tuition %+v", got.Tuitions)
+		}
+
+		for _, query := range []string{"status=paid", "unpaid=perhaps", "limit=x"} {
+			if rec := ta.do(http.MethodGet, "/api/v2/admin/tuitions?"+query, token, nil, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestAmendTuition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		id := ta.tuitionID("22070006071", "Fall2025")
+
+		for _, body := range []string{`{"amount": 900}`, `{"amount": 900, "reason": "  "}`, `{"reason": "typo"}`, `{"amount": -5, "reason": "typo"}`, `{"term": "", "reason": "typo"}`, `{"amont": 1, "reason": "typo"}`} {
+			if rec := ta.amend(id, body); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got %d, want 400", body, rec.Code)
+			}
+		}
+		if rec := ta.amend(99999, `{"amount": 900, "reason": "typo"}`); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown tuition: got %d, want 404", rec.Code)
+		}
+
+		// Unpaid: the new amount simply replaces the old one
+		rec := ta.amend(id, `{"amount": 1200, "reason": "wrong fee category"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("amend: %d %s", rec.Code, rec.Body)
+		}
+		if got := ta.tuitionTotal("22070006071", "Fall2025"); got != 1200 {
+			t.Errorf("tuition = %v, want 1200", got)
+		}
+
+		// Term can move while nothing has been paid for it, but only to a term in the calendar
+		if rec := ta.amend(id, `{"term": "Spring2062", "reason": "wrong term"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown term: got %d, want 400", rec.Code)
+		}
+		ta.addTerm("Spring2026")
+		ta.addTerm("Fall2026")
+		if rec := ta.amend(id, `{"term": "Spring2026", "reason": "wrong term"}`); rec.Code != http.StatusOK {
+			t.Fatalf("move term: %d %s", rec.Code, rec.Body)
+		}
+		ta.addTuition("22070006071", "Fall2025", 800)
+		if rec := ta.amend(id, `{"term": "Fall2025", "reason": "back"}`); rec.Code != http.StatusConflict {
+			t.Errorf("term already taken: got %d, want 409", rec.Code)
+		}
+
+		// Settled tuition: raising the amount makes the difference outstanding again
+		ta.pay(token, "22070006071", "Spring2026", "1100")
+		if got := ta.tuitionTotal("22070006071", "Spring2026"); got != 0 {
+			t.Fatalf("tuition not settled: %v", got)
+		}
+		if rec := ta.amend(id, `{"amount": 1500, "reason": "lab fee added"}`); rec.Code != http.StatusOK {
+			t.Fatalf("raise: %d %s", rec.Code, rec.Body)
+		}
+		if got := ta.tuitionTotal("22070006071", "Spring2026"); got != 300 {
+			t.Errorf("outstanding = %v, want 300", got)
+		}
+
+		// Lowering it below what was paid credits the difference back
+		rec = ta.amend(id, `{"amount": 1000, "reason": "scholarship"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("lower: %d %s", rec.Code, rec.Body)
+		}
+		got := decodeJSON[struct {
+			tuitionResponse
+			BalanceAdjustment float64 `json:"balance_adjustment"`
+		}](t, rec)
+		if got.BalanceAdjustment != 200 || got.TuitionTotal != 0 || got.BilledTotal != 1000 {
+			t.Errorf("unexpected amendment %+v", got)
+		}
+		if b := ta.balance("22070006071"); b != 200 {
+			t.Errorf("balance = %v, want 200", b)
+		}
+
+		if rec := ta.amend(id, `{"term": "Fall2026", "reason": "wrong term"}`); rec.Code != http.StatusConflict {
+			t.Errorf("moving a paid term: got %d, want 409", rec.Code)
+		}
+
+		rec = ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/tuitions/%d", id), token, nil, nil)
+		detail := decodeJSON[tuitionDetail](t, rec)
+		if len(detail.Changes) != 4 {
+			t.Fatalf("got %d changes, want 4: %+v", len(detail.Changes), detail.Changes)
+		}
+		first, last := detail.Changes[0], detail.Changes[3]
+		if first.Action != "amend" || first.OldAmount != 1000 || first.NewAmount != 1200 || first.Reason != "wrong fee category" || first.ChangedBy != "admin" {
+			t.Errorf("unexpected first change %+v", first)
+		}
+		if detail.Changes[1].OldTerm != "Fall2025" || detail.Changes[1].NewTerm != "Spring2026" {
+			t.Errorf("unexpected term change %+v", detail.Changes[1])
+		}
+		if last.BalanceAdjustment != 200 || last.Reason != "scholarship" {
+			t.Errorf("unexpected last change %+v", last)
+		}
+	})
+}
+
+func TestCancelTuition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 400)
+		unpaid := ta.tuitionID("22070006071", "Fall2025")
+		paid := ta.tuitionID("22070006071", "Spring2026")
+		ta.pay(token, "22070006071", "Spring2026", "300")
+
+		if rec := ta.cancel(unpaid, `{}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("missing reason: got %d, want 400", rec.Code)
+		}
+
+		rec := ta.cancel(unpaid, `{"reason": "withdrew"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cancel: %d %s", rec.Code, rec.Body)
+		}
+		if got := decodeJSON[tuitionResponse](t, rec); got.Status != "cancelled" || got.TuitionTotal != 0 {
+			t.Errorf("unexpected cancelled tuition %+v", got)
+		}
+		if b := ta.balance("22070006071"); b != 0 {
+			t.Errorf("balance = %v, want 0", b)
+		}
+
+		// Cancelled terms are gone for students and can be billed again
+		if rec := ta.pay(token, "22070006071", "Fall2025", "10"); rec.Code != http.StatusBadRequest {
+			t.Errorf("pay cancelled term: got %d, want 400", rec.Code)
+		}
+		if rec := ta.amend(unpaid, `{"amount": 5, "reason": "x"}`); rec.Code != http.StatusConflict {
+			t.Errorf("amend cancelled: got %d, want 409", rec.Code)
+		}
+		if rec := ta.cancel(unpaid, `{"reason": "again"}`); rec.Code != http.StatusConflict {
+			t.Errorf("cancel twice: got %d, want 409", rec.Code)
+		}
+		ta.addTuition("22070006071", "Fall2025", 900)
+
+		// Cancelling a settled tuition refunds it to the balance
+		rec = ta.cancel(paid, `{"reason": "billing error"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cancel paid: %d %s", rec.Code, rec.Body)
+		}
+		if b := ta.balance("22070006071"); b != 400 {
+			t.Errorf("balance = %v, want 400", b)
+		}
+
+		rec = ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/tuitions/%d", paid), token, nil, nil)
+		detail := decodeJSON[tuitionDetail](t, rec)
+		if len(detail.Changes) != 1 || detail.Changes[0].Action != "cancel" || detail.Changes[0].BalanceAdjustment != 400 {
+			t.Errorf("unexpected history %+v", detail.Changes)
+		}
+		if detail.AmountPaid != 0 || detail.Status != "cancelled" {
+			t.Errorf("unexpected tuition %+v", detail.tuitionResponse)
+		}
+	})
+}
+
+func TestSingleActiveTuition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("Fall2025")
+		ta.addTerm("Spring2026")
+		ctx := context.Background()
+
+		// The index holds even when the check in the handlers is skipped
+		params := db.AddTuitionToOneStudentParams{StudentNo: "22070006071", Term: "Fall2025", TuitionTotal: 100}
+		if _, err := ta.app.Store.AddTuitionToOneStudent(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ta.app.Store.AddTuitionToOneStudent(ctx, params); !isConstraintError(err, pgUniqueViolation) {
+			t.Fatalf("second active tuition: %v", err)
+		}
+		spring, err := ta.app.Store.AddTuitionToOneStudent(ctx, db.AddTuitionToOneStudentParams{StudentNo: "22070006071", Term: "Spring2026", TuitionTotal: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ta.app.Store.AmendTuition(ctx, db.AmendTuitionParams{TuitionID: spring.TuitionID, Term: "Fall2025", BilledTotal: 100, TuitionTotal: 100})
+		if !isConstraintError(err, pgUniqueViolation) {
+			t.Fatalf("amend onto a billed term: %v", err)
+		}
+
+		// A cancelled tuition leaves the term free
+		if rec := ta.cancel(spring.TuitionID, `{"reason": "withdrew"}`); rec.Code != http.StatusOK {
+			t.Fatalf("cancel: %d %s", rec.Code, rec.Body)
+		}
+
+		// Concurrent requests for the same term: one bills it, the rest conflict
+		var wg sync.WaitGroup
+		var created atomic.Int32
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := ta.createTuition(`{"student_no": "22070006071", "term": "Spring2026", "items": [{"fee_type": "tuition", "amount": 500}]}`)
+				switch rec.Code {
+				case http.StatusCreated:
+					created.Add(1)
+				case http.StatusConflict:
+				default:
+					t.Errorf("create: %d %s", rec.Code, rec.Body)
+				}
+			}()
+		}
+		wg.Wait()
+		if created.Load() != 1 {
+			t.Errorf("created %d tuitions for one term", created.Load())
+		}
+	})
+}
+
+func TestConcurrentAmendAndCancel(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006072", "Fall2025", 1000)
+		amended := ta.tuitionID("22070006071", "Fall2025")
+		cancelled := ta.tuitionID("22070006072", "Fall2025")
+
+		// Payments race an amend on one student and a cancel on the other
+		var wg sync.WaitGroup
+		var paid [2]atomic.Int32
+		for i, studentNo := range []string{"22070006071", "22070006072"} {
+			for range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					switch rec := ta.pay(token, studentNo, "Fall2025", "100"); rec.Code {
+					case http.StatusOK:
+						paid[i].Add(100)
+					case http.StatusBadRequest, http.StatusConflict:
+						// The tuition was cancelled before or during the payment
+					default:
+						t.Errorf("pay %s: %d %s", studentNo, rec.Code, rec.Body)
+					}
+				}()
+			}
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if rec := ta.amend(amended, `{"amount": 1500, "reason": "wrong fee category"}`); rec.Code != http.StatusOK {
+				t.Errorf("amend: %d %s", rec.Code, rec.Body)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if rec := ta.cancel(cancelled, `{"reason": "withdrew"}`); rec.Code != http.StatusOK {
+				t.Errorf("cancel: %d %s", rec.Code, rec.Body)
+			}
+		}()
+		wg.Wait()
+
+		// Every payment ends up either on the balance or on the tuition
+		for i, id := range []int32{amended, cancelled} {
+			rec := ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/tuitions/%d", id), token, nil, nil)
+			got := decodeJSON[tuitionDetail](t, rec)
+			if want := float64(10 + paid[i].Load()); ta.balance(got.StudentNo)+got.AmountPaid != want {
+				t.Errorf("%s: balance %v + paid %v, want %v", got.StudentNo, ta.balance(got.StudentNo), got.AmountPaid, want)
+			}
+			if got.Status == "active" && got.TuitionTotal != got.BilledTotal-got.AmountPaid {
+				t.Errorf("inconsistent tuition %+v", got.tuitionResponse)
+			}
+		}
+		rec := ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/tuitions/%d", amended), token, nil, nil)
+		if got := decodeJSON[tuitionDetail](t, rec); got.BilledTotal != 1500 {
+			t.Errorf("amended tuition billed %v, want 1500", got.BilledTotal)
+		}
+		rec = ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/tuitions/%d", cancelled), token, nil, nil)
+		if got := decodeJSON[tuitionDetail](t, rec); got.Status != "cancelled" || got.TuitionTotal != 0 {
+			t.Errorf("unexpected cancelled tuition %+v", got.tuitionResponse)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+)
+
+const testJWTSecret = "test-secret"
+
+func TestMain(m *testing.M) {
+	os.Setenv("JWT_SECRET", testJWTSecret)
+
+	// Keep request logs out of the test output and out of logs/api_requests.log
+	log.SetOutput(io.Discard)
+	f, err := os.CreateTemp("", "api_requests-*.log")
+	if err != nil {
+		panic(err)
+	}
+	logFile = f
+
+	code := m.Run()
+
+	f.Close()
+	os.Remove(f.Name())
+	os.Exit(code)
+}
+
+// testStores returns every backend the suite runs against. Postgres is included
+// when TEST_DATABASE_CONNECTION points at a database the tests may wipe.
+func testStores(t *testing.T) map[string]func(t *testing.T) Store {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store {
+			return NewMemoryStore()
+		},
+		"sqlite": func(t *testing.T) Store {
+			store, err := OpenSQLiteStore("sqlite://:memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrator, err := NewSQLiteMigrator(store.db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Up(context.Background(), 0); err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+
+	if dsn := os.Getenv("TEST_DATABASE_CONNECTION"); dsn != "" {
+		stores["postgres"] = func(t *testing.T) Store {
+			t.Setenv("DATABASE_CONNECTION", dsn)
+			pool, err := connectDatabase(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrator, err := NewPostgresMigrator(pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Up(context.Background(), 0); err != nil {
+				t.Fatal(err)
+			}
+			// Start every test from empty tables
+			_, err = pool.Exec(context.Background(), `DO $$ BEGIN
+				EXECUTE (SELECT 'TRUNCATE ' || string_agg(quote_ident(tablename), ', ') || ' RESTART IDENTITY CASCADE'
+				         FROM pg_tables WHERE schemaname = 'public' AND tablename <> 'schema_migrations');
+			END $$`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The truncate also dropped the fee types seeded by the migrations
+			store := NewPostgresStore(pool)
+			for _, f := range memDefaultFeeTypes() {
+				_, err := store.CreateFeeType(context.Background(), db.CreateFeeTypeParams{Code: f.Code, Name: f.Name, Priority: f.Priority})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			return store
+		}
+	}
+	return stores
+}
+
+// forEachStore runs fn once per backend with a fresh App.
+func forEachStore(t *testing.T, fn func(t *testing.T, ta *testApp)) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			t.Cleanup(store.Close)
+
+			app := &App{Store: store, Context: context.Background()}
+			fn(t, &testApp{t: t, app: app, handler: app.routes()})
+		})
+	}
+}
+
+type testApp struct {
+	t       *testing.T
+	app     *App
+	handler http.Handler
+}
+
+// do sends a request through the full router. A non-empty token is sent as a
+// bearer token.
+func (ta *testApp) do(method, target, token string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	req := httptest.NewRequest(method, target, body)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ta.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (ta *testApp) postJSON(target string, body any) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+	return ta.do(http.MethodPost, target, "", bytes.NewReader(raw), http.Header{"Content-Type": {"application/json"}})
+}
+
+func (ta *testApp) addStudent(studentNo string, balance float64) {
+	ta.t.Helper()
+	q := url.Values{"student_no": {studentNo}, "balance": {strconv.FormatFloat(balance, 'f', -1, 64)}}
+	rec := ta.do(http.MethodPost, "/api/v2/admin/add-student?"+q.Encode(), adminToken(ta.t), nil, nil)
+	if rec.Code != http.StatusOK {
+		ta.t.Fatalf("add student %s: %d %s", studentNo, rec.Code, rec.Body)
+	}
+}
+
+// addTerm puts term in the calendar unless it is already there.
+func (ta *testApp) addTerm(code string) {
+	ta.t.Helper()
+	_, err := ta.app.Store.CreateTerm(context.Background(), db.CreateTermParams{Code: code, Name: code})
+	if err != nil && !isConstraintError(err, pgUniqueViolation) {
+		ta.t.Fatalf("add term %s: %v", code, err)
+	}
+}
+
+func (ta *testApp) addTuition(studentNo, term string, amount float64) {
+	ta.t.Helper()
+	ta.addTerm(term)
+	q := url.Values{"student_no": {studentNo}, "term": {term}, "tuition_amount": {strconv.FormatFloat(amount, 'f', -1, 64)}}
+	rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition?"+q.Encode(), adminToken(ta.t), nil, nil)
+	if status := decodeJSON[TransactionStatus](ta.t, rec); status.Status != "Success" {
+		ta.t.Fatalf("add tuition %s/%s: %+v", studentNo, term, status)
+	}
+}
+
+// register creates the student's account and returns the issued token.
+func (ta *testApp) register(studentNo, password string) string {
+	ta.t.Helper()
+	rec := ta.postJSON("/api/v2/register", map[string]string{"student_no": studentNo, "password": password})
+	if rec.Code != http.StatusOK {
+		ta.t.Fatalf("register %s: %d %s", studentNo, rec.Code, rec.Body)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "jwt" {
+			return c.Value
+		}
+	}
+	ta.t.Fatalf("register %s: no jwt cookie", studentNo)
+	return ""
+}
+
+func (ta *testApp) pay(token, studentNo, term, amount string) *httptest.ResponseRecorder {
+	ta.t.Helper()
+	q := url.Values{"student_no": {studentNo}, "term": {term}, "amount": {amount}}
+	return ta.do(http.MethodPost, "/api/v2/banking/pay?"+q.Encode(), token, nil, nil)
+}
+
+func (ta *testApp) balance(studentNo string) float64 {
+	ta.t.Helper()
+	student, err := ta.app.Store.GetStudentById(context.Background(), studentNo)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+	return student.Balance
+}
+
+func (ta *testApp) tuitionTotal(studentNo, term string) float64 {
+	ta.t.Helper()
+	rows, err := ta.app.Store.GetTuitionByTerm(context.Background(), db.GetTuitionByTermParams{StudentNo: studentNo, Term: term})
+	if err != nil || len(rows) != 1 {
+		ta.t.Fatalf("tuition %s/%s: %v (%d rows)", studentNo, term, err, len(rows))
+	}
+	return rows[0].TuitionTotal
+}
+
+// "admin" is in the default ADMIN_SUBJECTS
+func adminToken(t *testing.T) string {
+	t.Helper()
+	token, err := GenerateJWT("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func decodeJSON[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatalf("cannot decode response %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func multipartFile(t *testing.T, field, filename, content string) (io.Reader, http.Header) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	mw.Close()
+	return &buf, http.Header{"Content-Type": {mw.FormDataContentType()}}
+}
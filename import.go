@@ -0,0 +1,597 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Import modes. An all_or_nothing import is rolled back if any row is invalid; a
+// skip_invalid import keeps the valid rows and reports the rest.
+const (
+	importAllOrNothing = "all_or_nothing"
+	importSkipInvalid  = "skip_invalid"
+)
+
+// What an importer did with a valid row
+const (
+	importCreated = "created"
+	importUpdated = "updated"
+	importSkipped = "skipped"
+)
+
+// Import file formats
+const (
+	formatCSV  = "csv"
+	formatXLSX = "xlsx"
+	formatJSON = "json"
+)
+
+var importContentTypes = map[string]string{
+	"text/csv":        formatCSV,
+	"application/csv": formatCSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": formatXLSX,
+	"application/json": formatJSON,
+}
+
+var importContentTypeOf = map[string]string{
+	formatCSV:  "text/csv",
+	formatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	formatJSON: "application/json",
+}
+
+// Used to roll back a dry run or a rejected all_or_nothing import
+var errImportRolledBack = errors.New("import rolled back")
+
+// importField is a column an import understands. Headers are matched against the
+// name and aliases after normalizeHeader.
+type importField struct {
+	Name     string
+	Aliases  []string
+	Required bool
+	// Shown in the template file
+	Example string
+}
+
+// importRow is one data row of an uploaded file, keyed by field name, with the
+// line it came from.
+type importRow struct {
+	Line   int
+	Values map[string]string
+}
+
+// importTable is an uploaded file ready to import.
+type importTable struct {
+	Rows []importRow
+	// Rows that could not be read, e.g. with a missing cell
+	Errors []importRowError
+}
+
+// importRowError is returned by an importer for a row it rejects.
+type importRowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *importRowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// rowError rejects the row being imported; runImport fills in the line.
+func rowError(field, message string) error {
+	return &importRowError{Field: field, Message: message}
+}
+
+type importOptions struct {
+	Mode   string
+	DryRun bool
+	// Field name -> header, for files whose headers match no alias
+	Mapping map[string]string
+}
+
+type importSummary struct {
+	Mode      string `json:"mode"`
+	DryRun    bool   `json:"dry_run"`
+	Committed bool   `json:"committed"`
+	Rows      int    `json:"rows"`
+	// Rows created or updated
+	Imported int              `json:"imported"`
+	Created  int              `json:"created"`
+	Updated  int              `json:"updated"`
+	Skipped  int              `json:"skipped"`
+	Invalid  int              `json:"invalid"`
+	Errors   []importRowError `json:"errors"`
+}
+
+// add counts the rows of o in s as well.
+func (s *importSummary) add(o importSummary) {
+	s.Imported += o.Imported
+	s.Created += o.Created
+	s.Updated += o.Updated
+	s.Skipped += o.Skipped
+	s.Errors = slices.Concat(s.Errors, o.Errors)
+}
+
+// discard reports that the rows counted as saved were rolled back after all.
+func (s *importSummary) discard() {
+	s.Imported, s.Created, s.Updated = 0, 0, 0
+}
+
+// importer turns rows into writes. Apply runs in its own savepoint, so a row it
+// rejects with rowError leaves nothing behind; any other error aborts the import.
+// It returns importCreated, importUpdated or importSkipped for a row it accepts.
+type importer struct {
+	Fields []importField
+	Apply  func(ctx context.Context, tx Store, row importRow) (string, error)
+}
+
+// requiredValue returns the value of a field every row must have.
+func requiredValue(row importRow, field string) (string, error) {
+	v := row.Values[field]
+	if v == "" {
+		return "", rowError(field, field+" is required")
+	}
+	return v, nil
+}
+
+// numberValue parses a numeric field; ok is false when the cell is empty.
+func numberValue(row importRow, field string) (n float64, ok bool, err error) {
+	v := row.Values[field]
+	if v == "" {
+		return 0, false, nil
+	}
+	n, err = strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false, rowError(field, field+" is not a number")
+	}
+	return n, true, nil
+}
+
+// integerValue parses a whole number field; ok is false when the cell is empty.
+// Spreadsheets may store 2022 as 2022.0, which is accepted.
+func integerValue(row importRow, field string) (n int32, ok bool, err error) {
+	f, ok, err := numberValue(row, field)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+		return 0, false, rowError(field, field+" must be a whole number")
+	}
+	return int32(f), true, nil
+}
+
+// parseImportOptions reads mode, dry_run and map.<field>=<header> from the form.
+func parseImportOptions(form url.Values) (importOptions, bool) {
+	opts := importOptions{Mode: form.Get("mode"), Mapping: map[string]string{}}
+	if opts.Mode == "" {
+		opts.Mode = importAllOrNothing
+	}
+	if opts.Mode != importAllOrNothing && opts.Mode != importSkipInvalid {
+		return opts, false
+	}
+	dryRun, ok := parseBoolFilter(form, "dry_run")
+	if !ok {
+		return opts, false
+	}
+	opts.DryRun = dryRun.Bool
+	for key, values := range form {
+		if field, ok := strings.CutPrefix(key, "map."); ok && len(values) > 0 {
+			opts.Mapping[field] = values[0]
+		}
+	}
+	return opts, true
+}
+
+// normalizeHeader makes "Student No", "student-no" and "STUDENT_NO" the same column.
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+}
+
+// mapColumns finds the column of every field in header. Missing required columns
+// are reported as errors on line 1.
+func mapColumns(header []string, fields []importField, mapping map[string]string) (map[string]int, []importRowError) {
+	index := map[string]int{}
+	for i, h := range header {
+		if _, ok := index[normalizeHeader(h)]; !ok {
+			index[normalizeHeader(h)] = i
+		}
+	}
+
+	columns := map[string]int{}
+	var errs []importRowError
+	for _, f := range fields {
+		names := append([]string{f.Name}, f.Aliases...)
+		if h, ok := mapping[f.Name]; ok {
+			names = []string{h}
+		}
+		for _, name := range names {
+			if i, ok := index[normalizeHeader(name)]; ok {
+				columns[f.Name] = i
+				break
+			}
+		}
+		if _, ok := columns[f.Name]; !ok && f.Required {
+			errs = append(errs, importRowError{Line: 1, Field: f.Name, Message: "missing column"})
+		}
+	}
+	return columns, errs
+}
+
+// newImportTable maps the records of a table whose first record is the header.
+// line gives the file line of a record. The errors returned are about the file as
+// a whole, like a missing column; nothing can be imported from it then.
+func newImportTable(records [][]string, line func(i int) int, fields []importField, mapping map[string]string) (importTable, []importRowError) {
+	var table importTable
+	if len(records) == 0 {
+		return table, []importRowError{{Line: 1, Message: "the file is empty"}}
+	}
+	columns, errs := mapColumns(records[0], fields, mapping)
+	if len(errs) > 0 {
+		return table, errs
+	}
+
+	for i, record := range records[1:] {
+		n := line(i + 1)
+		if slices.IndexFunc(record, func(s string) bool { return strings.TrimSpace(s) != "" }) < 0 {
+			continue
+		}
+		if len(record) != len(records[0]) {
+			table.Errors = append(table.Errors, importRowError{Line: n, Message: fmt.Sprintf("expected %d columns, found %d", len(records[0]), len(record))})
+			continue
+		}
+		row := importRow{Line: n, Values: map[string]string{}}
+		for field, col := range columns {
+			row.Values[field] = strings.TrimSpace(record[col])
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// readCSVRows reads a CSV upload. Malformed CSV is reported with its line.
+func readCSVRows(r io.Reader, fields []importField, mapping map[string]string) (importTable, []importRowError) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return importTable{}, []importRowError{{Line: parseErr.Line, Message: parseErr.Err.Error()}}
+		}
+		if err != nil {
+			return importTable{}, []importRowError{{Line: len(lines) + 1, Message: err.Error()}}
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return newImportTable(records, func(i int) int { return lines[i] }, fields, mapping)
+}
+
+// readXLSXRows reads the first sheet of an XLSX workbook. Cells are read as stored,
+// not as displayed, so 1,000.00 comes through as 1000.
+func readXLSXRows(r io.Reader, fields []importField, mapping map[string]string) (importTable, []importRowError) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return importTable{}, []importRowError{{Line: 1, Message: "the file is not an XLSX workbook"}}
+	}
+	defer f.Close()
+	records, err := f.GetRows(f.GetSheetName(0), excelize.Options{RawCellValue: true})
+	if err != nil {
+		return importTable{}, []importRowError{{Line: 1, Message: err.Error()}}
+	}
+	// Excel leaves out the empty cells at the end of a row
+	for i := 1; i < len(records); i++ {
+		if len(records[i]) < len(records[0]) {
+			records[i] = append(records[i], make([]string, len(records[0])-len(records[i]))...)
+		}
+	}
+	return newImportTable(records, func(i int) int { return i + 1 }, fields, mapping)
+}
+
+// readJSONRows reads a JSON array of objects, one row per object, with the keys as
+// headers. A row's line is the line its object starts on.
+func readJSONRows(r io.Reader, fields []importField, mapping map[string]string) (importTable, []importRowError) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return importTable{}, []importRowError{{Line: 1, Message: err.Error()}}
+	}
+	// The decoder's offset is before the separator and space ahead of an object
+	lineAt := func(offset int64) int {
+		for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+			offset++
+		}
+		return 1 + bytes.Count(data[:offset], []byte("\n"))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return importTable{}, []importRowError{{Line: 1, Message: "the file must be a JSON array of objects"}}
+	}
+	var objects []map[string]any
+	var lines []int
+	keys := map[string]bool{}
+	for dec.More() {
+		line := lineAt(dec.InputOffset())
+		var object map[string]any
+		if err := dec.Decode(&object); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return importTable{}, []importRowError{{Line: line, Message: "every item must be an object"}}
+			}
+			return importTable{}, []importRowError{{Line: lineAt(dec.InputOffset()), Message: err.Error()}}
+		}
+		for key := range object {
+			keys[key] = true
+		}
+		objects = append(objects, object)
+		lines = append(lines, line)
+	}
+	if len(objects) == 0 {
+		return importTable{}, nil
+	}
+
+	header := slices.Sorted(maps.Keys(keys))
+	records := [][]string{header}
+	for _, object := range objects {
+		record := make([]string, len(header))
+		for i, key := range header {
+			switch v := object[key].(type) {
+			case nil:
+			case string:
+				record[i] = v
+			case json.Number:
+				record[i] = v.String()
+			default:
+				raw, _ := json.Marshal(v)
+				record[i] = string(raw)
+			}
+		}
+		records = append(records, record)
+	}
+	return newImportTable(records, func(i int) int { return lines[i-1] }, fields, mapping)
+}
+
+// detectImportFormat tells the format of an upload from its content type, or from
+// its extension when the content type is generic. Anything else is read as CSV.
+func detectImportFormat(filename, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if format, ok := importContentTypes[mediaType]; ok {
+			return format
+		}
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		return formatXLSX
+	case ".json":
+		return formatJSON
+	}
+	return formatCSV
+}
+
+// readImportFile reads an upload in one of the supported formats.
+func readImportFile(format string, r io.Reader, fields []importField, mapping map[string]string) (importTable, []importRowError) {
+	switch format {
+	case formatCSV:
+		return readCSVRows(r, fields, mapping)
+	case formatXLSX:
+		return readXLSXRows(r, fields, mapping)
+	case formatJSON:
+		return readJSONRows(r, fields, mapping)
+	}
+	return importTable{}, []importRowError{{Line: 1, Message: "unsupported file format " + format}}
+}
+
+// applyRows applies rows in tx, each in its own savepoint, and adds the outcome to
+// summary. progress, when set, is called after every row.
+func applyRows(ctx context.Context, tx Store, imp importer, rows []importRow, summary *importSummary, progress func()) error {
+	for _, row := range rows {
+		var outcome string
+		err := tx.WithTx(ctx, func(rowTx Store) error {
+			var err error
+			outcome, err = imp.Apply(ctx, rowTx, row)
+			return err
+		})
+		var rowErr *importRowError
+		if errors.As(err, &rowErr) {
+			rowErr.Line = row.Line
+			summary.Errors = append(summary.Errors, *rowErr)
+		} else if err != nil {
+			return fmt.Errorf("line %d: %w", row.Line, err)
+		} else {
+			switch outcome {
+			case importCreated:
+				summary.Created++
+			case importUpdated:
+				summary.Updated++
+			}
+			if outcome == importSkipped {
+				summary.Skipped++
+			} else {
+				summary.Imported++
+			}
+		}
+		if progress != nil {
+			progress()
+		}
+	}
+	return nil
+}
+
+// rejectsImport tells whether the import must be rolled back.
+func rejectsImport(opts importOptions, invalid int) bool {
+	return opts.DryRun || (opts.Mode == importAllOrNothing && invalid > 0)
+}
+
+// runImport applies a table in one transaction. Rows that could not be read count
+// as invalid. The transaction is rolled back on a dry run, and in all_or_nothing
+// mode when any row is invalid.
+func runImport(ctx context.Context, store Store, imp importer, table importTable, opts importOptions) (importSummary, error) {
+	summary := importSummary{
+		Mode:   opts.Mode,
+		DryRun: opts.DryRun,
+		Rows:   len(table.Rows) + len(table.Errors),
+		Errors: append([]importRowError{}, table.Errors...),
+	}
+
+	err := store.WithTx(ctx, func(tx Store) error {
+		if err := applyRows(ctx, tx, imp, table.Rows, &summary, nil); err != nil {
+			return err
+		}
+		if rejectsImport(opts, len(summary.Errors)) {
+			return errImportRolledBack
+		}
+		return nil
+	})
+
+	slices.SortStableFunc(summary.Errors, func(a, b importRowError) int { return a.Line - b.Line })
+	summary.Invalid = len(summary.Errors)
+	// A dry run reports what the real import would have done
+	if opts.Mode == importAllOrNothing && summary.Invalid > 0 {
+		summary.discard()
+	}
+	if errors.Is(err, errImportRolledBack) {
+		return summary, nil
+	}
+	summary.Committed = err == nil
+	return summary, err
+}
+
+// writeImport runs the import and responds with its summary: 400 when the file
+// cannot be imported at all, 422 when an all_or_nothing import was rejected.
+func (a *App) writeImport(w http.ResponseWriter, r *http.Request, imp importer, table importTable, fileErrs []importRowError, opts importOptions) {
+	summary := importSummary{Mode: opts.Mode, DryRun: opts.DryRun, Invalid: len(fileErrs), Errors: fileErrs}
+	status := http.StatusBadRequest
+	if len(fileErrs) == 0 {
+		var err error
+		summary, err = runImport(r.Context(), a.Store, imp, table, opts)
+		if err != nil {
+			http.Error(w, `{"error":"Import failed"}`, http.StatusInternalServerError)
+			return
+		}
+		status = http.StatusOK
+		if !summary.Committed && !summary.DryRun {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// handleImport imports the file uploaded in the file field with the importer of a
+// job kind, at once or, with async=true, as a background job.
+func (a *App) handleImport(w http.ResponseWriter, r *http.Request, kind string) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, `{"error":"a CSV, XLSX or JSON file is required in the file field"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts, ok := parseImportOptions(r.Form)
+	if !ok {
+		http.Error(w, `{"error":"mode must be all_or_nothing or skip_invalid and dry_run true or false"}`, http.StatusBadRequest)
+		return
+	}
+	async, ok := parseBoolFilter(r.Form, "async")
+	if !ok {
+		http.Error(w, `{"error":"async must be true or false"}`, http.StatusBadRequest)
+		return
+	}
+	format := detectImportFormat(header.Filename, header.Header.Get("Content-Type"))
+	if async.Bool {
+		payload, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, `{"error":"the file cannot be read"}`, http.StatusBadRequest)
+			return
+		}
+		a.queueImport(w, r, kind, format, header.Filename, payload, opts)
+		return
+	}
+
+	imp := jobImporters[kind]
+	table, fileErrs := readImportFile(format, file, imp.Fields, opts.Mapping)
+	a.writeImport(w, r, imp, table, fileErrs, opts)
+}
+
+// writeImportTemplate responds with a file in the requested format (format=csv,
+// xlsx or json) holding the columns of an import and an example row.
+func writeImportTemplate(w http.ResponseWriter, r *http.Request, kind string) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatCSV
+	}
+	contentType, ok := importContentTypeOf[format]
+	if !ok {
+		http.Error(w, `{"error":"format must be csv, xlsx or json"}`, http.StatusBadRequest)
+		return
+	}
+
+	var header, example []string
+	for _, f := range jobImporters[kind].Fields {
+		header = append(header, f.Name)
+		example = append(example, f.Example)
+	}
+	var buf bytes.Buffer
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(&buf)
+		cw.WriteAll([][]string{header, example})
+	case formatXLSX:
+		f := excelize.NewFile()
+		defer f.Close()
+		sheet := f.GetSheetName(0)
+		for i, values := range [][]string{header, example} {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			// Text cells, so student numbers are not shown as 2.21E+10
+			row := make([]any, len(values))
+			for j, v := range values {
+				row[j] = v
+			}
+			if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+				http.Error(w, `{"error":"Template cannot be created"}`, http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := f.Write(&buf); err != nil {
+			http.Error(w, `{"error":"Template cannot be created"}`, http.StatusInternalServerError)
+			return
+		}
+	case formatJSON:
+		object := map[string]string{}
+		for i, name := range header {
+			object[name] = example[i]
+		}
+		data, _ := json.MarshalIndent([]map[string]string{object}, "", "  ")
+		buf.Write(append(data, '\n'))
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-template.%s"`, kind, format))
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func (ta *testApp) importTuitions(query, content string) (int, importSummary) {
+	ta.t.Helper()
+	body, header := multipartFile(ta.t, "file", "tuitions.csv", content)
+	rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch"+query, adminToken(ta.t), body, header)
+	return rec.Code, decodeJSON[importSummary](ta.t, rec)
+}
+
+func (ta *testApp) tuitionCount() int64 {
+	ta.t.Helper()
+	rec := ta.do(http.MethodGet, "/api/v2/admin/tuitions", adminToken(ta.t), nil, nil)
+	return decodeJSON[struct{ Total int64 }](ta.t, rec).Total
+}
+
+func TestImportTuitions(t *testing.T) {
+	// Line 3 has an unknown student, line 5 a bad amount and line 6 repeats line 2
+	const content = "\ufeffTerm,Amount,Student No\n" +
+		"Fall2025,1000,22070006071\n" +
+		"Fall2025,1000,22070009999\n" +
+		"Fall2025,1500,22070006072\n" +
+		"Fall2025,ten,22070006073\n" +
+		"Fall2025,1000,22070006071\n"
+
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		for _, no := range []string{"22070006071", "22070006072", "22070006073"} {
+			ta.addStudent(no, 10)
+		}
+		ta.addTerm("Fall2025")
+
+		code, got := ta.importTuitions("", content)
+		if code != http.StatusUnprocessableEntity || got.Committed || got.Rows != 5 || got.Imported != 0 || got.Invalid != 3 {
+			t.Fatalf("all_or_nothing: %d %+v", code, got)
+		}
+		lines := []int{}
+		for _, e := range got.Errors {
+			lines = append(lines, e.Line)
+		}
+		if len(lines) != 3 || lines[0] != 3 || lines[1] != 5 || lines[2] != 6 || got.Errors[1].Field != "amount" {
+			t.Errorf("unexpected errors %+v", got.Errors)
+		}
+		if n := ta.tuitionCount(); n != 0 {
+			t.Errorf("rejected import left %d tuitions", n)
+		}
+
+		code, got = ta.importTuitions("?mode=skip_invalid&dry_run=true", content)
+		if code != http.StatusOK || got.Committed || !got.DryRun || got.Imported != 2 || got.Invalid != 3 {
+			t.Errorf("dry run: %d %+v", code, got)
+		}
+		if n := ta.tuitionCount(); n != 0 {
+			t.Errorf("dry run left %d tuitions", n)
+		}
+
+		code, got = ta.importTuitions("?mode=skip_invalid", content)
+		if code != http.StatusOK || !got.Committed || got.Imported != 2 || got.Invalid != 3 {
+			t.Fatalf("skip_invalid: %d %+v", code, got)
+		}
+		if total := ta.tuitionTotal("22070006072", "Fall2025"); total != 1500 {
+			t.Errorf("imported tuition = %v, want 1500", total)
+		}
+	})
+}
+
+func TestImportTuitionsFileErrors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("Fall2025")
+
+		code, got := ta.importTuitions("", "student_no,term\n22070006071,Fall2025\n")
+		if code != http.StatusBadRequest || len(got.Errors) != 1 || got.Errors[0].Field != "amount" {
+			t.Errorf("missing column: %d %+v", code, got)
+		}
+		code, got = ta.importTuitions("", "student_no,term,amount\n22070006071,\"Fall2025,1000\n")
+		if code != http.StatusBadRequest || len(got.Errors) != 1 || got.Errors[0].Line != 2 {
+			t.Errorf("malformed csv: %d %+v", code, got)
+		}
+
+		// Short rows are reported, and a header can be mapped by hand
+		code, got = ta.importTuitions("?mode=skip_invalid&map.amount=Fee", "student_no,term,Fee\n22070006071,Fall2025\n22070006071,Fall2025,1000\n")
+		if code != http.StatusOK || got.Imported != 1 || len(got.Errors) != 1 || got.Errors[0].Line != 2 {
+			t.Errorf("mapped import: %d %+v", code, got)
+		}
+
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch?mode=best_effort", adminToken(t), nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown mode: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestImportFormats(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		for _, no := range []string{"22070006071", "22070006072", "22070006073"} {
+			ta.addStudent(no, 10)
+		}
+		ta.addTerm("Fall2025")
+
+		book := excelize.NewFile()
+		book.SetSheetRow("Sheet1", "A1", &[]any{"Student No", "Term", "Amount"})
+		book.SetSheetRow("Sheet1", "A2", &[]any{22070006071, "Fall2025", 1000.5})
+		// Row 3 is left empty, and row 4 has no amount
+		book.SetSheetRow("Sheet1", "A4", &[]any{"22070006072", "Fall2025"})
+		book.SetCellStyle("Sheet1", "C2", "C2", 4)
+		var xlsx bytes.Buffer
+		book.Write(&xlsx)
+
+		body, header := multipartFile(t, "file", "tuitions.xlsx", xlsx.String())
+		rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch?mode=skip_invalid", adminToken(t), body, header)
+		got := decodeJSON[importSummary](t, rec)
+		if rec.Code != http.StatusOK || got.Imported != 1 || len(got.Errors) != 1 || got.Errors[0].Line != 4 || got.Errors[0].Field != "amount" {
+			t.Fatalf("xlsx import: %d %+v", rec.Code, got)
+		}
+		if total := ta.tuitionTotal("22070006071", "Fall2025"); total != 1000.5 {
+			t.Errorf("xlsx tuition = %v, want 1000.5", total)
+		}
+
+		const content = `[
+  {"student_no": "22070006072", "term": "Fall2025", "amount": 1500},
+  {"student_no": "22070006073", "term": "Fall2025", "amount": "abc"}
+]`
+		body, header = multipartFile(t, "file", "tuitions.json", content)
+		rec = ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch?mode=skip_invalid", adminToken(t), body, header)
+		got = decodeJSON[importSummary](t, rec)
+		if rec.Code != http.StatusOK || got.Imported != 1 || len(got.Errors) != 1 || got.Errors[0].Line != 3 {
+			t.Fatalf("json import: %d %+v", rec.Code, got)
+		}
+
+		body, header = multipartFile(t, "file", "tuitions.json", `{"student_no": "22070006073"}`)
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch", adminToken(t), body, header); rec.Code != http.StatusBadRequest {
+			t.Errorf("json object: got %d, want 400", rec.Code)
+		}
+		body, header = multipartFile(t, "file", "tuitions.xlsx", "student_no,term,amount\n")
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch", adminToken(t), body, header); rec.Code != http.StatusBadRequest {
+			t.Errorf("csv named .xlsx: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestImportTemplates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("Fall2025")
+
+		// Every template imports its example row
+		for _, format := range []string{"csv", "xlsx", "json"} {
+			rec := ta.do(http.MethodGet, "/api/v2/admin/add-tuition-batch/template?format="+format, adminToken(t), nil, nil)
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != importContentTypeOf[format] {
+				t.Fatalf("%s template: %d %s", format, rec.Code, rec.Header().Get("Content-Type"))
+			}
+			body, header := multipartFile(t, "file", "template."+format, rec.Body.String())
+			rec = ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch?dry_run=true", adminToken(t), body, header)
+			if got := decodeJSON[importSummary](t, rec); got.Imported != 1 || got.Invalid != 0 {
+				t.Errorf("%s template import: %d %+v", format, rec.Code, got)
+			}
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/add-tuition-batch/template?format=ods", adminToken(t), nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown format: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	for _, tt := range []struct{ filename, contentType, want string }{
+		{"tuitions.csv", "application/octet-stream", formatCSV},
+		{"tuitions.XLSX", "", formatXLSX},
+		{"export", "application/json; charset=utf-8", formatJSON},
+		{"tuitions.txt", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", formatXLSX},
+		{"tuitions", "", formatCSV},
+	} {
+		if got := detectImportFormat(tt.filename, tt.contentType); got != tt.want {
+			t.Errorf("detectImportFormat(%q, %q) = %q, want %q", tt.filename, tt.contentType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dogukan-dev/tuition/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// A running job whose heartbeat is older than jobLease is taken over by another
+	// worker. ClaimImportJob has the same interval written out.
+	jobLease     = time.Minute
+	jobHeartbeat = 15 * time.Second
+	// Workers look for jobs queued by other instances this often
+	jobPoll = 2 * time.Second
+	// Rows a skip_invalid job commits at a time
+	jobChunkSize         = 100
+	defaultImportWorkers = 2
+)
+
+var jobStatuses = []string{"queued", "running", "completed", "failed", "cancelled"}
+
+var errJobCancelled = errors.New("import job cancelled")
+
+// jobImporters are the imports that can run as background jobs, by job kind.
+var jobImporters = map[string]importer{
+	"tuitions":          tuitionImporter,
+	"students":          studentImporter,
+	"settlement_totals": settlementTotalsImporter,
+}
+
+// liveJob is a job running in this process. Its progress is kept here as well, so
+// it can be reported without waiting on the transaction that is importing it.
+type liveJob struct {
+	job    db.ImportJob
+	errors []importRowError
+	cancel context.CancelCauseFunc
+}
+
+// jobRunner runs queued import jobs on a pool of workers. Jobs live in the
+// database, so a job left running by a stopped instance is resumed by whichever
+// worker claims it once its lease runs out.
+type jobRunner struct {
+	store   Store
+	workers int
+	wake    chan struct{}
+
+	mu   sync.Mutex
+	live map[int32]*liveJob
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+func newJobRunner(store Store, workers int) *jobRunner {
+	return &jobRunner{
+		store:   store,
+		workers: workers,
+		wake:    make(chan struct{}, 1),
+		live:    map[int32]*liveJob{},
+	}
+}
+
+func (j *jobRunner) Start(ctx context.Context) {
+	ctx, j.stop = context.WithCancel(ctx)
+	for range j.workers {
+		j.wg.Add(1)
+		go func() {
+			defer j.wg.Done()
+			j.work(ctx)
+		}()
+	}
+}
+
+// Stop stops the workers and waits for them. Jobs they were running stay running,
+// to be resumed after the restart.
+func (j *jobRunner) Stop() {
+	j.stop()
+	j.wg.Wait()
+}
+
+// notify wakes a worker for a job just queued. A nil runner does nothing; the job
+// waits for a runner to claim it.
+func (j *jobRunner) notify() {
+	if j == nil {
+		return
+	}
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+// cancel stops a job if it runs in this process.
+func (j *jobRunner) cancel(jobID int32) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if l, ok := j.live[jobID]; ok {
+		l.cancel(errJobCancelled)
+	}
+}
+
+// snapshot returns the state of a job running in this process.
+func (j *jobRunner) snapshot(jobID int32) (db.ImportJob, []importRowError, bool) {
+	if j == nil {
+		return db.ImportJob{}, nil, false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	l, ok := j.live[jobID]
+	if !ok {
+		return db.ImportJob{}, nil, false
+	}
+	return l.job, slices.Clone(l.errors), true
+}
+
+func (j *jobRunner) update(fn func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn()
+}
+
+func (j *jobRunner) work(ctx context.Context) {
+	for {
+		for j.claim(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-j.wake:
+		case <-time.After(jobPoll):
+		}
+	}
+}
+
+// claim runs the next job, if there is one.
+func (j *jobRunner) claim(ctx context.Context) bool {
+	job, err := j.store.ClaimImportJob(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("import jobs: cannot claim a job: %v", err)
+		}
+		return false
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	j.mu.Lock()
+	// Its heartbeat was held up by a long transaction; it is still ours
+	if _, ok := j.live[job.JobID]; ok {
+		j.mu.Unlock()
+		return true
+	}
+	l := &liveJob{job: job, cancel: cancel}
+	j.live[job.JobID] = l
+	j.mu.Unlock()
+	defer j.update(func() { delete(j.live, job.JobID) })
+
+	j.run(jobCtx, l)
+	return ctx.Err() == nil
+}
+
+// run processes a job and records how it ended, unless the runner is stopping.
+func (j *jobRunner) run(ctx context.Context, l *liveJob) {
+	go j.heartbeat(ctx, l)
+
+	err := j.process(ctx, l)
+	if err == nil {
+		return
+	}
+	cancelled := errors.Is(context.Cause(ctx), errJobCancelled)
+	if ctx.Err() != nil && !cancelled {
+		return
+	}
+
+	status, failure := "cancelled", ""
+	if !cancelled {
+		status, failure = "failed", err.Error()
+		log.Printf("import jobs: job %d failed: %v", l.job.JobID, err)
+	}
+	if len(failure) > jobFailureMaxLength {
+		failure = strings.ToValidUTF8(failure[:jobFailureMaxLength], "")
+	}
+	ctx = context.WithoutCancel(ctx)
+	err = j.store.WithTx(ctx, func(tx Store) error {
+		return j.finish(ctx, tx, l, status, failure)
+	})
+	if err != nil {
+		log.Printf("import jobs: cannot finish job %d: %v", l.job.JobID, err)
+	}
+}
+
+// heartbeat keeps the lease of a job and notices cancellations made on other
+// instances. Errors are left to the lease: if the heartbeat stops, the job is
+// eventually run again.
+func (j *jobRunner) heartbeat(ctx context.Context, l *liveJob) {
+	ticker := time.NewTicker(jobHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		j.store.HeartbeatImportJob(ctx, l.job.JobID)
+		if job, err := j.store.GetImportJob(ctx, l.job.JobID); err == nil && job.CancelRequested {
+			l.cancel(errJobCancelled)
+		}
+	}
+}
+
+func (j *jobRunner) process(ctx context.Context, l *liveJob) error {
+	job := l.job
+	if job.CancelRequested {
+		l.cancel(errJobCancelled)
+		return errJobCancelled
+	}
+	imp, ok := jobImporters[job.Kind]
+	if !ok {
+		return fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(job.Mapping), &mapping); err != nil {
+		return fmt.Errorf("mapping: %w", err)
+	}
+	payload, err := j.store.GetImportJobFile(ctx, job.JobID)
+	if err != nil {
+		return fmt.Errorf("file: %w", err)
+	}
+	table, fileErrs := readImportFile(job.Format, bytes.NewReader(payload), imp.Fields, mapping)
+	if len(fileErrs) > 0 {
+		return &fileErrs[0]
+	}
+
+	opts := importOptions{Mode: job.Mode, DryRun: job.DryRun}
+	if opts.Mode == importSkipInvalid && !opts.DryRun {
+		return j.processChunks(ctx, l, imp, table)
+	}
+	return j.processAll(ctx, l, imp, table, opts)
+}
+
+// setProgress reports the rows of summary as the live progress of a job.
+func (j *jobRunner) setProgress(l *liveJob, processed int, summary importSummary) {
+	j.update(func() {
+		l.job.ProcessedRows = int32(processed)
+		l.job.Imported = int32(summary.Imported)
+		l.job.Created = int32(summary.Created)
+		l.job.Updated = int32(summary.Updated)
+		l.job.Skipped = int32(summary.Skipped)
+		l.job.Invalid = int32(len(summary.Errors))
+		l.errors = slices.Clone(summary.Errors)
+	})
+}
+
+// processAll imports the whole table in one transaction, like a synchronous
+// import. Nothing is committed before the end, so a resumed job starts over.
+func (j *jobRunner) processAll(ctx context.Context, l *liveJob, imp importer, table importTable, opts importOptions) error {
+	summary := importSummary{Errors: slices.Clone(table.Errors)}
+	processed := len(table.Errors)
+	j.setProgress(l, processed, summary)
+
+	err := j.store.WithTx(ctx, func(tx Store) error {
+		err := applyRows(ctx, tx, imp, table.Rows, &summary, func() {
+			processed++
+			j.setProgress(l, processed, summary)
+		})
+		if err != nil {
+			return err
+		}
+		if rejectsImport(opts, len(summary.Errors)) {
+			return errImportRolledBack
+		}
+		j.update(func() { l.job.Committed = true })
+		return j.finish(ctx, tx, l, "completed", "")
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		summary.discard()
+		j.setProgress(l, processed, summary)
+		j.update(func() { l.job.Committed = false })
+		return err
+	}
+	if err == nil {
+		return nil
+	}
+
+	// A rejected import or a dry run only leaves its report
+	if opts.Mode == importAllOrNothing && len(summary.Errors) > 0 {
+		summary.discard()
+		j.setProgress(l, processed, summary)
+	}
+	return j.store.WithTx(ctx, func(tx Store) error {
+		if err := addJobErrors(ctx, tx, l.job.JobID, summary.Errors); err != nil {
+			return err
+		}
+		return j.finish(ctx, tx, l, "completed", "")
+	})
+}
+
+// processChunks imports a skip_invalid table jobChunkSize rows at a time. Each
+// chunk commits with its errors and the job's progress, and a resumed job carries
+// on after the last chunk committed.
+func (j *jobRunner) processChunks(ctx context.Context, l *liveJob, imp importer, table importTable) error {
+	id := l.job.JobID
+	done := int(l.job.ProcessedRows)
+	committed := importSummary{
+		Imported: int(l.job.Imported),
+		Created:  int(l.job.Created),
+		Updated:  int(l.job.Updated),
+		Skipped:  int(l.job.Skipped),
+	}
+	recorded, err := j.store.ListImportJobErrors(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, e := range recorded {
+		committed.Errors = append(committed.Errors, importRowError{Line: int(e.Line), Field: e.Field, Message: e.Message})
+	}
+	// Rows that could not be read go with the first chunk
+	pending, start := table.Errors, 0
+	if done > 0 {
+		pending, start = nil, done-len(table.Errors)
+		j.update(func() { l.job.Committed = true })
+	}
+
+	for start < len(table.Rows) || pending != nil {
+		if current, err := j.store.GetImportJob(ctx, id); err != nil {
+			return err
+		} else if current.CancelRequested {
+			l.cancel(errJobCancelled)
+			return errJobCancelled
+		}
+
+		end := min(start+jobChunkSize, len(table.Rows))
+		chunk := importSummary{Errors: pending}
+		processed := done + len(pending)
+		progress := func() {
+			total := committed
+			total.add(chunk)
+			j.setProgress(l, processed, total)
+		}
+		progress()
+
+		err := j.store.WithTx(ctx, func(tx Store) error {
+			err := applyRows(ctx, tx, imp, table.Rows[start:end], &chunk, func() {
+				processed++
+				progress()
+			})
+			if err != nil {
+				return err
+			}
+			if err := addJobErrors(ctx, tx, id, chunk.Errors); err != nil {
+				return err
+			}
+			total := committed
+			total.add(chunk)
+			return tx.UpdateImportJobProgress(ctx, db.UpdateImportJobProgressParams{
+				JobID:         id,
+				ProcessedRows: int32(processed),
+				Imported:      int32(total.Imported),
+				Invalid:       int32(len(total.Errors)),
+				Created:       int32(total.Created),
+				Updated:       int32(total.Updated),
+				Skipped:       int32(total.Skipped),
+			})
+		})
+		if err != nil {
+			// Report what was committed
+			j.setProgress(l, done, committed)
+			return err
+		}
+		done, start, pending = processed, end, nil
+		committed.add(chunk)
+		j.update(func() { l.job.Committed = true })
+	}
+
+	return j.store.WithTx(ctx, func(tx Store) error {
+		return j.finish(ctx, tx, l, "completed", "")
+	})
+}
+
+func addJobErrors(ctx context.Context, tx Store, jobID int32, errs []importRowError) error {
+	for _, e := range errs {
+		err := tx.AddImportJobError(ctx, db.AddImportJobErrorParams{
+			JobID:   jobID,
+			Line:    int32(e.Line),
+			Field:   e.Field,
+			Message: e.Message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish records the end of a job with its live counts and drops its file.
+func (j *jobRunner) finish(ctx context.Context, tx Store, l *liveJob, status, failure string) error {
+	var job db.ImportJob
+	j.update(func() { job = l.job })
+	err := tx.FinishImportJob(ctx, db.FinishImportJobParams{
+		JobID:         job.JobID,
+		Status:        status,
+		ProcessedRows: job.ProcessedRows,
+		Imported:      job.Imported,
+		Invalid:       job.Invalid,
+		Committed:     job.Committed,
+		Failure:       failure,
+		Created:       job.Created,
+		Updated:       job.Updated,
+		Skipped:       job.Skipped,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.DeleteImportJobFile(ctx, job.JobID)
+}
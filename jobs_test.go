@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startJobs gives the app a running job runner.
+func (ta *testApp) startJobs() {
+	ta.app.Jobs = newJobRunner(ta.app.Store, 2)
+	ta.app.Jobs.Start(context.Background())
+	ta.t.Cleanup(ta.app.Jobs.Stop)
+}
+
+func (ta *testApp) queueTuitions(query, content string) importJobResponse {
+	ta.t.Helper()
+	body, header := multipartFile(ta.t, "file", "tuitions.csv", content)
+	rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch?async=true"+query, adminToken(ta.t), body, header)
+	if rec.Code != http.StatusAccepted {
+		ta.t.Fatalf("queue import: %d %s", rec.Code, rec.Body)
+	}
+	job := decodeJSON[importJobResponse](ta.t, rec)
+	if want := fmt.Sprintf("/api/v2/admin/jobs/%d", job.JobID); rec.Header().Get("Location") != want {
+		ta.t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), want)
+	}
+	return job
+}
+
+func (ta *testApp) job(jobID int32) importJobResponse {
+	ta.t.Helper()
+	rec := ta.do(http.MethodGet, fmt.Sprintf("/api/v2/admin/jobs/%d", jobID), adminToken(ta.t), nil, nil)
+	if rec.Code != http.StatusOK {
+		ta.t.Fatalf("get job %d: %d %s", jobID, rec.Code, rec.Body)
+	}
+	return decodeJSON[importJobResponse](ta.t, rec)
+}
+
+// waitJob polls a job until it has finished.
+func (ta *testApp) waitJob(jobID int32) importJobResponse {
+	ta.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job := ta.job(jobID)
+		if job.Status != "queued" && job.Status != "running" {
+			return job
+		}
+		if time.Now().After(deadline) {
+			ta.t.Fatalf("job %d did not finish: %+v", jobID, job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestImportJob(t *testing.T) {
+	// Line 3 has an unknown student and line 4 a bad amount
+	const content = "student_no,term,amount\n" +
+		"22070006071,Fall2025,1000\n" +
+		"22070009999,Fall2025,1000\n" +
+		"22070006072,Fall2025,ten\n" +
+		"22070006072,Fall2025,1500\n"
+
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTerm("Fall2025")
+		ta.startJobs()
+
+		queued := ta.queueTuitions("", content)
+		if queued.Status != "queued" || queued.TotalRows != 4 || queued.Kind != "tuitions" || queued.CreatedBy != "admin" {
+			t.Errorf("unexpected queued job %+v", queued)
+		}
+		rejected := ta.waitJob(queued.JobID)
+		if rejected.Status != "completed" || rejected.Committed || rejected.Imported != 0 || rejected.Invalid != 2 || len(rejected.Errors) != 2 {
+			t.Fatalf("all_or_nothing job: %+v", rejected)
+		}
+		if rejected.Errors[0].Line != 3 || rejected.Errors[1].Field != "amount" {
+			t.Errorf("unexpected errors %+v", rejected.Errors)
+		}
+		if n := ta.tuitionCount(); n != 0 {
+			t.Errorf("rejected job left %d tuitions", n)
+		}
+
+		done := ta.waitJob(ta.queueTuitions("&mode=skip_invalid", content).JobID)
+		if done.Status != "completed" || !done.Committed || done.ProcessedRows != 4 || done.Imported != 2 || done.Created != 2 || done.Invalid != 2 || done.FinishedAt == nil {
+			t.Fatalf("skip_invalid job: %+v", done)
+		}
+		if total := ta.tuitionTotal("22070006072", "Fall2025"); total != 1500 {
+			t.Errorf("imported tuition = %v, want 1500", total)
+		}
+
+		rec := ta.do(http.MethodGet, "/api/v2/admin/jobs?status=completed", adminToken(t), nil, nil)
+		list := decodeJSON[struct {
+			Jobs  []importJobResponse `json:"jobs"`
+			Total int64               `json:"total"`
+		}](t, rec)
+		if list.Total != 2 || list.Jobs[0].JobID != done.JobID {
+			t.Errorf("unexpected job list %+v", list)
+		}
+
+		if rec := ta.do(http.MethodPost, fmt.Sprintf("/api/v2/admin/jobs/%d/cancel", done.JobID), adminToken(t), nil, nil); rec.Code != http.StatusConflict {
+			t.Errorf("cancelling a finished job: got %d, want 409", rec.Code)
+		}
+
+		// A file that cannot be imported at all is not queued
+		body, header := multipartFile(t, "file", "tuitions.csv", "student_no,term\n22070006071,Fall2025\n")
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/add-tuition-batch?async=true", adminToken(t), body, header); rec.Code != http.StatusBadRequest {
+			t.Errorf("missing column: got %d, want 400", rec.Code)
+		}
+	})
+}
+
+func TestCancelImportJob(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addTerm("Fall2025")
+
+		// No runner yet, so the job waits in the queue
+		queued := ta.queueTuitions("", "student_no,term,amount\n22070006071,Fall2025,1000\n")
+		rec := ta.do(http.MethodPost, fmt.Sprintf("/api/v2/admin/jobs/%d/cancel", queued.JobID), adminToken(t), nil, nil)
+		if got := decodeJSON[importJobResponse](t, rec); rec.Code != http.StatusOK || got.Status != "cancelled" || !got.CancelRequested {
+			t.Fatalf("cancel: %d %+v", rec.Code, got)
+		}
+
+		ta.startJobs()
+		ta.waitJob(ta.queueTuitions("", "student_no,term,amount\n").JobID)
+		if job := ta.job(queued.JobID); job.Status != "cancelled" || job.ProcessedRows != 0 {
+			t.Errorf("cancelled job ran: %+v", job)
+		}
+		if n := ta.tuitionCount(); n != 0 {
+			t.Errorf("cancelled job billed %d tuitions", n)
+		}
+
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/jobs/999", adminToken(t), nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown job: got %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestResumeImportJob(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		for _, no := range []string{"22070006071", "22070006072", "22070006073"} {
+			ta.addStudent(no, 10)
+		}
+		ta.addTerm("Fall2025")
+		queued := ta.queueTuitions("&mode=skip_invalid", "student_no,term,amount\n"+
+			"22070006071,Fall2025,1000\n"+
+			"22070006072,Fall2025,1000\n"+
+			"22070006073,Fall2025,1000\n")
+
+		// A worker that died after committing the first row
+		ctx := context.Background()
+		store := ta.app.Store
+		job, err := store.ClaimImportJob(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		err = store.UpdateImportJobProgress(ctx, db.UpdateImportJobProgressParams{JobID: job.JobID, ProcessedRows: 1, Imported: 1, Created: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.ClaimImportJob(ctx); err == nil {
+			t.Fatal("a job with a fresh heartbeat was claimed again")
+		}
+
+		// Another worker takes over once the lease runs out
+		job, _ = store.GetImportJob(ctx, queued.JobID)
+		runner := newJobRunner(store, 1)
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		runner.run(jobCtx, &liveJob{job: job, cancel: cancel})
+		cancel(nil)
+
+		done := ta.job(queued.JobID)
+		if done.Status != "completed" || done.ProcessedRows != 3 || done.Imported != 3 || done.Created != 3 || done.Invalid != 0 {
+			t.Fatalf("resumed job: %+v", done)
+		}
+		if n := ta.tuitionCount(); n != 3 {
+			t.Errorf("got %d tuitions, want 3", n)
+		}
+	})
+}
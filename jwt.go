@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
@@ -44,13 +46,31 @@ func extractToken(r *http.Request) string {
 }
 
 func GenerateJWT(studentNo string) (string, error) {
+	return generateJWT(studentNo, 24*time.Hour)
+}
+
+func generateJWT(subject string, ttl time.Duration) (string, error) {
 	JwtSecret := os.Getenv("JWT_SECRET")
 
 	claims := &jwt.RegisteredClaims{
-		Subject:   studentNo,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(JwtSecret))
 }
+
+// runIssueTokenCommand prints a token for SUBJECT, e.g. one listed in ADMIN_SUBJECTS.
+// Admins have no student account to log in with, so this is how they get a token.
+func runIssueTokenCommand(args []string) error {
+	if len(args) != 1 || args[0] == "" {
+		return errors.New("usage: issue-token SUBJECT")
+	}
+	token, err := GenerateJWT(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
@@ -2,19 +2,21 @@ package main
 
 import (
 	"context"
-	"dogukan-dev/tuition/db"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
 )
 
 type App struct {
-	Queries *db.Queries
+	Store   Store
 	Context context.Context
+	// Runs background imports; nil leaves queued jobs for another instance
+	Jobs *jobRunner
+	// Keeps computed reports for a while; nil computes every request
+	Reports *reportCache
 }
 
 func main() {
@@ -23,56 +25,174 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "issue-token" {
+		if err := runIssueTokenCommand(os.Args[2:]); err != nil {
+			log.Fatalf("issue-token: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
-	conn, err := pgx.Connect(ctx, os.Getenv("DATABASE_CONNECTION"))
+	shutdownTracer, err := initTracer(ctx)
+	if err != nil {
+		log.Fatalf("cannot initialize tracing: %v", err)
+	}
+	defer shutdownTracer(context.Background())
+
+	store, migrator, err := openStore(ctx)
 	if err != nil {
-		fmt.Errorf("Error on pgx connection")
+		log.Fatalf("cannot connect to database: %v", err)
 	}
-	defer conn.Close(ctx)
+	defer store.Close()
 
 	app := &App{
-		Queries: db.New(conn),
+		Store:   store,
 		Context: ctx,
 	}
 
-	// Read schema.sql
-	schema, err := os.ReadFile("schema.sql")
-	if err != nil {
-		log.Fatalf("cannot read schema.sql: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if migrator == nil {
+			log.Fatal("migrate: the configured store has no schema to migrate")
+		}
+		if err := runMigrateCommand(ctx, migrator, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
 	}
 
-	// Execute schema
-	_, err = conn.Exec(ctx, string(schema))
-	if err != nil {
-		log.Fatalf("failed to apply schema: %v", err)
+	if migrator != nil {
+		// Bring the schema up to date; other instances wait on the migration lock
+		applied, err := migrator.Up(ctx, 0)
+		if err != nil {
+			log.Fatalf("failed to apply migrations: %v", err)
+		}
+		logMigrations("applied", applied)
+	} else {
+		log.Println("Using the in-memory store; data is lost when the server stops")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "generate-tuitions" {
+		if err := runGenerateCommand(ctx, store, os.Args[2:]); err != nil {
+			log.Fatalf("generate-tuitions: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "close-settlements" {
+		if err := runCloseSettlementsCommand(ctx, store, os.Args[2:]); err != nil {
+			log.Fatalf("close-settlements: %v", err)
+		}
+		return
 	}
-	log.Println("\n\nSchema applied successfully!")
 
 	initLogger()
 	defer logFile.Close()
 
+	workers, err := envInt("IMPORT_WORKERS", defaultImportWorkers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if workers > 0 {
+		app.Jobs = newJobRunner(store, workers)
+		app.Jobs.Start(ctx)
+		defer app.Jobs.Stop()
+	}
+
+	cacheSeconds, err := envInt("REPORT_CACHE_SECONDS", defaultReportCacheSeconds)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cacheSeconds > 0 {
+		app.Reports = newReportCache(time.Duration(cacheSeconds) * time.Second)
+	}
+
+	port := ":" + os.Getenv("PORT")
+	log.Printf("Server starting on port %s", port)
+	log.Printf("Swagger documentation available at http://localhost%s/swagger.json", port)
+	log.Printf("Swagger ui available at http://localhost%s/swagger-ui", port)
+
+	if err := http.ListenAndServe(port, app.routes()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// routes builds the complete HTTP handler: both API versions, the swagger files and
+// the tracing middleware around everything.
+func (a *App) routes() http.Handler {
 	mux := http.NewServeMux()
 
 	// v1 API
 	v1Mux := http.NewServeMux()
-	v1Mux.HandleFunc("/health", healthHandler)
-	v1Mux.HandleFunc("/register", loggingMiddleware(app.registerHandler))
-	v1Mux.HandleFunc("/login", loggingMiddleware(app.loginHandler))
+	v1Mux.HandleFunc("/health", traced("healthHandler", a.healthHandler))
+	v1Mux.HandleFunc("/register", loggingMiddleware(traced("registerHandler", a.registerHandler)))
+	v1Mux.HandleFunc("/login", loggingMiddleware(traced("loginHandler", a.loginHandler)))
 
 	// v2 API
 	v2Mux := http.NewServeMux()
-	v2Mux.HandleFunc("/health", healthHandler)
-	v2Mux.HandleFunc("/mobile/tuition", loggingMiddleware(app.routingMiddleware(authMiddleware(app.rateLimitMiddleware(app.QueryTuitionHandler)))))
-	v2Mux.HandleFunc("/banking/tuition", loggingMiddleware(authMiddleware(app.QueryTuitionHandler)))
-	v2Mux.HandleFunc("/banking/pay", loggingMiddleware(authMiddleware(app.PayTuitionHandler)))
-	v2Mux.HandleFunc("/admin/add-tuition", loggingMiddleware(authMiddleware(app.addTuitionHandler)))
-	v2Mux.HandleFunc("/admin/add-tuition-batch", loggingMiddleware(authMiddleware(app.addTuitionBatchHandler)))
-	v2Mux.HandleFunc("/admin/logs", loggingMiddleware(authMiddleware(app.getLogsHandler)))
-	v2Mux.HandleFunc("/admin/unpaid-status", loggingMiddleware(authMiddleware(app.unpaidTuitionStatusHandler)))
-	v2Mux.HandleFunc("/admin/add-student", loggingMiddleware(app.addStudentHandler))
-	v2Mux.HandleFunc("/register", loggingMiddleware(app.registerHandler))
-	v2Mux.HandleFunc("/login", loggingMiddleware(app.loginHandler))
+	v2Mux.HandleFunc("/health", traced("healthHandler", a.healthHandler))
+	v2Mux.HandleFunc("/mobile/tuition", loggingMiddleware(a.routingMiddleware(authMiddleware(a.rateLimitMiddleware(traced("QueryTuitionHandler", a.QueryTuitionHandler))))))
+	v2Mux.HandleFunc("/banking/tuition", loggingMiddleware(authMiddleware(traced("QueryTuitionHandler", a.QueryTuitionHandler))))
+	v2Mux.HandleFunc("/banking/pay", loggingMiddleware(authMiddleware(traced("PayTuitionHandler", a.PayTuitionHandler))))
+	v2Mux.HandleFunc("GET /mobile/statement", loggingMiddleware(a.routingMiddleware(authMiddleware(a.rateLimitMiddleware(traced("statementHandler", a.statementHandler))))))
+	v2Mux.HandleFunc("GET /banking/statement", loggingMiddleware(authMiddleware(traced("statementHandler", a.statementHandler))))
+	v2Mux.HandleFunc("GET /mobile/tuitions", loggingMiddleware(a.routingMiddleware(authMiddleware(a.rateLimitMiddleware(traced("studentTermsHandler", a.studentTermsHandler))))))
+	v2Mux.HandleFunc("GET /banking/tuitions", loggingMiddleware(authMiddleware(traced("studentTermsHandler", a.studentTermsHandler))))
+	v2Mux.HandleFunc("GET /receipts/{receipt_no}", loggingMiddleware(authMiddleware(traced("receiptHandler", a.receiptHandler))))
+	v2Mux.HandleFunc("GET /receipts/{receipt_no}/verify", loggingMiddleware(traced("verifyReceiptHandler", a.verifyReceiptHandler)))
+	v2Mux.HandleFunc("/admin/add-tuition", loggingMiddleware(authMiddleware(adminMiddleware(traced("addTuitionHandler", a.addTuitionHandler)))))
+	v2Mux.HandleFunc("/admin/add-tuition-batch", loggingMiddleware(authMiddleware(adminMiddleware(traced("addTuitionBatchHandler", a.addTuitionBatchHandler)))))
+	v2Mux.HandleFunc("GET /admin/add-tuition-batch/template", loggingMiddleware(authMiddleware(adminMiddleware(traced("tuitionTemplateHandler", a.tuitionTemplateHandler)))))
+	v2Mux.HandleFunc("/admin/logs", loggingMiddleware(authMiddleware(adminMiddleware(traced("getLogsHandler", a.getLogsHandler)))))
+	v2Mux.HandleFunc("/admin/unpaid-status", loggingMiddleware(authMiddleware(adminMiddleware(traced("unpaidTuitionStatusHandler", a.unpaidTuitionStatusHandler)))))
+	v2Mux.HandleFunc("GET /admin/reports/aging", loggingMiddleware(authMiddleware(adminMiddleware(traced("agingReportHandler", a.agingReportHandler)))))
+	v2Mux.HandleFunc("GET /admin/reports/summary", loggingMiddleware(authMiddleware(adminMiddleware(traced("summaryReportHandler", a.summaryReportHandler)))))
+	v2Mux.HandleFunc("POST /admin/reconciliation/statements", loggingMiddleware(authMiddleware(adminMiddleware(traced("importStatementHandler", a.importStatementHandler)))))
+	v2Mux.HandleFunc("GET /admin/reconciliation/transactions", loggingMiddleware(authMiddleware(adminMiddleware(traced("listBankTransactionsHandler", a.listBankTransactionsHandler)))))
+	v2Mux.HandleFunc("GET /admin/reconciliation/transactions/{transaction_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("getBankTransactionHandler", a.getBankTransactionHandler)))))
+	v2Mux.HandleFunc("POST /admin/reconciliation/transactions/{transaction_id}/resolve", loggingMiddleware(authMiddleware(adminMiddleware(traced("resolveBankTransactionHandler", a.resolveBankTransactionHandler)))))
+	v2Mux.HandleFunc("POST /admin/reconciliation/transactions/{transaction_id}/dismiss", loggingMiddleware(authMiddleware(adminMiddleware(traced("dismissBankTransactionHandler", a.dismissBankTransactionHandler)))))
+	v2Mux.HandleFunc("GET /admin/partners", loggingMiddleware(authMiddleware(adminMiddleware(traced("listBankPartnersHandler", a.listBankPartnersHandler)))))
+	v2Mux.HandleFunc("POST /admin/partners", loggingMiddleware(authMiddleware(adminMiddleware(traced("createBankPartnerHandler", a.createBankPartnerHandler)))))
+	v2Mux.HandleFunc("POST /admin/partners/{code}/token", loggingMiddleware(authMiddleware(adminMiddleware(traced("issuePartnerTokenHandler", a.issuePartnerTokenHandler)))))
+	v2Mux.HandleFunc("POST /admin/settlements", loggingMiddleware(authMiddleware(adminMiddleware(traced("closeSettlementsHandler", a.closeSettlementsHandler)))))
+	v2Mux.HandleFunc("GET /admin/settlements", loggingMiddleware(authMiddleware(adminMiddleware(traced("listSettlementsHandler", a.listSettlementsHandler)))))
+	v2Mux.HandleFunc("POST /admin/settlements/import", loggingMiddleware(authMiddleware(adminMiddleware(traced("importSettlementTotalsHandler", a.importSettlementTotalsHandler)))))
+	v2Mux.HandleFunc("GET /admin/settlements/import/template", loggingMiddleware(authMiddleware(adminMiddleware(traced("settlementTotalsTemplateHandler", a.settlementTotalsTemplateHandler)))))
+	v2Mux.HandleFunc("GET /admin/settlements/{settlement_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("getSettlementHandler", a.getSettlementHandler)))))
+	v2Mux.HandleFunc("GET /admin/settlements/{settlement_id}/file", loggingMiddleware(authMiddleware(adminMiddleware(traced("settlementFileHandler", a.settlementFileHandler)))))
+	v2Mux.HandleFunc("POST /admin/settlements/{settlement_id}/reported", loggingMiddleware(authMiddleware(adminMiddleware(traced("reportSettlementHandler", a.reportSettlementHandler)))))
+	v2Mux.HandleFunc("/admin/add-student", loggingMiddleware(authMiddleware(adminMiddleware(traced("addStudentHandler", a.addStudentHandler)))))
+	v2Mux.HandleFunc("GET /me", loggingMiddleware(authMiddleware(traced("meHandler", a.meHandler))))
+	v2Mux.HandleFunc("GET /admin/students", loggingMiddleware(authMiddleware(adminMiddleware(traced("listStudentsHandler", a.listStudentsHandler)))))
+	v2Mux.HandleFunc("POST /admin/students/import", loggingMiddleware(authMiddleware(adminMiddleware(traced("importStudentsHandler", a.importStudentsHandler)))))
+	v2Mux.HandleFunc("GET /admin/students/import/template", loggingMiddleware(authMiddleware(adminMiddleware(traced("studentTemplateHandler", a.studentTemplateHandler)))))
+	v2Mux.HandleFunc("GET /admin/students/{student_no}", loggingMiddleware(authMiddleware(adminMiddleware(traced("getStudentHandler", a.getStudentHandler)))))
+	v2Mux.HandleFunc("PATCH /admin/students/{student_no}", loggingMiddleware(authMiddleware(adminMiddleware(traced("updateStudentHandler", a.updateStudentHandler)))))
+	v2Mux.HandleFunc("DELETE /admin/students/{student_no}", loggingMiddleware(authMiddleware(adminMiddleware(traced("deactivateStudentHandler", a.deactivateStudentHandler)))))
+	v2Mux.HandleFunc("GET /admin/tuitions", loggingMiddleware(authMiddleware(adminMiddleware(traced("listTuitionsHandler", a.listTuitionsHandler)))))
+	v2Mux.HandleFunc("POST /admin/tuitions", loggingMiddleware(authMiddleware(adminMiddleware(traced("createTuitionHandler", a.createTuitionHandler)))))
+	v2Mux.HandleFunc("GET /admin/tuitions/{tuition_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("getTuitionHandler", a.getTuitionHandler)))))
+	v2Mux.HandleFunc("PATCH /admin/tuitions/{tuition_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("amendTuitionHandler", a.amendTuitionHandler)))))
+	v2Mux.HandleFunc("POST /admin/tuitions/{tuition_id}/cancel", loggingMiddleware(authMiddleware(adminMiddleware(traced("cancelTuitionHandler", a.cancelTuitionHandler)))))
+	v2Mux.HandleFunc("GET /admin/terms", loggingMiddleware(authMiddleware(adminMiddleware(traced("listTermsHandler", a.listTermsHandler)))))
+	v2Mux.HandleFunc("POST /admin/terms", loggingMiddleware(authMiddleware(adminMiddleware(traced("createTermHandler", a.createTermHandler)))))
+	v2Mux.HandleFunc("GET /admin/terms/{code}", loggingMiddleware(authMiddleware(adminMiddleware(traced("getTermHandler", a.getTermHandler)))))
+	v2Mux.HandleFunc("PATCH /admin/terms/{code}", loggingMiddleware(authMiddleware(adminMiddleware(traced("updateTermHandler", a.updateTermHandler)))))
+	v2Mux.HandleFunc("DELETE /admin/terms/{code}", loggingMiddleware(authMiddleware(adminMiddleware(traced("deleteTermHandler", a.deleteTermHandler)))))
+	v2Mux.HandleFunc("GET /admin/fee-types", loggingMiddleware(authMiddleware(adminMiddleware(traced("listFeeTypesHandler", a.listFeeTypesHandler)))))
+	v2Mux.HandleFunc("POST /admin/fee-types", loggingMiddleware(authMiddleware(adminMiddleware(traced("createFeeTypeHandler", a.createFeeTypeHandler)))))
+	v2Mux.HandleFunc("PATCH /admin/fee-types/{code}", loggingMiddleware(authMiddleware(adminMiddleware(traced("updateFeeTypeHandler", a.updateFeeTypeHandler)))))
+	v2Mux.HandleFunc("POST /admin/terms/{code}/generate-tuitions", loggingMiddleware(authMiddleware(adminMiddleware(traced("generateTuitionsHandler", a.generateTuitionsHandler)))))
+	v2Mux.HandleFunc("GET /admin/fee-schedules", loggingMiddleware(authMiddleware(adminMiddleware(traced("listFeeSchedulesHandler", a.listFeeSchedulesHandler)))))
+	v2Mux.HandleFunc("POST /admin/fee-schedules", loggingMiddleware(authMiddleware(adminMiddleware(traced("createFeeScheduleHandler", a.createFeeScheduleHandler)))))
+	v2Mux.HandleFunc("PATCH /admin/fee-schedules/{schedule_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("updateFeeScheduleHandler", a.updateFeeScheduleHandler)))))
+	v2Mux.HandleFunc("DELETE /admin/fee-schedules/{schedule_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("deleteFeeScheduleHandler", a.deleteFeeScheduleHandler)))))
+	v2Mux.HandleFunc("GET /admin/jobs", loggingMiddleware(authMiddleware(adminMiddleware(traced("listJobsHandler", a.listJobsHandler)))))
+	v2Mux.HandleFunc("GET /admin/jobs/{job_id}", loggingMiddleware(authMiddleware(adminMiddleware(traced("getJobHandler", a.getJobHandler)))))
+	v2Mux.HandleFunc("POST /admin/jobs/{job_id}/cancel", loggingMiddleware(authMiddleware(adminMiddleware(traced("cancelJobHandler", a.cancelJobHandler)))))
+	v2Mux.HandleFunc("/register", loggingMiddleware(traced("registerHandler", a.registerHandler)))
+	v2Mux.HandleFunc("/login", loggingMiddleware(traced("loginHandler", a.loginHandler)))
 
 	mux.HandleFunc("/swagger-ui", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "./swagger-ui.html")
@@ -85,12 +205,5 @@ func main() {
 	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", v1Mux))
 	mux.Handle("/api/v2/", http.StripPrefix("/api/v2", v2Mux))
 
-	port := ":" + os.Getenv("PORT")
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Swagger documentation available at http://localhost%s/swagger.json", port)
-	log.Printf("Swagger ui available at http://localhost%s/swagger-ui", port)
-
-	if err := http.ListenAndServe(port, mux); err != nil {
-		log.Fatal(err)
-	}
+	return tracingMiddleware(mux)
 }
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logging middleware
@@ -73,6 +76,9 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 
 func loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		r, span := startSpan(r, "loggingMiddleware")
+		defer span.End()
+
 		start := time.Now()
 
 		rw := &responseWriter{
@@ -110,30 +116,37 @@ func loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 func (a *App) routingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		r, span := startSpan(r, "routingMiddleware")
+		defer span.End()
 
-		next.ServeHTTP(w, r.WithContext(a.Context))
+		// Keep the trace going on the app context so the rest of the chain stays in the same trace
+		next.ServeHTTP(w, r.WithContext(trace.ContextWithSpan(a.Context, span)))
 	}
 }
 
 // Rate Limiting Middleware
 func (a *App) rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		r, span := startSpan(r, "rateLimitMiddleware")
+		defer span.End()
 
 		studentNo := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
-		dailyLimit, err := a.Queries.GetStudentDailyLimit(r.Context(), studentNo)
+		dailyLimit, err := a.Store.GetStudentDailyLimit(r.Context(), studentNo)
 
 		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
 			http.Error(w, "Gateway Error", http.StatusBadGateway)
 			return
 		}
 
+		span.SetAttributes(attribute.Int("tuition.daily_limit", int(dailyLimit)))
 		if dailyLimit == 0 {
 			http.Error(w, "You've reached your daily limit.", http.StatusBadRequest)
 			return
 		}
 		next.ServeHTTP(w, r)
 
-		a.Queries.DecreasePaymentLimit(r.Context(), studentNo)
+		a.Store.DecreasePaymentLimit(r.Context(), studentNo)
 	}
 }
 
@@ -141,6 +154,9 @@ func (a *App) rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		r, span := startSpan(r, "authMiddleware")
+		defer span.End()
+
 		tokenStr := extractToken(r)
 
 		if tokenStr == "" {
@@ -162,13 +178,48 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return []byte(secret), nil
 		})
 		if err != nil || !token.Valid {
+			span.SetStatus(codes.Error, "invalid token")
 			msg := fmt.Errorf("Token Status: %w", err)
 			// http.Error(w, msg.Error(), http.StatusUnauthorized)
 			http.Error(w, "Invalid token"+msg.Error(), http.StatusUnauthorized)
 			return
 		}
+		span.SetAttributes(attribute.String("tuition.student_no", claims.Subject))
 		// Attach user ID (sub) to context for handlers
 		ctx := context.WithValue(r.Context(), "LOGGEDIN_STUDENT_NO", claims.Subject)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// Used when ADMIN_SUBJECTS is not set
+const defaultAdminSubjects = "admin"
+
+// Admin middleware, must run after authMiddleware. Only tokens whose subject is listed in
+// ADMIN_SUBJECTS (comma separated) get through, so student tokens can't reach /admin routes.
+func adminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, span := startSpan(r, "adminMiddleware")
+		defer span.End()
+
+		subject, _ := r.Context().Value("LOGGEDIN_STUDENT_NO").(string)
+		if !isAdminSubject(subject) {
+			span.SetStatus(codes.Error, "not an admin")
+			http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func isAdminSubject(subject string) bool {
+	admins := os.Getenv("ADMIN_SUBJECTS")
+	if admins == "" {
+		admins = defaultAdminSubjects
+	}
+	for _, admin := range strings.Split(admins, ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && admin == subject {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		target := "/api/v2/banking/tuition?student_no=22070006071&active_term=Fall2025"
+
+		valid := jwt.RegisteredClaims{
+			Subject:   "22070006071",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+		expired := jwt.RegisteredClaims{
+			Subject:   "22070006071",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		}
+
+		tests := []struct {
+			name  string
+			token string
+			want  int
+		}{
+			{"missing", "", http.StatusUnauthorized},
+			{"garbage", "not-a-jwt", http.StatusUnauthorized},
+			{"wrong secret", signToken(t, jwt.SigningMethodHS256, []byte("other-secret"), valid), http.StatusUnauthorized},
+			{"expired", signToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), expired), http.StatusUnauthorized},
+			{"alg none", signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), http.StatusUnauthorized},
+			{"valid", signToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), valid), http.StatusOK},
+		}
+		for _, tt := range tests {
+			rec := ta.do(http.MethodGet, target, tt.token, nil, nil)
+			if rec.Code != tt.want {
+				t.Errorf("%s: got %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+			}
+		}
+
+		t.Run("cookie", func(t *testing.T) {
+			token := signToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), valid)
+			header := http.Header{"Cookie": {"jwt=" + token}}
+			if rec := ta.do(http.MethodGet, target, "", nil, header); rec.Code != http.StatusOK {
+				t.Errorf("got %d, want 200", rec.Code)
+			}
+		})
+
+		t.Run("malformed authorization header", func(t *testing.T) {
+			token := signToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), valid)
+			header := http.Header{"Authorization": {"Token " + token}}
+			if rec := ta.do(http.MethodGet, target, "", nil, header); rec.Code != http.StatusUnauthorized {
+				t.Errorf("got %d, want 401", rec.Code)
+			}
+		})
+	})
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addStudent("22070006072", 100)
+		student := ta.register("22070006071", "secret")
+
+		// A student can't edit their own balance or deactivate someone else
+		rec := ta.do(http.MethodPatch, "/api/v2/admin/students/22070006071", student, strings.NewReader(`{"balance":99999}`), nil)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("patch: got %d, want 403 (%s)", rec.Code, rec.Body)
+		}
+		if rec := ta.do(http.MethodDelete, "/api/v2/admin/students/22070006072", student, nil, nil); rec.Code != http.StatusForbidden {
+			t.Fatalf("delete: got %d, want 403 (%s)", rec.Code, rec.Body)
+		}
+		if rec := ta.do(http.MethodPost, "/api/v2/admin/add-student?student_no=22070006073&balance=0", student, nil, nil); rec.Code != http.StatusForbidden {
+			t.Fatalf("add student: got %d, want 403 (%s)", rec.Code, rec.Body)
+		}
+		if ta.balance("22070006071") != 100 {
+			t.Fatal("a student changed their balance")
+		}
+
+		t.Setenv("ADMIN_SUBJECTS", "ops, 22070006071")
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/students/22070006072", student, nil, nil); rec.Code != http.StatusOK {
+			t.Fatalf("listed subject: got %d, want 200 (%s)", rec.Code, rec.Body)
+		}
+		if rec := ta.do(http.MethodGet, "/api/v2/admin/students/22070006072", adminToken(t), nil, nil); rec.Code != http.StatusForbidden {
+			t.Fatalf("unlisted admin: got %d, want 403 (%s)", rec.Code, rec.Body)
+		}
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 100)
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		token := ta.register("22070006071", "pw")
+
+		target := "/api/v2/mobile/tuition?student_no=22070006071&active_term=Fall2025"
+		for i := range 3 {
+			if rec := ta.do(http.MethodGet, target, token, nil, nil); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: got %d %s", i+1, rec.Code, rec.Body)
+			}
+		}
+		if rec := ta.do(http.MethodGet, target, token, nil, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("request over the daily limit: got %d, want 400", rec.Code)
+		}
+
+		// The banking route is not rate limited
+		if rec := ta.do(http.MethodGet, "/api/v2/banking/tuition?student_no=22070006071&active_term=Fall2025", token, nil, nil); rec.Code != http.StatusOK {
+			t.Errorf("banking query: got %d, want 200", rec.Code)
+		}
+	})
+}
+
+func TestConcurrentPayments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		token := adminToken(t)
+
+		// Many partial payments on a large tuition
+		{
+			ta.addStudent("22070006071", 10)
+			ta.addTuition("22070006071", "Fall2025", 1_000_000)
+
+			var wg sync.WaitGroup
+			for range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 10 {
+						if rec := ta.pay(token, "22070006071", "Fall2025", "5"); rec.Code != http.StatusOK {
+							t.Errorf("pay: %d %s", rec.Code, rec.Body)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := ta.balance("22070006071"); got != 10+20*10*5 {
+				t.Errorf("balance = %v, want %v", got, 10+20*10*5)
+			}
+		}
+
+		// Several payments race to settle the same term
+		{
+			ta.addStudent("22070006072", 2)
+			ta.addTuition("22070006072", "Fall2025", 100)
+
+			var wg sync.WaitGroup
+			for i := range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if rec := ta.pay(token, "22070006072", "Fall2025", "50"); rec.Code != http.StatusOK {
+						t.Errorf("pay %d: %d %s", i, rec.Code, rec.Body)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// Tuition is charged exactly once; everything else stays on the balance
+			if got, want := ta.balance("22070006072"), float64(2+10*50-100); got != want {
+				t.Errorf("balance = %v, want %v", got, want)
+			}
+			if got := ta.tuitionTotal("22070006072", "Fall2025"); got != 0 {
+				t.Errorf("tuition = %v, want 0", got)
+			}
+		}
+	})
+}
+
+func TestUnknownRoute(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		for _, path := range []string{"/api/v1/banking/pay", "/api/v3/health", "/api/v2/nope"} {
+			if rec := ta.do(http.MethodGet, path, "", nil, nil); rec.Code != http.StatusNotFound {
+				t.Errorf("%s: got %d, want 404", path, rec.Code)
+			}
+		}
+	})
+}
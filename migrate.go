@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are embedded so the binary can migrate without the source tree.
+// sqlc reads the same directories as its schema and skips the .down.sql files.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// Arbitrary key shared by every Postgres instance so only one of them migrates at a time
+const migrationLockKey int64 = 4458_0001
+
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type migrationStatus struct {
+	migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	target     migrationTarget
+	migrations []migration
+}
+
+func NewPostgresMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	return newMigrator(postgresMigrations{pool: pool}, "migrations/postgres")
+}
+
+func NewSQLiteMigrator(db *sql.DB) (*Migrator, error) {
+	return newMigrator(sqliteMigrations{db: db}, "migrations/sqlite")
+}
+
+func newMigrator(target migrationTarget, dir string) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{target: target, migrations: migrations}, nil
+}
+
+// loadMigrations pairs up/down files by version and returns them in ascending order.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected file in migrations: %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has files with different names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrationTarget is a database engine the Migrator can work on.
+type migrationTarget interface {
+	// withLock runs fn while holding an exclusive migration lock, after making
+	// sure schema_migrations exists.
+	withLock(ctx context.Context, fn func(conn migrationConn) error) error
+}
+
+type migrationConn interface {
+	appliedVersions(ctx context.Context) (map[int64]time.Time, error)
+	// apply runs script and records (up) or removes (down) the version atomically.
+	apply(ctx context.Context, script string, mig migration, up bool) error
+}
+
+// postgresMigrations serializes instances with a session-level advisory lock held
+// on one pooled connection; every migration runs in its own transaction.
+type postgresMigrations struct {
+	pool *pgxpool.Pool
+}
+
+type postgresMigrationConn struct {
+	conn *pgxpool.Conn
+}
+
+func (t postgresMigrations) withLock(ctx context.Context, fn func(conn migrationConn) error) error {
+	conn, err := t.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("cannot acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     BIGINT PRIMARY KEY,
+		name        TEXT NOT NULL,
+		applied_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("cannot create schema_migrations: %w", err)
+	}
+
+	return fn(postgresMigrationConn{conn: conn})
+}
+
+func (c postgresMigrationConn) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := c.conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (c postgresMigrationConn) apply(ctx context.Context, script string, mig migration, up bool) error {
+	return pgx.BeginFunc(ctx, c.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+		var err error
+		if up {
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+		} else {
+			_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+		}
+		return err
+	})
+}
+
+// sqliteMigrations takes SQLite's write lock with BEGIN IMMEDIATE for the whole run,
+// so other processes opening the same file wait. Each migration is a savepoint, so
+// the ones that succeeded before a failure are still committed.
+type sqliteMigrations struct {
+	db *sql.DB
+}
+
+type sqliteMigrationConn struct {
+	conn *sql.Conn
+}
+
+func (t sqliteMigrations) withLock(ctx context.Context, fn func(conn migrationConn) error) error {
+	conn, err := t.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("cannot acquire migration lock: %w", err)
+	}
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		name        TEXT NOT NULL,
+		applied_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err == nil {
+		err = fn(sqliteMigrationConn{conn: conn})
+	}
+
+	// Keep whatever was released before the failure
+	if _, cerr := conn.ExecContext(context.Background(), "COMMIT"); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (c sqliteMigrationConn) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := c.conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (c sqliteMigrationConn) apply(ctx context.Context, script string, mig migration, up bool) error {
+	if _, err := c.conn.ExecContext(ctx, "SAVEPOINT migration"); err != nil {
+		return err
+	}
+
+	_, err := c.conn.ExecContext(ctx, script)
+	if err == nil {
+		if up {
+			_, err = c.conn.ExecContext(ctx, "INSERT INTO schema_migrations(version, name) VALUES (?, ?)", mig.Version, mig.Name)
+		} else {
+			_, err = c.conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", mig.Version)
+		}
+	}
+	if err != nil {
+		c.conn.ExecContext(context.Background(), "ROLLBACK TO migration")
+		c.conn.ExecContext(context.Background(), "RELEASE migration")
+		return err
+	}
+	_, err = c.conn.ExecContext(ctx, "RELEASE migration")
+	return err
+}
+
+// Up applies up to n pending migrations, or all of them when n <= 0.
+func (m *Migrator) Up(ctx context.Context, n int) ([]migration, error) {
+	var done []migration
+	err := m.target.withLock(ctx, func(conn migrationConn) error {
+		applied, err := conn.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if n > 0 && len(done) == n {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := conn.apply(ctx, mig.Up, mig, true); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Apply applies the one migration with the given version, whatever else is pending.
+func (m *Migrator) Apply(ctx context.Context, version int64) ([]migration, error) {
+	i := slices.IndexFunc(m.migrations, func(mig migration) bool { return mig.Version == version })
+	if i < 0 {
+		return nil, fmt.Errorf("no migration with version %d", version)
+	}
+	mig := m.migrations[i]
+
+	var done []migration
+	err := m.target.withLock(ctx, func(conn migrationConn) error {
+		applied, err := conn.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := applied[mig.Version]; ok {
+			return nil
+		}
+		if err := conn.apply(ctx, mig.Up, mig, true); err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the n most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) ([]migration, error) {
+	var done []migration
+	err := m.target.withLock(ctx, func(conn migrationConn) error {
+		applied, err := conn.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+			}
+			if err := conn.apply(ctx, mig.Down, mig, false); err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]migrationStatus, error) {
+	var statuses []migrationStatus
+	err := m.target.withLock(ctx, func(conn migrationConn) error {
+		applied, err := conn.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			status := migrationStatus{migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				status.AppliedAt = &at
+				delete(applied, mig.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		// Versions recorded in the database that this binary doesn't know about
+		for version, at := range applied {
+			statuses = append(statuses, migrationStatus{
+				migration: migration{Version: version, Name: "(missing file)"},
+				AppliedAt: &at,
+			})
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// runMigrateCommand implements `migrate status|up [N]|down [N]|redo`.
+func runMigrateCommand(ctx context.Context, migrator *Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up [N]|down [N]|redo")
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		var err error
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			return fmt.Errorf("step count must be a positive number, got %q", args[1])
+		}
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return tw.Flush()
+	case "up":
+		done, err := migrator.Up(ctx, steps)
+		logMigrations("applied", done)
+		return err
+	case "down":
+		done, err := migrator.Down(ctx, max(steps, 1))
+		logMigrations("rolled back", done)
+		return err
+	case "redo":
+		done, err := migrator.Down(ctx, 1)
+		logMigrations("rolled back", done)
+		if err != nil || len(done) == 0 {
+			return err
+		}
+		// Up would start at the lowest pending version, which need not be this one
+		done, err = migrator.Apply(ctx, done[0].Version)
+		logMigrations("applied", done)
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func logMigrations(action string, migrations []migration) {
+	if len(migrations) == 0 {
+		log.Printf("no migrations %s", action)
+	}
+	for _, mig := range migrations {
+		log.Printf("%s %d_%s", action, mig.Version, mig.Name)
+	}
+}
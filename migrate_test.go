@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrateRedo(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenSQLiteStore("sqlite://:memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	migrator, err := NewSQLiteMigrator(store.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The latest migration is applied while the one before it is pending
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, older := statuses[len(statuses)-1].Version, statuses[len(statuses)-2].Version
+	if _, err := migrator.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := migrator.Apply(ctx, latest); err != nil || len(done) != 1 {
+		t.Fatalf("apply %d: %v %+v", latest, err, done)
+	}
+
+	if err := runMigrateCommand(ctx, migrator, []string{"redo"}); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if applied := s.AppliedAt != nil; applied != (s.Version != older) {
+			t.Errorf("migration %d_%s applied = %t", s.Version, s.Name, applied)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment;
+ALTER TABLE student DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,15 @@
+-- Soft delete: a deactivated student keeps their records but cannot log in or pay
+ALTER TABLE student ADD COLUMN deactivated_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS payment (
+    payment_id          SERIAL PRIMARY KEY,
+    student_no          VARCHAR(11) NOT NULL,
+    term                VARCHAR(50) NOT NULL,
+    amount              DOUBLE PRECISION NOT NULL,
+    balance_after       DOUBLE PRECISION NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no)
+);
+
+CREATE INDEX IF NOT EXISTS payment_student_no_idx ON payment(student_no);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS tuition_change;
+ALTER TABLE tuition DROP CONSTRAINT IF EXISTS tuition_status_valid;
+ALTER TABLE tuition DROP COLUMN IF EXISTS status;
+ALTER TABLE tuition DROP COLUMN IF EXISTS billed_total;
@@ -0,0 +1,26 @@
+-- tuition_total is what is still owed for the term; billed_total is what was billed
+ALTER TABLE tuition ADD COLUMN billed_total DOUBLE PRECISION NOT NULL DEFAULT 0;
+UPDATE tuition SET billed_total = tuition_total;
+
+ALTER TABLE tuition ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE tuition ADD CONSTRAINT tuition_status_valid CHECK (status IN ('active', 'cancelled'));
+
+CREATE TABLE IF NOT EXISTS tuition_change (
+    change_id           SERIAL PRIMARY KEY,
+    tuition_id          INT NOT NULL,
+    action              VARCHAR(20) NOT NULL,
+    old_term            VARCHAR(50) NOT NULL,
+    new_term            VARCHAR(50) NOT NULL,
+    old_amount          DOUBLE PRECISION NOT NULL,
+    new_amount          DOUBLE PRECISION NOT NULL,
+    -- Credited to (positive) or charged from (negative) the student's balance
+    balance_adjustment  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    reason              TEXT NOT NULL,
+    changed_by          VARCHAR(50) NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_tuition FOREIGN KEY (tuition_id) REFERENCES tuition(tuition_id),
+    CONSTRAINT tuition_change_action_valid CHECK (action IN ('amend', 'cancel'))
+);
+
+CREATE INDEX IF NOT EXISTS tuition_change_tuition_id_idx ON tuition_change(tuition_id);
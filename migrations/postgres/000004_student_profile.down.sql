@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS student_faculty_program_idx;
+ALTER TABLE student DROP CONSTRAINT IF EXISTS student_enrollment_status_valid;
+ALTER TABLE student DROP COLUMN IF EXISTS enrollment_status;
+ALTER TABLE student DROP COLUMN IF EXISTS enrollment_year;
+ALTER TABLE student DROP COLUMN IF EXISTS program;
+ALTER TABLE student DROP COLUMN IF EXISTS department;
+ALTER TABLE student DROP COLUMN IF EXISTS faculty;
+ALTER TABLE student DROP COLUMN IF EXISTS phone;
+ALTER TABLE student DROP COLUMN IF EXISTS email;
+ALTER TABLE student DROP COLUMN IF EXISTS last_name;
+ALTER TABLE student DROP COLUMN IF EXISTS first_name;
@@ -0,0 +1,13 @@
+ALTER TABLE student ADD COLUMN first_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN last_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN email VARCHAR(254) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN phone VARCHAR(30) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN faculty VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN department VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN program VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE student ADD COLUMN enrollment_year INT;
+ALTER TABLE student ADD COLUMN enrollment_status VARCHAR(20) NOT NULL DEFAULT 'enrolled';
+ALTER TABLE student ADD CONSTRAINT student_enrollment_status_valid
+    CHECK (enrollment_status IN ('enrolled', 'on_leave', 'graduated', 'withdrawn'));
+
+CREATE INDEX IF NOT EXISTS student_faculty_program_idx ON student(faculty, program);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS tuition_term_idx;
+ALTER TABLE tuition DROP CONSTRAINT IF EXISTS fk_term;
+DROP TABLE IF EXISTS term;
@@ -0,0 +1,23 @@
+-- Academic calendar. Tuitions reference a term by its code, e.g. Fall2025.
+CREATE TABLE IF NOT EXISTS term (
+    code                VARCHAR(50) PRIMARY KEY,
+    name                VARCHAR(100) NOT NULL,
+    start_date          DATE,
+    end_date            DATE,
+    payment_due_date    DATE,
+    active              BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT term_dates_valid CHECK (end_date >= start_date)
+);
+
+-- At most one term is active at a time
+CREATE UNIQUE INDEX IF NOT EXISTS term_single_active_idx ON term(active) WHERE active;
+
+-- Terms already billed become calendar entries without dates
+INSERT INTO term (code, name)
+SELECT DISTINCT term, term FROM tuition
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE tuition ADD CONSTRAINT fk_term FOREIGN KEY (term) REFERENCES term(code);
+CREATE INDEX IF NOT EXISTS tuition_term_idx ON tuition(term);
@@ -0,0 +1,40 @@
+-- Kinds of charges on an invoice. Payments settle items in ascending priority.
+CREATE TABLE IF NOT EXISTS fee_type (
+    code                VARCHAR(30) PRIMARY KEY,
+    name                VARCHAR(100) NOT NULL,
+    priority            INT NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO fee_type (code, name, priority) VALUES
+    ('tuition', 'Tuition', 10),
+    ('health_insurance', 'Health insurance', 20),
+    ('lab', 'Laboratory fee', 30),
+    ('dormitory', 'Dormitory', 40),
+    ('student_union', 'Student union fee', 50)
+ON CONFLICT DO NOTHING;
+
+-- Line items of a tuition. tuition.billed_total is the sum of amount and
+-- tuition.tuition_total the sum of what is still owed (amount - amount_paid).
+CREATE TABLE IF NOT EXISTS tuition_item (
+    item_id             SERIAL PRIMARY KEY,
+    tuition_id          INT NOT NULL,
+    fee_type            VARCHAR(30) NOT NULL,
+    description         VARCHAR(200) NOT NULL DEFAULT '',
+    amount              DOUBLE PRECISION NOT NULL,
+    amount_paid         DOUBLE PRECISION NOT NULL DEFAULT 0,
+
+    CONSTRAINT fk_tuition FOREIGN KEY (tuition_id) REFERENCES tuition(tuition_id),
+    CONSTRAINT fk_fee_type FOREIGN KEY (fee_type) REFERENCES fee_type(code),
+    CONSTRAINT tuition_item_amount_positive CHECK (amount > 0),
+    CONSTRAINT tuition_item_amount_paid_valid CHECK (amount_paid >= 0 AND amount_paid <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS tuition_item_tuition_id_idx ON tuition_item(tuition_id);
+
+-- Existing tuitions become a single tuition item
+INSERT INTO tuition_item (tuition_id, fee_type, amount, amount_paid)
+SELECT tuition_id, 'tuition', billed_total,
+       CASE WHEN status = 'active' THEN billed_total - tuition_total ELSE 0 END
+FROM tuition
+WHERE billed_total > 0;
@@ -0,0 +1,16 @@
+-- Amounts billed to every student of a program and cohort for a term, one row
+-- per fee type. Generating a term's tuitions turns them into tuition items.
+CREATE TABLE IF NOT EXISTS fee_schedule (
+    schedule_id         SERIAL PRIMARY KEY,
+    program             VARCHAR(100) NOT NULL,
+    enrollment_year     INT NOT NULL,
+    term                VARCHAR(50) NOT NULL,
+    fee_type            VARCHAR(30) NOT NULL DEFAULT 'tuition',
+    amount              DOUBLE PRECISION NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_term FOREIGN KEY (term) REFERENCES term(code),
+    CONSTRAINT fk_fee_type FOREIGN KEY (fee_type) REFERENCES fee_type(code),
+    CONSTRAINT fee_schedule_amount_positive CHECK (amount > 0),
+    CONSTRAINT fee_schedule_unique UNIQUE (term, program, enrollment_year, fee_type)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS import_job_error;
+DROP TABLE IF EXISTS import_job_file;
+DROP TABLE IF EXISTS import_job;
@@ -0,0 +1,49 @@
+-- Background imports. A job is claimed by a worker; a running job whose
+-- heartbeat stopped (its instance died) is picked up again by another worker.
+CREATE TABLE IF NOT EXISTS import_job (
+    job_id              SERIAL PRIMARY KEY,
+    kind                VARCHAR(30) NOT NULL,
+    status              VARCHAR(20) NOT NULL DEFAULT 'queued',
+    filename            VARCHAR(255) NOT NULL DEFAULT '',
+    format              VARCHAR(10) NOT NULL,
+    mode                VARCHAR(20) NOT NULL,
+    dry_run             BOOLEAN NOT NULL DEFAULT FALSE,
+    -- JSON object of field name to header
+    mapping             TEXT NOT NULL DEFAULT '{}',
+    total_rows          INT NOT NULL DEFAULT 0,
+    processed_rows      INT NOT NULL DEFAULT 0,
+    imported            INT NOT NULL DEFAULT 0,
+    invalid             INT NOT NULL DEFAULT 0,
+    committed           BOOLEAN NOT NULL DEFAULT FALSE,
+    failure             VARCHAR(500) NOT NULL DEFAULT '',
+    cancel_requested    BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by          VARCHAR(50) NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at          TIMESTAMPTZ,
+    heartbeat_at        TIMESTAMPTZ,
+    finished_at         TIMESTAMPTZ,
+
+    CONSTRAINT import_job_status_valid CHECK (status IN ('queued', 'running', 'completed', 'failed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS import_job_status_idx ON import_job(status);
+
+-- The uploaded file, kept until the job is done so it can be resumed
+CREATE TABLE IF NOT EXISTS import_job_file (
+    job_id              INT PRIMARY KEY,
+    payload             BYTEA NOT NULL,
+
+    CONSTRAINT fk_import_job FOREIGN KEY (job_id) REFERENCES import_job(job_id)
+);
+
+CREATE TABLE IF NOT EXISTS import_job_error (
+    error_id            SERIAL PRIMARY KEY,
+    job_id              INT NOT NULL,
+    line                INT NOT NULL,
+    field               VARCHAR(50) NOT NULL DEFAULT '',
+    message             VARCHAR(500) NOT NULL,
+
+    CONSTRAINT fk_import_job FOREIGN KEY (job_id) REFERENCES import_job(job_id)
+);
+
+CREATE INDEX IF NOT EXISTS import_job_error_job_id_idx ON import_job_error(job_id);
@@ -0,0 +1,3 @@
+ALTER TABLE import_job DROP COLUMN IF EXISTS skipped;
+ALTER TABLE import_job DROP COLUMN IF EXISTS updated;
+ALTER TABLE import_job DROP COLUMN IF EXISTS created;
@@ -0,0 +1 @@
+ALTER TABLE tuition DROP COLUMN IF EXISTS created_at;
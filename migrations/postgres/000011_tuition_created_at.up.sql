@@ -0,0 +1,8 @@
+-- When a tuition was billed, for statements. Tuitions billed before this are dated by
+-- their first payment or change, or else by this migration.
+ALTER TABLE tuition ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE tuition SET created_at = LEAST(
+    created_at,
+    (SELECT min(payment.created_at) FROM payment WHERE payment.student_no = tuition.student_no AND payment.term = tuition.term),
+    (SELECT min(tuition_change.created_at) FROM tuition_change WHERE tuition_change.tuition_id = tuition.tuition_id)
+);
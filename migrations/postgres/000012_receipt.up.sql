@@ -0,0 +1,25 @@
+-- A receipt is issued for every payment. Payments made before receipts have none.
+CREATE TABLE IF NOT EXISTS receipt (
+    receipt_no          VARCHAR(20) PRIMARY KEY,
+    payment_id          INT NOT NULL UNIQUE,
+    -- Printed on the receipt so anyone holding it can check it is genuine
+    verification_code   VARCHAR(20) NOT NULL,
+    -- What was still owed for the term after the payment
+    outstanding_after   DOUBLE PRECISION NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_payment FOREIGN KEY (payment_id) REFERENCES payment(payment_id)
+);
+
+-- The fee items a payment settled
+CREATE TABLE IF NOT EXISTS receipt_allocation (
+    allocation_id       SERIAL PRIMARY KEY,
+    receipt_no          VARCHAR(20) NOT NULL,
+    fee_type            VARCHAR(30) NOT NULL,
+    description         VARCHAR(200) NOT NULL DEFAULT '',
+    amount              DOUBLE PRECISION NOT NULL,
+
+    CONSTRAINT fk_receipt FOREIGN KEY (receipt_no) REFERENCES receipt(receipt_no)
+);
+
+CREATE INDEX IF NOT EXISTS receipt_allocation_receipt_no_idx ON receipt_allocation(receipt_no);
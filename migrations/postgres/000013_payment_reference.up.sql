@@ -0,0 +1,11 @@
+-- Structured references students quote on bank transfers, one per student and term.
+-- They are made the first time a student's tuition for the term is looked at.
+CREATE TABLE IF NOT EXISTS payment_reference (
+    reference           VARCHAR(25) PRIMARY KEY,
+    student_no          VARCHAR(11) NOT NULL,
+    term                VARCHAR(50) NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no),
+    CONSTRAINT payment_reference_student_term_key UNIQUE (student_no, term)
+);
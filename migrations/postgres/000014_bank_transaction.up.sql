@@ -0,0 +1,33 @@
+-- Credits read from imported bank statements (CAMT.053, MT940). A credit matched to
+-- a tuition is posted as a payment at once; the rest wait in the queue until an
+-- admin resolves or dismisses them.
+CREATE TABLE IF NOT EXISTS bank_transaction (
+    transaction_id      SERIAL PRIMARY KEY,
+    -- Identifies the statement entry so importing a statement again posts nothing twice
+    entry_key           VARCHAR(64) NOT NULL UNIQUE,
+    format              VARCHAR(10) NOT NULL,
+    statement_id        VARCHAR(70) NOT NULL DEFAULT '',
+    account             VARCHAR(34) NOT NULL DEFAULT '',
+    bank_reference      VARCHAR(35) NOT NULL DEFAULT '',
+    booking_date        DATE,
+    amount              DOUBLE PRECISION NOT NULL,
+    currency            VARCHAR(3) NOT NULL DEFAULT '',
+    debtor              VARCHAR(140) NOT NULL DEFAULT '',
+    remittance          VARCHAR(500) NOT NULL DEFAULT '',
+    status              VARCHAR(20) NOT NULL,
+    -- Why a queued credit was not posted: unmatched or ambiguous
+    issue               VARCHAR(20) NOT NULL DEFAULT '',
+    detail              VARCHAR(500) NOT NULL DEFAULT '',
+    student_no          VARCHAR(11),
+    term                VARCHAR(50),
+    payment_id          INT UNIQUE,
+    resolved_by         VARCHAR(50) NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at         TIMESTAMPTZ,
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no),
+    CONSTRAINT fk_payment FOREIGN KEY (payment_id) REFERENCES payment(payment_id),
+    CONSTRAINT bank_transaction_status_valid CHECK (status IN ('posted', 'queued', 'resolved', 'dismissed'))
+);
+
+CREATE INDEX IF NOT EXISTS bank_transaction_status_idx ON bank_transaction(status);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS settlement;
+DROP INDEX IF EXISTS payment_partner_idx;
+ALTER TABLE payment DROP COLUMN IF EXISTS partner;
+DROP TABLE IF EXISTS bank_partner;
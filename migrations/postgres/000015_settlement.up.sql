@@ -0,0 +1,31 @@
+-- Banks that take tuition payments through the banking API. A partner sends its code
+-- with each payment, and its payments are settled a business day at a time.
+CREATE TABLE IF NOT EXISTS bank_partner (
+    code                VARCHAR(30) PRIMARY KEY,
+    name                VARCHAR(100) NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE payment ADD COLUMN partner VARCHAR(30) REFERENCES bank_partner(code);
+CREATE INDEX IF NOT EXISTS payment_partner_idx ON payment(partner, created_at);
+
+-- A closed business day (UTC) of a partner: the payments it took that day, and the
+-- totals the partner reported for it once they are in.
+CREATE TABLE IF NOT EXISTS settlement (
+    settlement_id       SERIAL PRIMARY KEY,
+    partner             VARCHAR(30) NOT NULL,
+    business_date       DATE NOT NULL,
+    payment_count       INT NOT NULL,
+    total_amount        DOUBLE PRECISION NOT NULL,
+    -- closed until the partner's totals are in, then matched or discrepancy
+    status              VARCHAR(20) NOT NULL DEFAULT 'closed',
+    reported_count      INT,
+    reported_amount     DOUBLE PRECISION,
+    closed_by           VARCHAR(50) NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reported_at         TIMESTAMPTZ,
+
+    CONSTRAINT fk_partner FOREIGN KEY (partner) REFERENCES bank_partner(code),
+    CONSTRAINT settlement_partner_day_key UNIQUE (partner, business_date),
+    CONSTRAINT settlement_status_valid CHECK (status IN ('closed', 'matched', 'discrepancy'))
+);
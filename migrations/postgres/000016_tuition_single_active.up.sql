@@ -0,0 +1,3 @@
+-- A student has at most one active tuition per term. Cancel duplicates left by
+-- concurrent requests before applying this.
+CREATE UNIQUE INDEX IF NOT EXISTS tuition_single_active_idx ON tuition(student_no, term) WHERE status = 'active';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tuition;
+DROP TABLE IF EXISTS account;
+DROP TABLE IF EXISTS student;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS student (
+    student_no          TEXT PRIMARY KEY CHECK (length(student_no) <= 11),
+    balance             REAL NOT NULL,
+    daily_payment_limit INTEGER NOT NULL DEFAULT 3,
+    CONSTRAINT daily_payment_limit_nonnegative CHECK (daily_payment_limit >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS account (
+    account_no                  INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_no                  TEXT NOT NULL UNIQUE CHECK (length(student_no) <= 11),
+    hashed_password             TEXT NOT NULL CHECK (length(hashed_password) <= 148),
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no)
+);
+
+
+CREATE TABLE IF NOT EXISTS tuition (
+    tuition_id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_no          TEXT NOT NULL,
+    term                TEXT NOT NULL CHECK (length(term) <= 50),
+    tuition_total       REAL NOT NULL,
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no)
+);
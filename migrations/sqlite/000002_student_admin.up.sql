@@ -0,0 +1,16 @@
+-- Soft delete: a deactivated student keeps their records but cannot log in or pay
+ALTER TABLE student ADD COLUMN deactivated_at DATETIME;
+
+-- Timestamps are stored as UTC text that sorts chronologically
+CREATE TABLE IF NOT EXISTS payment (
+    payment_id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_no          TEXT NOT NULL CHECK (length(student_no) <= 11),
+    term                TEXT NOT NULL CHECK (length(term) <= 50),
+    amount              REAL NOT NULL,
+    balance_after       REAL NOT NULL,
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no)
+);
+
+CREATE INDEX IF NOT EXISTS payment_student_no_idx ON payment(student_no);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tuition_change;
+ALTER TABLE tuition DROP COLUMN status;
+ALTER TABLE tuition DROP COLUMN billed_total;
@@ -0,0 +1,26 @@
+-- tuition_total is what is still owed for the term; billed_total is what was billed
+ALTER TABLE tuition ADD COLUMN billed_total REAL NOT NULL DEFAULT 0;
+UPDATE tuition SET billed_total = tuition_total;
+
+ALTER TABLE tuition ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CONSTRAINT tuition_status_valid CHECK (status IN ('active', 'cancelled'));
+
+CREATE TABLE IF NOT EXISTS tuition_change (
+    change_id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    tuition_id          INTEGER NOT NULL,
+    action              TEXT NOT NULL CHECK (length(action) <= 20),
+    old_term            TEXT NOT NULL CHECK (length(old_term) <= 50),
+    new_term            TEXT NOT NULL CHECK (length(new_term) <= 50),
+    old_amount          REAL NOT NULL,
+    new_amount          REAL NOT NULL,
+    -- Credited to (positive) or charged from (negative) the student's balance
+    balance_adjustment  REAL NOT NULL DEFAULT 0,
+    reason              TEXT NOT NULL,
+    changed_by          TEXT NOT NULL CHECK (length(changed_by) <= 50),
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    CONSTRAINT fk_tuition FOREIGN KEY (tuition_id) REFERENCES tuition(tuition_id),
+    CONSTRAINT tuition_change_action_valid CHECK (action IN ('amend', 'cancel'))
+);
+
+CREATE INDEX IF NOT EXISTS tuition_change_tuition_id_idx ON tuition_change(tuition_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS student_faculty_program_idx;
+ALTER TABLE student DROP COLUMN enrollment_status;
+ALTER TABLE student DROP COLUMN enrollment_year;
+ALTER TABLE student DROP COLUMN program;
+ALTER TABLE student DROP COLUMN department;
+ALTER TABLE student DROP COLUMN faculty;
+ALTER TABLE student DROP COLUMN phone;
+ALTER TABLE student DROP COLUMN email;
+ALTER TABLE student DROP COLUMN last_name;
+ALTER TABLE student DROP COLUMN first_name;
@@ -0,0 +1,12 @@
+ALTER TABLE student ADD COLUMN first_name TEXT NOT NULL DEFAULT '' CHECK (length(first_name) <= 100);
+ALTER TABLE student ADD COLUMN last_name TEXT NOT NULL DEFAULT '' CHECK (length(last_name) <= 100);
+ALTER TABLE student ADD COLUMN email TEXT NOT NULL DEFAULT '' CHECK (length(email) <= 254);
+ALTER TABLE student ADD COLUMN phone TEXT NOT NULL DEFAULT '' CHECK (length(phone) <= 30);
+ALTER TABLE student ADD COLUMN faculty TEXT NOT NULL DEFAULT '' CHECK (length(faculty) <= 100);
+ALTER TABLE student ADD COLUMN department TEXT NOT NULL DEFAULT '' CHECK (length(department) <= 100);
+ALTER TABLE student ADD COLUMN program TEXT NOT NULL DEFAULT '' CHECK (length(program) <= 100);
+ALTER TABLE student ADD COLUMN enrollment_year INTEGER;
+ALTER TABLE student ADD COLUMN enrollment_status TEXT NOT NULL DEFAULT 'enrolled'
+    CONSTRAINT student_enrollment_status_valid CHECK (enrollment_status IN ('enrolled', 'on_leave', 'graduated', 'withdrawn'));
+
+CREATE INDEX IF NOT EXISTS student_faculty_program_idx ON student(faculty, program);
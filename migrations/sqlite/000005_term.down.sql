@@ -0,0 +1,28 @@
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TABLE tuition_copy (
+    tuition_id          INTEGER NOT NULL,
+    student_no          TEXT NOT NULL,
+    term                TEXT NOT NULL,
+    tuition_total       REAL NOT NULL,
+    billed_total        REAL NOT NULL,
+    status              TEXT NOT NULL
+);
+INSERT INTO tuition_copy SELECT tuition_id, student_no, term, tuition_total, billed_total, status FROM tuition;
+DROP TABLE tuition;
+
+CREATE TABLE tuition (
+    tuition_id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_no          TEXT NOT NULL,
+    term                TEXT NOT NULL CHECK (length(term) <= 50),
+    tuition_total       REAL NOT NULL,
+    billed_total        REAL NOT NULL DEFAULT 0,
+    status              TEXT NOT NULL DEFAULT 'active',
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no),
+    CONSTRAINT tuition_status_valid CHECK (status IN ('active', 'cancelled'))
+);
+INSERT INTO tuition SELECT * FROM tuition_copy;
+DROP TABLE tuition_copy;
+
+DROP TABLE IF EXISTS term;
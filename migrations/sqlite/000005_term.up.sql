@@ -0,0 +1,53 @@
+-- Academic calendar. Tuitions reference a term by its code, e.g. Fall2025.
+CREATE TABLE IF NOT EXISTS term (
+    code                TEXT PRIMARY KEY CHECK (length(code) <= 50),
+    name                TEXT NOT NULL CHECK (length(name) <= 100),
+    start_date          DATE,
+    end_date            DATE,
+    payment_due_date    DATE,
+    active              BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    CONSTRAINT term_dates_valid CHECK (end_date >= start_date)
+);
+
+-- At most one term is active at a time
+CREATE UNIQUE INDEX IF NOT EXISTS term_single_active_idx ON term(active) WHERE active;
+
+-- Terms already billed become calendar entries without dates
+INSERT OR IGNORE INTO term (code, name)
+SELECT DISTINCT term, term FROM tuition;
+
+-- SQLite cannot add a foreign key to an existing table, so tuition is rebuilt.
+-- tuition_change rows point at tuition while it is gone; deferring the check
+-- lets them reattach when the rows are copied back.
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TABLE tuition_copy (
+    tuition_id          INTEGER NOT NULL,
+    student_no          TEXT NOT NULL,
+    term                TEXT NOT NULL,
+    tuition_total       REAL NOT NULL,
+    billed_total        REAL NOT NULL,
+    status              TEXT NOT NULL
+);
+INSERT INTO tuition_copy SELECT tuition_id, student_no, term, tuition_total, billed_total, status FROM tuition;
+DROP TABLE tuition;
+
+CREATE TABLE tuition (
+    tuition_id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_no          TEXT NOT NULL,
+    term                TEXT NOT NULL CHECK (length(term) <= 50),
+    tuition_total       REAL NOT NULL,
+    -- tuition_total is what is still owed for the term; billed_total is what was billed
+    billed_total        REAL NOT NULL DEFAULT 0,
+    status              TEXT NOT NULL DEFAULT 'active',
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no),
+    CONSTRAINT fk_term FOREIGN KEY (term) REFERENCES term(code),
+    CONSTRAINT tuition_status_valid CHECK (status IN ('active', 'cancelled'))
+);
+INSERT INTO tuition SELECT * FROM tuition_copy;
+DROP TABLE tuition_copy;
+
+CREATE INDEX IF NOT EXISTS tuition_term_idx ON tuition(term);
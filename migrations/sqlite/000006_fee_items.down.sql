@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tuition_item;
+DROP TABLE IF EXISTS fee_type;
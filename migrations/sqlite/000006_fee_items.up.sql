@@ -0,0 +1,39 @@
+-- Kinds of charges on an invoice. Payments settle items in ascending priority.
+CREATE TABLE IF NOT EXISTS fee_type (
+    code                TEXT PRIMARY KEY CHECK (length(code) <= 30),
+    name                TEXT NOT NULL CHECK (length(name) <= 100),
+    priority            INTEGER NOT NULL,
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+INSERT OR IGNORE INTO fee_type (code, name, priority) VALUES
+    ('tuition', 'Tuition', 10),
+    ('health_insurance', 'Health insurance', 20),
+    ('lab', 'Laboratory fee', 30),
+    ('dormitory', 'Dormitory', 40),
+    ('student_union', 'Student union fee', 50);
+
+-- Line items of a tuition. tuition.billed_total is the sum of amount and
+-- tuition.tuition_total the sum of what is still owed (amount - amount_paid).
+CREATE TABLE IF NOT EXISTS tuition_item (
+    item_id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    tuition_id          INTEGER NOT NULL,
+    fee_type            TEXT NOT NULL CHECK (length(fee_type) <= 30),
+    description         TEXT NOT NULL DEFAULT '' CHECK (length(description) <= 200),
+    amount              REAL NOT NULL,
+    amount_paid         REAL NOT NULL DEFAULT 0,
+
+    CONSTRAINT fk_tuition FOREIGN KEY (tuition_id) REFERENCES tuition(tuition_id),
+    CONSTRAINT fk_fee_type FOREIGN KEY (fee_type) REFERENCES fee_type(code),
+    CONSTRAINT tuition_item_amount_positive CHECK (amount > 0),
+    CONSTRAINT tuition_item_amount_paid_valid CHECK (amount_paid >= 0 AND amount_paid <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS tuition_item_tuition_id_idx ON tuition_item(tuition_id);
+
+-- Existing tuitions become a single tuition item
+INSERT INTO tuition_item (tuition_id, fee_type, amount, amount_paid)
+SELECT tuition_id, 'tuition', billed_total,
+       CASE WHEN status = 'active' THEN billed_total - tuition_total ELSE 0 END
+FROM tuition
+WHERE billed_total > 0;
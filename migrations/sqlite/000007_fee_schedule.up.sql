@@ -0,0 +1,16 @@
+-- Amounts billed to every student of a program and cohort for a term, one row
+-- per fee type. Generating a term's tuitions turns them into tuition items.
+CREATE TABLE IF NOT EXISTS fee_schedule (
+    schedule_id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    program             TEXT NOT NULL CHECK (length(program) <= 100),
+    enrollment_year     INTEGER NOT NULL,
+    term                TEXT NOT NULL CHECK (length(term) <= 50),
+    fee_type            TEXT NOT NULL DEFAULT 'tuition' CHECK (length(fee_type) <= 30),
+    amount              REAL NOT NULL,
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    CONSTRAINT fk_term FOREIGN KEY (term) REFERENCES term(code),
+    CONSTRAINT fk_fee_type FOREIGN KEY (fee_type) REFERENCES fee_type(code),
+    CONSTRAINT fee_schedule_amount_positive CHECK (amount > 0),
+    CONSTRAINT fee_schedule_unique UNIQUE (term, program, enrollment_year, fee_type)
+);
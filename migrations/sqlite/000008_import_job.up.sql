@@ -0,0 +1,49 @@
+-- Background imports. A job is claimed by a worker; a running job whose
+-- heartbeat stopped (its instance died) is picked up again by another worker.
+CREATE TABLE IF NOT EXISTS import_job (
+    job_id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind                TEXT NOT NULL CHECK (length(kind) <= 30),
+    status              TEXT NOT NULL DEFAULT 'queued',
+    filename            TEXT NOT NULL DEFAULT '' CHECK (length(filename) <= 255),
+    format              TEXT NOT NULL CHECK (length(format) <= 10),
+    mode                TEXT NOT NULL CHECK (length(mode) <= 20),
+    dry_run             BOOLEAN NOT NULL DEFAULT FALSE,
+    -- JSON object of field name to header
+    mapping             TEXT NOT NULL DEFAULT '{}',
+    total_rows          INTEGER NOT NULL DEFAULT 0,
+    processed_rows      INTEGER NOT NULL DEFAULT 0,
+    imported            INTEGER NOT NULL DEFAULT 0,
+    invalid             INTEGER NOT NULL DEFAULT 0,
+    committed           BOOLEAN NOT NULL DEFAULT FALSE,
+    failure             TEXT NOT NULL DEFAULT '' CHECK (length(failure) <= 500),
+    cancel_requested    BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by          TEXT NOT NULL DEFAULT '' CHECK (length(created_by) <= 50),
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    started_at          DATETIME,
+    heartbeat_at        DATETIME,
+    finished_at         DATETIME,
+
+    CONSTRAINT import_job_status_valid CHECK (status IN ('queued', 'running', 'completed', 'failed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS import_job_status_idx ON import_job(status);
+
+-- The uploaded file, kept until the job is done so it can be resumed
+CREATE TABLE IF NOT EXISTS import_job_file (
+    job_id              INTEGER PRIMARY KEY,
+    payload             BLOB NOT NULL,
+
+    CONSTRAINT fk_import_job FOREIGN KEY (job_id) REFERENCES import_job(job_id)
+);
+
+CREATE TABLE IF NOT EXISTS import_job_error (
+    error_id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id              INTEGER NOT NULL,
+    line                INTEGER NOT NULL,
+    field               TEXT NOT NULL DEFAULT '' CHECK (length(field) <= 50),
+    message             TEXT NOT NULL CHECK (length(message) <= 500),
+
+    CONSTRAINT fk_import_job FOREIGN KEY (job_id) REFERENCES import_job(job_id)
+);
+
+CREATE INDEX IF NOT EXISTS import_job_error_job_id_idx ON import_job_error(job_id);
@@ -0,0 +1,3 @@
+ALTER TABLE import_job DROP COLUMN skipped;
+ALTER TABLE import_job DROP COLUMN updated;
+ALTER TABLE import_job DROP COLUMN created;
@@ -0,0 +1,4 @@
+-- What an import did with the rows it saved, for imports that update as well as create
+ALTER TABLE import_job ADD COLUMN created INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE import_job ADD COLUMN updated INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE import_job ADD COLUMN skipped INTEGER NOT NULL DEFAULT 0;
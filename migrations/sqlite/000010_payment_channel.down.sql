@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS payment_created_at_idx;
+ALTER TABLE payment DROP COLUMN channel;
@@ -0,0 +1,3 @@
+-- Where a payment was made, for reporting. Payments so far all came from the banking API.
+ALTER TABLE payment ADD COLUMN channel TEXT NOT NULL DEFAULT 'banking' CHECK (length(channel) <= 20);
+CREATE INDEX IF NOT EXISTS payment_created_at_idx ON payment(created_at);
@@ -0,0 +1 @@
+ALTER TABLE tuition DROP COLUMN created_at;
@@ -0,0 +1,8 @@
+-- When a tuition was billed, for statements. SQLite cannot add a column defaulting to
+-- the current time, so AddTuitionToOneStudent sets it. Tuitions billed before this
+-- are dated by their first payment or change, or else by this migration.
+ALTER TABLE tuition ADD COLUMN created_at DATETIME;
+UPDATE tuition SET created_at = min(
+    coalesce((SELECT min(payment.created_at) FROM payment WHERE payment.student_no = tuition.student_no AND payment.term = tuition.term), strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    coalesce((SELECT min(tuition_change.created_at) FROM tuition_change WHERE tuition_change.tuition_id = tuition.tuition_id), strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
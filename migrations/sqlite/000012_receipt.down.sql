@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS receipt_allocation;
+DROP TABLE IF EXISTS receipt;
@@ -0,0 +1,25 @@
+-- A receipt is issued for every payment. Payments made before receipts have none.
+CREATE TABLE IF NOT EXISTS receipt (
+    receipt_no          TEXT PRIMARY KEY CHECK (length(receipt_no) <= 20),
+    payment_id          INTEGER NOT NULL UNIQUE,
+    -- Printed on the receipt so anyone holding it can check it is genuine
+    verification_code   TEXT NOT NULL CHECK (length(verification_code) <= 20),
+    -- What was still owed for the term after the payment
+    outstanding_after   REAL NOT NULL,
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    CONSTRAINT fk_payment FOREIGN KEY (payment_id) REFERENCES payment(payment_id)
+);
+
+-- The fee items a payment settled
+CREATE TABLE IF NOT EXISTS receipt_allocation (
+    allocation_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    receipt_no          TEXT NOT NULL CHECK (length(receipt_no) <= 20),
+    fee_type            TEXT NOT NULL CHECK (length(fee_type) <= 30),
+    description         TEXT NOT NULL DEFAULT '' CHECK (length(description) <= 200),
+    amount              REAL NOT NULL,
+
+    CONSTRAINT fk_receipt FOREIGN KEY (receipt_no) REFERENCES receipt(receipt_no)
+);
+
+CREATE INDEX IF NOT EXISTS receipt_allocation_receipt_no_idx ON receipt_allocation(receipt_no);
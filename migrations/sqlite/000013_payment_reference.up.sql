@@ -0,0 +1,11 @@
+-- Structured references students quote on bank transfers, one per student and term.
+-- They are made the first time a student's tuition for the term is looked at.
+CREATE TABLE IF NOT EXISTS payment_reference (
+    reference           TEXT PRIMARY KEY CHECK (length(reference) <= 25),
+    student_no          TEXT NOT NULL CHECK (length(student_no) <= 11),
+    term                TEXT NOT NULL CHECK (length(term) <= 50),
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no),
+    CONSTRAINT payment_reference_student_term_key UNIQUE (student_no, term)
+);
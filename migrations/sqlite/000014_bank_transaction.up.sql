@@ -0,0 +1,33 @@
+-- Credits read from imported bank statements (CAMT.053, MT940). A credit matched to
+-- a tuition is posted as a payment at once; the rest wait in the queue until an
+-- admin resolves or dismisses them.
+CREATE TABLE IF NOT EXISTS bank_transaction (
+    transaction_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- Identifies the statement entry so importing a statement again posts nothing twice
+    entry_key           TEXT NOT NULL UNIQUE CHECK (length(entry_key) <= 64),
+    format              TEXT NOT NULL CHECK (length(format) <= 10),
+    statement_id        TEXT NOT NULL DEFAULT '' CHECK (length(statement_id) <= 70),
+    account             TEXT NOT NULL DEFAULT '' CHECK (length(account) <= 34),
+    bank_reference      TEXT NOT NULL DEFAULT '' CHECK (length(bank_reference) <= 35),
+    booking_date        DATE,
+    amount              REAL NOT NULL,
+    currency            TEXT NOT NULL DEFAULT '' CHECK (length(currency) <= 3),
+    debtor              TEXT NOT NULL DEFAULT '' CHECK (length(debtor) <= 140),
+    remittance          TEXT NOT NULL DEFAULT '' CHECK (length(remittance) <= 500),
+    status              TEXT NOT NULL,
+    -- Why a queued credit was not posted: unmatched or ambiguous
+    issue               TEXT NOT NULL DEFAULT '' CHECK (length(issue) <= 20),
+    detail              TEXT NOT NULL DEFAULT '' CHECK (length(detail) <= 500),
+    student_no          TEXT CHECK (length(student_no) <= 11),
+    term                TEXT CHECK (length(term) <= 50),
+    payment_id          INTEGER UNIQUE,
+    resolved_by         TEXT NOT NULL DEFAULT '' CHECK (length(resolved_by) <= 50),
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    resolved_at         DATETIME,
+
+    CONSTRAINT fk_student FOREIGN KEY (student_no) REFERENCES student(student_no),
+    CONSTRAINT fk_payment FOREIGN KEY (payment_id) REFERENCES payment(payment_id),
+    CONSTRAINT bank_transaction_status_valid CHECK (status IN ('posted', 'queued', 'resolved', 'dismissed'))
+);
+
+CREATE INDEX IF NOT EXISTS bank_transaction_status_idx ON bank_transaction(status);
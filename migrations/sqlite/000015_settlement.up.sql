@@ -0,0 +1,33 @@
+-- Banks that take tuition payments through the banking API. A partner sends its code
+-- with each payment, and its payments are settled a business day at a time.
+CREATE TABLE IF NOT EXISTS bank_partner (
+    code                TEXT PRIMARY KEY CHECK (length(code) <= 30),
+    name                TEXT NOT NULL CHECK (length(name) <= 100),
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- No REFERENCES: SQLite cannot drop a column used in a foreign key, and the down
+-- migration drops it
+ALTER TABLE payment ADD COLUMN partner TEXT CHECK (length(partner) <= 30);
+CREATE INDEX IF NOT EXISTS payment_partner_idx ON payment(partner, created_at);
+
+-- A closed business day (UTC) of a partner: the payments it took that day, and the
+-- totals the partner reported for it once they are in.
+CREATE TABLE IF NOT EXISTS settlement (
+    settlement_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    partner             TEXT NOT NULL CHECK (length(partner) <= 30),
+    business_date       DATE NOT NULL,
+    payment_count       INTEGER NOT NULL,
+    total_amount        REAL NOT NULL,
+    -- closed until the partner's totals are in, then matched or discrepancy
+    status              TEXT NOT NULL DEFAULT 'closed',
+    reported_count      INTEGER,
+    reported_amount     REAL,
+    closed_by           TEXT NOT NULL DEFAULT '' CHECK (length(closed_by) <= 50),
+    created_at          DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    reported_at         DATETIME,
+
+    CONSTRAINT fk_partner FOREIGN KEY (partner) REFERENCES bank_partner(code),
+    CONSTRAINT settlement_partner_day_key UNIQUE (partner, business_date),
+    CONSTRAINT settlement_status_valid CHECK (status IN ('closed', 'matched', 'discrepancy'))
+);
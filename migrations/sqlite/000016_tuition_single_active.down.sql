@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tuition_single_active_idx;
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page layout of a PDF report: A4 landscape with Courier, whose fixed width lets
+// callers lay out columns by character.
+const (
+	pdfPageWidth  = 842
+	pdfPageHeight = 595
+	pdfMargin     = 36
+	pdfFontSize   = 8
+	pdfLeading    = 10
+	// Characters and lines that fit on a page, less a line for the footer
+	pdfLineWidth = 160
+	pdfPageLines = (pdfPageHeight-2*pdfMargin)/pdfLeading - 1
+)
+
+// Objects written last, once every page is known
+const (
+	pdfCatalogObject = 1
+	pdfPagesObject   = 2
+	pdfFontObject    = 3
+	pdfInfoObject    = 4
+)
+
+// The Turkish letters outside Latin-1, placed where Windows-1254 has them. The
+// standard Courier font has these glyphs, so no font needs to be embedded.
+var pdfTurkish = map[rune]byte{'Ğ': 0xD0, 'İ': 0xDD, 'Ş': 0xDE, 'ğ': 0xF0, 'ı': 0xFD, 'ş': 0xFE}
+
+// pdfDocument writes a text-only PDF as it goes. A page is written out as soon as
+// it is full, so a long report never holds more than one page.
+type pdfDocument struct {
+	w       io.Writer
+	written int64
+	err     error
+	title   string
+	// Offsets of the objects, by object number
+	offsets []int64
+	pages   []int
+	heading []string
+	lines   []string
+}
+
+func newPDFDocument(w io.Writer, title string) *pdfDocument {
+	p := &pdfDocument{w: w, title: title, offsets: make([]int64, pdfInfoObject+1)}
+	p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	return p
+}
+
+func (p *pdfDocument) write(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := io.WriteString(p.w, s)
+	p.written += int64(n)
+	p.err = err
+}
+
+// object writes object n with the given body.
+func (p *pdfDocument) object(n int, body string) {
+	for len(p.offsets) <= n {
+		p.offsets = append(p.offsets, 0)
+	}
+	p.offsets[n] = p.written
+	p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", n, body))
+}
+
+// Heading writes lines that are repeated at the top of every later page.
+func (p *pdfDocument) Heading(lines ...string) {
+	p.heading = lines
+	for _, line := range lines {
+		p.Line(line)
+	}
+}
+
+// Line adds a line of text, starting a new page when this one is full. Text past
+// pdfLineWidth is cut off.
+func (p *pdfDocument) Line(text string) {
+	if runes := []rune(text); len(runes) > pdfLineWidth {
+		text = string(runes[:pdfLineWidth])
+	}
+	if len(p.lines) == pdfPageLines {
+		p.flushPage()
+		p.lines = append(p.lines, p.heading...)
+	}
+	p.lines = append(p.lines, text)
+}
+
+func (p *pdfDocument) flushPage() {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize)
+	for i, line := range p.lines {
+		if i > 0 {
+			content.WriteString("T*\n")
+		}
+		fmt.Fprintf(&content, "(%s) Tj\n", pdfString(line))
+	}
+	footer := fmt.Sprintf("%s - page %d", p.title, len(p.pages)+1)
+	fmt.Fprintf(&content, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(%s) Tj\nET\n", pdfFontSize, pdfMargin, pdfMargin-pdfFontSize, pdfString(footer))
+
+	contents := len(p.offsets)
+	p.object(contents, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	p.object(contents+1, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfFontObject, contents))
+	p.pages = append(p.pages, contents+1)
+	p.lines = p.lines[:0]
+}
+
+// Close writes the last page and the document trailer. It does not close the
+// underlying writer.
+func (p *pdfDocument) Close() error {
+	if len(p.lines) > 0 || len(p.pages) == 0 {
+		p.flushPage()
+	}
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.object(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	p.object(pdfFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding << /Type /Encoding /BaseEncoding /WinAnsiEncoding "+
+		"/Differences [208 /Gbreve 221 /Idotaccent 222 /Scedilla 240 /gbreve 253 /dotlessi 254 /scedilla] >> >>")
+	p.object(pdfInfoObject, fmt.Sprintf("<< /Title (%s) /Producer (tuition) >>", pdfString(p.title)))
+	p.object(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+
+	xref := p.written
+	p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)))
+	for _, offset := range p.offsets[1:] {
+		p.write(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets), pdfCatalogObject, pdfInfoObject, xref))
+	return p.err
+}
+
+// pdfString encodes text for a PDF string in the font's encoding. Characters it
+// cannot show become '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= ' ' && r < 0x7F:
+			b.WriteRune(r)
+		case pdfTurkish[r] != 0:
+			b.WriteByte(pdfTurkish[r])
+		case r >= 0xA0 && r <= 0xFF && !strings.ContainsRune("ÐÝÞðýþ", r):
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -18,7 +18,8 @@ SELECT * FROM student
 INNER JOIN tuition
 ON student.student_no = tuition.student_no
 WHERE student.student_no = $1
-AND tuition.term = $2;
+AND tuition.term = $2
+AND tuition.status = 'active';
 
 -- name: AddNewStudent :exec
 INSERT INTO student(student_no,balance)
@@ -45,9 +46,10 @@ SET tuition_total = 0
 WHERE student_no = $1 
 AND term = $2;
 
--- name: AddTuitionToOneStudent :exec
-INSERT INTO tuition(student_no,term,tuition_total)
-VALUES ($1,$2,$3);
+-- name: AddTuitionToOneStudent :one
+INSERT INTO tuition(student_no,term,tuition_total,billed_total)
+VALUES ($1,$2,$3,$3)
+RETURNING *;
 
 -- name: UnpaidTuitions :many
 SELECT *
@@ -56,3 +58,632 @@ INNER JOIN tuition
 ON student.student_no = tuition.student_no
 WHERE tuition.tuition_total > 0
 LIMIT $1 OFFSET $2;
+
+-- name: ListUnpaidReport :many
+-- Active tuitions with an amount outstanding, in report order. Pages are keyed by
+-- the last row of the previous page, so an export only ever holds one page.
+SELECT tuition.tuition_id, tuition.term, term.payment_due_date,
+       tuition.billed_total, tuition.tuition_total,
+       student.student_no, student.first_name, student.last_name, student.email, student.phone,
+       student.faculty, student.department, student.program, student.enrollment_year, student.enrollment_status
+FROM tuition
+INNER JOIN student
+ON student.student_no = tuition.student_no
+LEFT JOIN term
+ON term.code = tuition.term
+WHERE tuition.status = 'active'
+AND tuition.tuition_total > 0
+AND coalesce(tuition.term = sqlc.narg(term)::text, TRUE)
+AND coalesce(student.faculty = sqlc.narg(faculty)::text, TRUE)
+AND coalesce(student.program = sqlc.narg(program)::text, TRUE)
+AND (tuition.term, student.faculty, student.student_no, tuition.tuition_id)
+    > (sqlc.arg(after_term)::text, sqlc.arg(after_faculty)::text, sqlc.arg(after_student_no)::text, sqlc.arg(after_tuition_id)::int)
+ORDER BY tuition.term, student.faculty, student.student_no, tuition.tuition_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: LockStudentBalance :one
+SELECT balance
+FROM student
+WHERE student_no = $1
+FOR UPDATE;
+
+-- name: GetStudent :one
+SELECT * FROM student
+WHERE student_no = $1;
+
+-- name: ListStudents :many
+-- Students whose number starts with prefix; a null filter matches every student.
+SELECT * FROM student
+WHERE substr(student_no, 1, length(sqlc.arg(prefix)::text)) = sqlc.arg(prefix)::text
+AND coalesce(faculty = sqlc.narg(faculty)::text, TRUE)
+AND coalesce(department = sqlc.narg(department)::text, TRUE)
+AND coalesce(program = sqlc.narg(program)::text, TRUE)
+AND coalesce(enrollment_status = sqlc.narg(enrollment_status)::text, TRUE)
+AND coalesce(enrollment_year = sqlc.narg(enrollment_year)::integer, TRUE)
+AND coalesce((deactivated_at IS NULL) = sqlc.narg(active)::boolean, TRUE)
+AND coalesce(EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.tuition_total > 0
+) = sqlc.narg(has_unpaid)::boolean, TRUE)
+ORDER BY student_no
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountStudents :one
+SELECT count(*) FROM student
+WHERE substr(student_no, 1, length(sqlc.arg(prefix)::text)) = sqlc.arg(prefix)::text
+AND coalesce(faculty = sqlc.narg(faculty)::text, TRUE)
+AND coalesce(department = sqlc.narg(department)::text, TRUE)
+AND coalesce(program = sqlc.narg(program)::text, TRUE)
+AND coalesce(enrollment_status = sqlc.narg(enrollment_status)::text, TRUE)
+AND coalesce(enrollment_year = sqlc.narg(enrollment_year)::integer, TRUE)
+AND coalesce((deactivated_at IS NULL) = sqlc.narg(active)::boolean, TRUE)
+AND coalesce(EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.tuition_total > 0
+) = sqlc.narg(has_unpaid)::boolean, TRUE);
+
+-- name: UpdateStudent :exec
+UPDATE student
+SET balance = $2,
+    daily_payment_limit = $3,
+    first_name = $4,
+    last_name = $5,
+    email = $6,
+    phone = $7,
+    faculty = $8,
+    department = $9,
+    program = $10,
+    enrollment_year = $11,
+    enrollment_status = $12
+WHERE student_no = $1;
+
+-- name: DeactivateStudent :exec
+UPDATE student
+SET deactivated_at = now()
+WHERE student_no = $1
+AND deactivated_at IS NULL;
+
+-- name: ReactivateStudent :exec
+UPDATE student
+SET deactivated_at = NULL
+WHERE student_no = $1;
+
+-- name: ListTuitionsByStudent :many
+SELECT * FROM tuition
+WHERE student_no = $1
+ORDER BY tuition_id;
+
+-- name: AddPayment :one
+INSERT INTO payment(student_no,term,amount,balance_after,channel,partner)
+VALUES ($1,$2,$3,$4,$5,$6)
+RETURNING *;
+
+-- name: ListPaymentsByStudent :many
+SELECT * FROM payment
+WHERE student_no = $1
+ORDER BY created_at, payment_id;
+
+-- name: GetTuition :one
+SELECT * FROM tuition
+WHERE tuition_id = $1;
+
+-- name: LockTuition :one
+SELECT * FROM tuition
+WHERE tuition_id = $1
+FOR UPDATE;
+
+-- name: ListTuitions :many
+-- A null filter matches every tuition.
+SELECT * FROM tuition
+WHERE coalesce(student_no = sqlc.narg(student_no)::text, TRUE)
+AND coalesce(term = sqlc.narg(term)::text, TRUE)
+AND coalesce(status = sqlc.narg(status)::text, TRUE)
+AND coalesce((tuition_total > 0) = sqlc.narg(unpaid)::boolean, TRUE)
+ORDER BY tuition_id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountTuitions :one
+SELECT count(*) FROM tuition
+WHERE coalesce(student_no = sqlc.narg(student_no)::text, TRUE)
+AND coalesce(term = sqlc.narg(term)::text, TRUE)
+AND coalesce(status = sqlc.narg(status)::text, TRUE)
+AND coalesce((tuition_total > 0) = sqlc.narg(unpaid)::boolean, TRUE);
+
+-- name: AmendTuition :exec
+UPDATE tuition
+SET term = $2,
+    billed_total = $3,
+    tuition_total = $4
+WHERE tuition_id = $1;
+
+-- name: CancelTuition :exec
+UPDATE tuition
+SET status = 'cancelled',
+    tuition_total = 0
+WHERE tuition_id = $1;
+
+-- name: AddTuitionChange :exec
+INSERT INTO tuition_change(tuition_id,action,old_term,new_term,old_amount,new_amount,balance_adjustment,reason,changed_by)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9);
+
+-- name: ListTuitionChanges :many
+SELECT * FROM tuition_change
+WHERE tuition_id = $1
+ORDER BY created_at, change_id;
+
+-- name: ListStudentTuitionChanges :many
+SELECT tuition_change.* FROM tuition_change
+INNER JOIN tuition
+ON tuition.tuition_id = tuition_change.tuition_id
+WHERE tuition.student_no = $1
+ORDER BY tuition_change.created_at, tuition_change.change_id;
+
+-- name: CountPaymentsForTerm :one
+SELECT count(*) FROM payment
+WHERE student_no = $1
+AND term = $2;
+
+-- name: CreateTerm :one
+INSERT INTO term (code, name, start_date, end_date, payment_due_date, active)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetTerm :one
+SELECT * FROM term
+WHERE code = $1;
+
+-- name: GetActiveTerm :one
+SELECT * FROM term
+WHERE active;
+
+-- name: ListTerms :many
+-- Terms in calendar order; terms without dates come last.
+SELECT * FROM term
+ORDER BY start_date IS NULL, start_date, code;
+
+-- name: UpdateTerm :exec
+UPDATE term
+SET name = $2,
+    start_date = $3,
+    end_date = $4,
+    payment_due_date = $5,
+    active = $6
+WHERE code = $1;
+
+-- name: ClearActiveTerm :exec
+UPDATE term
+SET active = FALSE
+WHERE active;
+
+-- name: DeleteTerm :exec
+DELETE FROM term
+WHERE code = $1;
+
+-- name: ListFeeTypes :many
+SELECT * FROM fee_type
+ORDER BY priority, code;
+
+-- name: GetFeeType :one
+SELECT * FROM fee_type
+WHERE code = $1;
+
+-- name: CreateFeeType :one
+INSERT INTO fee_type (code, name, priority)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateFeeType :exec
+UPDATE fee_type
+SET name = $2,
+    priority = $3
+WHERE code = $1;
+
+-- name: AddTuitionItem :one
+INSERT INTO tuition_item (tuition_id, fee_type, description, amount, amount_paid)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListTuitionItems :many
+-- Items in the order payments settle them.
+SELECT tuition_item.*, fee_type.name AS fee_type_name, fee_type.priority
+FROM tuition_item
+INNER JOIN fee_type
+ON fee_type.code = tuition_item.fee_type
+WHERE tuition_item.tuition_id = $1
+ORDER BY fee_type.priority, tuition_item.item_id;
+
+-- name: UpdateTuitionItem :exec
+UPDATE tuition_item
+SET amount = $2,
+    amount_paid = $3
+WHERE item_id = $1;
+
+-- name: SetTuitionItemPaid :exec
+-- Settling an item leaves its amount alone.
+UPDATE tuition_item
+SET amount_paid = $2
+WHERE item_id = $1;
+
+-- name: SetTuitionOutstanding :one
+-- A cancelled tuition is left alone and returns no rows.
+UPDATE tuition
+SET tuition_total = $2
+WHERE tuition_id = $1
+AND status = 'active'
+RETURNING *;
+
+-- name: ListFeeSchedules :many
+-- A null filter matches every schedule.
+SELECT * FROM fee_schedule
+WHERE coalesce(term = sqlc.narg(term)::text, TRUE)
+AND coalesce(program = sqlc.narg(program)::text, TRUE)
+ORDER BY term, program, enrollment_year, schedule_id;
+
+-- name: GetFeeSchedule :one
+SELECT * FROM fee_schedule
+WHERE schedule_id = $1;
+
+-- name: CreateFeeSchedule :one
+INSERT INTO fee_schedule (program, enrollment_year, term, fee_type, amount)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UpdateFeeSchedule :exec
+UPDATE fee_schedule
+SET amount = $2
+WHERE schedule_id = $1;
+
+-- name: DeleteFeeSchedule :exec
+DELETE FROM fee_schedule
+WHERE schedule_id = $1;
+
+-- name: ListScheduledCharges :many
+-- The scheduled items of every enrolled student not yet billed for the term,
+-- grouped by student in payment order.
+SELECT student.student_no, fee_schedule.program, fee_schedule.enrollment_year, fee_schedule.fee_type, fee_schedule.amount
+FROM fee_schedule
+INNER JOIN student
+ON student.program = fee_schedule.program
+AND student.enrollment_year = fee_schedule.enrollment_year
+INNER JOIN fee_type
+ON fee_type.code = fee_schedule.fee_type
+WHERE fee_schedule.term = $1
+AND student.deactivated_at IS NULL
+AND student.enrollment_status = 'enrolled'
+AND NOT EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.term = fee_schedule.term
+    AND tuition.status = 'active'
+)
+ORDER BY student.student_no, fee_type.priority, fee_schedule.fee_type;
+
+-- name: CountScheduledBilled :one
+-- Enrolled students a schedule covers who already have a tuition for the term.
+SELECT count(DISTINCT student.student_no)
+FROM fee_schedule
+INNER JOIN student
+ON student.program = fee_schedule.program
+AND student.enrollment_year = fee_schedule.enrollment_year
+WHERE fee_schedule.term = $1
+AND student.deactivated_at IS NULL
+AND student.enrollment_status = 'enrolled'
+AND EXISTS (
+    SELECT 1 FROM tuition
+    WHERE tuition.student_no = student.student_no
+    AND tuition.term = fee_schedule.term
+    AND tuition.status = 'active'
+);
+
+-- name: CreateImportJob :one
+INSERT INTO import_job (kind, filename, format, mode, dry_run, mapping, total_rows, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: AddImportJobFile :exec
+INSERT INTO import_job_file (job_id, payload)
+VALUES ($1, $2);
+
+-- name: GetImportJobFile :one
+SELECT payload FROM import_job_file
+WHERE job_id = $1;
+
+-- name: DeleteImportJobFile :exec
+DELETE FROM import_job_file
+WHERE job_id = $1;
+
+-- name: GetImportJob :one
+SELECT * FROM import_job
+WHERE job_id = $1;
+
+-- name: ListImportJobs :many
+SELECT * FROM import_job
+WHERE coalesce(status = sqlc.narg(status)::text, TRUE)
+ORDER BY job_id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountImportJobs :one
+SELECT count(*) FROM import_job
+WHERE coalesce(status = sqlc.narg(status)::text, TRUE);
+
+-- name: ClaimImportJob :one
+-- Takes the oldest queued job, or a running one whose heartbeat is older than
+-- jobLease.
+UPDATE import_job
+SET status = 'running',
+    started_at = coalesce(started_at, now()),
+    heartbeat_at = now()
+WHERE job_id = (
+    SELECT job_id FROM import_job
+    WHERE status = 'queued'
+    OR (status = 'running' AND heartbeat_at < now() - interval '1 minute')
+    ORDER BY job_id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: HeartbeatImportJob :exec
+UPDATE import_job
+SET heartbeat_at = now()
+WHERE job_id = $1
+AND status = 'running';
+
+-- name: UpdateImportJobProgress :exec
+UPDATE import_job
+SET processed_rows = $2,
+    imported = $3,
+    invalid = $4,
+    created = $5,
+    updated = $6,
+    skipped = $7,
+    heartbeat_at = now()
+WHERE job_id = $1;
+
+-- name: FinishImportJob :exec
+UPDATE import_job
+SET status = $2,
+    processed_rows = $3,
+    imported = $4,
+    invalid = $5,
+    committed = $6,
+    failure = $7,
+    created = $8,
+    updated = $9,
+    skipped = $10,
+    finished_at = now()
+WHERE job_id = $1;
+
+-- name: CancelImportJob :exec
+-- A queued job is cancelled right away; a running one stops at its next check.
+UPDATE import_job
+SET cancel_requested = TRUE,
+    status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+    finished_at = CASE WHEN status = 'queued' THEN now() ELSE finished_at END
+WHERE job_id = $1
+AND status IN ('queued', 'running');
+
+-- name: AddImportJobError :exec
+INSERT INTO import_job_error (job_id, line, field, message)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListImportJobErrors :many
+SELECT * FROM import_job_error
+WHERE job_id = $1
+ORDER BY line, error_id;
+
+-- name: SummarizeTerms :many
+-- Billing per term with the payments received for it in the range. A student is
+-- fully paid for a term once nothing of it is outstanding.
+SELECT billed.term,
+       count(*)::int AS students,
+       sum(CASE WHEN billed.outstanding <= 0 THEN 1 ELSE 0 END)::int AS fully_paid,
+       sum(billed.billed)::float8 AS billed,
+       sum(billed.outstanding)::float8 AS outstanding,
+       coalesce(max(received.amount), 0)::float8 AS received
+FROM (
+    SELECT term, student_no, sum(billed_total) AS billed, sum(tuition_total) AS outstanding
+    FROM tuition
+    WHERE status = 'active'
+    GROUP BY term, student_no
+) billed
+LEFT JOIN (
+    SELECT term, sum(amount) AS amount
+    FROM payment
+    WHERE coalesce(created_at >= sqlc.narg(from_time)::timestamptz, TRUE)
+    AND coalesce(created_at < sqlc.narg(to_time)::timestamptz, TRUE)
+    GROUP BY term
+) received
+ON received.term = billed.term
+GROUP BY billed.term
+ORDER BY billed.term;
+
+-- name: SummarizeStudents :one
+-- Students with an active tuition, and how many of them owe nothing.
+SELECT count(*)::int AS students,
+       coalesce(sum(CASE WHEN outstanding <= 0 THEN 1 ELSE 0 END), 0)::int AS fully_paid
+FROM (
+    SELECT student_no, sum(tuition_total) AS outstanding
+    FROM tuition
+    WHERE status = 'active'
+    GROUP BY student_no
+) owed;
+
+-- name: SummarizePaymentsByDay :many
+-- Days are UTC days.
+SELECT to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+       count(*)::int AS payments,
+       sum(amount)::float8 AS amount
+FROM payment
+WHERE coalesce(created_at >= sqlc.narg(from_time)::timestamptz, TRUE)
+AND coalesce(created_at < sqlc.narg(to_time)::timestamptz, TRUE)
+GROUP BY day
+ORDER BY day;
+
+-- name: SummarizePaymentsByChannel :many
+SELECT channel,
+       count(*)::int AS payments,
+       sum(amount)::float8 AS amount
+FROM payment
+WHERE coalesce(created_at >= sqlc.narg(from_time)::timestamptz, TRUE)
+AND coalesce(created_at < sqlc.narg(to_time)::timestamptz, TRUE)
+GROUP BY channel
+ORDER BY channel;
+
+-- name: ListTopDebtors :many
+-- The students owing the most over all their active tuitions.
+SELECT student.student_no, student.first_name, student.last_name, student.faculty,
+       count(*)::int AS tuitions,
+       sum(tuition.tuition_total)::float8 AS outstanding
+FROM tuition
+INNER JOIN student
+ON student.student_no = tuition.student_no
+WHERE tuition.status = 'active'
+AND tuition.tuition_total > 0
+GROUP BY student.student_no
+ORDER BY outstanding DESC, student.student_no
+LIMIT $1;
+
+-- name: AddReceipt :one
+INSERT INTO receipt (receipt_no, payment_id, verification_code, outstanding_after)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: AddReceiptAllocation :exec
+INSERT INTO receipt_allocation (receipt_no, fee_type, description, amount)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetReceipt :one
+SELECT receipt.*, payment.student_no, payment.term, payment.amount, payment.balance_after, payment.channel,
+       payment.partner,
+       student.first_name, student.last_name, student.faculty, student.program
+FROM receipt
+INNER JOIN payment
+ON payment.payment_id = receipt.payment_id
+INNER JOIN student
+ON student.student_no = payment.student_no
+WHERE receipt.receipt_no = $1;
+
+-- name: ListReceiptAllocations :many
+SELECT * FROM receipt_allocation
+WHERE receipt_no = $1
+ORDER BY allocation_id;
+
+-- name: AddPaymentReference :one
+INSERT INTO payment_reference (reference, student_no, term)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetPaymentReference :one
+SELECT * FROM payment_reference
+WHERE reference = $1;
+
+-- name: GetStudentPaymentReference :one
+SELECT * FROM payment_reference
+WHERE student_no = $1
+AND term = $2;
+
+-- name: CountPaymentReferences :one
+SELECT count(*) FROM payment_reference
+WHERE student_no = $1;
+
+-- name: AddBankTransaction :one
+INSERT INTO bank_transaction (
+    entry_key, format, statement_id, account, bank_reference, booking_date, amount, currency,
+    debtor, remittance, status, issue, detail, student_no, term, payment_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+RETURNING *;
+
+-- name: CountBankTransactionsByKey :one
+SELECT count(*) FROM bank_transaction
+WHERE entry_key = $1;
+
+-- name: GetBankTransaction :one
+SELECT * FROM bank_transaction
+WHERE transaction_id = $1;
+
+-- name: ListBankTransactions :many
+SELECT * FROM bank_transaction
+WHERE coalesce(status = sqlc.narg(status)::text, TRUE)
+ORDER BY transaction_id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountBankTransactions :one
+SELECT count(*) FROM bank_transaction
+WHERE coalesce(status = sqlc.narg(status)::text, TRUE);
+
+-- name: CloseBankTransaction :one
+-- Resolves or dismisses a queued transaction; no row comes back when it is not queued.
+UPDATE bank_transaction
+SET status = $2,
+    detail = $3,
+    student_no = $4,
+    term = $5,
+    payment_id = $6,
+    resolved_by = $7,
+    resolved_at = now()
+WHERE transaction_id = $1
+AND status = 'queued'
+RETURNING *;
+
+-- name: AddBankPartner :one
+INSERT INTO bank_partner (code, name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetBankPartner :one
+SELECT * FROM bank_partner
+WHERE code = $1;
+
+-- name: ListBankPartners :many
+SELECT * FROM bank_partner
+ORDER BY code;
+
+-- name: ListPartnerPayments :many
+-- The payments a partner took in [from_time, to_time), with their receipts.
+SELECT payment.payment_id, payment.student_no, payment.term, payment.amount,
+       payment.created_at, coalesce(receipt.receipt_no, '')::text AS receipt_no
+FROM payment
+LEFT JOIN receipt ON receipt.payment_id = payment.payment_id
+WHERE payment.partner = sqlc.arg(partner)::text
+AND payment.created_at >= sqlc.arg(from_time)::timestamptz
+AND payment.created_at < sqlc.arg(to_time)::timestamptz
+ORDER BY payment.created_at, payment.payment_id;
+
+-- name: AddSettlement :one
+INSERT INTO settlement (partner, business_date, payment_count, total_amount, closed_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetSettlement :one
+SELECT * FROM settlement
+WHERE settlement_id = $1;
+
+-- name: GetSettlementByDay :one
+SELECT * FROM settlement
+WHERE partner = $1 AND business_date = $2;
+
+-- name: ListSettlements :many
+SELECT * FROM settlement
+WHERE coalesce(partner = sqlc.narg(partner)::text, TRUE)
+AND coalesce(status = sqlc.narg(status)::text, TRUE)
+ORDER BY business_date DESC, partner
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountSettlements :one
+SELECT count(*) FROM settlement
+WHERE coalesce(partner = sqlc.narg(partner)::text, TRUE)
+AND coalesce(status = sqlc.narg(status)::text, TRUE);
+
+-- name: ReportSettlement :one
+-- Records the totals the partner reported for the day; reporting again replaces them.
+UPDATE settlement
+SET reported_count = $2,
+    reported_amount = $3,
+    status = $4,
+    reported_at = now()
+WHERE settlement_id = $1
+RETURNING *;
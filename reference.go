@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Payment references are ISO 11649 creditor references: RF, two check digits and
+// the student number followed by the student's term number, e.g. RF29 2207 0006
+// 0710 1. Banks check the digits before a transfer is sent.
+const referencePrefix = "RF"
+
+// normalizeReference takes a reference as a person typed it, in groups of four or
+// in lower case, to its stored form.
+func normalizeReference(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// referenceMod97 is the remainder of s divided by 97 with letters counted as two
+// digit numbers, A as 10 up to Z as 35. It is -1 when s has anything else.
+func referenceMod97(s string) int {
+	mod := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			mod = (mod*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			mod = (mod*100 + int(r-'A') + 10) % 97
+		default:
+			return -1
+		}
+	}
+	return mod
+}
+
+// newReference adds the prefix and check digits to body.
+func newReference(body string) string {
+	body = strings.ToUpper(body)
+	return fmt.Sprintf("%s%02d%s", referencePrefix, 98-referenceMod97(body+referencePrefix+"00"), body)
+}
+
+// validReference checks the format and check digits of a normalized reference.
+func validReference(ref string) bool {
+	if len(ref) < 5 || len(ref) > referenceMaxLength || !strings.HasPrefix(ref, referencePrefix) {
+		return false
+	}
+	return referenceMod97(ref[4:]+ref[:4]) == 1
+}
+
+// paymentReference gives the student's reference for a term, making it the first
+// time it is asked for. References stay the same once made.
+func paymentReference(ctx context.Context, store Store, studentNo, term string) (string, error) {
+	key := db.GetStudentPaymentReferenceParams{StudentNo: studentNo, Term: term}
+	for attempt := 0; ; attempt++ {
+		ref, err := store.GetStudentPaymentReference(ctx, key)
+		if err == nil {
+			return ref.Reference, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+
+		count, err := store.CountPaymentReferences(ctx, studentNo)
+		if err != nil {
+			return "", err
+		}
+		ref, err = store.AddPaymentReference(ctx, db.AddPaymentReferenceParams{
+			Reference: newReference(fmt.Sprintf("%s%02d", studentNo, count+1)),
+			StudentNo: studentNo,
+			Term:      term,
+		})
+		// Another request made a reference for this student at the same time; the
+		// next attempt finds it or takes the next number
+		if isConstraintError(err, pgUniqueViolation) && attempt < 3 {
+			continue
+		}
+		return ref.Reference, err
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestReferenceCheckDigits(t *testing.T) {
+	if got := newReference("2207000607101"); got != "RF292207000607101" {
+		t.Errorf("newReference = %q", got)
+	}
+	for ref, want := range map[string]bool{
+		"RF18539007547034":   true, // ISO 11649 example
+		"RF292207000607101":  true,
+		"RF292207000607102":  false,
+		"RF922207000607101":  false,
+		"RF29":               false,
+		"XX292207000607101":  false,
+		"RF29-2207000607101": false,
+	} {
+		if got := validReference(ref); got != want {
+			t.Errorf("validReference(%q) = %v, want %v", ref, got, want)
+		}
+	}
+	if got := normalizeReference(" rf18 5390 0754 7034 "); got != "RF18539007547034" {
+		t.Errorf("normalizeReference = %q", got)
+	}
+}
+
+func TestPayByReference(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ta *testApp) {
+		ta.addStudent("22070006071", 10)
+		ta.addStudent("22070006072", 10)
+		ta.addTerm("Fall2025")
+		ta.addTerm("Spring2026")
+		ta.addTuition("22070006071", "Fall2025", 1000)
+		ta.addTuition("22070006071", "Spring2026", 1000)
+		ta.addTuition("22070006072", "Fall2025", 1000)
+		token := ta.register("22070006071", "pw")
+
+		type tuitionQuery struct {
+			Term             string
+			PaymentReference string
+		}
+		query := func(term string) string {
+			t.Helper()
+			rec := ta.do(http.MethodGet, "/api/v2/banking/tuition?student_no=22070006071&active_term="+term, token, nil, nil)
+			got := decodeJSON[tuitionQuery](t, rec)
+			if !validReference(got.PaymentReference) {
+				t.Fatalf("%s reference = %q", term, got.PaymentReference)
+			}
+			return got.PaymentReference
+		}
+		fall, spring := query("Fall2025"), query("Spring2026")
+		if fall == spring || query("Fall2025") != fall {
+			t.Fatalf("references: fall %q, spring %q", fall, spring)
+		}
+
+		pay := func(params url.Values) int {
+			return ta.do(http.MethodPost, "/api/v2/banking/pay?"+params.Encode(), token, nil, nil).Code
+		}
+		bad := fall[:len(fall)-1] + string('0'+(fall[len(fall)-1]-'0'+1)%10)
+		for _, tt := range []struct {
+			params url.Values
+			code   int
+		}{
+			{url.Values{"reference": {bad}, "amount": {"100"}}, http.StatusBadRequest},
+			{url.Values{"reference": {newReference("2207000607199")}, "amount": {"100"}}, http.StatusNotFound},
+			{url.Values{"reference": {fall}, "term": {"Spring2026"}, "amount": {"100"}}, http.StatusBadRequest},
+			{url.Values{"reference": {fall}, "student_no": {"22070006072"}, "amount": {"100"}}, http.StatusBadRequest},
+			{url.Values{"amount": {"100"}}, http.StatusBadRequest},
+		} {
+			if code := pay(tt.params); code != tt.code {
+				t.Errorf("pay %v: got %d, want %d", tt.params, code, tt.code)
+			}
+		}
+		if ta.tuitionTotal("22070006071", "Fall2025") != 1000 {
+			t.Fatal("a rejected payment was recorded")
+		}
+
+		// The reference is all a bank needs, written however the payer typed it
+		if code := pay(url.Values{"reference": {" " + fall[:4] + " " + fall[4:] + " "}, "amount": {"1000"}}); code != http.StatusOK {
+			t.Fatalf("pay by reference: %d", code)
+		}
+		if got := ta.tuitionTotal("22070006071", "Fall2025"); got != 0 {
+			t.Errorf("Fall2025 tuition total = %v", got)
+		}
+		if got := ta.tuitionTotal("22070006071", "Spring2026"); got != 1000 {
+			t.Errorf("Spring2026 tuition total = %v", got)
+		}
+
+		terms := decodeJSON[struct {
+			Terms []studentTermResponse `json:"terms"`
+		}](t, ta.do(http.MethodGet, "/api/v2/banking/tuitions", token, nil, nil))
+		if len(terms.Terms) != 2 {
+			t.Fatalf("terms = %+v", terms.Terms)
+		}
+		for _, term := range terms.Terms {
+			if want := map[string]string{"Fall2025": fall, "Spring2026": spring}[term.Term]; term.PaymentReference != want {
+				t.Errorf("%s reference = %q, want %q", term.Term, term.PaymentReference, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/xuri/excelize/v2"
+)
+
+// Reports are also exported as PDF
+const formatPDF = "pdf"
+
+// Rows a report export reads from the store at a time
+const reportPageSize = 500
+
+var reportFormats = map[string]string{
+	"application/json": formatJSON,
+	"text/csv":         formatCSV,
+	"application/csv":  formatCSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": formatXLSX,
+	"application/pdf": formatPDF,
+}
+
+var reportContentTypes = map[string]string{
+	formatJSON: "application/json",
+	formatCSV:  "text/csv; charset=utf-8",
+	formatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	formatPDF:  "application/pdf",
+}
+
+// reportFormat picks the format of a report from the format parameter, or else the
+// first type in the Accept header that a report can be written in. JSON is the
+// default; ok is false for an unknown format parameter.
+func reportFormat(r *http.Request) (format string, ok bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		_, ok := reportContentTypes[format]
+		return format, ok
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if format, ok := reportFormats[mediaType]; ok {
+			return format, true
+		}
+	}
+	return formatJSON, true
+}
+
+// reportColumn is a column of an exported report. Width is in characters and only
+// used by the PDF, which has less room; a column without one is left out of it.
+type reportColumn struct {
+	Name    string
+	Width   int
+	Numeric bool
+}
+
+// reportWriter writes a report as one or more tables, a row at a time. Values are
+// strings, ints or float64 amounts. Errors are kept and returned by Close.
+type reportWriter interface {
+	Table(title string, columns []reportColumn)
+	Row(values ...any)
+	Close() error
+}
+
+// newReportWriter starts a report download named filename in the given format,
+// which must not be JSON.
+func newReportWriter(w http.ResponseWriter, format, filename string) reportWriter {
+	w.Header().Set("Content-Type", reportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	switch format {
+	case formatXLSX:
+		return &xlsxReport{w: w, f: excelize.NewFile()}
+	case formatPDF:
+		return &pdfReport{doc: newPDFDocument(w, filename)}
+	}
+	return &csvReport{w: csv.NewWriter(w)}
+}
+
+// reportCell formats a value for a text format.
+func reportCell(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// reportDate formats a date for a report, leaving it blank when unset.
+func reportDate(d pgtype.Date) string {
+	if !d.Valid {
+		return ""
+	}
+	return d.Time.Format(time.DateOnly)
+}
+
+// reportYear formats a year for a report, leaving it blank when unset.
+func reportYear(y pgtype.Int4) any {
+	if !y.Valid {
+		return ""
+	}
+	return int(y.Int32)
+}
+
+// csvReport separates its tables with an empty line.
+type csvReport struct {
+	w      *csv.Writer
+	tables int
+}
+
+func (c *csvReport) Table(title string, columns []reportColumn) {
+	if c.tables > 0 {
+		c.w.Write(nil)
+	}
+	c.tables++
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	c.w.Write(header)
+}
+
+func (c *csvReport) Row(values ...any) {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = reportCell(v)
+	}
+	c.w.Write(record)
+}
+
+func (c *csvReport) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsxReport writes each table to a sheet of its own. Rows are streamed to a
+// temporary file once there are many, and the workbook is written out on Close.
+type xlsxReport struct {
+	w     io.Writer
+	f     *excelize.File
+	sheet *excelize.StreamWriter
+	row   int
+	err   error
+}
+
+func (x *xlsxReport) Table(title string, columns []reportColumn) {
+	x.flushSheet()
+	if x.err != nil {
+		return
+	}
+	if x.sheet == nil {
+		x.err = x.f.SetSheetName(x.f.GetSheetName(0), title)
+	} else {
+		_, x.err = x.f.NewSheet(title)
+	}
+	if x.err != nil {
+		return
+	}
+	if x.sheet, x.err = x.f.NewStreamWriter(title); x.err != nil {
+		return
+	}
+	header := make([]any, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	x.row = 0
+	x.Row(header...)
+}
+
+func (x *xlsxReport) Row(values ...any) {
+	if x.err != nil {
+		return
+	}
+	x.row++
+	cell, _ := excelize.CoordinatesToCellName(1, x.row)
+	x.err = x.sheet.SetRow(cell, values)
+}
+
+func (x *xlsxReport) flushSheet() {
+	if x.sheet != nil && x.err == nil {
+		x.err = x.sheet.Flush()
+	}
+}
+
+func (x *xlsxReport) Close() error {
+	defer x.f.Close()
+	x.flushSheet()
+	if x.err != nil {
+		return x.err
+	}
+	return x.f.Write(x.w)
+}
+
+// pdfReport lays tables out in fixed-width columns, with numeric columns aligned
+// on the right.
+type pdfReport struct {
+	doc     *pdfDocument
+	columns []reportColumn
+	tables  int
+}
+
+func (p *pdfReport) Table(title string, columns []reportColumn) {
+	if p.tables > 0 {
+		p.doc.Heading()
+		p.doc.Line("")
+	}
+	p.tables++
+	p.columns = columns
+	p.doc.Line(title)
+	header := make([]any, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	line := p.line(header)
+	p.doc.Heading(line, strings.Repeat("-", utf8.RuneCountInString(line)))
+}
+
+func (p *pdfReport) Row(values ...any) {
+	p.doc.Line(p.line(values))
+}
+
+func (p *pdfReport) line(values []any) string {
+	var cells []string
+	for i, col := range p.columns {
+		if col.Width == 0 || i >= len(values) {
+			continue
+		}
+		cell := []rune(reportCell(values[i]))
+		if len(cell) > col.Width {
+			cell = cell[:col.Width]
+		}
+		if col.Numeric {
+			cells = append(cells, fmt.Sprintf("%*s", col.Width, string(cell)))
+		} else {
+			cells = append(cells, fmt.Sprintf("%-*s", col.Width, string(cell)))
+		}
+	}
+	return strings.TrimRight(strings.Join(cells, " "), " ")
+}
+
+func (p *pdfReport) Close() error {
+	return p.doc.Close()
+}
+
+// How long a computed report is served from the cache by default
+const defaultReportCacheSeconds = 60
+
+// reportCache keeps computed reports, keyed by report and parameters, until they
+// are ttl old. A nil cache keeps nothing.
+type reportCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedReport
+}
+
+type cachedReport struct {
+	body    []byte
+	expires time.Time
+}
+
+func newReportCache(ttl time.Duration) *reportCache {
+	return &reportCache{ttl: ttl, entries: map[string]cachedReport{}}
+}
+
+func (c *reportCache) get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+func (c *reportCache) put(key string, body []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	// Expired entries are dropped here, so parameters seen once do not pile up
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedReport{body: body, expires: now.Add(c.ttl)}
+}
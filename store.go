@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"dogukan-dev/tuition/db"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres SQLSTATE codes. Handlers check errors against these, so the SQLite and
+// in-memory stores report their violations with the same codes.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+	pgNotNullViolation    = "23502"
+	pgStringTooLong       = "22001"
+	pgInvalidLimit        = "2201W"
+	pgInvalidOffset       = "2201X"
+	pgInFailedTransaction = "25P02"
+)
+
+// Column lengths from the migrations, checked by handlers before writing and by
+// MemoryStore on every write
+const (
+	studentNoMaxLength      = 11
+	termMaxLength           = 50
+	actionMaxLength         = 20
+	changedByMaxLength      = 50
+	hashedPasswordMaxLength = 148
+	nameMaxLength           = 100
+	emailMaxLength          = 254
+	phoneMaxLength          = 30
+	feeTypeMaxLength        = 30
+	descriptionMaxLength    = 200
+	jobKindMaxLength        = 30
+	jobFilenameMaxLength    = 255
+	jobFailureMaxLength     = 500
+	jobFieldMaxLength       = 50
+	jobMessageMaxLength     = 500
+	channelMaxLength        = 20
+	receiptNoMaxLength      = 20
+	verificationMaxLength   = 20
+	referenceMaxLength      = 25
+	entryKeyMaxLength       = 64
+	statementIDMaxLength    = 70
+	accountMaxLength        = 34
+	bankReferenceMaxLength  = 35
+	currencyMaxLength       = 3
+	debtorMaxLength         = 140
+	remittanceMaxLength     = 500
+	issueMaxLength          = 20
+	detailMaxLength         = 500
+	partnerCodeMaxLength    = 30
+)
+
+// Store is the data access layer used by handlers and middleware. It covers every
+// query in query.sql (through db.Querier) and adds transactions on top.
+type Store interface {
+	db.Querier
+
+	// WithTx runs fn inside a transaction. The Store passed to fn is bound to that
+	// transaction; returning an error from fn rolls everything back.
+	WithTx(ctx context.Context, fn func(Store) error) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// beginner is satisfied by both *pgxpool.Pool and pgx.Tx, so nested WithTx calls
+// become savepoints.
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// isConstraintError reports whether err is a constraint violation with the given
+// SQLSTATE. Every Store reports violations as *pgconn.PgError.
+func isConstraintError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// PostgresStore is the sqlc-backed Store.
+type PostgresStore struct {
+	*db.Queries
+	conn beginner
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{Queries: db.New(pool), conn: pool, pool: pool}
+}
+
+func (s *PostgresStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		return fn(&PostgresStore{Queries: s.Queries.WithTx(tx), conn: tx, pool: s.pool})
+	})
+}
+
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+// Used when DATABASE_CONNECTION is not set, so `go run .` works without any setup
+const defaultDatabaseConnection = "sqlite://tuition.db"
+
+// openStore picks the backend from the DATABASE_CONNECTION scheme and returns the
+// migrator for its schema (nil for the in-memory store, which has none).
+func openStore(ctx context.Context) (Store, *Migrator, error) {
+	dsn := os.Getenv("DATABASE_CONNECTION")
+	if dsn == "" {
+		dsn = defaultDatabaseConnection
+	}
+
+	switch {
+	case strings.HasPrefix(dsn, "memory:"):
+		return NewMemoryStore(), nil, nil
+	case strings.HasPrefix(dsn, "sqlite:"):
+		store, err := OpenSQLiteStore(dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		migrator, err := NewSQLiteMigrator(store.db)
+		if err != nil {
+			store.Close()
+			return nil, nil, err
+		}
+		return store, migrator, nil
+	default:
+		pool, err := connectDatabase(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		migrator, err := NewPostgresMigrator(pool)
+		if err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		return NewPostgresStore(pool), migrator, nil
+	}
+}
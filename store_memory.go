@@ -0,0 +1,2432 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"dogukan-dev/tuition/db"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MemoryStore is a Store that keeps everything in process memory. It mirrors the
+// Postgres schema closely enough for tests and a zero-dependency dev mode: the same
+// constraints are enforced and violations come back as *pgconn.PgError with the
+// SQLSTATE Postgres would use.
+type MemoryStore struct {
+	// mu guards data. A transaction holds it until it commits or rolls back, so
+	// transactions are fully serialized.
+	mu   *sync.Mutex
+	data *memData
+	seq  *memSequences
+
+	// Set on stores handed to WithTx callbacks
+	tx      bool
+	aborted *bool
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type memData struct {
+	students  map[string]db.Student
+	accounts  []db.Account
+	tuitions  []db.Tuition
+	payments  []db.Payment
+	changes   []db.TuitionChange
+	terms     map[string]db.Term
+	feeTypes  map[string]db.FeeType
+	items     []db.TuitionItem
+	schedules []db.FeeSchedule
+	jobs      []db.ImportJob
+	jobFiles  map[int32][]byte
+	jobErrors []db.ImportJobError
+	receipts  []db.Receipt
+	allocs    []db.ReceiptAllocation
+	refs      []db.PaymentReference
+	bankTxs   []db.BankTransaction
+	partners  map[string]db.BankPartner
+	settles   []db.Settlement
+}
+
+// Like Postgres sequences, these are not rolled back with a transaction.
+type memSequences struct {
+	accountNo  int32
+	tuitionID  int32
+	paymentID  int32
+	changeID   int32
+	itemID     int32
+	scheduleID int32
+	jobID      int32
+	jobErrorID int32
+	allocID    int32
+	bankTxID   int32
+	settleID   int32
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu:   &sync.Mutex{},
+		data: &memData{students: map[string]db.Student{}, terms: map[string]db.Term{}, feeTypes: memDefaultFeeTypes(), jobFiles: map[int32][]byte{}, partners: map[string]db.BankPartner{}},
+		seq:  &memSequences{},
+	}
+}
+
+func (d *memData) clone() *memData {
+	return &memData{
+		students:  maps.Clone(d.students),
+		accounts:  slices.Clone(d.accounts),
+		tuitions:  slices.Clone(d.tuitions),
+		payments:  slices.Clone(d.payments),
+		changes:   slices.Clone(d.changes),
+		terms:     maps.Clone(d.terms),
+		feeTypes:  maps.Clone(d.feeTypes),
+		items:     slices.Clone(d.items),
+		schedules: slices.Clone(d.schedules),
+		jobs:      slices.Clone(d.jobs),
+		jobFiles:  maps.Clone(d.jobFiles),
+		jobErrors: slices.Clone(d.jobErrors),
+		receipts:  slices.Clone(d.receipts),
+		allocs:    slices.Clone(d.allocs),
+		refs:      slices.Clone(d.refs),
+		bankTxs:   slices.Clone(d.bankTxs),
+		partners:  maps.Clone(d.partners),
+		settles:   slices.Clone(d.settles),
+	}
+}
+
+// run executes a single statement. Outside a transaction it takes the lock itself;
+// inside one the lock is already held by WithTx.
+func (s *MemoryStore) run(ctx context.Context, fn func(d *memData) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.tx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	} else if *s.aborted {
+		return &pgconn.PgError{
+			Severity: "ERROR",
+			Code:     pgInFailedTransaction,
+			Message:  "current transaction is aborted, commands ignored until end of transaction block",
+		}
+	}
+
+	err := fn(s.data)
+	if _, ok := err.(*pgconn.PgError); ok && s.tx {
+		*s.aborted = true
+	}
+	return err
+}
+
+func (s *MemoryStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.tx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+
+	// Work on a copy and swap it in on commit; a nested call acts as a savepoint
+	aborted := false
+	txStore := &MemoryStore{mu: s.mu, data: s.data.clone(), seq: s.seq, tx: true, aborted: &aborted}
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	if aborted {
+		return &pgconn.PgError{Severity: "ERROR", Code: pgInFailedTransaction, Message: "transaction was aborted and has been rolled back"}
+	}
+	*s.data = *txStore.data
+	return nil
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *MemoryStore) Close() {}
+
+func memConstraintError(code, table, constraint, format string, args ...any) *pgconn.PgError {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           code,
+		Message:        fmt.Sprintf(format, args...),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// memNow is what now() would return inside Postgres
+func memNow() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true}
+}
+
+func checkLength(value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return memConstraintError(pgStringTooLong, "", "", "value too long for type character varying(%d)", max)
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddNewStudent(ctx context.Context, arg db.AddNewStudentParams) error {
+	return s.run(ctx, func(d *memData) error {
+		if err := checkLength(arg.StudentNo, studentNoMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.students[arg.StudentNo]; ok {
+			return memConstraintError(pgUniqueViolation, "student", "student_pkey",
+				`duplicate key value violates unique constraint "student_pkey"`)
+		}
+		d.students[arg.StudentNo] = db.Student{
+			StudentNo:         arg.StudentNo,
+			Balance:           arg.Balance,
+			DailyPaymentLimit: 3,
+			EnrollmentStatus:  "enrolled",
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) AddStudentAccount(ctx context.Context, arg db.AddStudentAccountParams) error {
+	return s.run(ctx, func(d *memData) error {
+		s.seq.accountNo++
+		accountNo := s.seq.accountNo
+
+		if err := checkLength(arg.StudentNo, studentNoMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.HashedPassword, hashedPasswordMaxLength); err != nil {
+			return err
+		}
+		for _, account := range d.accounts {
+			if account.StudentNo == arg.StudentNo {
+				return memConstraintError(pgUniqueViolation, "account", "account_student_no_key",
+					`duplicate key value violates unique constraint "account_student_no_key"`)
+			}
+		}
+		if _, ok := d.students[arg.StudentNo]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "account", "fk_student",
+				`insert or update on table "account" violates foreign key constraint "fk_student"`)
+		}
+		d.accounts = append(d.accounts, db.Account{
+			AccountNo:      accountNo,
+			StudentNo:      arg.StudentNo,
+			HashedPassword: arg.HashedPassword,
+		})
+		return nil
+	})
+}
+
+func (s *MemoryStore) AddTuitionToOneStudent(ctx context.Context, arg db.AddTuitionToOneStudentParams) (db.Tuition, error) {
+	var tuition db.Tuition
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.tuitionID++
+		tuitionID := s.seq.tuitionID
+
+		if err := checkLength(arg.StudentNo, studentNoMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Term, termMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.students[arg.StudentNo]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "tuition", "fk_student",
+				`insert or update on table "tuition" violates foreign key constraint "fk_student"`)
+		}
+		if err := d.checkTermExists(arg.Term); err != nil {
+			return err
+		}
+		if err := d.checkSingleActiveTuition(arg.StudentNo, arg.Term, tuitionID); err != nil {
+			return err
+		}
+		tuition = db.Tuition{
+			TuitionID:    tuitionID,
+			StudentNo:    arg.StudentNo,
+			Term:         arg.Term,
+			TuitionTotal: arg.TuitionTotal,
+			BilledTotal:  arg.TuitionTotal,
+			Status:       "active",
+			CreatedAt:    memNow(),
+		}
+		d.tuitions = append(d.tuitions, tuition)
+		return nil
+	})
+	return tuition, err
+}
+
+func (s *MemoryStore) DecreasePaymentLimit(ctx context.Context, studentNo string) error {
+	return s.run(ctx, func(d *memData) error {
+		student, ok := d.students[studentNo]
+		if !ok {
+			return nil
+		}
+		if student.DailyPaymentLimit-1 < 0 {
+			return memConstraintError(pgCheckViolation, "student", "daily_payment_limit_nonnegative",
+				`new row for relation "student" violates check constraint "daily_payment_limit_nonnegative"`)
+		}
+		student.DailyPaymentLimit--
+		d.students[studentNo] = student
+		return nil
+	})
+}
+
+func (s *MemoryStore) GetAccountByStudentNo(ctx context.Context, studentNo string) (db.Account, error) {
+	var account db.Account
+	err := s.run(ctx, func(d *memData) error {
+		for _, a := range d.accounts {
+			if a.StudentNo == studentNo {
+				account = a
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return account, err
+}
+
+// GetStudentById left joins tuition, so a student without tuitions still comes back
+// with null tuition columns. Like Postgres without ORDER BY, only the first
+// matching tuition is returned.
+func (s *MemoryStore) GetStudentById(ctx context.Context, studentNo string) (db.GetStudentByIdRow, error) {
+	var row db.GetStudentByIdRow
+	err := s.run(ctx, func(d *memData) error {
+		student, ok := d.students[studentNo]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		row = db.GetStudentByIdRow{
+			StudentNo:         student.StudentNo,
+			Balance:           student.Balance,
+			DailyPaymentLimit: student.DailyPaymentLimit,
+			DeactivatedAt:     student.DeactivatedAt,
+			FirstName:         student.FirstName,
+			LastName:          student.LastName,
+			Email:             student.Email,
+			Phone:             student.Phone,
+			Faculty:           student.Faculty,
+			Department:        student.Department,
+			Program:           student.Program,
+			EnrollmentYear:    student.EnrollmentYear,
+			EnrollmentStatus:  student.EnrollmentStatus,
+		}
+		for _, t := range d.tuitions {
+			if t.StudentNo == studentNo {
+				row.TuitionID = pgtype.Int4{Int32: t.TuitionID, Valid: true}
+				row.StudentNo_2 = pgtype.Text{String: t.StudentNo, Valid: true}
+				row.Term = pgtype.Text{String: t.Term, Valid: true}
+				row.TuitionTotal = pgtype.Float8{Float64: t.TuitionTotal, Valid: true}
+				row.BilledTotal = pgtype.Float8{Float64: t.BilledTotal, Valid: true}
+				row.Status = pgtype.Text{String: t.Status, Valid: true}
+				break
+			}
+		}
+		return nil
+	})
+	return row, err
+}
+
+func (s *MemoryStore) GetStudentDailyLimit(ctx context.Context, studentNo string) (int32, error) {
+	var limit int32
+	err := s.run(ctx, func(d *memData) error {
+		student, ok := d.students[studentNo]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		limit = student.DailyPaymentLimit
+		return nil
+	})
+	return limit, err
+}
+
+// memJoinTuition is a row of student INNER JOIN tuition. The queries selecting
+// that join all have the same row shape, so their row types convert from this one.
+func memJoinTuition(student db.Student, t db.Tuition) db.GetTuitionByTermRow {
+	return db.GetTuitionByTermRow{
+		StudentNo:         student.StudentNo,
+		Balance:           student.Balance,
+		DailyPaymentLimit: student.DailyPaymentLimit,
+		DeactivatedAt:     student.DeactivatedAt,
+		FirstName:         student.FirstName,
+		LastName:          student.LastName,
+		Email:             student.Email,
+		Phone:             student.Phone,
+		Faculty:           student.Faculty,
+		Department:        student.Department,
+		Program:           student.Program,
+		EnrollmentYear:    student.EnrollmentYear,
+		EnrollmentStatus:  student.EnrollmentStatus,
+		TuitionID:         t.TuitionID,
+		StudentNo_2:       t.StudentNo,
+		Term:              t.Term,
+		TuitionTotal:      t.TuitionTotal,
+		BilledTotal:       t.BilledTotal,
+		Status:            t.Status,
+	}
+}
+
+func (s *MemoryStore) GetTuitionByTerm(ctx context.Context, arg db.GetTuitionByTermParams) ([]db.GetTuitionByTermRow, error) {
+	var rows []db.GetTuitionByTermRow
+	err := s.run(ctx, func(d *memData) error {
+		student, ok := d.students[arg.StudentNo]
+		if !ok {
+			return nil
+		}
+		for _, t := range d.tuitions {
+			if t.StudentNo == arg.StudentNo && t.Term == arg.Term && t.Status == "active" {
+				rows = append(rows, memJoinTuition(student, t))
+			}
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// LockStudentBalance needs no lock of its own: transactions already hold the store lock.
+func (s *MemoryStore) LockStudentBalance(ctx context.Context, studentNo string) (float64, error) {
+	var balance float64
+	err := s.run(ctx, func(d *memData) error {
+		student, ok := d.students[studentNo]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		balance = student.Balance
+		return nil
+	})
+	return balance, err
+}
+
+func (s *MemoryStore) ResetTuitionTotal(ctx context.Context, arg db.ResetTuitionTotalParams) error {
+	return s.run(ctx, func(d *memData) error {
+		for i, t := range d.tuitions {
+			if t.StudentNo == arg.StudentNo && t.Term == arg.Term {
+				d.tuitions[i].TuitionTotal = 0
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) UnpaidTuitions(ctx context.Context, arg db.UnpaidTuitionsParams) ([]db.UnpaidTuitionsRow, error) {
+	var rows []db.UnpaidTuitionsRow
+	err := s.run(ctx, func(d *memData) error {
+		if arg.Limit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		if arg.Offset < 0 {
+			return memConstraintError(pgInvalidOffset, "", "", "OFFSET must not be negative")
+		}
+
+		skipped := int32(0)
+		for _, t := range d.tuitions {
+			if int32(len(rows)) == arg.Limit {
+				break
+			}
+			if t.TuitionTotal <= 0 {
+				continue
+			}
+			if skipped < arg.Offset {
+				skipped++
+				continue
+			}
+			student := d.students[t.StudentNo]
+			rows = append(rows, db.UnpaidTuitionsRow(memJoinTuition(student, t)))
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) ListUnpaidReport(ctx context.Context, arg db.ListUnpaidReportParams) ([]db.ListUnpaidReportRow, error) {
+	var rows []db.ListUnpaidReportRow
+	err := s.run(ctx, func(d *memData) error {
+		if arg.RowLimit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		after := db.ListUnpaidReportRow{Term: arg.AfterTerm, Faculty: arg.AfterFaculty, StudentNo: arg.AfterStudentNo, TuitionID: arg.AfterTuitionID}
+		for _, t := range d.tuitions {
+			if t.Status != "active" || t.TuitionTotal <= 0 {
+				continue
+			}
+			student := d.students[t.StudentNo]
+			if arg.Term.Valid && t.Term != arg.Term.String || arg.Faculty.Valid && student.Faculty != arg.Faculty.String ||
+				arg.Program.Valid && student.Program != arg.Program.String {
+				continue
+			}
+			row := db.ListUnpaidReportRow{
+				TuitionID:        t.TuitionID,
+				Term:             t.Term,
+				PaymentDueDate:   d.terms[t.Term].PaymentDueDate,
+				BilledTotal:      t.BilledTotal,
+				TuitionTotal:     t.TuitionTotal,
+				StudentNo:        student.StudentNo,
+				FirstName:        student.FirstName,
+				LastName:         student.LastName,
+				Email:            student.Email,
+				Phone:            student.Phone,
+				Faculty:          student.Faculty,
+				Department:       student.Department,
+				Program:          student.Program,
+				EnrollmentYear:   student.EnrollmentYear,
+				EnrollmentStatus: student.EnrollmentStatus,
+			}
+			if memCompareUnpaidReport(row, after) > 0 {
+				rows = append(rows, row)
+			}
+		}
+		slices.SortFunc(rows, memCompareUnpaidReport)
+		if len(rows) > int(arg.RowLimit) {
+			rows = rows[:arg.RowLimit]
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// memCompareUnpaidReport orders report rows by term, faculty, student and tuition.
+func memCompareUnpaidReport(a, b db.ListUnpaidReportRow) int {
+	return cmp.Or(
+		cmp.Compare(a.Term, b.Term),
+		cmp.Compare(a.Faculty, b.Faculty),
+		cmp.Compare(a.StudentNo, b.StudentNo),
+		cmp.Compare(a.TuitionID, b.TuitionID),
+	)
+}
+
+func (s *MemoryStore) UpdateBalance(ctx context.Context, arg db.UpdateBalanceParams) error {
+	return s.run(ctx, func(d *memData) error {
+		student, ok := d.students[arg.StudentNo]
+		if !ok {
+			return nil
+		}
+		student.Balance = arg.Balance
+		d.students[arg.StudentNo] = student
+		return nil
+	})
+}
+
+func (s *MemoryStore) GetStudent(ctx context.Context, studentNo string) (db.Student, error) {
+	var student db.Student
+	err := s.run(ctx, func(d *memData) error {
+		var ok bool
+		if student, ok = d.students[studentNo]; !ok {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	return student, err
+}
+
+// matchStudent applies the ListStudents and CountStudents filters.
+func (d *memData) matchStudent(student db.Student, arg db.CountStudentsParams) bool {
+	if !strings.HasPrefix(student.StudentNo, arg.Prefix) {
+		return false
+	}
+	for _, f := range []struct {
+		filter pgtype.Text
+		value  string
+	}{
+		{arg.Faculty, student.Faculty},
+		{arg.Department, student.Department},
+		{arg.Program, student.Program},
+		{arg.EnrollmentStatus, student.EnrollmentStatus},
+	} {
+		if f.filter.Valid && f.filter.String != f.value {
+			return false
+		}
+	}
+	if arg.EnrollmentYear.Valid && arg.EnrollmentYear != student.EnrollmentYear {
+		return false
+	}
+	active, hasUnpaid := arg.Active, arg.HasUnpaid
+	if active.Valid && active.Bool == student.DeactivatedAt.Valid {
+		return false
+	}
+	if hasUnpaid.Valid {
+		unpaid := slices.ContainsFunc(d.tuitions, func(t db.Tuition) bool {
+			return t.StudentNo == student.StudentNo && t.TuitionTotal > 0
+		})
+		if unpaid != hasUnpaid.Bool {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *MemoryStore) ListStudents(ctx context.Context, arg db.ListStudentsParams) ([]db.Student, error) {
+	var students []db.Student
+	err := s.run(ctx, func(d *memData) error {
+		if arg.RowLimit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		if arg.RowOffset < 0 {
+			return memConstraintError(pgInvalidOffset, "", "", "OFFSET must not be negative")
+		}
+
+		skipped := int32(0)
+		for _, studentNo := range slices.Sorted(maps.Keys(d.students)) {
+			if int32(len(students)) == arg.RowLimit {
+				break
+			}
+			student := d.students[studentNo]
+			if !d.matchStudent(student, db.CountStudentsParams{
+				Prefix:           arg.Prefix,
+				Faculty:          arg.Faculty,
+				Department:       arg.Department,
+				Program:          arg.Program,
+				EnrollmentStatus: arg.EnrollmentStatus,
+				EnrollmentYear:   arg.EnrollmentYear,
+				Active:           arg.Active,
+				HasUnpaid:        arg.HasUnpaid,
+			}) {
+				continue
+			}
+			if skipped < arg.RowOffset {
+				skipped++
+				continue
+			}
+			students = append(students, student)
+		}
+		return nil
+	})
+	return students, err
+}
+
+func (s *MemoryStore) CountStudents(ctx context.Context, arg db.CountStudentsParams) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, student := range d.students {
+			if d.matchStudent(student, arg) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *MemoryStore) UpdateStudent(ctx context.Context, arg db.UpdateStudentParams) error {
+	return s.run(ctx, func(d *memData) error {
+		student, ok := d.students[arg.StudentNo]
+		if !ok {
+			return nil
+		}
+		if arg.DailyPaymentLimit < 0 {
+			return memConstraintError(pgCheckViolation, "student", "daily_payment_limit_nonnegative",
+				`new row for relation "student" violates check constraint "daily_payment_limit_nonnegative"`)
+		}
+		for _, field := range []struct {
+			value string
+			max   int
+		}{
+			{arg.FirstName, nameMaxLength},
+			{arg.LastName, nameMaxLength},
+			{arg.Email, emailMaxLength},
+			{arg.Phone, phoneMaxLength},
+			{arg.Faculty, nameMaxLength},
+			{arg.Department, nameMaxLength},
+			{arg.Program, nameMaxLength},
+		} {
+			if err := checkLength(field.value, field.max); err != nil {
+				return err
+			}
+		}
+		if !slices.Contains(enrollmentStatuses, arg.EnrollmentStatus) {
+			return memConstraintError(pgCheckViolation, "student", "student_enrollment_status_valid",
+				`new row for relation "student" violates check constraint "student_enrollment_status_valid"`)
+		}
+		student.Balance = arg.Balance
+		student.DailyPaymentLimit = arg.DailyPaymentLimit
+		student.FirstName = arg.FirstName
+		student.LastName = arg.LastName
+		student.Email = arg.Email
+		student.Phone = arg.Phone
+		student.Faculty = arg.Faculty
+		student.Department = arg.Department
+		student.Program = arg.Program
+		student.EnrollmentYear = arg.EnrollmentYear
+		student.EnrollmentStatus = arg.EnrollmentStatus
+		d.students[arg.StudentNo] = student
+		return nil
+	})
+}
+
+func (s *MemoryStore) DeactivateStudent(ctx context.Context, studentNo string) error {
+	return s.run(ctx, func(d *memData) error {
+		student, ok := d.students[studentNo]
+		if !ok || student.DeactivatedAt.Valid {
+			return nil
+		}
+		student.DeactivatedAt = memNow()
+		d.students[studentNo] = student
+		return nil
+	})
+}
+
+func (s *MemoryStore) ReactivateStudent(ctx context.Context, studentNo string) error {
+	return s.run(ctx, func(d *memData) error {
+		student, ok := d.students[studentNo]
+		if !ok {
+			return nil
+		}
+		student.DeactivatedAt = pgtype.Timestamptz{}
+		d.students[studentNo] = student
+		return nil
+	})
+}
+
+func (s *MemoryStore) ListTuitionsByStudent(ctx context.Context, studentNo string) ([]db.Tuition, error) {
+	var tuitions []db.Tuition
+	err := s.run(ctx, func(d *memData) error {
+		for _, t := range d.tuitions {
+			if t.StudentNo == studentNo {
+				tuitions = append(tuitions, t)
+			}
+		}
+		return nil
+	})
+	return tuitions, err
+}
+
+func (s *MemoryStore) AddPayment(ctx context.Context, arg db.AddPaymentParams) (db.Payment, error) {
+	var payment db.Payment
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.paymentID++
+		paymentID := s.seq.paymentID
+
+		if err := checkLength(arg.StudentNo, studentNoMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Term, termMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Channel, channelMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Partner.String, partnerCodeMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.students[arg.StudentNo]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "payment", "fk_student",
+				`insert or update on table "payment" violates foreign key constraint "fk_student"`)
+		}
+		if _, ok := d.partners[arg.Partner.String]; arg.Partner.Valid && !ok {
+			return memConstraintError(pgForeignKeyViolation, "payment", "payment_partner_fkey",
+				`insert or update on table "payment" violates foreign key constraint "payment_partner_fkey"`)
+		}
+		payment = db.Payment{
+			PaymentID:    paymentID,
+			StudentNo:    arg.StudentNo,
+			Term:         arg.Term,
+			Amount:       arg.Amount,
+			BalanceAfter: arg.BalanceAfter,
+			CreatedAt:    memNow(),
+			Channel:      arg.Channel,
+			Partner:      arg.Partner,
+		}
+		d.payments = append(d.payments, payment)
+		return nil
+	})
+	return payment, err
+}
+
+// Payments are appended in creation order, which is also created_at order.
+func (s *MemoryStore) ListPaymentsByStudent(ctx context.Context, studentNo string) ([]db.Payment, error) {
+	var payments []db.Payment
+	err := s.run(ctx, func(d *memData) error {
+		for _, p := range d.payments {
+			if p.StudentNo == studentNo {
+				payments = append(payments, p)
+			}
+		}
+		return nil
+	})
+	return payments, err
+}
+
+func (s *MemoryStore) GetTuition(ctx context.Context, tuitionID int32) (db.Tuition, error) {
+	var tuition db.Tuition
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.tuitions, func(t db.Tuition) bool { return t.TuitionID == tuitionID })
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		tuition = d.tuitions[i]
+		return nil
+	})
+	return tuition, err
+}
+
+// LockTuition needs no lock of its own: transactions already hold the store lock.
+func (s *MemoryStore) LockTuition(ctx context.Context, tuitionID int32) (db.Tuition, error) {
+	return s.GetTuition(ctx, tuitionID)
+}
+
+// matchTuition applies the ListTuitions and CountTuitions filters.
+func matchTuition(t db.Tuition, studentNo, term, status pgtype.Text, unpaid pgtype.Bool) bool {
+	return (!studentNo.Valid || t.StudentNo == studentNo.String) &&
+		(!term.Valid || t.Term == term.String) &&
+		(!status.Valid || t.Status == status.String) &&
+		(!unpaid.Valid || (t.TuitionTotal > 0) == unpaid.Bool)
+}
+
+func (s *MemoryStore) ListTuitions(ctx context.Context, arg db.ListTuitionsParams) ([]db.Tuition, error) {
+	var tuitions []db.Tuition
+	err := s.run(ctx, func(d *memData) error {
+		if arg.RowLimit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		if arg.RowOffset < 0 {
+			return memConstraintError(pgInvalidOffset, "", "", "OFFSET must not be negative")
+		}
+
+		// Tuitions are appended in tuition_id order
+		skipped := int32(0)
+		for _, t := range d.tuitions {
+			if int32(len(tuitions)) == arg.RowLimit {
+				break
+			}
+			if !matchTuition(t, arg.StudentNo, arg.Term, arg.Status, arg.Unpaid) {
+				continue
+			}
+			if skipped < arg.RowOffset {
+				skipped++
+				continue
+			}
+			tuitions = append(tuitions, t)
+		}
+		return nil
+	})
+	return tuitions, err
+}
+
+func (s *MemoryStore) CountTuitions(ctx context.Context, arg db.CountTuitionsParams) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, t := range d.tuitions {
+			if matchTuition(t, arg.StudentNo, arg.Term, arg.Status, arg.Unpaid) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *MemoryStore) AmendTuition(ctx context.Context, arg db.AmendTuitionParams) error {
+	return s.run(ctx, func(d *memData) error {
+		if err := checkLength(arg.Term, termMaxLength); err != nil {
+			return err
+		}
+		if err := d.checkTermExists(arg.Term); err != nil {
+			return err
+		}
+		for i, t := range d.tuitions {
+			if t.TuitionID == arg.TuitionID {
+				if t.Status == "active" {
+					if err := d.checkSingleActiveTuition(t.StudentNo, arg.Term, t.TuitionID); err != nil {
+						return err
+					}
+				}
+				d.tuitions[i].Term = arg.Term
+				d.tuitions[i].BilledTotal = arg.BilledTotal
+				d.tuitions[i].TuitionTotal = arg.TuitionTotal
+			}
+		}
+		return nil
+	})
+}
+
+// checkSingleActiveTuition enforces tuition_single_active_idx for an active tuition
+// of the student for term, other than tuitionID.
+func (d *memData) checkSingleActiveTuition(studentNo, term string, tuitionID int32) error {
+	for _, t := range d.tuitions {
+		if t.TuitionID != tuitionID && t.StudentNo == studentNo && t.Term == term && t.Status == "active" {
+			return memConstraintError(pgUniqueViolation, "tuition", "tuition_single_active_idx",
+				`duplicate key value violates unique constraint "tuition_single_active_idx"`)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CancelTuition(ctx context.Context, tuitionID int32) error {
+	return s.run(ctx, func(d *memData) error {
+		for i, t := range d.tuitions {
+			if t.TuitionID == tuitionID {
+				d.tuitions[i].Status = "cancelled"
+				d.tuitions[i].TuitionTotal = 0
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) AddTuitionChange(ctx context.Context, arg db.AddTuitionChangeParams) error {
+	return s.run(ctx, func(d *memData) error {
+		s.seq.changeID++
+		changeID := s.seq.changeID
+
+		for _, check := range []struct {
+			value string
+			max   int
+		}{
+			{arg.Action, actionMaxLength},
+			{arg.OldTerm, termMaxLength},
+			{arg.NewTerm, termMaxLength},
+			{arg.ChangedBy, changedByMaxLength},
+		} {
+			if err := checkLength(check.value, check.max); err != nil {
+				return err
+			}
+		}
+		if arg.Action != "amend" && arg.Action != "cancel" {
+			return memConstraintError(pgCheckViolation, "tuition_change", "tuition_change_action_valid",
+				`new row for relation "tuition_change" violates check constraint "tuition_change_action_valid"`)
+		}
+		if !slices.ContainsFunc(d.tuitions, func(t db.Tuition) bool { return t.TuitionID == arg.TuitionID }) {
+			return memConstraintError(pgForeignKeyViolation, "tuition_change", "fk_tuition",
+				`insert or update on table "tuition_change" violates foreign key constraint "fk_tuition"`)
+		}
+		d.changes = append(d.changes, db.TuitionChange{
+			ChangeID:          changeID,
+			TuitionID:         arg.TuitionID,
+			Action:            arg.Action,
+			OldTerm:           arg.OldTerm,
+			NewTerm:           arg.NewTerm,
+			OldAmount:         arg.OldAmount,
+			NewAmount:         arg.NewAmount,
+			BalanceAdjustment: arg.BalanceAdjustment,
+			Reason:            arg.Reason,
+			ChangedBy:         arg.ChangedBy,
+			CreatedAt:         memNow(),
+		})
+		return nil
+	})
+}
+
+func (s *MemoryStore) ListTuitionChanges(ctx context.Context, tuitionID int32) ([]db.TuitionChange, error) {
+	var changes []db.TuitionChange
+	err := s.run(ctx, func(d *memData) error {
+		for _, c := range d.changes {
+			if c.TuitionID == tuitionID {
+				changes = append(changes, c)
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+func (s *MemoryStore) ListStudentTuitionChanges(ctx context.Context, studentNo string) ([]db.TuitionChange, error) {
+	var changes []db.TuitionChange
+	err := s.run(ctx, func(d *memData) error {
+		for _, c := range d.changes {
+			i := slices.IndexFunc(d.tuitions, func(t db.Tuition) bool { return t.TuitionID == c.TuitionID })
+			if i >= 0 && d.tuitions[i].StudentNo == studentNo {
+				changes = append(changes, c)
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+func (s *MemoryStore) CountPaymentsForTerm(ctx context.Context, arg db.CountPaymentsForTermParams) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, p := range d.payments {
+			if p.StudentNo == arg.StudentNo && p.Term == arg.Term {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// checkTermExists enforces the fk_term foreign key of tuition.
+func (d *memData) checkTermExists(code string) error {
+	if _, ok := d.terms[code]; !ok {
+		return memConstraintError(pgForeignKeyViolation, "tuition", "fk_term",
+			`insert or update on table "tuition" violates foreign key constraint "fk_term"`)
+	}
+	return nil
+}
+
+// checkTerm enforces the term constraints and the single active term index.
+func (d *memData) checkTerm(t db.Term) error {
+	if err := checkLength(t.Code, termMaxLength); err != nil {
+		return err
+	}
+	if err := checkLength(t.Name, nameMaxLength); err != nil {
+		return err
+	}
+	if t.StartDate.Valid && t.EndDate.Valid && t.EndDate.Time.Before(t.StartDate.Time) {
+		return memConstraintError(pgCheckViolation, "term", "term_dates_valid",
+			`new row for relation "term" violates check constraint "term_dates_valid"`)
+	}
+	if t.Active {
+		for code, other := range d.terms {
+			if other.Active && code != t.Code {
+				return memConstraintError(pgUniqueViolation, "term", "term_single_active_idx",
+					`duplicate key value violates unique constraint "term_single_active_idx"`)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateTerm(ctx context.Context, arg db.CreateTermParams) (db.Term, error) {
+	term := db.Term{
+		Code:           arg.Code,
+		Name:           arg.Name,
+		StartDate:      arg.StartDate,
+		EndDate:        arg.EndDate,
+		PaymentDueDate: arg.PaymentDueDate,
+		Active:         arg.Active,
+		CreatedAt:      memNow(),
+	}
+	err := s.run(ctx, func(d *memData) error {
+		if err := d.checkTerm(term); err != nil {
+			return err
+		}
+		if _, ok := d.terms[term.Code]; ok {
+			return memConstraintError(pgUniqueViolation, "term", "term_pkey",
+				`duplicate key value violates unique constraint "term_pkey"`)
+		}
+		d.terms[term.Code] = term
+		return nil
+	})
+	return term, err
+}
+
+func (s *MemoryStore) GetTerm(ctx context.Context, code string) (db.Term, error) {
+	var term db.Term
+	err := s.run(ctx, func(d *memData) error {
+		var ok bool
+		if term, ok = d.terms[code]; !ok {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	return term, err
+}
+
+func (s *MemoryStore) GetActiveTerm(ctx context.Context) (db.Term, error) {
+	var term db.Term
+	err := s.run(ctx, func(d *memData) error {
+		for _, t := range d.terms {
+			if t.Active {
+				term = t
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return term, err
+}
+
+func (s *MemoryStore) ListTerms(ctx context.Context) ([]db.Term, error) {
+	var terms []db.Term
+	err := s.run(ctx, func(d *memData) error {
+		terms = slices.Collect(maps.Values(d.terms))
+		slices.SortFunc(terms, func(a, b db.Term) int {
+			if a.StartDate.Valid != b.StartDate.Valid {
+				if a.StartDate.Valid {
+					return -1
+				}
+				return 1
+			}
+			if c := a.StartDate.Time.Compare(b.StartDate.Time); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Code, b.Code)
+		})
+		return nil
+	})
+	return terms, err
+}
+
+func (s *MemoryStore) UpdateTerm(ctx context.Context, arg db.UpdateTermParams) error {
+	return s.run(ctx, func(d *memData) error {
+		term, ok := d.terms[arg.Code]
+		if !ok {
+			return nil
+		}
+		term.Name = arg.Name
+		term.StartDate = arg.StartDate
+		term.EndDate = arg.EndDate
+		term.PaymentDueDate = arg.PaymentDueDate
+		term.Active = arg.Active
+		if err := d.checkTerm(term); err != nil {
+			return err
+		}
+		d.terms[arg.Code] = term
+		return nil
+	})
+}
+
+func (s *MemoryStore) ClearActiveTerm(ctx context.Context) error {
+	return s.run(ctx, func(d *memData) error {
+		for code, t := range d.terms {
+			if t.Active {
+				t.Active = false
+				d.terms[code] = t
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) DeleteTerm(ctx context.Context, code string) error {
+	return s.run(ctx, func(d *memData) error {
+		if slices.ContainsFunc(d.tuitions, func(t db.Tuition) bool { return t.Term == code }) {
+			return memConstraintError(pgForeignKeyViolation, "tuition", "fk_term",
+				`update or delete on table "term" violates foreign key constraint "fk_term" on table "tuition"`)
+		}
+		if slices.ContainsFunc(d.schedules, func(f db.FeeSchedule) bool { return f.Term == code }) {
+			return memConstraintError(pgForeignKeyViolation, "fee_schedule", "fk_term",
+				`update or delete on table "term" violates foreign key constraint "fk_term" on table "fee_schedule"`)
+		}
+		delete(d.terms, code)
+		return nil
+	})
+}
+
+// memDefaultFeeTypes are the fee types the fee_items migration inserts.
+func memDefaultFeeTypes() map[string]db.FeeType {
+	feeTypes := map[string]db.FeeType{}
+	for _, f := range []db.FeeType{
+		{Code: "tuition", Name: "Tuition", Priority: 10},
+		{Code: "health_insurance", Name: "Health insurance", Priority: 20},
+		{Code: "lab", Name: "Laboratory fee", Priority: 30},
+		{Code: "dormitory", Name: "Dormitory", Priority: 40},
+		{Code: "student_union", Name: "Student union fee", Priority: 50},
+	} {
+		f.CreatedAt = memNow()
+		feeTypes[f.Code] = f
+	}
+	return feeTypes
+}
+
+func (s *MemoryStore) ListFeeTypes(ctx context.Context) ([]db.FeeType, error) {
+	var feeTypes []db.FeeType
+	err := s.run(ctx, func(d *memData) error {
+		feeTypes = slices.SortedFunc(maps.Values(d.feeTypes), func(a, b db.FeeType) int {
+			if a.Priority != b.Priority {
+				return int(a.Priority - b.Priority)
+			}
+			return strings.Compare(a.Code, b.Code)
+		})
+		return nil
+	})
+	return feeTypes, err
+}
+
+func (s *MemoryStore) GetFeeType(ctx context.Context, code string) (db.FeeType, error) {
+	var feeType db.FeeType
+	err := s.run(ctx, func(d *memData) error {
+		var ok bool
+		if feeType, ok = d.feeTypes[code]; !ok {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	return feeType, err
+}
+
+func (s *MemoryStore) CreateFeeType(ctx context.Context, arg db.CreateFeeTypeParams) (db.FeeType, error) {
+	feeType := db.FeeType{Code: arg.Code, Name: arg.Name, Priority: arg.Priority, CreatedAt: memNow()}
+	err := s.run(ctx, func(d *memData) error {
+		if err := checkLength(arg.Code, feeTypeMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Name, nameMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.feeTypes[arg.Code]; ok {
+			return memConstraintError(pgUniqueViolation, "fee_type", "fee_type_pkey",
+				`duplicate key value violates unique constraint "fee_type_pkey"`)
+		}
+		d.feeTypes[arg.Code] = feeType
+		return nil
+	})
+	return feeType, err
+}
+
+func (s *MemoryStore) UpdateFeeType(ctx context.Context, arg db.UpdateFeeTypeParams) error {
+	return s.run(ctx, func(d *memData) error {
+		feeType, ok := d.feeTypes[arg.Code]
+		if !ok {
+			return nil
+		}
+		if err := checkLength(arg.Name, nameMaxLength); err != nil {
+			return err
+		}
+		feeType.Name = arg.Name
+		feeType.Priority = arg.Priority
+		d.feeTypes[arg.Code] = feeType
+		return nil
+	})
+}
+
+// checkTuitionItem enforces the tuition_item amount constraints.
+func checkTuitionItem(amount, amountPaid float64) error {
+	if amount <= 0 {
+		return memConstraintError(pgCheckViolation, "tuition_item", "tuition_item_amount_positive",
+			`new row for relation "tuition_item" violates check constraint "tuition_item_amount_positive"`)
+	}
+	if amountPaid < 0 || amountPaid > amount {
+		return memConstraintError(pgCheckViolation, "tuition_item", "tuition_item_amount_paid_valid",
+			`new row for relation "tuition_item" violates check constraint "tuition_item_amount_paid_valid"`)
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddTuitionItem(ctx context.Context, arg db.AddTuitionItemParams) (db.TuitionItem, error) {
+	var item db.TuitionItem
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.itemID++
+		itemID := s.seq.itemID
+
+		if err := checkLength(arg.FeeType, feeTypeMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Description, descriptionMaxLength); err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(d.tuitions, func(t db.Tuition) bool { return t.TuitionID == arg.TuitionID }) {
+			return memConstraintError(pgForeignKeyViolation, "tuition_item", "fk_tuition",
+				`insert or update on table "tuition_item" violates foreign key constraint "fk_tuition"`)
+		}
+		if _, ok := d.feeTypes[arg.FeeType]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "tuition_item", "fk_fee_type",
+				`insert or update on table "tuition_item" violates foreign key constraint "fk_fee_type"`)
+		}
+		if err := checkTuitionItem(arg.Amount, arg.AmountPaid); err != nil {
+			return err
+		}
+		item = db.TuitionItem{
+			ItemID:      itemID,
+			TuitionID:   arg.TuitionID,
+			FeeType:     arg.FeeType,
+			Description: arg.Description,
+			Amount:      arg.Amount,
+			AmountPaid:  arg.AmountPaid,
+		}
+		d.items = append(d.items, item)
+		return nil
+	})
+	return item, err
+}
+
+func (s *MemoryStore) ListTuitionItems(ctx context.Context, tuitionID int32) ([]db.ListTuitionItemsRow, error) {
+	var rows []db.ListTuitionItemsRow
+	err := s.run(ctx, func(d *memData) error {
+		for _, item := range d.items {
+			if item.TuitionID != tuitionID {
+				continue
+			}
+			feeType := d.feeTypes[item.FeeType]
+			rows = append(rows, db.ListTuitionItemsRow{
+				ItemID:      item.ItemID,
+				TuitionID:   item.TuitionID,
+				FeeType:     item.FeeType,
+				Description: item.Description,
+				Amount:      item.Amount,
+				AmountPaid:  item.AmountPaid,
+				FeeTypeName: feeType.Name,
+				Priority:    feeType.Priority,
+			})
+		}
+		// Items are appended in item_id order, so a stable sort keeps it within a priority
+		slices.SortStableFunc(rows, func(a, b db.ListTuitionItemsRow) int {
+			return int(a.Priority - b.Priority)
+		})
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) UpdateTuitionItem(ctx context.Context, arg db.UpdateTuitionItemParams) error {
+	return s.run(ctx, func(d *memData) error {
+		for i, item := range d.items {
+			if item.ItemID == arg.ItemID {
+				if err := checkTuitionItem(arg.Amount, arg.AmountPaid); err != nil {
+					return err
+				}
+				d.items[i].Amount = arg.Amount
+				d.items[i].AmountPaid = arg.AmountPaid
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) SetTuitionItemPaid(ctx context.Context, arg db.SetTuitionItemPaidParams) error {
+	return s.run(ctx, func(d *memData) error {
+		for i, item := range d.items {
+			if item.ItemID == arg.ItemID {
+				if err := checkTuitionItem(item.Amount, arg.AmountPaid); err != nil {
+					return err
+				}
+				d.items[i].AmountPaid = arg.AmountPaid
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) SetTuitionOutstanding(ctx context.Context, arg db.SetTuitionOutstandingParams) (db.Tuition, error) {
+	var tuition db.Tuition
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.tuitions, func(t db.Tuition) bool {
+			return t.TuitionID == arg.TuitionID && t.Status == "active"
+		})
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		d.tuitions[i].TuitionTotal = arg.TuitionTotal
+		tuition = d.tuitions[i]
+		return nil
+	})
+	return tuition, err
+}
+
+func (s *MemoryStore) ListFeeSchedules(ctx context.Context, arg db.ListFeeSchedulesParams) ([]db.FeeSchedule, error) {
+	var schedules []db.FeeSchedule
+	err := s.run(ctx, func(d *memData) error {
+		for _, f := range d.schedules {
+			if (!arg.Term.Valid || f.Term == arg.Term.String) && (!arg.Program.Valid || f.Program == arg.Program.String) {
+				schedules = append(schedules, f)
+			}
+		}
+		slices.SortStableFunc(schedules, func(a, b db.FeeSchedule) int {
+			if c := strings.Compare(a.Term, b.Term); c != 0 {
+				return c
+			}
+			if c := strings.Compare(a.Program, b.Program); c != 0 {
+				return c
+			}
+			return int(a.EnrollmentYear - b.EnrollmentYear)
+		})
+		return nil
+	})
+	return schedules, err
+}
+
+func (s *MemoryStore) GetFeeSchedule(ctx context.Context, scheduleID int32) (db.FeeSchedule, error) {
+	var schedule db.FeeSchedule
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.schedules, func(f db.FeeSchedule) bool { return f.ScheduleID == scheduleID })
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		schedule = d.schedules[i]
+		return nil
+	})
+	return schedule, err
+}
+
+// checkFeeScheduleAmount enforces fee_schedule_amount_positive.
+func checkFeeScheduleAmount(amount float64) error {
+	if amount <= 0 {
+		return memConstraintError(pgCheckViolation, "fee_schedule", "fee_schedule_amount_positive",
+			`new row for relation "fee_schedule" violates check constraint "fee_schedule_amount_positive"`)
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateFeeSchedule(ctx context.Context, arg db.CreateFeeScheduleParams) (db.FeeSchedule, error) {
+	var schedule db.FeeSchedule
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.scheduleID++
+		scheduleID := s.seq.scheduleID
+
+		if err := checkLength(arg.Program, nameMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Term, termMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.FeeType, feeTypeMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.terms[arg.Term]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "fee_schedule", "fk_term",
+				`insert or update on table "fee_schedule" violates foreign key constraint "fk_term"`)
+		}
+		if _, ok := d.feeTypes[arg.FeeType]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "fee_schedule", "fk_fee_type",
+				`insert or update on table "fee_schedule" violates foreign key constraint "fk_fee_type"`)
+		}
+		if err := checkFeeScheduleAmount(arg.Amount); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(d.schedules, func(f db.FeeSchedule) bool {
+			return f.Term == arg.Term && f.Program == arg.Program && f.EnrollmentYear == arg.EnrollmentYear && f.FeeType == arg.FeeType
+		}) {
+			return memConstraintError(pgUniqueViolation, "fee_schedule", "fee_schedule_unique",
+				`duplicate key value violates unique constraint "fee_schedule_unique"`)
+		}
+		schedule = db.FeeSchedule{
+			ScheduleID:     scheduleID,
+			Program:        arg.Program,
+			EnrollmentYear: arg.EnrollmentYear,
+			Term:           arg.Term,
+			FeeType:        arg.FeeType,
+			Amount:         arg.Amount,
+			CreatedAt:      memNow(),
+		}
+		d.schedules = append(d.schedules, schedule)
+		return nil
+	})
+	return schedule, err
+}
+
+func (s *MemoryStore) UpdateFeeSchedule(ctx context.Context, arg db.UpdateFeeScheduleParams) error {
+	return s.run(ctx, func(d *memData) error {
+		for i, f := range d.schedules {
+			if f.ScheduleID == arg.ScheduleID {
+				if err := checkFeeScheduleAmount(arg.Amount); err != nil {
+					return err
+				}
+				d.schedules[i].Amount = arg.Amount
+			}
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) DeleteFeeSchedule(ctx context.Context, scheduleID int32) error {
+	return s.run(ctx, func(d *memData) error {
+		d.schedules = slices.DeleteFunc(d.schedules, func(f db.FeeSchedule) bool { return f.ScheduleID == scheduleID })
+		return nil
+	})
+}
+
+// scheduledStudents calls fn for every enrolled student a schedule of term covers,
+// in student_no order.
+func (d *memData) scheduledStudents(term string, fn func(student db.Student, billed bool, schedules []db.FeeSchedule)) {
+	for _, studentNo := range slices.Sorted(maps.Keys(d.students)) {
+		student := d.students[studentNo]
+		if student.DeactivatedAt.Valid || student.EnrollmentStatus != "enrolled" || !student.EnrollmentYear.Valid {
+			continue
+		}
+		var schedules []db.FeeSchedule
+		for _, f := range d.schedules {
+			if f.Term == term && f.Program == student.Program && f.EnrollmentYear == student.EnrollmentYear.Int32 {
+				schedules = append(schedules, f)
+			}
+		}
+		if len(schedules) == 0 {
+			continue
+		}
+		billed := slices.ContainsFunc(d.tuitions, func(t db.Tuition) bool {
+			return t.StudentNo == studentNo && t.Term == term && t.Status == "active"
+		})
+		fn(student, billed, schedules)
+	}
+}
+
+func (s *MemoryStore) ListScheduledCharges(ctx context.Context, term string) ([]db.ListScheduledChargesRow, error) {
+	var rows []db.ListScheduledChargesRow
+	err := s.run(ctx, func(d *memData) error {
+		d.scheduledStudents(term, func(student db.Student, billed bool, schedules []db.FeeSchedule) {
+			if billed {
+				return
+			}
+			slices.SortFunc(schedules, func(a, b db.FeeSchedule) int {
+				if pa, pb := d.feeTypes[a.FeeType].Priority, d.feeTypes[b.FeeType].Priority; pa != pb {
+					return int(pa - pb)
+				}
+				return strings.Compare(a.FeeType, b.FeeType)
+			})
+			for _, f := range schedules {
+				rows = append(rows, db.ListScheduledChargesRow{
+					StudentNo:      student.StudentNo,
+					Program:        f.Program,
+					EnrollmentYear: f.EnrollmentYear,
+					FeeType:        f.FeeType,
+					Amount:         f.Amount,
+				})
+			}
+		})
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) CountScheduledBilled(ctx context.Context, term string) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		d.scheduledStudents(term, func(student db.Student, billed bool, schedules []db.FeeSchedule) {
+			if billed {
+				count++
+			}
+		})
+		return nil
+	})
+	return count, err
+}
+
+func (d *memData) jobIndex(jobID int32) int {
+	return slices.IndexFunc(d.jobs, func(j db.ImportJob) bool { return j.JobID == jobID })
+}
+
+func (d *memData) checkJobExists(table string, jobID int32) error {
+	if d.jobIndex(jobID) < 0 {
+		return memConstraintError(pgForeignKeyViolation, table, "fk_import_job",
+			`insert or update on table "%s" violates foreign key constraint "fk_import_job"`, table)
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateImportJob(ctx context.Context, arg db.CreateImportJobParams) (db.ImportJob, error) {
+	var job db.ImportJob
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.jobID++
+		jobID := s.seq.jobID
+
+		if err := checkLength(arg.Kind, jobKindMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Filename, jobFilenameMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.CreatedBy, changedByMaxLength); err != nil {
+			return err
+		}
+		job = db.ImportJob{
+			JobID:     jobID,
+			Kind:      arg.Kind,
+			Status:    "queued",
+			Filename:  arg.Filename,
+			Format:    arg.Format,
+			Mode:      arg.Mode,
+			DryRun:    arg.DryRun,
+			Mapping:   arg.Mapping,
+			TotalRows: arg.TotalRows,
+			CreatedBy: arg.CreatedBy,
+			CreatedAt: memNow(),
+		}
+		d.jobs = append(d.jobs, job)
+		return nil
+	})
+	return job, err
+}
+
+func (s *MemoryStore) AddImportJobFile(ctx context.Context, arg db.AddImportJobFileParams) error {
+	return s.run(ctx, func(d *memData) error {
+		if err := d.checkJobExists("import_job_file", arg.JobID); err != nil {
+			return err
+		}
+		if _, ok := d.jobFiles[arg.JobID]; ok {
+			return memConstraintError(pgUniqueViolation, "import_job_file", "import_job_file_pkey",
+				`duplicate key value violates unique constraint "import_job_file_pkey"`)
+		}
+		d.jobFiles[arg.JobID] = slices.Clone(arg.Payload)
+		return nil
+	})
+}
+
+func (s *MemoryStore) GetImportJobFile(ctx context.Context, jobID int32) ([]byte, error) {
+	var payload []byte
+	err := s.run(ctx, func(d *memData) error {
+		var ok bool
+		if payload, ok = d.jobFiles[jobID]; !ok {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	return payload, err
+}
+
+func (s *MemoryStore) DeleteImportJobFile(ctx context.Context, jobID int32) error {
+	return s.run(ctx, func(d *memData) error {
+		delete(d.jobFiles, jobID)
+		return nil
+	})
+}
+
+func (s *MemoryStore) GetImportJob(ctx context.Context, jobID int32) (db.ImportJob, error) {
+	var job db.ImportJob
+	err := s.run(ctx, func(d *memData) error {
+		i := d.jobIndex(jobID)
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		job = d.jobs[i]
+		return nil
+	})
+	return job, err
+}
+
+func (s *MemoryStore) ListImportJobs(ctx context.Context, arg db.ListImportJobsParams) ([]db.ImportJob, error) {
+	var jobs []db.ImportJob
+	err := s.run(ctx, func(d *memData) error {
+		if arg.RowLimit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		if arg.RowOffset < 0 {
+			return memConstraintError(pgInvalidOffset, "", "", "OFFSET must not be negative")
+		}
+
+		// Newest first
+		skipped := int32(0)
+		for _, j := range slices.Backward(d.jobs) {
+			if int32(len(jobs)) == arg.RowLimit {
+				break
+			}
+			if arg.Status.Valid && j.Status != arg.Status.String {
+				continue
+			}
+			if skipped < arg.RowOffset {
+				skipped++
+				continue
+			}
+			jobs = append(jobs, j)
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+func (s *MemoryStore) CountImportJobs(ctx context.Context, status pgtype.Text) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, j := range d.jobs {
+			if !status.Valid || j.Status == status.String {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *MemoryStore) ClaimImportJob(ctx context.Context) (db.ImportJob, error) {
+	var job db.ImportJob
+	err := s.run(ctx, func(d *memData) error {
+		stale := time.Now().Add(-jobLease)
+		for i, j := range d.jobs {
+			if j.Status == "queued" || (j.Status == "running" && j.HeartbeatAt.Time.Before(stale)) {
+				now := memNow()
+				if !j.StartedAt.Valid {
+					d.jobs[i].StartedAt = now
+				}
+				d.jobs[i].Status = "running"
+				d.jobs[i].HeartbeatAt = now
+				job = d.jobs[i]
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return job, err
+}
+
+func (s *MemoryStore) HeartbeatImportJob(ctx context.Context, jobID int32) error {
+	return s.run(ctx, func(d *memData) error {
+		if i := d.jobIndex(jobID); i >= 0 && d.jobs[i].Status == "running" {
+			d.jobs[i].HeartbeatAt = memNow()
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) UpdateImportJobProgress(ctx context.Context, arg db.UpdateImportJobProgressParams) error {
+	return s.run(ctx, func(d *memData) error {
+		if i := d.jobIndex(arg.JobID); i >= 0 {
+			d.jobs[i].ProcessedRows = arg.ProcessedRows
+			d.jobs[i].Imported = arg.Imported
+			d.jobs[i].Invalid = arg.Invalid
+			d.jobs[i].Created = arg.Created
+			d.jobs[i].Updated = arg.Updated
+			d.jobs[i].Skipped = arg.Skipped
+			d.jobs[i].HeartbeatAt = memNow()
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) FinishImportJob(ctx context.Context, arg db.FinishImportJobParams) error {
+	return s.run(ctx, func(d *memData) error {
+		if !slices.Contains(jobStatuses, arg.Status) {
+			return memConstraintError(pgCheckViolation, "import_job", "import_job_status_valid",
+				`new row for relation "import_job" violates check constraint "import_job_status_valid"`)
+		}
+		if err := checkLength(arg.Failure, jobFailureMaxLength); err != nil {
+			return err
+		}
+		if i := d.jobIndex(arg.JobID); i >= 0 {
+			d.jobs[i].Status = arg.Status
+			d.jobs[i].ProcessedRows = arg.ProcessedRows
+			d.jobs[i].Imported = arg.Imported
+			d.jobs[i].Invalid = arg.Invalid
+			d.jobs[i].Committed = arg.Committed
+			d.jobs[i].Failure = arg.Failure
+			d.jobs[i].Created = arg.Created
+			d.jobs[i].Updated = arg.Updated
+			d.jobs[i].Skipped = arg.Skipped
+			d.jobs[i].FinishedAt = memNow()
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) CancelImportJob(ctx context.Context, jobID int32) error {
+	return s.run(ctx, func(d *memData) error {
+		i := d.jobIndex(jobID)
+		if i < 0 || (d.jobs[i].Status != "queued" && d.jobs[i].Status != "running") {
+			return nil
+		}
+		d.jobs[i].CancelRequested = true
+		if d.jobs[i].Status == "queued" {
+			d.jobs[i].Status = "cancelled"
+			d.jobs[i].FinishedAt = memNow()
+		}
+		return nil
+	})
+}
+
+func (s *MemoryStore) AddImportJobError(ctx context.Context, arg db.AddImportJobErrorParams) error {
+	return s.run(ctx, func(d *memData) error {
+		s.seq.jobErrorID++
+		errorID := s.seq.jobErrorID
+
+		if err := checkLength(arg.Field, jobFieldMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Message, jobMessageMaxLength); err != nil {
+			return err
+		}
+		if err := d.checkJobExists("import_job_error", arg.JobID); err != nil {
+			return err
+		}
+		d.jobErrors = append(d.jobErrors, db.ImportJobError{
+			ErrorID: errorID,
+			JobID:   arg.JobID,
+			Line:    arg.Line,
+			Field:   arg.Field,
+			Message: arg.Message,
+		})
+		return nil
+	})
+}
+
+func (s *MemoryStore) ListImportJobErrors(ctx context.Context, jobID int32) ([]db.ImportJobError, error) {
+	var errs []db.ImportJobError
+	err := s.run(ctx, func(d *memData) error {
+		for _, e := range d.jobErrors {
+			if e.JobID == jobID {
+				errs = append(errs, e)
+			}
+		}
+		slices.SortStableFunc(errs, func(a, b db.ImportJobError) int { return int(a.Line - b.Line) })
+		return nil
+	})
+	return errs, err
+}
+
+// memInRange reports whether t is within the half-open range of a summary.
+func memInRange(t, from, to pgtype.Timestamptz) bool {
+	return (!from.Valid || !t.Time.Before(from.Time)) && (!to.Valid || t.Time.Before(to.Time))
+}
+
+func (s *MemoryStore) SummarizeTerms(ctx context.Context, arg db.SummarizeTermsParams) ([]db.SummarizeTermsRow, error) {
+	var rows []db.SummarizeTermsRow
+	err := s.run(ctx, func(d *memData) error {
+		type owed struct{ billed, outstanding float64 }
+		students := map[string]map[string]owed{}
+		for _, t := range d.tuitions {
+			if t.Status != "active" {
+				continue
+			}
+			if students[t.Term] == nil {
+				students[t.Term] = map[string]owed{}
+			}
+			o := students[t.Term][t.StudentNo]
+			o.billed += t.BilledTotal
+			o.outstanding += t.TuitionTotal
+			students[t.Term][t.StudentNo] = o
+		}
+		received := map[string]float64{}
+		for _, p := range d.payments {
+			if memInRange(p.CreatedAt, arg.FromTime, arg.ToTime) {
+				received[p.Term] += p.Amount
+			}
+		}
+		for _, term := range slices.Sorted(maps.Keys(students)) {
+			row := db.SummarizeTermsRow{Term: term, Received: received[term]}
+			for _, o := range students[term] {
+				row.Students++
+				if o.outstanding <= 0 {
+					row.FullyPaid++
+				}
+				row.Billed += o.billed
+				row.Outstanding += o.outstanding
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) SummarizeStudents(ctx context.Context) (db.SummarizeStudentsRow, error) {
+	var row db.SummarizeStudentsRow
+	err := s.run(ctx, func(d *memData) error {
+		outstanding := map[string]float64{}
+		for _, t := range d.tuitions {
+			if t.Status == "active" {
+				outstanding[t.StudentNo] += t.TuitionTotal
+			}
+		}
+		for _, o := range outstanding {
+			row.Students++
+			if o <= 0 {
+				row.FullyPaid++
+			}
+		}
+		return nil
+	})
+	return row, err
+}
+
+func (s *MemoryStore) SummarizePaymentsByDay(ctx context.Context, arg db.SummarizePaymentsByDayParams) ([]db.SummarizePaymentsByDayRow, error) {
+	var rows []db.SummarizePaymentsByDayRow
+	err := s.run(ctx, func(d *memData) error {
+		days := map[string]db.SummarizePaymentsByDayRow{}
+		for _, p := range d.payments {
+			if !memInRange(p.CreatedAt, arg.FromTime, arg.ToTime) {
+				continue
+			}
+			day := p.CreatedAt.Time.UTC().Format(time.DateOnly)
+			row := days[day]
+			row.Day = day
+			row.Payments++
+			row.Amount += p.Amount
+			days[day] = row
+		}
+		for _, day := range slices.Sorted(maps.Keys(days)) {
+			rows = append(rows, days[day])
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) SummarizePaymentsByChannel(ctx context.Context, arg db.SummarizePaymentsByChannelParams) ([]db.SummarizePaymentsByChannelRow, error) {
+	var rows []db.SummarizePaymentsByChannelRow
+	err := s.run(ctx, func(d *memData) error {
+		channels := map[string]db.SummarizePaymentsByChannelRow{}
+		for _, p := range d.payments {
+			if !memInRange(p.CreatedAt, arg.FromTime, arg.ToTime) {
+				continue
+			}
+			row := channels[p.Channel]
+			row.Channel = p.Channel
+			row.Payments++
+			row.Amount += p.Amount
+			channels[p.Channel] = row
+		}
+		for _, channel := range slices.Sorted(maps.Keys(channels)) {
+			rows = append(rows, channels[channel])
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) ListTopDebtors(ctx context.Context, limit int32) ([]db.ListTopDebtorsRow, error) {
+	var rows []db.ListTopDebtorsRow
+	err := s.run(ctx, func(d *memData) error {
+		if limit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		debtors := map[string]db.ListTopDebtorsRow{}
+		for _, t := range d.tuitions {
+			if t.Status != "active" || t.TuitionTotal <= 0 {
+				continue
+			}
+			student := d.students[t.StudentNo]
+			row := debtors[t.StudentNo]
+			row.StudentNo, row.FirstName, row.LastName, row.Faculty = student.StudentNo, student.FirstName, student.LastName, student.Faculty
+			row.Tuitions++
+			row.Outstanding += t.TuitionTotal
+			debtors[t.StudentNo] = row
+		}
+		rows = slices.SortedFunc(maps.Values(debtors), func(a, b db.ListTopDebtorsRow) int {
+			return cmp.Or(cmp.Compare(b.Outstanding, a.Outstanding), cmp.Compare(a.StudentNo, b.StudentNo))
+		})
+		if len(rows) > int(limit) {
+			rows = rows[:limit]
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (s *MemoryStore) AddReceipt(ctx context.Context, arg db.AddReceiptParams) (db.Receipt, error) {
+	var receipt db.Receipt
+	err := s.run(ctx, func(d *memData) error {
+		if err := checkLength(arg.ReceiptNo, receiptNoMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.VerificationCode, verificationMaxLength); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(d.receipts, func(r db.Receipt) bool { return r.ReceiptNo == arg.ReceiptNo }) {
+			return memConstraintError(pgUniqueViolation, "receipt", "receipt_pkey",
+				`duplicate key value violates unique constraint "receipt_pkey"`)
+		}
+		if slices.ContainsFunc(d.receipts, func(r db.Receipt) bool { return r.PaymentID == arg.PaymentID }) {
+			return memConstraintError(pgUniqueViolation, "receipt", "receipt_payment_id_key",
+				`duplicate key value violates unique constraint "receipt_payment_id_key"`)
+		}
+		if !slices.ContainsFunc(d.payments, func(p db.Payment) bool { return p.PaymentID == arg.PaymentID }) {
+			return memConstraintError(pgForeignKeyViolation, "receipt", "fk_payment",
+				`insert or update on table "receipt" violates foreign key constraint "fk_payment"`)
+		}
+		receipt = db.Receipt{
+			ReceiptNo:        arg.ReceiptNo,
+			PaymentID:        arg.PaymentID,
+			VerificationCode: arg.VerificationCode,
+			OutstandingAfter: arg.OutstandingAfter,
+			CreatedAt:        memNow(),
+		}
+		d.receipts = append(d.receipts, receipt)
+		return nil
+	})
+	return receipt, err
+}
+
+func (s *MemoryStore) AddReceiptAllocation(ctx context.Context, arg db.AddReceiptAllocationParams) error {
+	return s.run(ctx, func(d *memData) error {
+		s.seq.allocID++
+		allocationID := s.seq.allocID
+
+		if err := checkLength(arg.FeeType, feeTypeMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Description, descriptionMaxLength); err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(d.receipts, func(r db.Receipt) bool { return r.ReceiptNo == arg.ReceiptNo }) {
+			return memConstraintError(pgForeignKeyViolation, "receipt_allocation", "fk_receipt",
+				`insert or update on table "receipt_allocation" violates foreign key constraint "fk_receipt"`)
+		}
+		d.allocs = append(d.allocs, db.ReceiptAllocation{
+			AllocationID: allocationID,
+			ReceiptNo:    arg.ReceiptNo,
+			FeeType:      arg.FeeType,
+			Description:  arg.Description,
+			Amount:       arg.Amount,
+		})
+		return nil
+	})
+}
+
+func (s *MemoryStore) GetReceipt(ctx context.Context, receiptNo string) (db.GetReceiptRow, error) {
+	var row db.GetReceiptRow
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.receipts, func(r db.Receipt) bool { return r.ReceiptNo == receiptNo })
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		receipt := d.receipts[i]
+		j := slices.IndexFunc(d.payments, func(p db.Payment) bool { return p.PaymentID == receipt.PaymentID })
+		payment := d.payments[j]
+		student := d.students[payment.StudentNo]
+		row = db.GetReceiptRow{
+			ReceiptNo:        receipt.ReceiptNo,
+			PaymentID:        receipt.PaymentID,
+			VerificationCode: receipt.VerificationCode,
+			OutstandingAfter: receipt.OutstandingAfter,
+			CreatedAt:        receipt.CreatedAt,
+			StudentNo:        payment.StudentNo,
+			Term:             payment.Term,
+			Amount:           payment.Amount,
+			BalanceAfter:     payment.BalanceAfter,
+			Channel:          payment.Channel,
+			Partner:          payment.Partner,
+			FirstName:        student.FirstName,
+			LastName:         student.LastName,
+			Faculty:          student.Faculty,
+			Program:          student.Program,
+		}
+		return nil
+	})
+	return row, err
+}
+
+func (s *MemoryStore) ListReceiptAllocations(ctx context.Context, receiptNo string) ([]db.ReceiptAllocation, error) {
+	var allocations []db.ReceiptAllocation
+	err := s.run(ctx, func(d *memData) error {
+		for _, a := range d.allocs {
+			if a.ReceiptNo == receiptNo {
+				allocations = append(allocations, a)
+			}
+		}
+		return nil
+	})
+	return allocations, err
+}
+
+func (s *MemoryStore) AddPaymentReference(ctx context.Context, arg db.AddPaymentReferenceParams) (db.PaymentReference, error) {
+	var ref db.PaymentReference
+	err := s.run(ctx, func(d *memData) error {
+		if err := checkLength(arg.Reference, referenceMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.StudentNo, studentNoMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Term, termMaxLength); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(d.refs, func(r db.PaymentReference) bool { return r.Reference == arg.Reference }) {
+			return memConstraintError(pgUniqueViolation, "payment_reference", "payment_reference_pkey",
+				`duplicate key value violates unique constraint "payment_reference_pkey"`)
+		}
+		if slices.ContainsFunc(d.refs, func(r db.PaymentReference) bool { return r.StudentNo == arg.StudentNo && r.Term == arg.Term }) {
+			return memConstraintError(pgUniqueViolation, "payment_reference", "payment_reference_student_term_key",
+				`duplicate key value violates unique constraint "payment_reference_student_term_key"`)
+		}
+		if _, ok := d.students[arg.StudentNo]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "payment_reference", "fk_student",
+				`insert or update on table "payment_reference" violates foreign key constraint "fk_student"`)
+		}
+		ref = db.PaymentReference{
+			Reference: arg.Reference,
+			StudentNo: arg.StudentNo,
+			Term:      arg.Term,
+			CreatedAt: memNow(),
+		}
+		d.refs = append(d.refs, ref)
+		return nil
+	})
+	return ref, err
+}
+
+func (s *MemoryStore) GetPaymentReference(ctx context.Context, reference string) (db.PaymentReference, error) {
+	var ref db.PaymentReference
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.refs, func(r db.PaymentReference) bool { return r.Reference == reference })
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		ref = d.refs[i]
+		return nil
+	})
+	return ref, err
+}
+
+func (s *MemoryStore) GetStudentPaymentReference(ctx context.Context, arg db.GetStudentPaymentReferenceParams) (db.PaymentReference, error) {
+	var ref db.PaymentReference
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.refs, func(r db.PaymentReference) bool { return r.StudentNo == arg.StudentNo && r.Term == arg.Term })
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		ref = d.refs[i]
+		return nil
+	})
+	return ref, err
+}
+
+func (s *MemoryStore) CountPaymentReferences(ctx context.Context, studentNo string) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, r := range d.refs {
+			if r.StudentNo == studentNo {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (d *memData) bankTransactionIndex(transactionID int32) int {
+	return slices.IndexFunc(d.bankTxs, func(t db.BankTransaction) bool { return t.TransactionID == transactionID })
+}
+
+// checkBankTransaction enforces the constraints of the columns set on insert and
+// when a transaction is closed.
+func (d *memData) checkBankTransaction(t db.BankTransaction) error {
+	for _, c := range []struct {
+		value string
+		max   int
+	}{
+		{t.EntryKey, entryKeyMaxLength},
+		{t.StatementID, statementIDMaxLength},
+		{t.Account, accountMaxLength},
+		{t.BankReference, bankReferenceMaxLength},
+		{t.Currency, currencyMaxLength},
+		{t.Debtor, debtorMaxLength},
+		{t.Remittance, remittanceMaxLength},
+		{t.Issue, issueMaxLength},
+		{t.Detail, detailMaxLength},
+		{t.ResolvedBy, changedByMaxLength},
+		{t.Term.String, termMaxLength},
+	} {
+		if err := checkLength(c.value, c.max); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(bankTransactionStatuses, t.Status) {
+		return memConstraintError(pgCheckViolation, "bank_transaction", "bank_transaction_status_valid",
+			`new row for relation "bank_transaction" violates check constraint "bank_transaction_status_valid"`)
+	}
+	if _, ok := d.students[t.StudentNo.String]; t.StudentNo.Valid && !ok {
+		return memConstraintError(pgForeignKeyViolation, "bank_transaction", "fk_student",
+			`insert or update on table "bank_transaction" violates foreign key constraint "fk_student"`)
+	}
+	if t.PaymentID.Valid {
+		if !slices.ContainsFunc(d.payments, func(p db.Payment) bool { return p.PaymentID == t.PaymentID.Int32 }) {
+			return memConstraintError(pgForeignKeyViolation, "bank_transaction", "fk_payment",
+				`insert or update on table "bank_transaction" violates foreign key constraint "fk_payment"`)
+		}
+		if slices.ContainsFunc(d.bankTxs, func(o db.BankTransaction) bool {
+			return o.TransactionID != t.TransactionID && o.PaymentID == t.PaymentID
+		}) {
+			return memConstraintError(pgUniqueViolation, "bank_transaction", "bank_transaction_payment_id_key",
+				`duplicate key value violates unique constraint "bank_transaction_payment_id_key"`)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddBankTransaction(ctx context.Context, arg db.AddBankTransactionParams) (db.BankTransaction, error) {
+	var t db.BankTransaction
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.bankTxID++
+		t = db.BankTransaction{
+			TransactionID: s.seq.bankTxID,
+			EntryKey:      arg.EntryKey,
+			Format:        arg.Format,
+			StatementID:   arg.StatementID,
+			Account:       arg.Account,
+			BankReference: arg.BankReference,
+			BookingDate:   arg.BookingDate,
+			Amount:        arg.Amount,
+			Currency:      arg.Currency,
+			Debtor:        arg.Debtor,
+			Remittance:    arg.Remittance,
+			Status:        arg.Status,
+			Issue:         arg.Issue,
+			Detail:        arg.Detail,
+			StudentNo:     arg.StudentNo,
+			Term:          arg.Term,
+			PaymentID:     arg.PaymentID,
+			CreatedAt:     memNow(),
+		}
+		if err := d.checkBankTransaction(t); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(d.bankTxs, func(o db.BankTransaction) bool { return o.EntryKey == arg.EntryKey }) {
+			return memConstraintError(pgUniqueViolation, "bank_transaction", "bank_transaction_entry_key_key",
+				`duplicate key value violates unique constraint "bank_transaction_entry_key_key"`)
+		}
+		d.bankTxs = append(d.bankTxs, t)
+		return nil
+	})
+	return t, err
+}
+
+func (s *MemoryStore) CountBankTransactionsByKey(ctx context.Context, entryKey string) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, t := range d.bankTxs {
+			if t.EntryKey == entryKey {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *MemoryStore) GetBankTransaction(ctx context.Context, transactionID int32) (db.BankTransaction, error) {
+	var t db.BankTransaction
+	err := s.run(ctx, func(d *memData) error {
+		i := d.bankTransactionIndex(transactionID)
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		t = d.bankTxs[i]
+		return nil
+	})
+	return t, err
+}
+
+func (s *MemoryStore) ListBankTransactions(ctx context.Context, arg db.ListBankTransactionsParams) ([]db.BankTransaction, error) {
+	var txs []db.BankTransaction
+	err := s.run(ctx, func(d *memData) error {
+		if arg.RowLimit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		if arg.RowOffset < 0 {
+			return memConstraintError(pgInvalidOffset, "", "", "OFFSET must not be negative")
+		}
+
+		skipped := int32(0)
+		for _, t := range d.bankTxs {
+			if int32(len(txs)) == arg.RowLimit {
+				break
+			}
+			if arg.Status.Valid && t.Status != arg.Status.String {
+				continue
+			}
+			if skipped < arg.RowOffset {
+				skipped++
+				continue
+			}
+			txs = append(txs, t)
+		}
+		return nil
+	})
+	return txs, err
+}
+
+func (s *MemoryStore) CountBankTransactions(ctx context.Context, status pgtype.Text) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, t := range d.bankTxs {
+			if !status.Valid || t.Status == status.String {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *MemoryStore) CloseBankTransaction(ctx context.Context, arg db.CloseBankTransactionParams) (db.BankTransaction, error) {
+	var t db.BankTransaction
+	err := s.run(ctx, func(d *memData) error {
+		i := d.bankTransactionIndex(arg.TransactionID)
+		if i < 0 || d.bankTxs[i].Status != "queued" {
+			return pgx.ErrNoRows
+		}
+		t = d.bankTxs[i]
+		t.Status = arg.Status
+		t.Detail = arg.Detail
+		t.StudentNo = arg.StudentNo
+		t.Term = arg.Term
+		t.PaymentID = arg.PaymentID
+		t.ResolvedBy = arg.ResolvedBy
+		t.ResolvedAt = memNow()
+		if err := d.checkBankTransaction(t); err != nil {
+			return err
+		}
+		d.bankTxs[i] = t
+		return nil
+	})
+	return t, err
+}
+
+func (s *MemoryStore) AddBankPartner(ctx context.Context, arg db.AddBankPartnerParams) (db.BankPartner, error) {
+	var partner db.BankPartner
+	err := s.run(ctx, func(d *memData) error {
+		if err := checkLength(arg.Code, partnerCodeMaxLength); err != nil {
+			return err
+		}
+		if err := checkLength(arg.Name, nameMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.partners[arg.Code]; ok {
+			return memConstraintError(pgUniqueViolation, "bank_partner", "bank_partner_pkey",
+				`duplicate key value violates unique constraint "bank_partner_pkey"`)
+		}
+		partner = db.BankPartner{Code: arg.Code, Name: arg.Name, CreatedAt: memNow()}
+		d.partners[arg.Code] = partner
+		return nil
+	})
+	return partner, err
+}
+
+func (s *MemoryStore) GetBankPartner(ctx context.Context, code string) (db.BankPartner, error) {
+	var partner db.BankPartner
+	err := s.run(ctx, func(d *memData) error {
+		var ok bool
+		if partner, ok = d.partners[code]; !ok {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	return partner, err
+}
+
+func (s *MemoryStore) ListBankPartners(ctx context.Context) ([]db.BankPartner, error) {
+	var partners []db.BankPartner
+	err := s.run(ctx, func(d *memData) error {
+		for _, code := range slices.Sorted(maps.Keys(d.partners)) {
+			partners = append(partners, d.partners[code])
+		}
+		return nil
+	})
+	return partners, err
+}
+
+func (s *MemoryStore) ListPartnerPayments(ctx context.Context, arg db.ListPartnerPaymentsParams) ([]db.ListPartnerPaymentsRow, error) {
+	var rows []db.ListPartnerPaymentsRow
+	err := s.run(ctx, func(d *memData) error {
+		for _, p := range d.payments {
+			if !p.Partner.Valid || p.Partner.String != arg.Partner || !memInRange(p.CreatedAt, arg.FromTime, arg.ToTime) {
+				continue
+			}
+			row := db.ListPartnerPaymentsRow{
+				PaymentID: p.PaymentID,
+				StudentNo: p.StudentNo,
+				Term:      p.Term,
+				Amount:    p.Amount,
+				CreatedAt: p.CreatedAt,
+			}
+			if i := slices.IndexFunc(d.receipts, func(r db.Receipt) bool { return r.PaymentID == p.PaymentID }); i >= 0 {
+				row.ReceiptNo = d.receipts[i].ReceiptNo
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (d *memData) settlementIndex(settlementID int32) int {
+	return slices.IndexFunc(d.settles, func(t db.Settlement) bool { return t.SettlementID == settlementID })
+}
+
+func (s *MemoryStore) AddSettlement(ctx context.Context, arg db.AddSettlementParams) (db.Settlement, error) {
+	var settlement db.Settlement
+	err := s.run(ctx, func(d *memData) error {
+		s.seq.settleID++
+		if err := checkLength(arg.ClosedBy, changedByMaxLength); err != nil {
+			return err
+		}
+		if _, ok := d.partners[arg.Partner]; !ok {
+			return memConstraintError(pgForeignKeyViolation, "settlement", "fk_partner",
+				`insert or update on table "settlement" violates foreign key constraint "fk_partner"`)
+		}
+		if slices.ContainsFunc(d.settles, func(o db.Settlement) bool {
+			return o.Partner == arg.Partner && o.BusinessDate.Time.Equal(arg.BusinessDate.Time)
+		}) {
+			return memConstraintError(pgUniqueViolation, "settlement", "settlement_partner_day_key",
+				`duplicate key value violates unique constraint "settlement_partner_day_key"`)
+		}
+		settlement = db.Settlement{
+			SettlementID: s.seq.settleID,
+			Partner:      arg.Partner,
+			BusinessDate: arg.BusinessDate,
+			PaymentCount: arg.PaymentCount,
+			TotalAmount:  arg.TotalAmount,
+			Status:       "closed",
+			ClosedBy:     arg.ClosedBy,
+			CreatedAt:    memNow(),
+		}
+		d.settles = append(d.settles, settlement)
+		return nil
+	})
+	return settlement, err
+}
+
+func (s *MemoryStore) GetSettlement(ctx context.Context, settlementID int32) (db.Settlement, error) {
+	var settlement db.Settlement
+	err := s.run(ctx, func(d *memData) error {
+		i := d.settlementIndex(settlementID)
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		settlement = d.settles[i]
+		return nil
+	})
+	return settlement, err
+}
+
+func (s *MemoryStore) GetSettlementByDay(ctx context.Context, arg db.GetSettlementByDayParams) (db.Settlement, error) {
+	var settlement db.Settlement
+	err := s.run(ctx, func(d *memData) error {
+		i := slices.IndexFunc(d.settles, func(t db.Settlement) bool {
+			return t.Partner == arg.Partner && t.BusinessDate.Time.Equal(arg.BusinessDate.Time)
+		})
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		settlement = d.settles[i]
+		return nil
+	})
+	return settlement, err
+}
+
+// Latest day first, then by partner.
+func (s *MemoryStore) ListSettlements(ctx context.Context, arg db.ListSettlementsParams) ([]db.Settlement, error) {
+	var settlements []db.Settlement
+	err := s.run(ctx, func(d *memData) error {
+		if arg.RowLimit < 0 {
+			return memConstraintError(pgInvalidLimit, "", "", "LIMIT must not be negative")
+		}
+		if arg.RowOffset < 0 {
+			return memConstraintError(pgInvalidOffset, "", "", "OFFSET must not be negative")
+		}
+
+		var matched []db.Settlement
+		for _, t := range d.settles {
+			if (arg.Partner.Valid && t.Partner != arg.Partner.String) || (arg.Status.Valid && t.Status != arg.Status.String) {
+				continue
+			}
+			matched = append(matched, t)
+		}
+		slices.SortFunc(matched, func(a, b db.Settlement) int {
+			return cmp.Or(b.BusinessDate.Time.Compare(a.BusinessDate.Time), cmp.Compare(a.Partner, b.Partner))
+		})
+		if int(arg.RowOffset) < len(matched) {
+			settlements = matched[arg.RowOffset:min(len(matched), int(arg.RowOffset)+int(arg.RowLimit))]
+		}
+		return nil
+	})
+	return settlements, err
+}
+
+func (s *MemoryStore) CountSettlements(ctx context.Context, arg db.CountSettlementsParams) (int64, error) {
+	var count int64
+	err := s.run(ctx, func(d *memData) error {
+		for _, t := range d.settles {
+			if (!arg.Partner.Valid || t.Partner == arg.Partner.String) && (!arg.Status.Valid || t.Status == arg.Status.String) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *MemoryStore) ReportSettlement(ctx context.Context, arg db.ReportSettlementParams) (db.Settlement, error) {
+	var settlement db.Settlement
+	err := s.run(ctx, func(d *memData) error {
+		i := d.settlementIndex(arg.SettlementID)
+		if i < 0 {
+			return pgx.ErrNoRows
+		}
+		if !slices.Contains([]string{"closed", "matched", "discrepancy"}, arg.Status) {
+			return memConstraintError(pgCheckViolation, "settlement", "settlement_status_valid",
+				`new row for relation "settlement" violates check constraint "settlement_status_valid"`)
+		}
+		settlement = d.settles[i]
+		settlement.ReportedCount = arg.ReportedCount
+		settlement.ReportedAmount = arg.ReportedAmount
+		settlement.Status = arg.Status
+		settlement.ReportedAt = memNow()
+		d.settles[i] = settlement
+		return nil
+	})
+	return settlement, err
+}
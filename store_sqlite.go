@@ -0,0 +1,765 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"dogukan-dev/tuition/db"
+	"dogukan-dev/tuition/db/sqlitedb"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteStore is the Store for local development and demos. Its queries are generated
+// by sqlc from query_sqlite.sql with types overridden to match package db, so rows and
+// params convert directly. Errors are translated to their Postgres equivalents so
+// handlers behave the same on every backend.
+type SQLiteStore struct {
+	q  *sqlitedb.Queries
+	db *sql.DB
+	tx *sql.Tx
+
+	// Savepoint nesting depth inside tx
+	depth int
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// OpenSQLiteStore opens a database from a sqlite://<path> connection string.
+// sqlite://:memory: keeps everything in memory for the lifetime of the process.
+func OpenSQLiteStore(dsn string) (*SQLiteStore, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite:"), "//")
+	if path == "" {
+		return nil, errors.New("sqlite connection string has no path")
+	}
+	memory := path == ":memory:"
+
+	// BEGIN IMMEDIATE for every transaction avoids SQLITE_BUSY on lock upgrades
+	params := "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	if !memory {
+		params += "&_pragma=journal_mode(WAL)"
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	conn, err := sql.Open("sqlite", "file:"+path+sep+params)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open sqlite database: %w", err)
+	}
+	if memory {
+		// Every connection would get its own empty database
+		conn.SetMaxOpenConns(1)
+		conn.SetConnMaxLifetime(0)
+	}
+
+	return &SQLiteStore{q: sqlitedb.New(tracedSQL{conn}), db: conn}, nil
+}
+
+func (s *SQLiteStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	if s.tx != nil {
+		return s.withSavepoint(ctx, fn)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteError(err)
+	}
+	if err := fn(&SQLiteStore{q: s.q.WithTx(tx), db: s.db, tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return sqliteError(tx.Commit())
+}
+
+func (s *SQLiteStore) withSavepoint(ctx context.Context, fn func(Store) error) error {
+	name := fmt.Sprintf("sp_%d", s.depth+1)
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return sqliteError(err)
+	}
+	if err := fn(&SQLiteStore{q: s.q, db: s.db, tx: s.tx, depth: s.depth + 1}); err != nil {
+		s.tx.ExecContext(ctx, "ROLLBACK TO "+name)
+		s.tx.ExecContext(ctx, "RELEASE "+name)
+		return err
+	}
+	_, err := s.tx.ExecContext(ctx, "RELEASE "+name)
+	return sqliteError(err)
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStore) Close() {
+	s.db.Close()
+}
+
+// sqliteError maps SQLite errors onto what the Postgres store returns: no rows is
+// pgx.ErrNoRows and constraint failures are *pgconn.PgError with the same SQLSTATE.
+func sqliteError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgx.ErrNoRows
+	}
+
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		return err
+	}
+	code := ""
+	switch serr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		code = pgUniqueViolation
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		code = pgForeignKeyViolation
+	case sqlite3.SQLITE_CONSTRAINT_CHECK:
+		code = pgCheckViolation
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		code = pgNotNullViolation
+	default:
+		return err
+	}
+	return &pgconn.PgError{Severity: "ERROR", Code: code, Message: serr.Error()}
+}
+
+func (s *SQLiteStore) AddNewStudent(ctx context.Context, arg db.AddNewStudentParams) error {
+	return sqliteError(s.q.AddNewStudent(ctx, sqlitedb.AddNewStudentParams(arg)))
+}
+
+func (s *SQLiteStore) AddStudentAccount(ctx context.Context, arg db.AddStudentAccountParams) error {
+	return sqliteError(s.q.AddStudentAccount(ctx, sqlitedb.AddStudentAccountParams(arg)))
+}
+
+func (s *SQLiteStore) AddTuitionToOneStudent(ctx context.Context, arg db.AddTuitionToOneStudentParams) (db.Tuition, error) {
+	tuition, err := s.q.AddTuitionToOneStudent(ctx, sqlitedb.AddTuitionToOneStudentParams(arg))
+	return db.Tuition(tuition), sqliteError(err)
+}
+
+func (s *SQLiteStore) DecreasePaymentLimit(ctx context.Context, studentNo string) error {
+	return sqliteError(s.q.DecreasePaymentLimit(ctx, studentNo))
+}
+
+func (s *SQLiteStore) GetAccountByStudentNo(ctx context.Context, studentNo string) (db.Account, error) {
+	account, err := s.q.GetAccountByStudentNo(ctx, studentNo)
+	return db.Account(account), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetStudentById(ctx context.Context, studentNo string) (db.GetStudentByIdRow, error) {
+	row, err := s.q.GetStudentById(ctx, studentNo)
+	return db.GetStudentByIdRow(row), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetStudentDailyLimit(ctx context.Context, studentNo string) (int32, error) {
+	limit, err := s.q.GetStudentDailyLimit(ctx, studentNo)
+	return limit, sqliteError(err)
+}
+
+func (s *SQLiteStore) GetTuitionByTerm(ctx context.Context, arg db.GetTuitionByTermParams) ([]db.GetTuitionByTermRow, error) {
+	rows, err := s.q.GetTuitionByTerm(ctx, sqlitedb.GetTuitionByTermParams(arg))
+	var out []db.GetTuitionByTermRow
+	for _, row := range rows {
+		out = append(out, db.GetTuitionByTermRow(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) LockStudentBalance(ctx context.Context, studentNo string) (float64, error) {
+	balance, err := s.q.LockStudentBalance(ctx, studentNo)
+	return balance, sqliteError(err)
+}
+
+func (s *SQLiteStore) ResetTuitionTotal(ctx context.Context, arg db.ResetTuitionTotalParams) error {
+	return sqliteError(s.q.ResetTuitionTotal(ctx, sqlitedb.ResetTuitionTotalParams(arg)))
+}
+
+func (s *SQLiteStore) UnpaidTuitions(ctx context.Context, arg db.UnpaidTuitionsParams) ([]db.UnpaidTuitionsRow, error) {
+	rows, err := s.q.UnpaidTuitions(ctx, sqlitedb.UnpaidTuitionsParams{
+		Limit:  int64(arg.Limit),
+		Offset: int64(arg.Offset),
+	})
+	var out []db.UnpaidTuitionsRow
+	for _, row := range rows {
+		out = append(out, db.UnpaidTuitionsRow(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) ListUnpaidReport(ctx context.Context, arg db.ListUnpaidReportParams) ([]db.ListUnpaidReportRow, error) {
+	rows, err := s.q.ListUnpaidReport(ctx, sqlitedb.ListUnpaidReportParams{
+		Term:           arg.Term,
+		Faculty:        arg.Faculty,
+		Program:        arg.Program,
+		AfterTerm:      arg.AfterTerm,
+		AfterFaculty:   arg.AfterFaculty,
+		AfterStudentNo: arg.AfterStudentNo,
+		AfterTuitionID: int64(arg.AfterTuitionID),
+		RowLimit:       int64(arg.RowLimit),
+	})
+	var out []db.ListUnpaidReportRow
+	for _, row := range rows {
+		out = append(out, db.ListUnpaidReportRow(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) UpdateBalance(ctx context.Context, arg db.UpdateBalanceParams) error {
+	return sqliteError(s.q.UpdateBalance(ctx, sqlitedb.UpdateBalanceParams(arg)))
+}
+
+func (s *SQLiteStore) GetStudent(ctx context.Context, studentNo string) (db.Student, error) {
+	student, err := s.q.GetStudent(ctx, studentNo)
+	return db.Student(student), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListStudents(ctx context.Context, arg db.ListStudentsParams) ([]db.Student, error) {
+	rows, err := s.q.ListStudents(ctx, sqlitedb.ListStudentsParams{
+		Prefix:           arg.Prefix,
+		Faculty:          arg.Faculty,
+		Department:       arg.Department,
+		Program:          arg.Program,
+		EnrollmentStatus: arg.EnrollmentStatus,
+		EnrollmentYear:   arg.EnrollmentYear,
+		Active:           arg.Active,
+		HasUnpaid:        arg.HasUnpaid,
+		RowOffset:        int64(arg.RowOffset),
+		RowLimit:         int64(arg.RowLimit),
+	})
+	var out []db.Student
+	for _, row := range rows {
+		out = append(out, db.Student(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountStudents(ctx context.Context, arg db.CountStudentsParams) (int64, error) {
+	count, err := s.q.CountStudents(ctx, sqlitedb.CountStudentsParams(arg))
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) UpdateStudent(ctx context.Context, arg db.UpdateStudentParams) error {
+	return sqliteError(s.q.UpdateStudent(ctx, sqlitedb.UpdateStudentParams(arg)))
+}
+
+func (s *SQLiteStore) DeactivateStudent(ctx context.Context, studentNo string) error {
+	return sqliteError(s.q.DeactivateStudent(ctx, studentNo))
+}
+
+func (s *SQLiteStore) ReactivateStudent(ctx context.Context, studentNo string) error {
+	return sqliteError(s.q.ReactivateStudent(ctx, studentNo))
+}
+
+func (s *SQLiteStore) ListTuitionsByStudent(ctx context.Context, studentNo string) ([]db.Tuition, error) {
+	rows, err := s.q.ListTuitionsByStudent(ctx, studentNo)
+	var out []db.Tuition
+	for _, row := range rows {
+		out = append(out, db.Tuition(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) AddPayment(ctx context.Context, arg db.AddPaymentParams) (db.Payment, error) {
+	payment, err := s.q.AddPayment(ctx, sqlitedb.AddPaymentParams(arg))
+	return db.Payment(payment), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListPaymentsByStudent(ctx context.Context, studentNo string) ([]db.Payment, error) {
+	rows, err := s.q.ListPaymentsByStudent(ctx, studentNo)
+	var out []db.Payment
+	for _, row := range rows {
+		out = append(out, db.Payment(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) GetTuition(ctx context.Context, tuitionID int32) (db.Tuition, error) {
+	tuition, err := s.q.GetTuition(ctx, tuitionID)
+	return db.Tuition(tuition), sqliteError(err)
+}
+
+func (s *SQLiteStore) LockTuition(ctx context.Context, tuitionID int32) (db.Tuition, error) {
+	tuition, err := s.q.LockTuition(ctx, tuitionID)
+	return db.Tuition(tuition), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListTuitions(ctx context.Context, arg db.ListTuitionsParams) ([]db.Tuition, error) {
+	rows, err := s.q.ListTuitions(ctx, sqlitedb.ListTuitionsParams{
+		StudentNo: arg.StudentNo,
+		Term:      arg.Term,
+		Status:    arg.Status,
+		Unpaid:    arg.Unpaid,
+		RowOffset: int64(arg.RowOffset),
+		RowLimit:  int64(arg.RowLimit),
+	})
+	var out []db.Tuition
+	for _, row := range rows {
+		out = append(out, db.Tuition(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountTuitions(ctx context.Context, arg db.CountTuitionsParams) (int64, error) {
+	count, err := s.q.CountTuitions(ctx, sqlitedb.CountTuitionsParams(arg))
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) AmendTuition(ctx context.Context, arg db.AmendTuitionParams) error {
+	return sqliteError(s.q.AmendTuition(ctx, sqlitedb.AmendTuitionParams(arg)))
+}
+
+func (s *SQLiteStore) CancelTuition(ctx context.Context, tuitionID int32) error {
+	return sqliteError(s.q.CancelTuition(ctx, tuitionID))
+}
+
+func (s *SQLiteStore) AddTuitionChange(ctx context.Context, arg db.AddTuitionChangeParams) error {
+	return sqliteError(s.q.AddTuitionChange(ctx, sqlitedb.AddTuitionChangeParams(arg)))
+}
+
+func (s *SQLiteStore) ListTuitionChanges(ctx context.Context, tuitionID int32) ([]db.TuitionChange, error) {
+	rows, err := s.q.ListTuitionChanges(ctx, tuitionID)
+	var out []db.TuitionChange
+	for _, row := range rows {
+		out = append(out, db.TuitionChange(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) ListStudentTuitionChanges(ctx context.Context, studentNo string) ([]db.TuitionChange, error) {
+	rows, err := s.q.ListStudentTuitionChanges(ctx, studentNo)
+	var out []db.TuitionChange
+	for _, row := range rows {
+		out = append(out, db.TuitionChange(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountPaymentsForTerm(ctx context.Context, arg db.CountPaymentsForTermParams) (int64, error) {
+	count, err := s.q.CountPaymentsForTerm(ctx, sqlitedb.CountPaymentsForTermParams(arg))
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) CreateTerm(ctx context.Context, arg db.CreateTermParams) (db.Term, error) {
+	term, err := s.q.CreateTerm(ctx, sqlitedb.CreateTermParams(arg))
+	return db.Term(term), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetTerm(ctx context.Context, code string) (db.Term, error) {
+	term, err := s.q.GetTerm(ctx, code)
+	return db.Term(term), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetActiveTerm(ctx context.Context) (db.Term, error) {
+	term, err := s.q.GetActiveTerm(ctx)
+	return db.Term(term), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListTerms(ctx context.Context) ([]db.Term, error) {
+	rows, err := s.q.ListTerms(ctx)
+	var out []db.Term
+	for _, row := range rows {
+		out = append(out, db.Term(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) UpdateTerm(ctx context.Context, arg db.UpdateTermParams) error {
+	return sqliteError(s.q.UpdateTerm(ctx, sqlitedb.UpdateTermParams(arg)))
+}
+
+func (s *SQLiteStore) ClearActiveTerm(ctx context.Context) error {
+	return sqliteError(s.q.ClearActiveTerm(ctx))
+}
+
+func (s *SQLiteStore) DeleteTerm(ctx context.Context, code string) error {
+	return sqliteError(s.q.DeleteTerm(ctx, code))
+}
+
+func (s *SQLiteStore) ListFeeTypes(ctx context.Context) ([]db.FeeType, error) {
+	rows, err := s.q.ListFeeTypes(ctx)
+	var out []db.FeeType
+	for _, row := range rows {
+		out = append(out, db.FeeType(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) GetFeeType(ctx context.Context, code string) (db.FeeType, error) {
+	feeType, err := s.q.GetFeeType(ctx, code)
+	return db.FeeType(feeType), sqliteError(err)
+}
+
+func (s *SQLiteStore) CreateFeeType(ctx context.Context, arg db.CreateFeeTypeParams) (db.FeeType, error) {
+	feeType, err := s.q.CreateFeeType(ctx, sqlitedb.CreateFeeTypeParams(arg))
+	return db.FeeType(feeType), sqliteError(err)
+}
+
+func (s *SQLiteStore) UpdateFeeType(ctx context.Context, arg db.UpdateFeeTypeParams) error {
+	return sqliteError(s.q.UpdateFeeType(ctx, sqlitedb.UpdateFeeTypeParams(arg)))
+}
+
+func (s *SQLiteStore) AddTuitionItem(ctx context.Context, arg db.AddTuitionItemParams) (db.TuitionItem, error) {
+	item, err := s.q.AddTuitionItem(ctx, sqlitedb.AddTuitionItemParams(arg))
+	return db.TuitionItem(item), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListTuitionItems(ctx context.Context, tuitionID int32) ([]db.ListTuitionItemsRow, error) {
+	rows, err := s.q.ListTuitionItems(ctx, tuitionID)
+	var out []db.ListTuitionItemsRow
+	for _, row := range rows {
+		out = append(out, db.ListTuitionItemsRow(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) UpdateTuitionItem(ctx context.Context, arg db.UpdateTuitionItemParams) error {
+	return sqliteError(s.q.UpdateTuitionItem(ctx, sqlitedb.UpdateTuitionItemParams(arg)))
+}
+
+func (s *SQLiteStore) SetTuitionItemPaid(ctx context.Context, arg db.SetTuitionItemPaidParams) error {
+	return sqliteError(s.q.SetTuitionItemPaid(ctx, sqlitedb.SetTuitionItemPaidParams(arg)))
+}
+
+func (s *SQLiteStore) SetTuitionOutstanding(ctx context.Context, arg db.SetTuitionOutstandingParams) (db.Tuition, error) {
+	tuition, err := s.q.SetTuitionOutstanding(ctx, sqlitedb.SetTuitionOutstandingParams(arg))
+	return db.Tuition(tuition), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListFeeSchedules(ctx context.Context, arg db.ListFeeSchedulesParams) ([]db.FeeSchedule, error) {
+	rows, err := s.q.ListFeeSchedules(ctx, sqlitedb.ListFeeSchedulesParams(arg))
+	var out []db.FeeSchedule
+	for _, row := range rows {
+		out = append(out, db.FeeSchedule(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) GetFeeSchedule(ctx context.Context, scheduleID int32) (db.FeeSchedule, error) {
+	schedule, err := s.q.GetFeeSchedule(ctx, scheduleID)
+	return db.FeeSchedule(schedule), sqliteError(err)
+}
+
+func (s *SQLiteStore) CreateFeeSchedule(ctx context.Context, arg db.CreateFeeScheduleParams) (db.FeeSchedule, error) {
+	schedule, err := s.q.CreateFeeSchedule(ctx, sqlitedb.CreateFeeScheduleParams(arg))
+	return db.FeeSchedule(schedule), sqliteError(err)
+}
+
+func (s *SQLiteStore) UpdateFeeSchedule(ctx context.Context, arg db.UpdateFeeScheduleParams) error {
+	return sqliteError(s.q.UpdateFeeSchedule(ctx, sqlitedb.UpdateFeeScheduleParams(arg)))
+}
+
+func (s *SQLiteStore) DeleteFeeSchedule(ctx context.Context, scheduleID int32) error {
+	return sqliteError(s.q.DeleteFeeSchedule(ctx, scheduleID))
+}
+
+func (s *SQLiteStore) ListScheduledCharges(ctx context.Context, term string) ([]db.ListScheduledChargesRow, error) {
+	rows, err := s.q.ListScheduledCharges(ctx, term)
+	var out []db.ListScheduledChargesRow
+	for _, row := range rows {
+		out = append(out, db.ListScheduledChargesRow(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountScheduledBilled(ctx context.Context, term string) (int64, error) {
+	count, err := s.q.CountScheduledBilled(ctx, term)
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) CreateImportJob(ctx context.Context, arg db.CreateImportJobParams) (db.ImportJob, error) {
+	job, err := s.q.CreateImportJob(ctx, sqlitedb.CreateImportJobParams(arg))
+	return db.ImportJob(job), sqliteError(err)
+}
+
+func (s *SQLiteStore) AddImportJobFile(ctx context.Context, arg db.AddImportJobFileParams) error {
+	return sqliteError(s.q.AddImportJobFile(ctx, sqlitedb.AddImportJobFileParams(arg)))
+}
+
+func (s *SQLiteStore) GetImportJobFile(ctx context.Context, jobID int32) ([]byte, error) {
+	payload, err := s.q.GetImportJobFile(ctx, jobID)
+	return payload, sqliteError(err)
+}
+
+func (s *SQLiteStore) DeleteImportJobFile(ctx context.Context, jobID int32) error {
+	return sqliteError(s.q.DeleteImportJobFile(ctx, jobID))
+}
+
+func (s *SQLiteStore) GetImportJob(ctx context.Context, jobID int32) (db.ImportJob, error) {
+	job, err := s.q.GetImportJob(ctx, jobID)
+	return db.ImportJob(job), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListImportJobs(ctx context.Context, arg db.ListImportJobsParams) ([]db.ImportJob, error) {
+	rows, err := s.q.ListImportJobs(ctx, sqlitedb.ListImportJobsParams{
+		Status:    arg.Status,
+		RowOffset: int64(arg.RowOffset),
+		RowLimit:  int64(arg.RowLimit),
+	})
+	var out []db.ImportJob
+	for _, row := range rows {
+		out = append(out, db.ImportJob(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountImportJobs(ctx context.Context, status pgtype.Text) (int64, error) {
+	count, err := s.q.CountImportJobs(ctx, status)
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) ClaimImportJob(ctx context.Context) (db.ImportJob, error) {
+	job, err := s.q.ClaimImportJob(ctx)
+	return db.ImportJob(job), sqliteError(err)
+}
+
+func (s *SQLiteStore) HeartbeatImportJob(ctx context.Context, jobID int32) error {
+	return sqliteError(s.q.HeartbeatImportJob(ctx, jobID))
+}
+
+func (s *SQLiteStore) UpdateImportJobProgress(ctx context.Context, arg db.UpdateImportJobProgressParams) error {
+	return sqliteError(s.q.UpdateImportJobProgress(ctx, sqlitedb.UpdateImportJobProgressParams(arg)))
+}
+
+func (s *SQLiteStore) FinishImportJob(ctx context.Context, arg db.FinishImportJobParams) error {
+	return sqliteError(s.q.FinishImportJob(ctx, sqlitedb.FinishImportJobParams(arg)))
+}
+
+func (s *SQLiteStore) CancelImportJob(ctx context.Context, jobID int32) error {
+	return sqliteError(s.q.CancelImportJob(ctx, jobID))
+}
+
+func (s *SQLiteStore) AddImportJobError(ctx context.Context, arg db.AddImportJobErrorParams) error {
+	return sqliteError(s.q.AddImportJobError(ctx, sqlitedb.AddImportJobErrorParams(arg)))
+}
+
+func (s *SQLiteStore) ListImportJobErrors(ctx context.Context, jobID int32) ([]db.ImportJobError, error) {
+	rows, err := s.q.ListImportJobErrors(ctx, jobID)
+	var out []db.ImportJobError
+	for _, row := range rows {
+		out = append(out, db.ImportJobError(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) AddReceipt(ctx context.Context, arg db.AddReceiptParams) (db.Receipt, error) {
+	receipt, err := s.q.AddReceipt(ctx, sqlitedb.AddReceiptParams(arg))
+	return db.Receipt(receipt), sqliteError(err)
+}
+
+func (s *SQLiteStore) AddReceiptAllocation(ctx context.Context, arg db.AddReceiptAllocationParams) error {
+	return sqliteError(s.q.AddReceiptAllocation(ctx, sqlitedb.AddReceiptAllocationParams(arg)))
+}
+
+func (s *SQLiteStore) GetReceipt(ctx context.Context, receiptNo string) (db.GetReceiptRow, error) {
+	row, err := s.q.GetReceipt(ctx, receiptNo)
+	return db.GetReceiptRow(row), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListReceiptAllocations(ctx context.Context, receiptNo string) ([]db.ReceiptAllocation, error) {
+	rows, err := s.q.ListReceiptAllocations(ctx, receiptNo)
+	var out []db.ReceiptAllocation
+	for _, row := range rows {
+		out = append(out, db.ReceiptAllocation(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) AddPaymentReference(ctx context.Context, arg db.AddPaymentReferenceParams) (db.PaymentReference, error) {
+	ref, err := s.q.AddPaymentReference(ctx, sqlitedb.AddPaymentReferenceParams(arg))
+	return db.PaymentReference(ref), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetPaymentReference(ctx context.Context, reference string) (db.PaymentReference, error) {
+	ref, err := s.q.GetPaymentReference(ctx, reference)
+	return db.PaymentReference(ref), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetStudentPaymentReference(ctx context.Context, arg db.GetStudentPaymentReferenceParams) (db.PaymentReference, error) {
+	ref, err := s.q.GetStudentPaymentReference(ctx, sqlitedb.GetStudentPaymentReferenceParams(arg))
+	return db.PaymentReference(ref), sqliteError(err)
+}
+
+func (s *SQLiteStore) CountPaymentReferences(ctx context.Context, studentNo string) (int64, error) {
+	count, err := s.q.CountPaymentReferences(ctx, studentNo)
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) AddBankTransaction(ctx context.Context, arg db.AddBankTransactionParams) (db.BankTransaction, error) {
+	t, err := s.q.AddBankTransaction(ctx, sqlitedb.AddBankTransactionParams(arg))
+	return db.BankTransaction(t), sqliteError(err)
+}
+
+func (s *SQLiteStore) CountBankTransactionsByKey(ctx context.Context, entryKey string) (int64, error) {
+	count, err := s.q.CountBankTransactionsByKey(ctx, entryKey)
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) GetBankTransaction(ctx context.Context, transactionID int32) (db.BankTransaction, error) {
+	t, err := s.q.GetBankTransaction(ctx, transactionID)
+	return db.BankTransaction(t), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListBankTransactions(ctx context.Context, arg db.ListBankTransactionsParams) ([]db.BankTransaction, error) {
+	rows, err := s.q.ListBankTransactions(ctx, sqlitedb.ListBankTransactionsParams{
+		Status:    arg.Status,
+		RowOffset: int64(arg.RowOffset),
+		RowLimit:  int64(arg.RowLimit),
+	})
+	var out []db.BankTransaction
+	for _, row := range rows {
+		out = append(out, db.BankTransaction(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountBankTransactions(ctx context.Context, status pgtype.Text) (int64, error) {
+	count, err := s.q.CountBankTransactions(ctx, status)
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) CloseBankTransaction(ctx context.Context, arg db.CloseBankTransactionParams) (db.BankTransaction, error) {
+	t, err := s.q.CloseBankTransaction(ctx, sqlitedb.CloseBankTransactionParams(arg))
+	return db.BankTransaction(t), sqliteError(err)
+}
+
+func (s *SQLiteStore) AddBankPartner(ctx context.Context, arg db.AddBankPartnerParams) (db.BankPartner, error) {
+	partner, err := s.q.AddBankPartner(ctx, sqlitedb.AddBankPartnerParams(arg))
+	return db.BankPartner(partner), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetBankPartner(ctx context.Context, code string) (db.BankPartner, error) {
+	partner, err := s.q.GetBankPartner(ctx, code)
+	return db.BankPartner(partner), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListBankPartners(ctx context.Context) ([]db.BankPartner, error) {
+	rows, err := s.q.ListBankPartners(ctx)
+	var out []db.BankPartner
+	for _, row := range rows {
+		out = append(out, db.BankPartner(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) ListPartnerPayments(ctx context.Context, arg db.ListPartnerPaymentsParams) ([]db.ListPartnerPaymentsRow, error) {
+	rows, err := s.q.ListPartnerPayments(ctx, sqlitedb.ListPartnerPaymentsParams{
+		Partner:  arg.Partner,
+		FromTime: sqliteTime(arg.FromTime).String,
+		ToTime:   sqliteTime(arg.ToTime).String,
+	})
+	var out []db.ListPartnerPaymentsRow
+	for _, row := range rows {
+		out = append(out, db.ListPartnerPaymentsRow(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) AddSettlement(ctx context.Context, arg db.AddSettlementParams) (db.Settlement, error) {
+	settlement, err := s.q.AddSettlement(ctx, sqlitedb.AddSettlementParams(arg))
+	return db.Settlement(settlement), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetSettlement(ctx context.Context, settlementID int32) (db.Settlement, error) {
+	settlement, err := s.q.GetSettlement(ctx, settlementID)
+	return db.Settlement(settlement), sqliteError(err)
+}
+
+func (s *SQLiteStore) GetSettlementByDay(ctx context.Context, arg db.GetSettlementByDayParams) (db.Settlement, error) {
+	settlement, err := s.q.GetSettlementByDay(ctx, sqlitedb.GetSettlementByDayParams(arg))
+	return db.Settlement(settlement), sqliteError(err)
+}
+
+func (s *SQLiteStore) ListSettlements(ctx context.Context, arg db.ListSettlementsParams) ([]db.Settlement, error) {
+	rows, err := s.q.ListSettlements(ctx, sqlitedb.ListSettlementsParams{
+		Partner:   arg.Partner,
+		Status:    arg.Status,
+		RowOffset: int64(arg.RowOffset),
+		RowLimit:  int64(arg.RowLimit),
+	})
+	var out []db.Settlement
+	for _, row := range rows {
+		out = append(out, db.Settlement(row))
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) CountSettlements(ctx context.Context, arg db.CountSettlementsParams) (int64, error) {
+	count, err := s.q.CountSettlements(ctx, sqlitedb.CountSettlementsParams(arg))
+	return count, sqliteError(err)
+}
+
+func (s *SQLiteStore) ReportSettlement(ctx context.Context, arg db.ReportSettlementParams) (db.Settlement, error) {
+	settlement, err := s.q.ReportSettlement(ctx, sqlitedb.ReportSettlementParams(arg))
+	return db.Settlement(settlement), sqliteError(err)
+}
+
+// sqliteTime formats a time like the timestamps SQLite stores, so the two compare
+// as text.
+func sqliteTime(t pgtype.Timestamptz) pgtype.Text {
+	if !t.Valid {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: t.Time.UTC().Format("2006-01-02 15:04:05.000+00:00"), Valid: true}
+}
+
+func (s *SQLiteStore) SummarizeTerms(ctx context.Context, arg db.SummarizeTermsParams) ([]db.SummarizeTermsRow, error) {
+	rows, err := s.q.SummarizeTerms(ctx, sqlitedb.SummarizeTermsParams{FromTime: sqliteTime(arg.FromTime), ToTime: sqliteTime(arg.ToTime)})
+	var out []db.SummarizeTermsRow
+	for _, row := range rows {
+		out = append(out, db.SummarizeTermsRow{
+			Term:        row.Term,
+			Students:    int32(row.Students),
+			FullyPaid:   int32(row.FullyPaid),
+			Billed:      row.Billed,
+			Outstanding: row.Outstanding,
+			Received:    row.Received,
+		})
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) SummarizeStudents(ctx context.Context) (db.SummarizeStudentsRow, error) {
+	row, err := s.q.SummarizeStudents(ctx)
+	return db.SummarizeStudentsRow{Students: int32(row.Students), FullyPaid: int32(row.FullyPaid)}, sqliteError(err)
+}
+
+func (s *SQLiteStore) SummarizePaymentsByDay(ctx context.Context, arg db.SummarizePaymentsByDayParams) ([]db.SummarizePaymentsByDayRow, error) {
+	rows, err := s.q.SummarizePaymentsByDay(ctx, sqlitedb.SummarizePaymentsByDayParams{FromTime: sqliteTime(arg.FromTime), ToTime: sqliteTime(arg.ToTime)})
+	var out []db.SummarizePaymentsByDayRow
+	for _, row := range rows {
+		out = append(out, db.SummarizePaymentsByDayRow{Day: row.Day, Payments: int32(row.Payments), Amount: row.Amount})
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) SummarizePaymentsByChannel(ctx context.Context, arg db.SummarizePaymentsByChannelParams) ([]db.SummarizePaymentsByChannelRow, error) {
+	rows, err := s.q.SummarizePaymentsByChannel(ctx, sqlitedb.SummarizePaymentsByChannelParams{FromTime: sqliteTime(arg.FromTime), ToTime: sqliteTime(arg.ToTime)})
+	var out []db.SummarizePaymentsByChannelRow
+	for _, row := range rows {
+		out = append(out, db.SummarizePaymentsByChannelRow{Channel: row.Channel, Payments: int32(row.Payments), Amount: row.Amount})
+	}
+	return out, sqliteError(err)
+}
+
+func (s *SQLiteStore) ListTopDebtors(ctx context.Context, limit int32) ([]db.ListTopDebtorsRow, error) {
+	rows, err := s.q.ListTopDebtors(ctx, int64(limit))
+	var out []db.ListTopDebtorsRow
+	for _, row := range rows {
+		out = append(out, db.ListTopDebtorsRow{
+			StudentNo:   row.StudentNo,
+			FirstName:   row.FirstName,
+			LastName:    row.LastName,
+			Faculty:     row.Faculty,
+			Tuitions:    int32(row.Tuitions),
+			Outstanding: row.Outstanding,
+		})
+	}
+	return out, sqliteError(err)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"dogukan-dev/tuition/db/sqlitedb"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("dogukan-dev/tuition")
+
+// initTracer installs the global tracer provider selected by OTEL_TRACES_EXPORTER
+// (otlp, stdout, file or none) and returns a function that flushes pending spans.
+func initTracer(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	closeFile := func() error { return nil }
+
+	switch strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		// Endpoint, headers and TLS are read from the standard OTEL_EXPORTER_OTLP_* variables
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			path = "logs/traces.json"
+		}
+		file, ferr := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if ferr != nil {
+			return nil, fmt.Errorf("cannot open trace file: %w", ferr)
+		}
+		closeFile = file.Close
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", os.Getenv("OTEL_TRACES_EXPORTER"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create trace exporter: %w", err)
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "tuition-api"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("cannot build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if cerr := closeFile(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+// Tracing middleware
+// Starts the server span for every request, continuing the caller's trace when a
+// W3C traceparent header is present.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", r.RemoteAddr),
+			),
+		)
+		defer span.End()
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rw.statusCode))
+		if rw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+		}
+	})
+}
+
+// startSpan opens a child span of the request's current span and returns the
+// request carrying it, so every middleware layer shows up separately in a trace.
+func startSpan(r *http.Request, name string) (*http.Request, trace.Span) {
+	ctx, span := tracer.Start(r.Context(), name)
+	return r.WithContext(ctx), span
+}
+
+// traced wraps a handler in its own span.
+func traced(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, span := startSpan(r, name)
+		defer span.End()
+
+		next(w, r)
+	}
+}
+
+// sqlc prefixes every statement with "-- name: <Query> :<kind>"
+var sqlcQueryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// queryTracer implements pgx.QueryTracer and records a span for every
+// db.Queries call made through the connection.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = startQuerySpan(ctx, "postgresql", data.SQL)
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endQuerySpan(trace.SpanFromContext(ctx), data.Err, data.CommandTag.RowsAffected())
+}
+
+func startQuerySpan(ctx context.Context, system, query string) (context.Context, trace.Span) {
+	name := "db.query"
+	if m := sqlcQueryName.FindStringSubmatch(query); m != nil {
+		name = "db." + m[1]
+	}
+
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.query.text", query),
+		),
+	)
+}
+
+// rowsAffected is -1 when unknown
+func endQuerySpan(span trace.Span, err error, rowsAffected int64) {
+	defer span.End()
+
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if rowsAffected >= 0 {
+		span.SetAttributes(attribute.Int64("db.response.rows_affected", rowsAffected))
+	}
+}
+
+// tracedSQL wraps a database/sql connection (the SQLite backend) so its sqlc
+// queries are traced like the pgx ones.
+type tracedSQL struct {
+	sqlitedb.DBTX
+}
+
+func (t tracedSQL) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, "sqlite", query)
+	result, err := t.DBTX.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		rows, _ = result.RowsAffected()
+	}
+	endQuerySpan(span, err, rows)
+	return result, err
+}
+
+func (t tracedSQL) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, "sqlite", query)
+	rows, err := t.DBTX.QueryContext(ctx, query, args...)
+	endQuerySpan(span, err, -1)
+	return rows, err
+}
+
+func (t tracedSQL) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, "sqlite", query)
+	row := t.DBTX.QueryRowContext(ctx, query, args...)
+	endQuerySpan(span, row.Err(), -1)
+	return row
+}